@@ -18,7 +18,10 @@ package v1alpha1
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -27,6 +30,7 @@ import (
 
 	"github.com/Masterminds/semver/v3"
 	hrepo "helm.sh/helm/v3/pkg/repo"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -34,6 +38,8 @@ import (
 	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	kustomize "sigs.k8s.io/kustomize/api/types"
+
+	"github.com/kubebb/core/pkg/utils"
 )
 
 const (
@@ -102,8 +108,44 @@ type ValuesReference struct {
 	// +kubebuilder:validation:Pattern=`^([a-zA-Z0-9_\-.\\\/]|\[[0-9]{1,5}\])+$`
 	// +optional
 	TargetPath string `json:"targetPath,omitempty"`
+
+	// Decryption decrypts the sops encrypted values in the values file
+	// +optional
+	Decryption *ValuesDecryption `json:"decryption,omitempty"`
+}
+
+// ValuesDecryption defines how to decrypt the sops encrypted values,
+// the values must be encrypted for an age recipient whose identity is read from a Secret.
+type ValuesDecryption struct {
+	// SecretRef is the name of the Secret which holds the age identity, the Secret should reside in the same namespace.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	SecretRef string `json:"secretRef"`
+
+	// Key is the data key of the Secret where the age identity can be found at. Defaults to 'sops.key'.
+	// +optional
+	Key string `json:"key,omitempty"`
 }
 
+func (d *ValuesDecryption) GetKey() string {
+	if len(d.Key) == 0 {
+		return "sops.key"
+	}
+	return d.Key
+}
+
+// ValuesFromPolicy decides what happens when the content of ValuesFrom changes after the release is installed
+type ValuesFromPolicy string
+
+const (
+	// ValuesFromPolicyAuto upgrades the release automatically
+	ValuesFromPolicyAuto ValuesFromPolicy = "Auto"
+	// ValuesFromPolicyManual only records an event, the release is upgraded when the ComponentPlan spec changes
+	ValuesFromPolicyManual ValuesFromPolicy = "Manual"
+	// ValuesFromPolicyIgnore ignores the change
+	ValuesFromPolicyIgnore ValuesFromPolicy = "Ignore"
+)
+
 func (v *ValuesReference) GetValuesKey() string {
 	if len(v.ValuesKey) == 0 {
 		return "values.yaml"
@@ -124,6 +166,10 @@ type Override struct {
 	// ValuesFrom holds references to resources containing Helm values for this HelmRelease,
 	// and information about how they should be merged.
 	ValuesFrom []*ValuesReference `json:"valuesFrom,omitempty"`
+	// ValuesFromPolicy decides what happens when the content of ValuesFrom changes, default is Auto
+	// +kubebuilder:validation:Enum=Auto;Manual;Ignore
+	// +optional
+	ValuesFromPolicy ValuesFromPolicy `json:"valuesFromPolicy,omitempty"`
 	// Values holds the values for this Helm release.
 	// +optional
 	Values *apiextensionsv1.JSON `json:"values,omitempty"`
@@ -151,13 +197,17 @@ func (v *Override) GetValueFileDir(helmCacheHome, namespace, name string) string
 	return filepath.Join(helmCacheHome, "embed."+namespace+"."+name)
 }
 
-// Parse parses ValuesReference
-func (v *ValuesReference) Parse(ctx context.Context, cli client.Client, ns, dir string) (fileName string, err error) {
-	if dir == "" {
-		return "", nil
+// GetValuesFromPolicy returns the ValuesFromPolicy, default is Auto
+func (v *Override) GetValuesFromPolicy() ValuesFromPolicy {
+	if v.ValuesFromPolicy == "" {
+		return ValuesFromPolicyAuto
 	}
+	return v.ValuesFromPolicy
+}
+
+// GetData gets the raw values data from the referenced ConfigMap or Secret
+func (v *ValuesReference) GetData(ctx context.Context, cli client.Client, ns string) (data string, err error) {
 	n := types.NamespacedName{Namespace: ns, Name: v.Name}
-	var data string
 	ok := false
 	switch v.Kind {
 	case "ConfigMap":
@@ -189,6 +239,50 @@ func (v *ValuesReference) Parse(ctx context.Context, cli client.Client, ns, dir
 	default:
 		return "", errors.New("no Kind setting found")
 	}
+	return data, nil
+}
+
+// GetDecryptionKey gets the age identity in the decryption Secret
+func (v *ValuesReference) GetDecryptionKey(ctx context.Context, cli client.Client, ns string) ([]byte, error) {
+	secret := corev1.Secret{}
+	if err := cli.Get(ctx, types.NamespacedName{Namespace: ns, Name: v.Decryption.SecretRef}, &secret); err != nil {
+		return nil, err
+	}
+	key, ok := secret.Data[v.Decryption.GetKey()]
+	if !ok || len(key) == 0 {
+		return nil, fmt.Errorf("no data key %s found in secret %s", v.Decryption.GetKey(), v.Decryption.SecretRef)
+	}
+	return key, nil
+}
+
+// Decrypt decrypts the sops encrypted values with the age identity in the decryption Secret
+func (v *ValuesReference) Decrypt(ctx context.Context, cli client.Client, ns, data string) (string, error) {
+	if v.Decryption == nil {
+		return data, nil
+	}
+	key, err := v.GetDecryptionKey(ctx, cli, ns)
+	if err != nil {
+		return "", err
+	}
+	out, err := utils.DecryptSOPSValues([]byte(data), key)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// Parse parses ValuesReference
+func (v *ValuesReference) Parse(ctx context.Context, cli client.Client, ns, dir string) (fileName string, err error) {
+	if dir == "" {
+		return "", nil
+	}
+	data, err := v.GetData(ctx, cli, ns)
+	if err != nil {
+		return "", err
+	}
+	if data, err = v.Decrypt(ctx, cli, ns, data); err != nil {
+		return "", err
+	}
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return "", err
 	}
@@ -203,6 +297,43 @@ func (v *ValuesReference) Parse(ctx context.Context, cli client.Client, ns, dir
 	return filepath.Join(dir, v.GetValuesKey()), nil
 }
 
+// ValuesFromHash calculates the hash of the raw content of all ValuesFrom references,
+// the decryption keys are hashed too, so a rotated key is taken as a change.
+func ValuesFromHash(ctx context.Context, cli client.Client, ns string, refs []*ValuesReference) (string, error) {
+	if len(refs) == 0 {
+		return "", nil
+	}
+	h := sha256.New()
+	for _, ref := range refs {
+		data, err := ref.GetData(ctx, cli, ns)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s/%s/%s\n%s\n", ref.Kind, ref.Name, ref.GetValuesKey(), data)
+		if ref.Decryption == nil {
+			continue
+		}
+		key, err := ref.GetDecryptionKey(ctx, cli, ns)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "Secret/%s/%s\n%s\n", ref.Decryption.SecretRef, ref.Decryption.GetKey(), key)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ValuesFromIndexValues returns the index values of the ConfigMaps and Secrets referenced by ValuesFrom,
+// including the Secrets holding the decryption keys, in the format of Kind/Name
+func ValuesFromIndexValues(refs []*ValuesReference) (res []string) {
+	for _, ref := range refs {
+		res = utils.AddString(res, ref.Kind+"/"+ref.Name)
+		if ref.Decryption != nil {
+			res = utils.AddString(res, "Secret/"+ref.Decryption.SecretRef)
+		}
+	}
+	return res
+}
+
 // Config defines the configuration of the ComponentPlan
 // Greatly inspired by https://github.com/helm/helm/blob/2398830f183b6d569224ae693ae9215fed5d1372/cmd/helm/install.go#L161
 // And https://github.com/helm/helm/blob/2398830f183b6d569224ae693ae9215fed5d1372/cmd/helm/upgrade.go#L70
@@ -291,6 +422,175 @@ type Config struct {
 	// RecreatePods is pass to helm rollback --recreate-pods
 	// performs pods restart for the resource if applicable. default is false
 	RecreatePods bool `json:"recreatePods,omitempty"`
+
+	// UninstallPolicy decides what happens to the helm release when the ComponentPlan is deleted.
+	// Delete (the default) uninstalls the release, Orphan removes the ComponentPlan but keeps the release and its workload.
+	// +kubebuilder:validation:Enum=Delete;Orphan
+	// +optional
+	UninstallPolicy UninstallPolicy `json:"uninstallPolicy,omitempty"`
+
+	// UninstallBackup exports all resources of the release into a Secret or ConfigMap before uninstalling
+	// +optional
+	UninstallBackup *UninstallBackup `json:"uninstallBackup,omitempty"`
+
+	// Verification runs after install or upgrade, the ComponentPlan is only succeeded when the verification passes
+	// +optional
+	Verification *Verification `json:"verification,omitempty"`
+
+	// TargetCluster is the member cluster where the release is installed, default is the cluster where kubebb runs
+	// +optional
+	TargetCluster *TargetCluster `json:"targetCluster,omitempty"`
+
+	// PrePull pulls the images on the nodes by a DaemonSet before installing or upgrading,
+	// so the release does not fail with ImagePullBackOff when the images are slow to pull.
+	// +optional
+	PrePull *PrePull `json:"prePull,omitempty"`
+
+	// PostRender customizes the manifests rendered by helm with kustomize,
+	// in the same kustomization with the Images of Override and before the image overrides of the Repository.
+	// +optional
+	PostRender *PostRender `json:"postRender,omitempty"`
+}
+
+// PostRender is the kustomization applied to the rendered manifests, see https://kubectl.docs.kubernetes.io/references/kustomize/kustomization
+type PostRender struct {
+	// Patches are strategic merge patches or JSON 6902 patches, the Target is required by JSON 6902 patches
+	// +optional
+	Patches []PostRenderPatch `json:"patches,omitempty"`
+	// CommonLabels are added to the metadata of all resources, but not to the selectors and pod templates
+	// +optional
+	CommonLabels map[string]string `json:"commonLabels,omitempty"`
+	// CommonAnnotations are added to the metadata of all resources and pod templates
+	// +optional
+	CommonAnnotations map[string]string `json:"commonAnnotations,omitempty"`
+	// Namespace is set to all namespaced resources, only the namespace of the ComponentPlan is allowed.
+	// The patches and Components can only modify the resources of the chart, without moving them to other namespaces either.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Components are the kustomize Components applied in order, before the fields above.
+	// +optional
+	Components []PostRenderComponent `json:"components,omitempty"`
+}
+
+// PostRenderPatch is a patch of kustomize
+type PostRenderPatch struct {
+	// Patch is the content of the patch in yaml or json
+	// +kubebuilder:validation:MinLength=1
+	Patch string `json:"patch"`
+	// Target selects the resources to patch, the resource with the same name and kind of a strategic merge patch is selected if it is not set
+	// +optional
+	Target *PostRenderPatchTarget `json:"target,omitempty"`
+}
+
+// PostRenderPatchTarget selects the resources to patch, the fields except selectors are regular expressions
+type PostRenderPatchTarget struct {
+	Group              string `json:"group,omitempty"`
+	Version            string `json:"version,omitempty"`
+	Kind               string `json:"kind,omitempty"`
+	Name               string `json:"name,omitempty"`
+	Namespace          string `json:"namespace,omitempty"`
+	LabelSelector      string `json:"labelSelector,omitempty"`
+	AnnotationSelector string `json:"annotationSelector,omitempty"`
+}
+
+// PostRenderComponent refers to a ConfigMap in the namespace of the ComponentPlan which holds a kustomize Component,
+// each key is a file of the Component and the key kustomization.yaml is required.
+// The changes of the ConfigMap take effect when the ComponentPlan is installed or upgraded next time.
+type PostRenderComponent struct {
+	// ConfigMapRef is the name of the ConfigMap
+	// +kubebuilder:validation:MinLength=1
+	ConfigMapRef string `json:"configMapRef"`
+}
+
+// PrePull defines where to pull the images before installing
+type PrePull struct {
+	// NodeSelector selects the nodes to pull the images, default is all nodes
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Tolerations allow the images to be pulled on the tainted nodes, default is to pull on the untainted nodes only
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+}
+
+// TargetCluster refers to a Secret which holds the kubeconfig of a member cluster.
+// The release is installed in the namespace with the same name as the ComponentPlan in the member cluster.
+type TargetCluster struct {
+	// SecretRef is the name of the kubeconfig Secret, the Secret should reside in the same namespace.
+	// +kubebuilder:validation:MinLength=1
+	// +required
+	SecretRef string `json:"secretRef"`
+
+	// Key is the data key of the Secret where the kubeconfig can be found at. Defaults to 'kubeconfig'.
+	// +optional
+	Key string `json:"key,omitempty"`
+}
+
+func (t *TargetCluster) GetKey() string {
+	if len(t.Key) == 0 {
+		return "kubeconfig"
+	}
+	return t.Key
+}
+
+// GetKubeConfig gets the kubeconfig of the member cluster and the resourceVersion of the Secret
+func (t *TargetCluster) GetKubeConfig(ctx context.Context, cli client.Client, ns string) (kubeconfig []byte, resourceVersion string, err error) {
+	secret := corev1.Secret{}
+	if err = cli.Get(ctx, types.NamespacedName{Namespace: ns, Name: t.SecretRef}, &secret); err != nil {
+		return nil, "", err
+	}
+	kubeconfig, ok := secret.Data[t.GetKey()]
+	if !ok || len(kubeconfig) == 0 {
+		return nil, "", fmt.Errorf("no kubeconfig %s found in secret %s", t.GetKey(), t.SecretRef)
+	}
+	return kubeconfig, secret.GetResourceVersion(), nil
+}
+
+// Verification defines the checks to run after the release is installed or upgraded
+type Verification struct {
+	// HelmTest runs the test hooks of the chart, like `helm test`
+	// +optional
+	HelmTest bool `json:"helmTest,omitempty"`
+
+	// JobTemplate is a user-supplied Job which runs in the namespace of the ComponentPlan,
+	// the verification passes when the Job completes
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	JobTemplate *batchv1.JobTemplateSpec `json:"jobTemplate,omitempty"`
+
+	// RollbackOnFailure rolls the release back to the previous revision when the verification fails
+	// +optional
+	RollbackOnFailure bool `json:"rollbackOnFailure,omitempty"`
+
+	// TimeoutSeconds is the timeout of the whole verification, default is 300s
+	// +optional
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+func (v *Verification) Timeout() time.Duration {
+	if v.TimeoutSeconds == 0 {
+		return 300 * time.Second
+	}
+	return time.Duration(v.TimeoutSeconds) * time.Second
+}
+
+// UninstallPolicy decides what happens to the helm release when the ComponentPlan is deleted
+type UninstallPolicy string
+
+const (
+	UninstallPolicyDelete UninstallPolicy = "Delete"
+	UninstallPolicyOrphan UninstallPolicy = "Orphan"
+)
+
+// UninstallBackup defines where to export the release resources before uninstalling
+type UninstallBackup struct {
+	// Kind of the backup bundle, Secret or ConfigMap
+	// +kubebuilder:validation:Enum=Secret;ConfigMap
+	// +kubebuilder:default=Secret
+	Kind string `json:"kind,omitempty"`
+	// Name of the backup bundle, default is backup.<ComponentPlan name>
+	// +optional
+	Name string `json:"name,omitempty"`
 }
 
 func (c *Config) Timeout() time.Duration {
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 	"testing"
@@ -26,6 +27,7 @@ import (
 	hrepo "helm.sh/helm/v3/pkg/repo"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestUpdateCondwithFixedLen(t *testing.T) {
@@ -914,6 +916,146 @@ func TestGetValuesKey(t *testing.T) {
 	}
 }
 
+// TestValuesDecryptionGetKey for ValuesDecryption.GetKey
+func TestValuesDecryptionGetKey(t *testing.T) {
+	testCases := []struct {
+		obj    ValuesDecryption
+		expect string
+	}{
+		{obj: ValuesDecryption{}, expect: "sops.key"},
+		{obj: ValuesDecryption{Key: "age.key"}, expect: "age.key"},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("test: %d", i), func(t *testing.T) {
+			if r := tc.obj.GetKey(); r != tc.expect {
+				t.Fatalf("Test Failed, expected: %v, got: %v", tc.expect, r)
+			}
+		})
+	}
+}
+
+// TestGetValuesFromPolicy for Override.GetValuesFromPolicy
+func TestGetValuesFromPolicy(t *testing.T) {
+	testCases := []struct {
+		obj    Override
+		expect ValuesFromPolicy
+	}{
+		{obj: Override{}, expect: ValuesFromPolicyAuto},
+		{obj: Override{ValuesFromPolicy: ValuesFromPolicyManual}, expect: ValuesFromPolicyManual},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("test: %d", i), func(t *testing.T) {
+			if r := tc.obj.GetValuesFromPolicy(); r != tc.expect {
+				t.Fatalf("Test Failed, expected: %v, got: %v", tc.expect, r)
+			}
+		})
+	}
+}
+
+// TestValuesFromIndexValues for ValuesFromIndexValues
+func TestValuesFromIndexValues(t *testing.T) {
+	testCases := []struct {
+		refs   []*ValuesReference
+		expect []string
+	}{
+		{refs: nil, expect: nil},
+		{
+			refs: []*ValuesReference{
+				{Kind: "ConfigMap", Name: "a"},
+				{Kind: "Secret", Name: "b", Decryption: &ValuesDecryption{SecretRef: "key"}},
+				{Kind: "Secret", Name: "key"},
+			},
+			expect: []string{"ConfigMap/a", "Secret/b", "Secret/key"},
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("test: %d", i), func(t *testing.T) {
+			if r := ValuesFromIndexValues(tc.refs); !reflect.DeepEqual(r, tc.expect) {
+				t.Fatalf("Test Failed, expected: %v, got: %v", tc.expect, r)
+			}
+		})
+	}
+}
+
+// TestValuesFromHash for ValuesFromHash
+func TestValuesFromHash(t *testing.T) {
+	cm := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"}, Data: map[string]string{"values.yaml": "a: 1"}}
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default"}, Data: map[string][]byte{"values.yaml": []byte("b: 2")}}
+	refs := []*ValuesReference{{Kind: "ConfigMap", Name: "a"}, {Kind: "Secret", Name: "b"}}
+	cli := fake.NewClientBuilder().WithObjects(cm, secret).Build()
+
+	empty, err := ValuesFromHash(context.Background(), cli, "default", nil)
+	if err != nil || empty != "" {
+		t.Fatalf("Test Failed, expected empty hash, got: %v %v", empty, err)
+	}
+	before, err := ValuesFromHash(context.Background(), cli, "default", refs)
+	if err != nil || before == "" {
+		t.Fatalf("Test Failed, expected hash, got: %v %v", before, err)
+	}
+	secret.Data["values.yaml"] = []byte("b: 3")
+	if err = cli.Update(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+	after, err := ValuesFromHash(context.Background(), cli, "default", refs)
+	if err != nil || after == before {
+		t.Fatalf("Test Failed, expected hash changed, got: %v %v", after, err)
+	}
+	if _, err = ValuesFromHash(context.Background(), cli, "default", []*ValuesReference{{Kind: "Secret", Name: "none"}}); err == nil {
+		t.Fatalf("Test Failed, expected error for missing secret")
+	}
+
+	key := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "key", Namespace: "default"}, Data: map[string][]byte{"sops.key": []byte("key-1")}}
+	if err = cli.Create(context.Background(), key); err != nil {
+		t.Fatal(err)
+	}
+	encrypted := []*ValuesReference{{Kind: "Secret", Name: "b", Decryption: &ValuesDecryption{SecretRef: "key"}}}
+	before, err = ValuesFromHash(context.Background(), cli, "default", encrypted)
+	if err != nil || before == "" {
+		t.Fatalf("Test Failed, expected hash, got: %v %v", before, err)
+	}
+	key.Data["sops.key"] = []byte("key-2")
+	if err = cli.Update(context.Background(), key); err != nil {
+		t.Fatal(err)
+	}
+	after, err = ValuesFromHash(context.Background(), cli, "default", encrypted)
+	if err != nil || after == before {
+		t.Fatalf("Test Failed, expected hash changed after decryption key rotated, got: %v %v", after, err)
+	}
+	if _, err = ValuesFromHash(context.Background(), cli, "default", []*ValuesReference{{Kind: "Secret", Name: "b", Decryption: &ValuesDecryption{SecretRef: "none"}}}); err == nil {
+		t.Fatalf("Test Failed, expected error for missing decryption secret")
+	}
+}
+
+// TestTargetClusterGetKubeConfig for TargetCluster.GetKey and TargetCluster.GetKubeConfig
+func TestTargetClusterGetKubeConfig(t *testing.T) {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "member", Namespace: "default"},
+		Data:       map[string][]byte{"kubeconfig": []byte("apiVersion: v1"), "other": nil},
+	}
+	cli := fake.NewClientBuilder().WithObjects(secret).Build()
+	testCases := []struct {
+		obj     TargetCluster
+		key     string
+		expect  string
+		wantErr bool
+	}{
+		{obj: TargetCluster{SecretRef: "member"}, key: "kubeconfig", expect: "apiVersion: v1"},
+		{obj: TargetCluster{SecretRef: "member", Key: "other"}, key: "other", wantErr: true},
+		{obj: TargetCluster{SecretRef: "none"}, key: "kubeconfig", wantErr: true},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("test: %d", i), func(t *testing.T) {
+			if r := tc.obj.GetKey(); r != tc.key {
+				t.Fatalf("Test Failed, expected: %v, got: %v", tc.key, r)
+			}
+			r, _, err := tc.obj.GetKubeConfig(context.Background(), cli, "default")
+			if (err != nil) != tc.wantErr || string(r) != tc.expect {
+				t.Fatalf("Test Failed, expected: %v %v, got: %v %v", tc.expect, tc.wantErr, string(r), err)
+			}
+		})
+	}
+}
+
 // TestValuesReferenceGetValuesFileDir for ValuesReference.GetValuesFileDir
 func TestValuesReferenceGetValuesFileDir(t *testing.T) {
 	testCases := []struct {
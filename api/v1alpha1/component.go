@@ -16,7 +16,12 @@ limitations under the License.
 
 package v1alpha1
 
-import "github.com/Masterminds/semver/v3"
+import (
+	"sort"
+	"strconv"
+
+	"github.com/Masterminds/semver/v3"
+)
 
 const (
 	AddEventMsgTemplate    = "add new component %s"
@@ -77,3 +82,101 @@ func ComponentVersionDiff(o, n Component) ([]string, []string, []string) {
 func GetComponentChartValuesConfigmapName(componentName, version string) string {
 	return componentName + "-" + version
 }
+
+// SetVersionScore records the score of one version, the old score of the same version is replaced.
+// Scores are sorted by version in descending order, and a score is marked as Regression
+// when it is lower than the score of the previous version.
+func (status *ComponentStatus) SetVersionScore(score ComponentVersionScore) {
+	replaced := false
+	for i := range status.Scores {
+		if status.Scores[i].Version == score.Version {
+			status.Scores[i] = score
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		status.Scores = append(status.Scores, score)
+	}
+	sort.SliceStable(status.Scores, func(i, j int) bool {
+		vi, erri := semver.NewVersion(status.Scores[i].Version)
+		vj, errj := semver.NewVersion(status.Scores[j].Version)
+		if erri != nil || errj != nil {
+			return status.Scores[i].Version > status.Scores[j].Version
+		}
+		return vi.GreaterThan(vj)
+	})
+	for i := range status.Scores {
+		status.Scores[i].Regression = false
+		if i+1 == len(status.Scores) {
+			continue
+		}
+		cur, err := strconv.ParseFloat(status.Scores[i].Score, 64)
+		if err != nil {
+			continue
+		}
+		prev, err := strconv.ParseFloat(status.Scores[i+1].Score, 64)
+		if err != nil {
+			continue
+		}
+		status.Scores[i].Regression = cur < prev
+	}
+}
+
+// LatestVersion returns the latest version which is not deprecated
+func (c *Component) LatestVersion() (latest ComponentVersion, ok bool) {
+	var latestSem *semver.Version
+	for _, v := range c.Status.Versions {
+		if v.Deprecated {
+			continue
+		}
+		sem, err := semver.NewVersion(v.Version)
+		if err != nil {
+			continue
+		}
+		if latestSem == nil || sem.GreaterThan(latestSem) {
+			latest, latestSem = v, sem
+		}
+	}
+	return latest, latestSem != nil
+}
+
+// NewVersionsToRate returns the versions not rated yet among the newest limit versions, from the newest to the oldest,
+// so the newest version is rated first and the old versions are never rated. There is no limit if limit is not positive.
+// The deprecated versions and the ones not following semver are skipped.
+func (c *Component) NewVersionsToRate(rated map[string]bool, limit int) []ComponentVersion {
+	type semVersion struct {
+		ComponentVersion
+		sem *semver.Version
+	}
+	versions := make([]semVersion, 0)
+	for _, v := range c.Status.Versions {
+		if v.Deprecated {
+			continue
+		}
+		if sem, err := semver.NewVersion(v.Version); err == nil {
+			versions = append(versions, semVersion{ComponentVersion: v, sem: sem})
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].sem.GreaterThan(versions[j].sem) })
+	if limit > 0 && len(versions) > limit {
+		versions = versions[:limit]
+	}
+	res := make([]ComponentVersion, 0, len(versions))
+	for _, v := range versions {
+		if !rated[v.Version] {
+			res = append(res, v.ComponentVersion)
+		}
+	}
+	return res
+}
+
+// GetVersion returns the version of the component
+func (c *Component) GetVersion(version string) (ComponentVersion, bool) {
+	for _, v := range c.Status.Versions {
+		if v.Version == version {
+			return v, true
+		}
+	}
+	return ComponentVersion{}, false
+}
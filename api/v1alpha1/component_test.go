@@ -277,3 +277,123 @@ func TestComponentVersionDiff(t *testing.T) {
 		})
 	}
 }
+
+func TestSetVersionScore(t *testing.T) {
+	type input struct {
+		name      string
+		scores    []ComponentVersionScore
+		score     ComponentVersionScore
+		expScores []ComponentVersionScore
+	}
+	testCases := []input{
+		{
+			name:      "first score",
+			score:     ComponentVersionScore{Version: "0.1.0", Score: "8.00"},
+			expScores: []ComponentVersionScore{{Version: "0.1.0", Score: "8.00"}},
+		},
+		{
+			name:   "new version regression",
+			scores: []ComponentVersionScore{{Version: "0.1.0", Score: "8.00"}},
+			score:  ComponentVersionScore{Version: "0.2.0", Score: "7.50"},
+			expScores: []ComponentVersionScore{
+				{Version: "0.2.0", Score: "7.50", Regression: true},
+				{Version: "0.1.0", Score: "8.00"},
+			},
+		},
+		{
+			name: "old version rated again",
+			scores: []ComponentVersionScore{
+				{Version: "0.10.0", Score: "7.50", Regression: true},
+				{Version: "0.2.0", Score: "8.00"},
+			},
+			score: ComponentVersionScore{Version: "0.2.0", Score: "6.00"},
+			expScores: []ComponentVersionScore{
+				{Version: "0.10.0", Score: "7.50"},
+				{Version: "0.2.0", Score: "6.00"},
+			},
+		},
+		{
+			name:   "old version inserted",
+			scores: []ComponentVersionScore{{Version: "0.3.0", Score: "8.00"}, {Version: "0.1.0", Score: "7.00"}},
+			score:  ComponentVersionScore{Version: "0.2.0", Score: "9.00"},
+			expScores: []ComponentVersionScore{
+				{Version: "0.3.0", Score: "8.00", Regression: true},
+				{Version: "0.2.0", Score: "9.00"},
+				{Version: "0.1.0", Score: "7.00"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("test: %s", tc.name), func(t *testing.T) {
+			status := ComponentStatus{Scores: tc.scores}
+			status.SetVersionScore(tc.score)
+			if !reflect.DeepEqual(status.Scores, tc.expScores) {
+				t.Fatalf("%s expect %v get %v\n", tc.name, tc.expScores, status.Scores)
+			}
+		})
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	type input struct {
+		name     string
+		versions []ComponentVersion
+		exp      string
+		expOK    bool
+	}
+	for _, tc := range []input{
+		{name: "no versions"},
+		{name: "only deprecated", versions: []ComponentVersion{{Version: "0.2.0", Deprecated: true}}},
+		{
+			name:     "skip deprecated and invalid",
+			versions: []ComponentVersion{{Version: "0.3.0", Deprecated: true}, {Version: "invalid"}, {Version: "0.1.0"}, {Version: "0.2.0"}},
+			exp:      "0.2.0",
+			expOK:    true,
+		},
+	} {
+		c := Component{Status: ComponentStatus{Versions: tc.versions}}
+		if r, ok := c.LatestVersion(); r.Version != tc.exp || ok != tc.expOK {
+			t.Fatalf("%s expect %s %v get %s %v", tc.name, tc.exp, tc.expOK, r.Version, ok)
+		}
+	}
+}
+
+func TestNewVersionsToRate(t *testing.T) {
+	versions := []ComponentVersion{{Version: "0.1.0"}, {Version: "0.4.0", Deprecated: true}, {Version: "0.3.0"}, {Version: "invalid"}, {Version: "0.2.0"}, {Version: "0.3.1"}}
+	for _, tc := range []struct {
+		name  string
+		rated map[string]bool
+		limit int
+		exp   []string
+	}{
+		{name: "no version rated", limit: 2, exp: []string{"0.3.1", "0.3.0"}},
+		{name: "no limit", exp: []string{"0.3.1", "0.3.0", "0.2.0", "0.1.0"}},
+		{name: "old version rated", limit: 3, rated: map[string]bool{"0.1.0": true}, exp: []string{"0.3.1", "0.3.0", "0.2.0"}},
+		{name: "version older than the rated", limit: 3, rated: map[string]bool{"0.3.0": true, "0.3.1": true}, exp: []string{"0.2.0"}},
+		{name: "latest rated", limit: 1, rated: map[string]bool{"0.3.1": true}, exp: []string{}},
+	} {
+		c := Component{Status: ComponentStatus{Versions: versions}}
+		got := make([]string, 0)
+		for _, v := range c.NewVersionsToRate(tc.rated, tc.limit) {
+			got = append(got, v.Version)
+		}
+		if !reflect.DeepEqual(got, tc.exp) {
+			t.Fatalf("%s expect %v get %v", tc.name, tc.exp, got)
+		}
+	}
+}
+
+func TestGetVersion(t *testing.T) {
+	c := Component{Status: ComponentStatus{Versions: []ComponentVersion{{Version: "0.1.0"}, {Version: "0.2.0", Deprecated: true}}}}
+	for _, tc := range []struct {
+		version string
+		expOK   bool
+	}{
+		{version: "0.2.0", expOK: true},
+		{version: "0.3.0"},
+	} {
+		if r, ok := c.GetVersion(tc.version); ok != tc.expOK || (ok && r.Version != tc.version) {
+			t.Fatalf("Test Failed. expect %s %v get %s %v", tc.version, tc.expOK, r.Version, ok)
+		}
+	}
+}
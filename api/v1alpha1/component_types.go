@@ -52,6 +52,24 @@ type ComponentStatus struct {
 	// The current component is not in the return list of URLs
 	// and will not be deleted but marked as deprecated by this field.
 	Deprecated bool `json:"deprecated,omitempty"`
+	// Scores is the rating history of the versions, sorted by version in descending order
+	Scores []ComponentVersionScore `json:"scores,omitempty"`
+}
+
+// ComponentVersionScore is the aggregate score of one version of the component
+type ComponentVersionScore struct {
+	// Version of the component
+	Version string `json:"version"`
+	// Score is the weighted average of the final ratings of all dimensions
+	Score string `json:"score"`
+	// Dimensions contains the final rating of each dimension
+	Dimensions map[string]string `json:"dimensions,omitempty"`
+	// Rating is the name of the Rating which gives the score
+	Rating string `json:"rating,omitempty"`
+	// RatedAt is the time when the score is given
+	RatedAt metav1.Time `json:"ratedAt,omitempty"`
+	// Regression is true when the score is lower than the one of the previous version
+	Regression bool `json:"regression,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -18,7 +18,9 @@ package v1alpha1
 
 import (
 	"context"
+	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
@@ -28,6 +30,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 
 	"github.com/kubebb/core/pkg/utils"
 )
@@ -36,6 +39,15 @@ const (
 	ComponentPlanReleaseNameLabel     = Group + "/componentplan-release"
 	ComponentPlanRetryTimesAnnotation = Group + "/componentplan-retry"
 	ComponentPlanRollBackLabel        = Group + "/rollback"
+	// ComponentPlanAdoptReleaseAnnotation marks a ComponentPlan to take over an existing helm release
+	// which is installed by plain helm, instead of installing or upgrading it.
+	ComponentPlanAdoptReleaseAnnotation = Group + "/adopt-release"
+	// ComponentPlanDeletionProtectionAnnotation with value "true" makes the webhook reject the deletion of the ComponentPlan
+	ComponentPlanDeletionProtectionAnnotation = Group + "/deletion-protection"
+	// ComponentPlanValuesFromHashAnnotation records the ValuesFrom hash on the manifest ConfigMap when it is generated
+	ComponentPlanValuesFromHashAnnotation = Group + "/valuesfrom-hash"
+	// ComponentPlanImageOverrideHashAnnotation records the hash of the image override rules on the manifest ConfigMap when it is generated
+	ComponentPlanImageOverrideHashAnnotation = Group + "/imageoverride-hash"
 )
 
 // ConditionType for ComponentPlan
@@ -43,6 +55,7 @@ const (
 	ComponentPlanTypeSucceeded ConditionType = "Succeeded"
 	ComponentPlanTypeApproved  ConditionType = "Approved"
 	ComponentPlanTypeActioned  ConditionType = "Actioned"
+	ComponentPlanTypeVerified  ConditionType = "Verified"
 )
 
 // Condition resons for ComponentPlan
@@ -60,6 +73,11 @@ const (
 	ComponentPlanReasonUpgradeFailed    ConditionReason = "UpgradeFailed"
 	ComponentPlanReasonRollBackSuccess  ConditionReason = "RollBackSuccess"
 	ComponentPlanReasonRollBackFailed   ConditionReason = "RollBackFailed"
+	ComponentPlanReasonAdoptSuccess     ConditionReason = "AdoptSuccess"
+	ComponentPlanReasonVerifying        ConditionReason = "Verifying"
+	ComponentPlanReasonVerifySuccess    ConditionReason = "VerifySuccess"
+	ComponentPlanReasonVerifyFailed     ConditionReason = "VerifyFailed"
+	ComponentPlanReasonPolicyViolation  ConditionReason = "PolicyViolation"
 )
 
 // GenerateComponentPlanName generates the name of the component plan for a given subscription
@@ -72,6 +90,51 @@ func GenerateComponentPlanManifestConfigMapName(plan *ComponentPlan) string {
 	return "manifest." + plan.Name
 }
 
+// GenerateComponentPlanBackupName generates the name of the backup bundle of the component plan
+func GenerateComponentPlanBackupName(plan *ComponentPlan) string {
+	if plan.Spec.UninstallBackup != nil && plan.Spec.UninstallBackup.Name != "" {
+		return plan.Spec.UninstallBackup.Name
+	}
+	return "backup." + plan.Name
+}
+
+// GenerateComponentPlanPrePullName generates the name of the DaemonSet to pre-pull the images of the component plan
+func GenerateComponentPlanPrePullName(plan *ComponentPlan) string {
+	return "prepull." + plan.Name
+}
+
+// NeedPrePull returns true if the images of the current generation need to be pre-pulled before installing
+func (c *ComponentPlan) NeedPrePull() bool {
+	return c.Spec.PrePull != nil && len(c.Status.Images) > 0 && c.Status.PrePullGeneration != c.Generation
+}
+
+// SetImagePrePull sets the pre-pull phases of the images in the inventory, the images not in the inventory are added
+func SetImagePrePull(inventory []ImageInventory, phases map[string]PrePullPhase) []ImageInventory {
+	res := make([]ImageInventory, 0, len(inventory))
+	seen := make(map[string]bool)
+	for _, item := range inventory {
+		item.PrePull = phases[item.Image]
+		seen[item.Image] = true
+		res = append(res, item)
+	}
+	images := make([]string, 0)
+	for image := range phases {
+		if !seen[image] {
+			images = append(images, image)
+		}
+	}
+	sort.Strings(images)
+	for _, image := range images {
+		res = append(res, ImageInventory{Image: image, PrePull: phases[image]})
+	}
+	return res
+}
+
+// GenerateComponentPlanVerifyJobName generates the name of the verification job of the given release revision
+func GenerateComponentPlanVerifyJobName(plan *ComponentPlan, revision int) string {
+	return fmt.Sprintf("verify.%s.%d", plan.Name, revision)
+}
+
 func ComponentPlanSucceeded() Condition {
 	return componentPlanCondition(ComponentPlanTypeSucceeded, "", corev1.ConditionTrue, nil)
 }
@@ -142,6 +205,35 @@ func ComponentPlanRollBackFailed(err error) Condition {
 func ComponentPlanRollingBack() Condition {
 	return componentPlanCondition(ComponentPlanTypeActioned, ComponentPlanReasonRollingBack, corev1.ConditionFalse, nil)
 }
+
+func ComponentPlanAdoptSuccess() Condition {
+	return componentPlanCondition(ComponentPlanTypeActioned, ComponentPlanReasonAdoptSuccess, corev1.ConditionTrue, nil)
+}
+func ComponentPlanPolicyViolation(err error) Condition {
+	return componentPlanCondition(ComponentPlanTypeActioned, ComponentPlanReasonPolicyViolation, corev1.ConditionFalse, err)
+}
+
+// IsPolicyViolated returns whether the ComponentPlan is blocked by the Policies
+func (c *ComponentPlan) IsPolicyViolated() bool {
+	return c.Status.GetCondition(ComponentPlanTypeActioned).Reason == ComponentPlanReasonPolicyViolation
+}
+
+func ComponentPlanWaitVerify() Condition {
+	return componentPlanCondition(ComponentPlanTypeVerified, ComponentPlanReasonWaitDo, corev1.ConditionFalse, nil)
+}
+
+func ComponentPlanVerifying() Condition {
+	return componentPlanCondition(ComponentPlanTypeVerified, ComponentPlanReasonVerifying, corev1.ConditionFalse, nil)
+}
+
+func ComponentPlanVerifySuccess() Condition {
+	return componentPlanCondition(ComponentPlanTypeVerified, ComponentPlanReasonVerifySuccess, corev1.ConditionTrue, nil)
+}
+
+func ComponentPlanVerifyFailed(err error) Condition {
+	return componentPlanCondition(ComponentPlanTypeVerified, ComponentPlanReasonVerifyFailed, corev1.ConditionFalse, err)
+}
+
 func componentPlanCondition(ct ConditionType, reason ConditionReason, status corev1.ConditionStatus, err error) Condition {
 	if status == "" {
 		status = corev1.ConditionUnknown
@@ -159,11 +251,19 @@ func componentPlanCondition(ct ConditionType, reason ConditionReason, status cor
 }
 
 func (c *ComponentPlan) InitCondition() []Condition {
+	conds := []Condition{ComponentPlanUnapproved(), ComponentPlanWaitDo(nil), ComponentPlanInitSucceeded()}
 	if c.Spec.Approved {
-		return []Condition{ComponentPlanApproved(), ComponentPlanWaitDo(nil), ComponentPlanInitSucceeded()}
-	} else {
-		return []Condition{ComponentPlanUnapproved(), ComponentPlanWaitDo(nil), ComponentPlanInitSucceeded()}
+		conds[0] = ComponentPlanApproved()
 	}
+	if c.Spec.Verification != nil {
+		conds = append(conds, ComponentPlanWaitVerify())
+	}
+	return conds
+}
+
+// IsVerifiedReason checks the reason of the Verified condition
+func (c *ComponentPlan) IsVerifiedReason(cr ConditionReason) bool {
+	return c.Status.GetCondition(ComponentPlanTypeVerified).Reason == cr
 }
 
 func (c *ComponentPlan) IsActionedReason(cr ConditionReason) bool {
@@ -174,6 +274,22 @@ func (c *ComponentPlan) GetReleaseName() string {
 	return c.Spec.Name
 }
 
+// IsDeletionProtected returns true if the ComponentPlan should not be deleted
+func (c *ComponentPlan) IsDeletionProtected() bool {
+	return c.GetAnnotations()[ComponentPlanDeletionProtectionAnnotation] == "true"
+}
+
+// IsOrphanUninstall returns true if the helm release should be kept after the ComponentPlan is deleted
+func (c *ComponentPlan) IsOrphanUninstall() bool {
+	return c.Spec.UninstallPolicy == UninstallPolicyOrphan
+}
+
+// NeedAdoptRelease returns true if the ComponentPlan should take over an existing helm release
+// instead of installing a new one.
+func (c *ComponentPlan) NeedAdoptRelease() bool {
+	return c.GetAnnotations()[ComponentPlanAdoptReleaseAnnotation] == "true"
+}
+
 // ComponentPlanDiffIgnorePaths is the list of paths to ignore when comparing
 // These fields will almost certainly change when componentplan is updated, and displaying these
 // changes will only result in more invalid information, so they need to be ignored
@@ -184,8 +300,9 @@ var ComponentPlanDiffIgnorePaths = []string{
 	"spec.template.metadata.labels.helm.sh/chart",
 }
 
-// GetResourcesAndImages get resource slices, image lists from manifests
-func GetResourcesAndImages(ctx context.Context, logger logr.Logger, c client.Client, data, namespace string) (resources []Resource, images []string, err error) {
+// GetResourcesAndImages get resource slices, image lists from manifests.
+// The images are collected from the built-in workloads and the fields located by fieldSpecs, the same as the post renderer.
+func GetResourcesAndImages(ctx context.Context, logger logr.Logger, c client.Client, data, namespace string, fieldSpecs []ImageFieldSpec) (resources []Resource, images []string, err error) {
 	manifests, err := utils.SplitYAML([]byte(data))
 	if err != nil {
 		return nil, nil, err
@@ -193,17 +310,9 @@ func GetResourcesAndImages(ctx context.Context, logger logr.Logger, c client.Cli
 	resources = make([]Resource, len(manifests))
 	for i, manifest := range manifests {
 		obj := manifest
-		if len(obj.GetNamespace()) == 0 {
-			if rs, err := c.RESTMapper().RESTMapping(obj.GroupVersionKind().GroupKind()); err != nil {
-				logger.Error(err, "get RESTMapping err, just ignore and continue", "obj", klog.KObj(obj))
-				continue
-			} else {
-				if rs.Scope.Name() == meta.RESTScopeNameNamespace {
-					obj.SetNamespace(namespace)
-				} else {
-					obj.SetNamespace("")
-				}
-			}
+		if err := setNamespaceByScope(c, obj, namespace); err != nil {
+			logger.Error(err, "get RESTMapping err, just ignore and continue", "obj", klog.KObj(obj))
+			continue
 		}
 		has := &unstructured.Unstructured{}
 		has.SetKind(obj.GetKind())
@@ -238,27 +347,9 @@ func GetResourcesAndImages(ctx context.Context, logger logr.Logger, c client.Cli
 			}
 		}
 		resources[i] = r
-		gvk := obj.GroupVersionKind()
-		switch gvk.Group {
-		case "":
-			switch gvk.Kind { // nolint
-			case "Pod":
-				images = append(images, utils.GetPodImage(obj)...)
-			}
-		case "apps":
-			switch gvk.Kind {
-			case "Deployment":
-				images = append(images, utils.GetDeploymentImage(obj)...)
-			case "StatefulSet":
-				images = append(images, utils.GetStatefulSetImage(obj)...)
-			}
-		case "batch":
-			switch gvk.Kind {
-			case "Job":
-				images = append(images, utils.GetJobImage(obj)...)
-			case "CronJob":
-				images = append(images, utils.GetCronJobImage(obj)...)
-			}
+		images = append(images, utils.GetWorkloadImage(obj)...)
+		for _, spec := range fieldSpecs {
+			images = append(images, spec.Images(obj)...)
 		}
 	}
 	imageMap := make(map[string]bool)
@@ -272,3 +363,106 @@ func GetResourcesAndImages(ctx context.Context, logger logr.Logger, c client.Cli
 	sort.Strings(images)
 	return resources, images, nil
 }
+
+// setNamespaceByScope sets the namespace of the object if it is namespaced and has no namespace in manifest
+func setNamespaceByScope(c client.Client, obj *unstructured.Unstructured, namespace string) error {
+	if len(obj.GetNamespace()) != 0 {
+		return nil
+	}
+	rs, err := c.RESTMapper().RESTMapping(obj.GroupVersionKind().GroupKind())
+	if err != nil {
+		return err
+	}
+	if rs.Scope.Name() == meta.RESTScopeNameNamespace {
+		obj.SetNamespace(namespace)
+	} else {
+		obj.SetNamespace("")
+	}
+	return nil
+}
+
+// ExportReleaseResources gets the live objects of all resources in the manifests and returns them as a multi-document yaml.
+// Cluster generated fields like status, resourceVersion and managedFields are removed, so the result can be applied again.
+func ExportReleaseResources(ctx context.Context, logger logr.Logger, c client.Client, data, namespace string) (string, error) {
+	manifests, err := utils.SplitYAML([]byte(data))
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	for _, obj := range manifests {
+		if err = setNamespaceByScope(c, obj, namespace); err != nil {
+			logger.Error(err, "get RESTMapping err, just ignore and continue", "obj", klog.KObj(obj))
+			continue
+		}
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(obj.GroupVersionKind())
+		if err = c.Get(ctx, client.ObjectKeyFromObject(obj), live); err != nil {
+			if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+				logger.Info("Resource not found, skip export", "obj", klog.KObj(obj), "kind", obj.GetKind())
+				continue
+			}
+			return "", err
+		}
+		unstructured.RemoveNestedField(live.Object, "status")
+		for _, field := range []string{"managedFields", "resourceVersion", "uid", "creationTimestamp", "generation", "selfLink"} {
+			unstructured.RemoveNestedField(live.Object, "metadata", field)
+		}
+		out, err := yaml.Marshal(live.Object)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString("---\n")
+		buf.Write(out)
+	}
+	return buf.String(), nil
+}
+
+// Validate checks the PostRender keeps the resources in the namespace of the ComponentPlan,
+// neither Namespace nor the patches can move them to another namespace.
+func (p *PostRender) Validate(namespace string) error {
+	if p == nil {
+		return nil
+	}
+	if p.Namespace != "" && p.Namespace != namespace {
+		return fmt.Errorf("%w: namespace %s", ErrPostRenderNamespace, p.Namespace)
+	}
+	for i, patch := range p.Patches {
+		var content interface{}
+		if err := yaml.Unmarshal([]byte(patch.Patch), &content); err != nil {
+			return fmt.Errorf("invalid postRender.patches[%d]: %w", i, err)
+		}
+		if err := validatePatchNamespace(content, namespace); err != nil {
+			return fmt.Errorf("postRender.patches[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// validatePatchNamespace rejects the strategic merge patch with another metadata.namespace,
+// and the JSON 6902 patch operating on metadata.namespace.
+func validatePatchNamespace(content interface{}, namespace string) error {
+	switch patch := content.(type) {
+	case map[string]interface{}:
+		metadata, _ := patch["metadata"].(map[string]interface{})
+		if ns, ok := metadata["namespace"]; ok && ns != namespace {
+			return fmt.Errorf("%w: metadata.namespace %v", ErrPostRenderNamespace, ns)
+		}
+	case []interface{}:
+		for _, item := range patch {
+			op, _ := item.(map[string]interface{})
+			if op["op"] == "test" {
+				continue
+			}
+			paths := []interface{}{op["path"]}
+			if op["op"] == "move" {
+				paths = append(paths, op["from"])
+			}
+			for _, path := range paths {
+				if path == "/metadata" || path == "/metadata/namespace" {
+					return fmt.Errorf("%w: %v %s", ErrPostRenderNamespace, op["op"], path)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -17,13 +17,21 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/utils/strings/slices"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 // TestGenerateComponentPlanName for GenerateComponentPlanName
@@ -498,10 +506,45 @@ func TestInitCondition(t *testing.T) {
 				},
 			},
 		},
+		{
+			description: "Component plan with verification",
+			cp: ComponentPlan{
+				Spec: ComponentPlanSpec{
+					Approved: true,
+					Config: Config{
+						Verification: &Verification{HelmTest: true},
+					},
+				},
+			},
+
+			expected: []Condition{
+				{
+					Type:   ComponentPlanTypeApproved,
+					Status: corev1.ConditionTrue,
+					Reason: "",
+				},
+				{
+					Type:   ComponentPlanTypeActioned,
+					Status: corev1.ConditionFalse,
+					Reason: ComponentPlanReasonWaitDo,
+				}, {
+					Type:   ComponentPlanTypeSucceeded,
+					Status: corev1.ConditionFalse,
+					Reason: "",
+				}, {
+					Type:   ComponentPlanTypeVerified,
+					Status: corev1.ConditionFalse,
+					Reason: ComponentPlanReasonWaitDo,
+				},
+			},
+		},
 	}
 	for _, testCase := range testCases {
 		t.Run(fmt.Sprintf("test: %s", testCase.description), func(t *testing.T) {
 			result := testCase.cp.InitCondition()
+			if len(result) != len(testCase.expected) {
+				t.Fatalf("Test Failed: %s, expected: %v, actual: %v", testCase.description, testCase.expected, result)
+			}
 			for i, condition := range result {
 				if !IsEquivCondition(condition, testCase.expected[i]) {
 					t.Fatalf("Test Failed: %s, expected: %v, actual: %v", testCase.description, testCase.expected, condition)
@@ -593,3 +636,365 @@ func TestGetReleaseName(t *testing.T) {
 		}
 	}
 }
+
+// TestComponentPlanAdoptSuccess for ComponentPlanAdoptSuccess
+func TestComponentPlanAdoptSuccess(t *testing.T) {
+	testCases := []struct {
+		expected Condition
+	}{
+		{
+			expected: Condition{
+				Type:   ComponentPlanTypeActioned,
+				Status: corev1.ConditionTrue,
+				Reason: ComponentPlanReasonAdoptSuccess,
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		if !IsEquivCondition(ComponentPlanAdoptSuccess(), testCase.expected) {
+			t.Fatalf("Test Failed, expected: %v, actual: %v", testCase.expected, ComponentPlanAdoptSuccess())
+		}
+	}
+}
+
+// TestNeedAdoptRelease for ComponentPlan.NeedAdoptRelease
+func TestNeedAdoptRelease(t *testing.T) {
+	testCases := []struct {
+		plan *ComponentPlan
+
+		expected bool
+	}{
+		{
+			plan:     &ComponentPlan{},
+			expected: false,
+		},
+		{
+			plan: &ComponentPlan{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{ComponentPlanAdoptReleaseAnnotation: "false"},
+				},
+			},
+			expected: false,
+		},
+		{
+			plan: &ComponentPlan{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{ComponentPlanAdoptReleaseAnnotation: "true"},
+				},
+			},
+			expected: true,
+		},
+	}
+	for _, testCase := range testCases {
+		if testCase.plan.NeedAdoptRelease() != testCase.expected {
+			t.Fatalf("Test Failed, expected: %v, actual: %v", testCase.expected, testCase.plan.NeedAdoptRelease())
+		}
+	}
+}
+
+// TestGenerateComponentPlanBackupName for GenerateComponentPlanBackupName
+func TestGenerateComponentPlanBackupName(t *testing.T) {
+	testCases := []struct {
+		plan *ComponentPlan
+
+		expected string
+	}{
+		{
+			plan: &ComponentPlan{
+				ObjectMeta: metav1.ObjectMeta{Name: "nginx"},
+				Spec:       ComponentPlanSpec{Config: Config{UninstallBackup: &UninstallBackup{Kind: "Secret"}}},
+			},
+			expected: "backup.nginx",
+		},
+		{
+			plan: &ComponentPlan{
+				ObjectMeta: metav1.ObjectMeta{Name: "nginx"},
+				Spec:       ComponentPlanSpec{Config: Config{UninstallBackup: &UninstallBackup{Kind: "ConfigMap", Name: "nginx-bundle"}}},
+			},
+			expected: "nginx-bundle",
+		},
+	}
+	for _, testCase := range testCases {
+		if actual := GenerateComponentPlanBackupName(testCase.plan); actual != testCase.expected {
+			t.Fatalf("Test Failed, expected: %v, actual: %v", testCase.expected, actual)
+		}
+	}
+}
+
+// TestIsDeletionProtected for ComponentPlan.IsDeletionProtected
+func TestIsDeletionProtected(t *testing.T) {
+	testCases := []struct {
+		annotations map[string]string
+
+		expected bool
+	}{
+		{annotations: nil, expected: false},
+		{annotations: map[string]string{ComponentPlanDeletionProtectionAnnotation: "false"}, expected: false},
+		{annotations: map[string]string{ComponentPlanDeletionProtectionAnnotation: "true"}, expected: true},
+	}
+	for _, testCase := range testCases {
+		plan := &ComponentPlan{ObjectMeta: metav1.ObjectMeta{Annotations: testCase.annotations}}
+		if plan.IsDeletionProtected() != testCase.expected {
+			t.Fatalf("Test Failed, expected: %v, actual: %v", testCase.expected, plan.IsDeletionProtected())
+		}
+	}
+}
+
+// TestIsOrphanUninstall for ComponentPlan.IsOrphanUninstall
+func TestIsOrphanUninstall(t *testing.T) {
+	testCases := []struct {
+		policy UninstallPolicy
+
+		expected bool
+	}{
+		{policy: "", expected: false},
+		{policy: UninstallPolicyDelete, expected: false},
+		{policy: UninstallPolicyOrphan, expected: true},
+	}
+	for _, testCase := range testCases {
+		plan := &ComponentPlan{Spec: ComponentPlanSpec{Config: Config{UninstallPolicy: testCase.policy}}}
+		if plan.IsOrphanUninstall() != testCase.expected {
+			t.Fatalf("Test Failed, expected: %v, actual: %v", testCase.expected, plan.IsOrphanUninstall())
+		}
+	}
+}
+
+// TestComponentPlanValidateDelete for ComponentPlan.ValidateDelete
+func TestComponentPlanValidateDelete(t *testing.T) {
+	ctx := admission.NewContextWithRequest(context.Background(), admission.Request{})
+	testCases := []struct {
+		plan *ComponentPlan
+
+		expected error
+	}{
+		{
+			plan:     &ComponentPlan{},
+			expected: nil,
+		},
+		{
+			plan: &ComponentPlan{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{ComponentPlanDeletionProtectionAnnotation: "true"},
+				},
+			},
+			expected: ErrDeletionProtected,
+		},
+	}
+	for _, testCase := range testCases {
+		if err := (&componentPlanValidator{}).ValidateDelete(ctx, testCase.plan); !errors.Is(err, testCase.expected) {
+			t.Fatalf("Test Failed, expected: %v, actual: %v", testCase.expected, err)
+		}
+	}
+}
+
+func TestComponentPlanVerifySuccess(t *testing.T) {
+	testCases := []struct {
+		expected Condition
+	}{
+		{
+			expected: Condition{
+				Type:   ComponentPlanTypeVerified,
+				Status: corev1.ConditionTrue,
+				Reason: ComponentPlanReasonVerifySuccess,
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		if !IsEquivCondition(ComponentPlanVerifySuccess(), testCase.expected) {
+			t.Fatalf("Test Failed, expected: %v, actual: %v", testCase.expected, ComponentPlanVerifySuccess())
+		}
+	}
+}
+
+func TestComponentPlanVerifyFailed(t *testing.T) {
+	testCases := []struct {
+		err      error
+		expected Condition
+	}{
+		{
+			err: errors.New("job failed"),
+			expected: Condition{
+				Type:    ComponentPlanTypeVerified,
+				Status:  corev1.ConditionFalse,
+				Reason:  ComponentPlanReasonVerifyFailed,
+				Message: "job failed",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		if !IsEquivCondition(ComponentPlanVerifyFailed(testCase.err), testCase.expected) {
+			t.Fatalf("Test Failed, expected: %v, actual: %v", testCase.expected, ComponentPlanVerifyFailed(testCase.err))
+		}
+	}
+}
+
+func TestVerificationTimeout(t *testing.T) {
+	testCases := []struct {
+		verification Verification
+		expected     time.Duration
+	}{
+		{
+			verification: Verification{},
+			expected:     300 * time.Second,
+		},
+		{
+			verification: Verification{TimeoutSeconds: 10},
+			expected:     10 * time.Second,
+		},
+	}
+	for _, testCase := range testCases {
+		if actual := testCase.verification.Timeout(); actual != testCase.expected {
+			t.Fatalf("Test Failed, expected: %v, actual: %v", testCase.expected, actual)
+		}
+	}
+}
+
+// TestGenerateComponentPlanVerifyJobName for GenerateComponentPlanVerifyJobName
+func TestGenerateComponentPlanVerifyJobName(t *testing.T) {
+	testCases := []struct {
+		plan     *ComponentPlan
+		revision int
+		expected string
+	}{
+		{
+			plan: &ComponentPlan{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test",
+				},
+			},
+			revision: 2,
+			expected: "verify.test.2",
+		},
+	}
+	for _, testCase := range testCases {
+		if actual := GenerateComponentPlanVerifyJobName(testCase.plan, testCase.revision); actual != testCase.expected {
+			t.Fatalf("Test Failed, expected: %v, actual: %v", testCase.expected, actual)
+		}
+	}
+}
+
+func TestNeedPrePull(t *testing.T) {
+	plan := &ComponentPlan{ObjectMeta: metav1.ObjectMeta{Name: "test", Generation: 2}}
+	plan.Status.Images = []string{"nginx:1.25"}
+	if plan.NeedPrePull() {
+		t.Fatalf("Test Failed, expected no pre-pull without spec.prePull")
+	}
+	plan.Spec.PrePull = &PrePull{}
+	if !plan.NeedPrePull() {
+		t.Fatalf("Test Failed, expected pre-pull for the new generation")
+	}
+	plan.Status.PrePullGeneration = 2
+	if plan.NeedPrePull() {
+		t.Fatalf("Test Failed, expected no pre-pull after the generation is pre-pulled")
+	}
+}
+
+func TestSetImagePrePull(t *testing.T) {
+	inventory := []ImageInventory{{Image: "nginx:1.25", Digest: "sha256:123"}, {Image: "old:1.0", PrePull: PrePullPulled}}
+	phases := map[string]PrePullPhase{"nginx:1.25": PrePullPulled, "busybox:1.36": PrePullFailed, "alpine:3.18": PrePullPulling}
+	expected := []ImageInventory{
+		{Image: "nginx:1.25", Digest: "sha256:123", PrePull: PrePullPulled},
+		{Image: "old:1.0"},
+		{Image: "alpine:3.18", PrePull: PrePullPulling},
+		{Image: "busybox:1.36", PrePull: PrePullFailed},
+	}
+	if actual := SetImagePrePull(inventory, phases); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("Test Failed, expected: %v, actual: %v", expected, actual)
+	}
+}
+
+func TestPostRenderValidate(t *testing.T) {
+	ctx := admission.NewContextWithRequest(context.Background(), admission.Request{})
+	testCases := []struct {
+		postRender *PostRender
+
+		expected error
+	}{
+		{postRender: nil},
+		{postRender: &PostRender{Namespace: "default"}},
+		{postRender: &PostRender{Namespace: "other"}, expected: ErrPostRenderNamespace},
+		{postRender: &PostRender{Patches: []PostRenderPatch{{Patch: "kind: Deployment\nmetadata:\n  name: a\n  namespace: default\n"}}}},
+		{postRender: &PostRender{Patches: []PostRenderPatch{{Patch: "kind: Deployment\nmetadata:\n  name: a\n  namespace: other\n"}}}, expected: ErrPostRenderNamespace},
+		{postRender: &PostRender{Patches: []PostRenderPatch{{Patch: `[{"op": "replace", "path": "/spec/replicas", "value": 2}]`}}}},
+		{postRender: &PostRender{Patches: []PostRenderPatch{{Patch: `[{"op": "add", "path": "/metadata/namespace", "value": "other"}]`}}}, expected: ErrPostRenderNamespace},
+		{postRender: &PostRender{Patches: []PostRenderPatch{{Patch: "- op: replace\n  path: /metadata\n  value: {}\n"}}}, expected: ErrPostRenderNamespace},
+		{postRender: &PostRender{Patches: []PostRenderPatch{{Patch: `[{"op": "move", "from": "/metadata/namespace", "path": "/metadata/labels/ns"}]`}}}, expected: ErrPostRenderNamespace},
+	}
+	for _, testCase := range testCases {
+		plan := &ComponentPlan{
+			ObjectMeta: metav1.ObjectMeta{Name: "plan", Namespace: "default"},
+			Spec: ComponentPlanSpec{
+				ComponentRef: &corev1.ObjectReference{Name: "component", Namespace: "default"},
+				Config:       Config{PostRender: testCase.postRender},
+			},
+		}
+		if err := plan.Spec.PostRender.Validate(plan.Namespace); !errors.Is(err, testCase.expected) {
+			t.Fatalf("Test Failed, postRender: %v, expected: %v, actual: %v", testCase.postRender, testCase.expected, err)
+		}
+		if err := (&componentPlanValidator{}).ValidateCreate(ctx, plan); !errors.Is(err, testCase.expected) {
+			t.Fatalf("Test Failed, postRender: %v, expected webhook error: %v, actual: %v", testCase.postRender, testCase.expected, err)
+		}
+	}
+}
+
+// reviewClient answers the SubjectAccessReviews with allowed users
+type reviewClient struct {
+	client.Client
+	allowed []string
+}
+
+func (c *reviewClient) Create(_ context.Context, obj client.Object, _ ...client.CreateOption) error {
+	sar := obj.(*authorizationv1.SubjectAccessReview)
+	sar.Status.Allowed = slices.Contains(c.allowed, sar.Spec.User)
+	return nil
+}
+
+// TestComponentPlanCheckTargetClusterAccess for componentPlanValidator.checkTargetClusterAccess
+func TestComponentPlanCheckTargetClusterAccess(t *testing.T) {
+	testCases := []struct {
+		user    authenticationv1.UserInfo
+		creator string
+
+		expected error
+	}{
+		{
+			user:     authenticationv1.UserInfo{Username: "admin", Groups: []string{user.SystemPrivilegedGroup}},
+			expected: nil,
+		},
+		{
+			user:     authenticationv1.UserInfo{Username: "admin", Groups: []string{user.SystemPrivilegedGroup}},
+			creator:  "bob",
+			expected: ErrTargetClusterDenied,
+		},
+		{
+			user:     authenticationv1.UserInfo{Username: "admin", Groups: []string{user.SystemPrivilegedGroup}},
+			creator:  "alice",
+			expected: nil,
+		},
+		{
+			user:     authenticationv1.UserInfo{Username: "alice"},
+			creator:  "alice",
+			expected: nil,
+		},
+		{
+			user:     authenticationv1.UserInfo{Username: "bob"},
+			creator:  "bob",
+			expected: ErrTargetClusterDenied,
+		},
+	}
+	v := &componentPlanValidator{client: &reviewClient{allowed: []string{"alice"}}}
+	for _, testCase := range testCases {
+		plan := &ComponentPlan{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default"},
+			Spec: ComponentPlanSpec{
+				Config: Config{
+					Creator:       testCase.creator,
+					TargetCluster: &TargetCluster{SecretRef: "member"},
+				},
+			},
+		}
+		if err := v.checkTargetClusterAccess(context.Background(), testCase.user, plan); !errors.Is(err, testCase.expected) {
+			t.Fatalf("Test Failed, expected: %v, actual: %v", testCase.expected, err)
+		}
+	}
+}
@@ -52,15 +52,34 @@ type ComponentPlanStatus struct {
 	// +optional
 	// FIXME rethink this field
 	Latest *bool `json:"latest,omitempty"`
-	// Portal only use for kubebb, FIXME: parse it
+	// Portal is the Portal of the installed chart, declared in the Chart.yaml annotations or rendered by the chart
 	// +optional
 	Portal Router `json:"portal,omitempty"`
+	// Menus are the Menus of the installed chart, declared in the Chart.yaml annotations or rendered by the chart
+	// +optional
+	Menus []MenuReference `json:"menus,omitempty"`
 
 	ConditionedStatus `json:",inline"`
 	// +optional
 	Resources []Resource `json:"resources,omitempty"`
 	// +optional
 	Images []string `json:"images,omitempty"`
+	// ImageInventory shows the digests, vulnerabilities and availability of the Images
+	// +optional
+	ImageInventory []ImageInventory `json:"imageInventory,omitempty"`
+	// ImageDigests are the digests of the Images pinned when planning, with the image as the key
+	// +optional
+	ImageDigests map[string]string `json:"imageDigests,omitempty"`
+	// PrePullGeneration is the generation whose images are pre-pulled
+	// +optional
+	PrePullGeneration int64 `json:"prePullGeneration,omitempty"`
+	// ValuesFromHash is the hash of the content of spec.override.valuesFrom used by the last install or upgrade
+	// +optional
+	ValuesFromHash string `json:"valuesFromHash,omitempty"`
+	// PendingValuesFromHash is the hash of the changed content of spec.override.valuesFrom
+	// which waits for a manual upgrade when the valuesFrom policy is Manual
+	// +optional
+	PendingValuesFromHash string `json:"pendingValuesFromHash,omitempty"`
 }
 
 type Router struct {
@@ -70,6 +89,49 @@ type Router struct {
 	Entry string `json:"entry,omitempty"`
 }
 
+// ImageInventory is an image pulled by the ComponentPlan
+type ImageInventory struct {
+	// Image is the image referenced in the manifest
+	Image string `json:"image"`
+	// Digest is the digest of the image, resolved from the registry if the image is referenced by tag
+	// +optional
+	Digest string `json:"digest,omitempty"`
+	// Message shows why the digest or the vulnerabilities can not be got
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Vulnerabilities is the summary of the scanner report of the image, empty if no report is found
+	// +optional
+	Vulnerabilities *VulnerabilitySummary `json:"vulnerabilities,omitempty"`
+	// Available shows whether the image exists in its registry with the pull secrets, it is checked when the digest is resolved
+	// +optional
+	Available *bool `json:"available,omitempty"`
+	// PrePull is the phase of pulling the image on the nodes before installing
+	// +optional
+	PrePull PrePullPhase `json:"prePull,omitempty"`
+}
+
+type PrePullPhase string
+
+const (
+	PrePullPulling PrePullPhase = "Pulling"
+	PrePullPulled  PrePullPhase = "Pulled"
+	PrePullFailed  PrePullPhase = "Failed"
+)
+
+// VulnerabilitySummary counts the vulnerabilities of an image by severity
+type VulnerabilitySummary struct {
+	// Source is the scanner which reports the vulnerabilities, like trivy
+	Source string `json:"source"`
+	// ScannedAt is the time the report is created
+	// +optional
+	ScannedAt *metav1.Time `json:"scannedAt,omitempty"`
+	Critical  int          `json:"critical"`
+	High      int          `json:"high"`
+	Medium    int          `json:"medium"`
+	Low       int          `json:"low"`
+	Unknown   int          `json:"unknown"`
+}
+
 // Resource represents one single resource in the ComponentPlan
 // because the resource, if namespaced, is the same namepsace as the ComponentPlan,
 // it is either a cluster and does not have namespace,
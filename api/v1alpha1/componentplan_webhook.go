@@ -18,9 +18,16 @@ package v1alpha1
 
 import (
 	"context"
+	"fmt"
+	"reflect"
 
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
@@ -32,7 +39,7 @@ func (c *ComponentPlan) SetupWebhookWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(c).
 		WithDefaulter(c).
-		WithValidator(c).
+		WithValidator(&componentPlanValidator{client: mgr.GetClient()}).
 		Complete()
 }
 
@@ -61,13 +68,19 @@ func (c *ComponentPlan) Default(ctx context.Context, obj runtime.Object) error {
 	return nil
 }
 
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
 //+kubebuilder:webhook:path=/validate-core-kubebb-k8s-com-cn-v1alpha1-componentplan,mutating=false,failurePolicy=fail,sideEffects=None,groups=core.kubebb.k8s.com.cn,resources=componentplans,verbs=create;update;delete,versions=v1alpha1,name=vcomponentplan.kb.io,admissionReviewVersions=v1
 
-var _ webhook.CustomValidator = &ComponentPlan{}
+// componentPlanValidator validates the ComponentPlans with the client to get the related resources
+type componentPlanValidator struct {
+	client client.Client
+}
+
+var _ webhook.CustomValidator = &componentPlanValidator{}
 
 // ValidateCreate implements webhook.Validator so a webhook will be registered for the type
-func (c *ComponentPlan) ValidateCreate(ctx context.Context, obj runtime.Object) error {
-	log := componentplanlog.WithValues("name", c.Name, "method", "ValidateCreate")
+func (v *componentPlanValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	log := componentplanlog.WithValues("method", "ValidateCreate")
 	user, err := getReqUserInfo(ctx)
 	if err != nil {
 		log.Error(err, "get ReqUser err")
@@ -79,17 +92,30 @@ func (c *ComponentPlan) ValidateCreate(ctx context.Context, obj runtime.Object)
 		log.Error(ErrDecode, "obj "+ErrDecode.Error())
 		return ErrDecode
 	}
+	log = log.WithValues("name", p.Name)
 	if err = p.validateSpec(); err != nil {
 		log.Info(err.Error())
 		return err
 	}
+	if p.Spec.TargetCluster != nil {
+		if err = v.checkTargetClusterAccess(ctx, user, p); err != nil {
+			log.Info(err.Error())
+			return err
+		}
+	}
+	if p.Spec.Approved {
+		if err = v.checkRatingPolicy(ctx, p); err != nil {
+			log.Info(err.Error())
+			return err
+		}
+	}
 	log.Info("validate create done")
 	return nil
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
-func (c *ComponentPlan) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
-	log := componentplanlog.WithValues("name", c.Name, "method", "ValidateUpdate")
+func (v *componentPlanValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
+	log := componentplanlog.WithValues("method", "ValidateUpdate")
 	user, err := getReqUserInfo(ctx)
 	if err != nil {
 		log.Error(err, "get ReqUser err")
@@ -106,6 +132,7 @@ func (c *ComponentPlan) ValidateUpdate(ctx context.Context, oldObj runtime.Objec
 		log.Error(ErrDecode, "newObj "+ErrDecode.Error())
 		return ErrDecode
 	}
+	log = log.WithValues("name", np.Name)
 	if err = np.validateSpec(); err != nil {
 		log.Info(err.Error())
 		return err
@@ -122,19 +149,42 @@ func (c *ComponentPlan) ValidateUpdate(ctx context.Context, oldObj runtime.Objec
 		log.Info(ErrCreatorChange.Error(), "old", p.Spec.Creator, "new", np.Spec.Creator)
 		return ErrCreatorChange
 	}
+	if np.Spec.TargetCluster != nil && !reflect.DeepEqual(p.Spec.TargetCluster, np.Spec.TargetCluster) {
+		if err = v.checkTargetClusterAccess(ctx, user, np); err != nil {
+			log.Info(err.Error())
+			return err
+		}
+	}
+	// only check the rating policy when the plan is approved or the version changes after approved
+	if np.Spec.Approved && (!p.Spec.Approved || p.Spec.InstallVersion != np.Spec.InstallVersion) {
+		if err = v.checkRatingPolicy(ctx, np); err != nil {
+			log.Info(err.Error())
+			return err
+		}
+	}
 	log.Info("validate update done")
 	return nil
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
-func (c *ComponentPlan) ValidateDelete(ctx context.Context, obj runtime.Object) error {
-	log := componentplanlog.WithValues("name", c.Name, "method", "ValidateDelete")
+func (v *componentPlanValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	log := componentplanlog.WithValues("method", "ValidateDelete")
 	user, err := getReqUserInfo(ctx)
 	if err != nil {
 		log.Error(err, "get ReqUser err")
 		return err
 	}
 	log = log.WithValues("user", user)
+	p, ok := obj.(*ComponentPlan)
+	if !ok {
+		log.Error(ErrDecode, "obj "+ErrDecode.Error())
+		return ErrDecode
+	}
+	log = log.WithValues("name", p.Name)
+	if p.IsDeletionProtected() {
+		log.Info(ErrDeletionProtected.Error())
+		return ErrDeletionProtected
+	}
 	log.Info("validate delete done")
 	return nil
 }
@@ -143,5 +193,57 @@ func (c *ComponentPlan) validateSpec() error {
 	if c.Spec.ComponentRef == nil || c.Spec.ComponentRef.Namespace == "" || c.Spec.ComponentRef.Name == "" {
 		return ErrComponentMissing
 	}
+	return c.Spec.PostRender.Validate(c.Namespace)
+}
+
+// checkRatingPolicy checks the install version against the rating policy of the component's repository
+func (v *componentPlanValidator) checkRatingPolicy(ctx context.Context, c *ComponentPlan) error {
+	component := &Component{}
+	if err := v.client.Get(ctx, types.NamespacedName{Namespace: c.Spec.ComponentRef.Namespace, Name: c.Spec.ComponentRef.Name}, component); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	return CheckRatingPolicy(ctx, v.client, component, c.Spec.InstallVersion)
+}
+
+// checkTargetClusterAccess checks the creator can get the kubeconfig Secret of the target cluster by a SubjectAccessReview.
+// The release in the target cluster is installed with the identity of the kubeconfig instead of the creator,
+// so a user who can not read the Secret should not be able to use it by a ComponentPlan.
+// The requesting user is reviewed, or the creator in spec if the request comes from a super user, like the Subscription controller.
+func (v *componentPlanValidator) checkTargetClusterAccess(ctx context.Context, user authenticationv1.UserInfo, c *ComponentPlan) error {
+	sar := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: c.Namespace,
+				Verb:      "get",
+				Resource:  "secrets",
+				Name:      c.Spec.TargetCluster.SecretRef,
+			},
+		},
+	}
+	if isSuperUser(user) {
+		if c.Spec.Creator == "" {
+			return nil
+		}
+		sar.Spec.User = c.Spec.Creator
+	} else {
+		sar.Spec.User = user.Username
+		sar.Spec.UID = user.UID
+		sar.Spec.Groups = user.Groups
+		if len(user.Extra) != 0 {
+			sar.Spec.Extra = make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+			for k, val := range user.Extra {
+				sar.Spec.Extra[k] = authorizationv1.ExtraValue(val)
+			}
+		}
+	}
+	if err := v.client.Create(ctx, sar); err != nil {
+		return err
+	}
+	if !sar.Status.Allowed {
+		return fmt.Errorf("%w: user %s, secret %s/%s", ErrTargetClusterDenied, sar.Spec.User, c.Namespace, c.Spec.TargetCluster.SecretRef)
+	}
 	return nil
 }
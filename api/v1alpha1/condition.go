@@ -61,13 +61,16 @@ const (
 	RatingRunning              ConditionReason = "Running"
 	RatingSucceeded            ConditionReason = "Succeeded"
 	RatingDidabled             ConditionReason = "Disabled"
-
-	PipelineRunning      ConditionReason = "PipelineRunning"
-	PipelineRunFailed    ConditionReason = "PipelineRunFailed"
-	PipelineRunSucceeded ConditionReason = "PipelineRunSucceeded"
-	EvaluationRunning    ConditionReason = "EvaluationRunning"
-	EvaluationFailed     ConditionReason = "EvaluationFailed"
-	EvaluationSucceeded  ConditionReason = "EvaluationSucceeded"
+	RatingCancelled            ConditionReason = "Cancelled"
+
+	PipelineRunning           ConditionReason = "PipelineRunning"
+	PipelineRunFailed         ConditionReason = "PipelineRunFailed"
+	PipelineRunSucceeded      ConditionReason = "PipelineRunSucceeded"
+	EvaluationRunning         ConditionReason = "EvaluationRunning"
+	EvaluationFailed          ConditionReason = "EvaluationFailed"
+	EvaluationSucceeded       ConditionReason = "EvaluationSucceeded"
+	EvaluationParseError      ConditionReason = "EvaluationParseError"
+	EvaluationInvalidTemplate ConditionReason = "EvaluationInvalidTemplate"
 )
 
 // A Condition that may apply to a resource.
@@ -171,6 +174,24 @@ func (s *ConditionedStatus) SetConditions(c ...Condition) {
 	}
 }
 
+// RemoveConditions removes the conditions of the supplied types.
+func (s *ConditionedStatus) RemoveConditions(ct ...ConditionType) {
+	conds := make([]Condition, 0, len(s.Conditions))
+	for _, c := range s.Conditions {
+		remove := false
+		for _, t := range ct {
+			if c.Type == t {
+				remove = true
+				break
+			}
+		}
+		if !remove {
+			conds = append(conds, c)
+		}
+	}
+	s.Conditions = conds
+}
+
 // Equal returns true if the status is identical to the supplied status,
 // ignoring the LastTransitionTimes and order of statuses.
 func (s *ConditionedStatus) Equal(other *ConditionedStatus) bool {
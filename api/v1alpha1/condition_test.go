@@ -575,6 +575,57 @@ func TestSetConditions(t *testing.T) {
 	}
 }
 
+// TestRemoveConditions tests ConditionedStatus.RemoveConditions
+func TestRemoveConditions(t *testing.T) {
+	testCases := []struct {
+		description       string
+		conditionedStatus ConditionedStatus
+		types             []ConditionType
+
+		expected ConditionedStatus
+	}{
+		{
+			description: "remove nothing",
+			conditionedStatus: ConditionedStatus{
+				Conditions: []Condition{
+					{Type: TypeReady, Status: corev1.ConditionFalse, Reason: ReasonCreating},
+				},
+			},
+			types: []ConditionType{TypeSynced},
+
+			expected: ConditionedStatus{
+				Conditions: []Condition{
+					{Type: TypeReady, Status: corev1.ConditionFalse, Reason: ReasonCreating},
+				},
+			},
+		},
+		{
+			description: "remove one condition",
+			conditionedStatus: ConditionedStatus{
+				Conditions: []Condition{
+					{Type: TypeReady, Status: corev1.ConditionFalse, Reason: ReasonCreating},
+					{Type: TypeSynced, Status: corev1.ConditionFalse, Reason: ReasonCreating},
+				},
+			},
+			types: []ConditionType{TypeSynced},
+
+			expected: ConditionedStatus{
+				Conditions: []Condition{
+					{Type: TypeReady, Status: corev1.ConditionFalse, Reason: ReasonCreating},
+				},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(fmt.Sprintf("test: %s", testCase.description), func(t *testing.T) {
+			testCase.conditionedStatus.RemoveConditions(testCase.types...)
+			if !reflect.DeepEqual(testCase.conditionedStatus, testCase.expected) {
+				t.Fatalf("Test Failed: %s, expected: %v, actual: %v", testCase.description, testCase.expected, testCase.conditionedStatus)
+			}
+		})
+	}
+}
+
 // TestConditionedStatusEqual tests ConditionedStatus.Equal
 func TestConditionedStatusEqual(t *testing.T) {
 	testCases := []struct {
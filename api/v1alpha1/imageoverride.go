@@ -0,0 +1,268 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	pathpkg "path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kubebb/core/pkg/utils"
+)
+
+const (
+	// ImageOverrideConfigMapName is the ConfigMap in the operator namespace with the default image override rules of all Repositories
+	ImageOverrideConfigMapName = "kubebb-image-override"
+	// ImageOverrideConfigMapOverrideKey is the key of the rules in yaml, which is a list of ImageOverride
+	ImageOverrideConfigMapOverrideKey = "imageOverride"
+	// ImageOverrideConfigMapPinDigestKey is the key of whether to pin the images of all Repositories to digests, like "true"
+	ImageOverrideConfigMapPinDigestKey = "pinImageDigest"
+	// ImageOverrideConfigMapFieldSpecsKey is the key of the image fields in yaml, which is a list of ImageFieldSpec
+	ImageOverrideConfigMapFieldSpecsKey = "imageFieldSpecs"
+	// ImageOverrideConfigMapFieldPresetsKey is the key of the names of ImageFieldPresets in yaml
+	ImageOverrideConfigMapFieldPresetsKey = "imageFieldPresets"
+)
+
+// ImageFieldPresets are the image fields of the common custom resources, with the preset name as the key
+var ImageFieldPresets = map[string][]ImageFieldSpec{
+	"argo-rollouts": {
+		{Group: "argoproj.io", Kind: "Rollout", Path: "spec/template/spec/containers/image"},
+		{Group: "argoproj.io", Kind: "Rollout", Path: "spec/template/spec/initContainers/image"},
+	},
+	"argo-workflows": {
+		{Group: "argoproj.io", Kind: "Workflow", Path: "spec/templates/container/image"},
+		{Group: "argoproj.io", Kind: "Workflow", Path: "spec/templates/script/image"},
+		{Group: "argoproj.io", Kind: "Workflow", Path: "spec/templates/initContainers/image"},
+		{Group: "argoproj.io", Kind: "WorkflowTemplate", Path: "spec/templates/container/image"},
+		{Group: "argoproj.io", Kind: "WorkflowTemplate", Path: "spec/templates/script/image"},
+		{Group: "argoproj.io", Kind: "WorkflowTemplate", Path: "spec/templates/initContainers/image"},
+		{Group: "argoproj.io", Kind: "ClusterWorkflowTemplate", Path: "spec/templates/container/image"},
+		{Group: "argoproj.io", Kind: "ClusterWorkflowTemplate", Path: "spec/templates/script/image"},
+		{Group: "argoproj.io", Kind: "ClusterWorkflowTemplate", Path: "spec/templates/initContainers/image"},
+	},
+	"knative": {
+		{Group: "serving.knative.dev", Kind: "Service", Path: "spec/template/spec/containers/image"},
+		{Group: "serving.knative.dev", Kind: "Configuration", Path: "spec/template/spec/containers/image"},
+	},
+}
+
+// ImageOverrideConfig is the image override rules applied to the components of a Repository
+type ImageOverrideConfig struct {
+	ImageOverride     []ImageOverride  `json:"imageOverride,omitempty"`
+	ImageFieldSpecs   []ImageFieldSpec `json:"imageFieldSpecs,omitempty"`
+	ImageFieldPresets []string         `json:"imageFieldPresets,omitempty"`
+	PinImageDigest    bool             `json:"pinImageDigest,omitempty"`
+}
+
+// ParseImageOverrideConfig parses the default image override rules from the data of the ConfigMap
+func ParseImageOverrideConfig(data map[string]string) (*ImageOverrideConfig, error) {
+	cfg := &ImageOverrideConfig{}
+	if v := data[ImageOverrideConfigMapOverrideKey]; v != "" {
+		if err := yaml.Unmarshal([]byte(v), &cfg.ImageOverride); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", ImageOverrideConfigMapOverrideKey, err)
+		}
+		if err := ValidateImageOverrides(cfg.ImageOverride); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", ImageOverrideConfigMapOverrideKey, err)
+		}
+	}
+	if v := data[ImageOverrideConfigMapFieldSpecsKey]; v != "" {
+		if err := yaml.Unmarshal([]byte(v), &cfg.ImageFieldSpecs); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", ImageOverrideConfigMapFieldSpecsKey, err)
+		}
+	}
+	if v := data[ImageOverrideConfigMapFieldPresetsKey]; v != "" {
+		if err := yaml.Unmarshal([]byte(v), &cfg.ImageFieldPresets); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", ImageOverrideConfigMapFieldPresetsKey, err)
+		}
+		if err := ValidateImageFieldPresets(cfg.ImageFieldPresets); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", ImageOverrideConfigMapFieldPresetsKey, err)
+		}
+	}
+	if v := data[ImageOverrideConfigMapPinDigestKey]; v != "" {
+		pin, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", ImageOverrideConfigMapPinDigestKey, err)
+		}
+		cfg.PinImageDigest = pin
+	}
+	return cfg, nil
+}
+
+// GetImageOverrideConfig gets the default image override rules from the ConfigMap in the operator namespace.
+// An empty config is returned if the ConfigMap does not exist, or the operator namespace is unknown
+// because it runs out of cluster without POD_NAMESPACE.
+func GetImageOverrideConfig(ctx context.Context, c client.Client) (*ImageOverrideConfig, error) {
+	namespace, err := utils.GetNamespace()
+	if err != nil {
+		return &ImageOverrideConfig{}, nil
+	}
+	cm := &corev1.ConfigMap{}
+	if err = c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ImageOverrideConfigMapName}, cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return &ImageOverrideConfig{}, nil
+		}
+		return nil, err
+	}
+	return ParseImageOverrideConfig(cm.Data)
+}
+
+// IsImageOverrideConfigMap returns true if the object is the ConfigMap of the default image override rules
+func IsImageOverrideConfigMap(o client.Object) bool {
+	if o.GetName() != ImageOverrideConfigMapName {
+		return false
+	}
+	namespace, err := utils.GetNamespace()
+	return err == nil && o.GetNamespace() == namespace
+}
+
+// Hash returns the hash of the rules, the manifest rendered with other rules is generated again
+func (c *ImageOverrideConfig) Hash() string {
+	data, _ := json.Marshal(c)
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// Compile validates the Glob and compiles the Regex anchored to the whole image name, the regexp is nil if Regex is empty
+func (m *ImageMatch) Compile() (*regexp.Regexp, error) {
+	if m.Glob != "" {
+		if _, err := pathpkg.Match(m.Glob, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", m.Glob, err)
+		}
+	}
+	if m.Regex == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile("^(?:" + m.Regex + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex %q: %w", m.Regex, err)
+	}
+	return re, nil
+}
+
+// ValidateImageOverrides checks the Glob and Regex patterns of the rules
+func ValidateImageOverrides(overrides []ImageOverride) error {
+	for i := range overrides {
+		if overrides[i].Match == nil {
+			continue
+		}
+		if _, err := overrides[i].Match.Compile(); err != nil {
+			return fmt.Errorf("imageOverride[%d].match: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// ValidateImageFieldPresets checks the names of the presets are in ImageFieldPresets
+func ValidateImageFieldPresets(names []string) error {
+	for _, name := range names {
+		if _, ok := ImageFieldPresets[name]; !ok {
+			return fmt.Errorf("unknown image field preset %q", name)
+		}
+	}
+	return nil
+}
+
+// ForRepository returns the rules of the Repository with the default rules.
+// The default rules go first, so the rules of the Repository take precedence when both match an image.
+func (c *ImageOverrideConfig) ForRepository(repo *Repository) *ImageOverrideConfig {
+	res := &ImageOverrideConfig{PinImageDigest: c.PinImageDigest}
+	res.ImageOverride = append(res.ImageOverride, c.ImageOverride...)
+	res.ImageFieldSpecs = append(res.ImageFieldSpecs, c.ImageFieldSpecs...)
+	res.ImageFieldPresets = append(res.ImageFieldPresets, c.ImageFieldPresets...)
+	if repo != nil {
+		res.ImageOverride = append(res.ImageOverride, repo.Spec.ImageOverride...)
+		res.ImageFieldSpecs = append(res.ImageFieldSpecs, repo.Spec.ImageFieldSpecs...)
+		res.ImageFieldPresets = append(res.ImageFieldPresets, repo.Spec.ImageFieldPresets...)
+		res.PinImageDigest = res.PinImageDigest || repo.Spec.PinImageDigest
+	}
+	return res
+}
+
+// FieldSpecs returns the image fields to override, which are the paths of GetImageOverridePath for all kinds,
+// the fields of the presets and the ImageFieldSpecs, the duplicated ones are removed.
+func (c *ImageOverrideConfig) FieldSpecs() ([]ImageFieldSpec, error) {
+	res := make([]ImageFieldSpec, 0)
+	seen := make(map[ImageFieldSpec]bool)
+	add := func(specs ...ImageFieldSpec) {
+		for _, spec := range specs {
+			if spec.Path == "" || seen[spec] {
+				continue
+			}
+			seen[spec] = true
+			res = append(res, spec)
+		}
+	}
+	for _, p := range GetImageOverridePath() {
+		add(ImageFieldSpec{Path: p})
+	}
+	for _, name := range c.ImageFieldPresets {
+		preset, ok := ImageFieldPresets[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown image field preset %q", name)
+		}
+		add(preset...)
+	}
+	add(c.ImageFieldSpecs...)
+	return res, nil
+}
+
+// Images returns the images in the fields of the object located by the spec, the lists on the path are traversed
+// like the fields overridden by the post renderer.
+func (s ImageFieldSpec) Images(obj *unstructured.Unstructured) []string {
+	gvk := obj.GroupVersionKind()
+	if (s.Group != "" && s.Group != gvk.Group) || (s.Version != "" && s.Version != gvk.Version) || (s.Kind != "" && s.Kind != gvk.Kind) {
+		return nil
+	}
+	if s.Path == "" {
+		return nil
+	}
+	return fieldStrings(obj.Object, strings.Split(s.Path, "/"))
+}
+
+func fieldStrings(v interface{}, path []string) []string {
+	switch value := v.(type) {
+	case []interface{}:
+		res := make([]string, 0)
+		for _, item := range value {
+			res = append(res, fieldStrings(item, path)...)
+		}
+		return res
+	case map[string]interface{}:
+		if len(path) == 0 {
+			return nil
+		}
+		return fieldStrings(value[strings.TrimSuffix(path[0], "[]")], path[1:])
+	case string:
+		if len(path) == 0 && value != "" {
+			return []string{value}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,248 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseImageOverrideConfig(t *testing.T) {
+	type testCase struct {
+		data     map[string]string
+		expected *ImageOverrideConfig
+		err      bool
+	}
+	for _, tc := range []testCase{
+		{data: nil, expected: &ImageOverrideConfig{}},
+		{
+			data: map[string]string{
+				ImageOverrideConfigMapOverrideKey:  "- match:\n    glob: docker.io/*/*\n  newRegistry: mirror.io\n  newTag: v1",
+				ImageOverrideConfigMapPinDigestKey: "true",
+			},
+			expected: &ImageOverrideConfig{
+				ImageOverride:  []ImageOverride{{Match: &ImageMatch{Glob: "docker.io/*/*"}, NewRegistry: "mirror.io", NewTag: "v1"}},
+				PinImageDigest: true,
+			},
+		},
+		{
+			data: map[string]string{
+				ImageOverrideConfigMapFieldSpecsKey:   "- kind: Foo\n  path: spec/image",
+				ImageOverrideConfigMapFieldPresetsKey: "- knative",
+			},
+			expected: &ImageOverrideConfig{
+				ImageFieldSpecs:   []ImageFieldSpec{{Kind: "Foo", Path: "spec/image"}},
+				ImageFieldPresets: []string{"knative"},
+			},
+		},
+		{data: map[string]string{ImageOverrideConfigMapOverrideKey: "registry: docker.io"}, err: true},
+		{data: map[string]string{ImageOverrideConfigMapOverrideKey: "- match:\n    glob: docker.io/["}, err: true},
+		{data: map[string]string{ImageOverrideConfigMapOverrideKey: "- match:\n    regex: docker.io/("}, err: true},
+		{data: map[string]string{ImageOverrideConfigMapFieldPresetsKey: "- unknown"}, err: true},
+		{data: map[string]string{ImageOverrideConfigMapFieldPresetsKey: "knative"}, err: true},
+		{data: map[string]string{ImageOverrideConfigMapPinDigestKey: "yes"}, err: true},
+	} {
+		cfg, err := ParseImageOverrideConfig(tc.data)
+		if (err != nil) != tc.err {
+			t.Fatalf("Test Failed. data: %v, expected error: %t, actual: %v", tc.data, tc.err, err)
+		}
+		if !tc.err && !reflect.DeepEqual(cfg, tc.expected) {
+			t.Fatalf("Test Failed. expected: %v, actual: %v", tc.expected, cfg)
+		}
+	}
+}
+
+func TestGetImageOverrideConfig(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: ImageOverrideConfigMapName, Namespace: "kubebb-system"},
+		Data:       map[string]string{ImageOverrideConfigMapPinDigestKey: "true"},
+	}
+
+	t.Setenv("POD_NAMESPACE", "")
+	if cfg, err := GetImageOverrideConfig(context.TODO(), fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm).Build()); err != nil || !reflect.DeepEqual(cfg, &ImageOverrideConfig{}) {
+		t.Fatalf("Test Failed. expected empty config without operator namespace, actual: %v %v", cfg, err)
+	}
+	if IsImageOverrideConfigMap(cm) {
+		t.Fatalf("Test Failed. expected not the image override configmap without operator namespace")
+	}
+
+	t.Setenv("POD_NAMESPACE", "kubebb-system")
+	if !IsImageOverrideConfigMap(cm) {
+		t.Fatalf("Test Failed. expected the image override configmap")
+	}
+
+	cfg, err := GetImageOverrideConfig(context.TODO(), fake.NewClientBuilder().WithScheme(scheme).Build())
+	if err != nil || !reflect.DeepEqual(cfg, &ImageOverrideConfig{}) {
+		t.Fatalf("Test Failed. expected empty config without configmap, actual: %v %v", cfg, err)
+	}
+	cfg, err = GetImageOverrideConfig(context.TODO(), fake.NewClientBuilder().WithScheme(scheme).WithObjects(cm).Build())
+	if err != nil || !cfg.PinImageDigest {
+		t.Fatalf("Test Failed. expected pinImageDigest from configmap, actual: %v %v", cfg, err)
+	}
+}
+
+func TestImageOverrideConfigForRepository(t *testing.T) {
+	cluster := &ImageOverrideConfig{ImageOverride: []ImageOverride{{Registry: "docker.io", NewRegistry: "mirror.io"}}}
+	repo := &Repository{Spec: RepositorySpec{
+		ImageOverride:  []ImageOverride{{Registry: "docker.io", NewRegistry: "repo.io"}},
+		PinImageDigest: true,
+	}}
+	type testCase struct {
+		repo     *Repository
+		expected *ImageOverrideConfig
+	}
+	for _, tc := range []testCase{
+		{repo: nil, expected: cluster},
+		{repo: repo, expected: &ImageOverrideConfig{
+			ImageOverride:  []ImageOverride{{Registry: "docker.io", NewRegistry: "mirror.io"}, {Registry: "docker.io", NewRegistry: "repo.io"}},
+			PinImageDigest: true,
+		}},
+	} {
+		if cfg := cluster.ForRepository(tc.repo); !reflect.DeepEqual(cfg, tc.expected) {
+			t.Fatalf("Test Failed. expected: %v, actual: %v", tc.expected, cfg)
+		}
+	}
+	if len(cluster.ImageOverride) != 1 || cluster.PinImageDigest {
+		t.Fatalf("Test Failed. the default config should not be changed, actual: %v", cluster)
+	}
+}
+
+func TestImageOverrideConfigFieldSpecs(t *testing.T) {
+	t.Setenv("IMAGEOVERRIDE_PATH", "spec/containers/image")
+	type testCase struct {
+		cfg      *ImageOverrideConfig
+		expected []ImageFieldSpec
+		err      bool
+	}
+	for _, tc := range []testCase{
+		{cfg: &ImageOverrideConfig{}, expected: []ImageFieldSpec{{Path: "spec/containers/image"}}},
+		{
+			cfg: &ImageOverrideConfig{
+				ImageFieldPresets: []string{"knative"},
+				ImageFieldSpecs:   []ImageFieldSpec{{Path: "spec/containers/image"}, {Kind: "Foo", Path: "spec/image"}, {Kind: "Bar"}},
+			},
+			expected: append(append([]ImageFieldSpec{{Path: "spec/containers/image"}}, ImageFieldPresets["knative"]...), ImageFieldSpec{Kind: "Foo", Path: "spec/image"}),
+		},
+		{cfg: &ImageOverrideConfig{ImageFieldPresets: []string{"unknown"}}, err: true},
+	} {
+		specs, err := tc.cfg.FieldSpecs()
+		if (err != nil) != tc.err {
+			t.Fatalf("Test Failed. expected error: %t, actual: %v", tc.err, err)
+		}
+		if !tc.err && !reflect.DeepEqual(specs, tc.expected) {
+			t.Fatalf("Test Failed. expected: %v, actual: %v", tc.expected, specs)
+		}
+	}
+}
+
+func TestValidateImageOverrides(t *testing.T) {
+	type testCase struct {
+		overrides []ImageOverride
+		err       bool
+	}
+	for _, tc := range []testCase{
+		{overrides: nil},
+		{overrides: []ImageOverride{{Registry: "docker.io"}, {Match: &ImageMatch{Glob: "docker.io/*/nginx", Regex: ".*/library/.*"}}}},
+		{overrides: []ImageOverride{{Registry: "docker.io"}, {Match: &ImageMatch{Glob: "docker.io/["}}}, err: true},
+		{overrides: []ImageOverride{{Match: &ImageMatch{Regex: "docker.io/("}}}, err: true},
+	} {
+		if err := ValidateImageOverrides(tc.overrides); (err != nil) != tc.err {
+			t.Fatalf("Test Failed. overrides: %v, expected error: %t, actual: %v", tc.overrides, tc.err, err)
+		}
+	}
+
+	repo := &Repository{Spec: RepositorySpec{ImageOverride: []ImageOverride{{Match: &ImageMatch{Regex: "("}}}}}
+	if err := repo.ValidateCreate(context.TODO(), repo); err == nil {
+		t.Fatalf("Test Failed. expected the repository with an invalid regex to be rejected")
+	}
+	repo.Spec.ImageOverride[0].Match.Regex = "docker.io/.*"
+	if err := repo.ValidateUpdate(context.TODO(), repo, repo); err != nil {
+		t.Fatalf("Test Failed. expected the repository to be valid, actual: %v", err)
+	}
+}
+
+func TestImageOverrideConfigHash(t *testing.T) {
+	cfg := &ImageOverrideConfig{ImageOverride: []ImageOverride{{Registry: "docker.io", NewRegistry: "mirror.io"}}}
+	if cfg.Hash() != cfg.DeepCopy().Hash() {
+		t.Fatalf("Test Failed. expected the same hash of the same config")
+	}
+	changed := cfg.DeepCopy()
+	changed.PinImageDigest = true
+	if cfg.Hash() == changed.Hash() {
+		t.Fatalf("Test Failed. expected different hashes of different configs")
+	}
+}
+
+func TestImageFieldSpecImages(t *testing.T) {
+	rollout := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Rollout",
+		"metadata":   map[string]interface{}{"name": "app", "namespace": "default"},
+		"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+			"containers":     []interface{}{map[string]interface{}{"name": "a", "image": "nginx:1.25"}, map[string]interface{}{"name": "b", "image": "redis:7"}},
+			"initContainers": []interface{}{map[string]interface{}{"name": "init", "image": "busybox:1.36"}},
+		}}},
+	}}
+	type testCase struct {
+		spec     ImageFieldSpec
+		expected []string
+	}
+	for _, tc := range []testCase{
+		{spec: ImageFieldSpec{Path: "spec/template/spec/containers/image"}, expected: []string{"nginx:1.25", "redis:7"}},
+		{spec: ImageFieldSpec{Group: "argoproj.io", Kind: "Rollout", Path: "spec/template/spec/initContainers[]/image"}, expected: []string{"busybox:1.36"}},
+		{spec: ImageFieldSpec{Kind: "Deployment", Path: "spec/template/spec/containers/image"}},
+		{spec: ImageFieldSpec{Path: "spec/template/spec/containers"}},
+		{spec: ImageFieldSpec{Path: "spec/missing/image"}},
+	} {
+		if images := tc.spec.Images(rollout); len(images) != len(tc.expected) || (len(images) > 0 && !reflect.DeepEqual(images, tc.expected)) {
+			t.Fatalf("Test Failed. spec: %v, expected: %v, actual: %v", tc.spec, tc.expected, images)
+		}
+	}
+
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	manifest := `apiVersion: argoproj.io/v1alpha1
+kind: Rollout
+metadata:
+  name: app
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: a
+        image: nginx:1.25
+`
+	presets, err := (&ImageOverrideConfig{ImageFieldPresets: []string{"argo-rollouts"}}).FieldSpecs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, images, err := GetResourcesAndImages(context.TODO(), logr.Discard(), fake.NewClientBuilder().WithScheme(scheme).Build(), manifest, "default", presets)
+	if err != nil || !reflect.DeepEqual(images, []string{"nginx:1.25"}) {
+		t.Fatalf("Test Failed. expected the images of the preset fields, actual: %v %v", images, err)
+	}
+}
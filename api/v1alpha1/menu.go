@@ -0,0 +1,148 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Condition reasons for Menu
+const (
+	MenuReasonValid          ConditionReason = "Valid"
+	MenuReasonParentNotFound ConditionReason = "ParentNotFound"
+	MenuReasonCycle          ConditionReason = "Cycle"
+	MenuReasonSlotConflict   ConditionReason = "SlotConflict"
+)
+
+// GetParent returns the name of the parent Menu, Parent takes precedence over ParentOwnerReferences
+func (m *Menu) GetParent() string {
+	if m.Spec.Parent != "" {
+		return m.Spec.Parent
+	}
+	if m.Spec.ParentOwnerReferences.Kind == "" || m.Spec.ParentOwnerReferences.Kind == "Menu" {
+		return m.Spec.ParentOwnerReferences.Name
+	}
+	return ""
+}
+
+// IsValid returns true if the Menu is not marked invalid in the menu tree
+func (m *Menu) IsValid() bool {
+	return m.Status.GetCondition(TypeReady).Status != corev1.ConditionFalse
+}
+
+func MenuValid() Condition {
+	return menuCondition(corev1.ConditionTrue, MenuReasonValid, "")
+}
+
+func MenuInvalid(reason ConditionReason, msg string) Condition {
+	return menuCondition(corev1.ConditionFalse, reason, msg)
+}
+
+func menuCondition(status corev1.ConditionStatus, reason ConditionReason, msg string) Condition {
+	return Condition{
+		Type:               TypeReady,
+		Status:             status,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            msg,
+	}
+}
+
+// ValidateMenuTree checks the parent/child tree of the menus and returns the Ready condition of each menu by name.
+// A menu is invalid if its parent does not exist, it is in a parent cycle,
+// or it takes the same Column and RankingInColumn as an older enabled sibling,
+// so a new Menu in a taken slot does not invalidate the existing one.
+func ValidateMenuTree(menus []Menu) map[string]Condition {
+	byName := make(map[string]*Menu, len(menus))
+	for i := range menus {
+		byName[menus[i].Name] = &menus[i]
+	}
+	res := make(map[string]Condition, len(menus))
+
+	for i := range menus {
+		name := menus[i].Name
+		parent := menus[i].GetParent()
+		if parent != "" && byName[parent] == nil {
+			res[name] = MenuInvalid(MenuReasonParentNotFound, fmt.Sprintf("parent menu %s not found", parent))
+		}
+	}
+
+	for i := range menus {
+		if _, ok := res[menus[i].Name]; ok {
+			continue
+		}
+		// walk up the parents, the menu is in a cycle if the walk comes back to itself
+		path := []string{menus[i].Name}
+		visited := map[string]bool{menus[i].Name: true}
+		for cur := byName[menus[i].GetParent()]; cur != nil; cur = byName[cur.GetParent()] {
+			if cur.Name == menus[i].Name {
+				res[menus[i].Name] = MenuInvalid(MenuReasonCycle, fmt.Sprintf("parent cycle %s", strings.Join(append(path, cur.Name), " -> ")))
+				break
+			}
+			if visited[cur.Name] {
+				break
+			}
+			visited[cur.Name] = true
+			path = append(path, cur.Name)
+		}
+	}
+
+	slots := make(map[string][]string)
+	for i := range menus {
+		m := &menus[i]
+		if m.Spec.Disabled || m.Spec.RankingInColumn == 0 {
+			continue
+		}
+		slot := fmt.Sprintf("%s/%d/%d", m.GetParent(), m.Spec.Column, m.Spec.RankingInColumn)
+		slots[slot] = append(slots[slot], m.Name)
+	}
+	for _, names := range slots {
+		// the oldest menu keeps the slot, the invalid ones in the slot are ignored
+		holders := make([]*Menu, 0, len(names))
+		for _, name := range names {
+			if _, ok := res[name]; !ok {
+				holders = append(holders, byName[name])
+			}
+		}
+		if len(holders) < 2 {
+			continue
+		}
+		sort.Slice(holders, func(i, j int) bool {
+			ti, tj := holders[i].CreationTimestamp, holders[j].CreationTimestamp
+			if !ti.Equal(&tj) {
+				return ti.Before(&tj)
+			}
+			return holders[i].Name < holders[j].Name
+		})
+		for _, m := range holders[1:] {
+			res[m.Name] = MenuInvalid(MenuReasonSlotConflict, fmt.Sprintf("column %d ranking %d is taken by menu %s",
+				m.Spec.Column, m.Spec.RankingInColumn, holders[0].Name))
+		}
+	}
+
+	for i := range menus {
+		if _, ok := res[menus[i].Name]; !ok {
+			res[menus[i].Name] = MenuValid()
+		}
+	}
+	return res
+}
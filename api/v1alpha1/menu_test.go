@@ -0,0 +1,84 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMenuGetParent(t *testing.T) {
+	m := Menu{ObjectMeta: metav1.ObjectMeta{Name: "a"}, Spec: MenuSpec{ParentOwnerReferences: metav1.OwnerReference{Kind: "Menu", Name: "owner"}}}
+	if r := m.GetParent(); r != "owner" {
+		t.Fatalf("Test Failed. expect owner get %s", r)
+	}
+	m.Spec.Parent = "parent"
+	if r := m.GetParent(); r != "parent" {
+		t.Fatalf("Test Failed. expect parent get %s", r)
+	}
+}
+
+func TestValidateMenuTree(t *testing.T) {
+	now := metav1.Now()
+	menus := []Menu{
+		{ObjectMeta: metav1.ObjectMeta{Name: "root"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "child"}, Spec: MenuSpec{Parent: "root", RankingInColumn: 1}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "grandchild"}, Spec: MenuSpec{Parent: "child", RankingInColumn: 1}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "dangling"}, Spec: MenuSpec{Parent: "missing"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "self"}, Spec: MenuSpec{Parent: "self"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "cycle-a"}, Spec: MenuSpec{Parent: "cycle-b"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "cycle-b"}, Spec: MenuSpec{Parent: "cycle-a"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "under-cycle"}, Spec: MenuSpec{Parent: "cycle-a"}},
+		// slot-old is created before slot-a and slot-b, so it keeps the slot
+		{ObjectMeta: metav1.ObjectMeta{Name: "slot-a", CreationTimestamp: now}, Spec: MenuSpec{Parent: "root", Column: 1, RankingInColumn: 1}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "slot-b", CreationTimestamp: now}, Spec: MenuSpec{Parent: "root", Column: 1, RankingInColumn: 1}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "slot-old", CreationTimestamp: metav1.NewTime(now.Add(-time.Hour))}, Spec: MenuSpec{Parent: "root", Column: 1, RankingInColumn: 1}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "same-time-a"}, Spec: MenuSpec{Parent: "child", Column: 1, RankingInColumn: 1}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "same-time-b"}, Spec: MenuSpec{Parent: "child", Column: 1, RankingInColumn: 1}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "dangling-slot"}, Spec: MenuSpec{Parent: "missing", Column: 1, RankingInColumn: 1}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "disabled"}, Spec: MenuSpec{Parent: "root", Column: 1, RankingInColumn: 1, Disabled: true}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "other-column"}, Spec: MenuSpec{Parent: "root", Column: 2, RankingInColumn: 1}},
+	}
+	conditions := ValidateMenuTree(menus)
+	for name, expect := range map[string]ConditionReason{
+		"root":          MenuReasonValid,
+		"child":         MenuReasonValid,
+		"grandchild":    MenuReasonValid,
+		"dangling":      MenuReasonParentNotFound,
+		"self":          MenuReasonCycle,
+		"cycle-a":       MenuReasonCycle,
+		"cycle-b":       MenuReasonCycle,
+		"under-cycle":   MenuReasonValid,
+		"slot-old":      MenuReasonValid,
+		"slot-a":        MenuReasonSlotConflict,
+		"slot-b":        MenuReasonSlotConflict,
+		"same-time-a":   MenuReasonValid,
+		"same-time-b":   MenuReasonSlotConflict,
+		"dangling-slot": MenuReasonParentNotFound,
+		"disabled":      MenuReasonValid,
+		"other-column":  MenuReasonValid,
+	} {
+		if r := conditions[name].Reason; r != expect {
+			t.Fatalf("Test Failed. %s expect %s get %s: %s", name, expect, r, conditions[name].Message)
+		}
+	}
+	if len(conditions) != len(menus) {
+		t.Fatalf("Test Failed. expect %d conditions get %d", len(menus), len(conditions))
+	}
+}
@@ -105,8 +105,9 @@ type GetTitleForReplaceSider struct {
 
 // MenuStatus defines the observed state of Menu
 type MenuStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// ConditionedStatus shows whether the Menu is a valid node of the menu tree,
+	// the invalid Menus are not rendered.
+	ConditionedStatus `json:",inline"`
 }
 
 //+kubebuilder:object:root=true
@@ -0,0 +1,56 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/utils/strings/slices"
+)
+
+var ErrPolicyRuleInvalid = errors.New("invalid policy rule")
+
+// Matches returns whether the rule selects the resource of the api group and kind
+func (r *PolicyRule) Matches(group, kind string) bool {
+	if r.Match == nil {
+		return true
+	}
+	return (len(r.Match.APIGroups) == 0 || slices.Contains(r.Match.APIGroups, group)) &&
+		(len(r.Match.Kinds) == 0 || slices.Contains(r.Match.Kinds, kind))
+}
+
+// GetMessage returns the message shown when a resource violates the rule
+func (r *PolicyRule) GetMessage() string {
+	if r.Message != "" {
+		return r.Message
+	}
+	return r.Expression
+}
+
+// Validate checks the rule names are unique, the expressions are compiled by pkg/policy
+func (p *Policy) Validate() error {
+	names := make(map[string]bool, len(p.Spec.Rules))
+	for i := range p.Spec.Rules {
+		rule := &p.Spec.Rules[i]
+		if names[rule.Name] {
+			return fmt.Errorf("%w: duplicated rule name %s", ErrPolicyRuleInvalid, rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPolicyValidate(t *testing.T) {
+	type testCase struct {
+		rules []PolicyRule
+		err   bool
+	}
+	for _, tc := range []testCase{
+		{rules: []PolicyRule{{Name: "a", Expression: "object.kind == 'Pod'"}, {Name: "b", Expression: "has(object.metadata.labels)"}}},
+		{rules: []PolicyRule{{Name: "dyn", Expression: "object.spec.enabled"}}},
+		{rules: []PolicyRule{{Name: "a", Expression: "true"}, {Name: "a", Expression: "true"}}, err: true},
+	} {
+		p := &Policy{Spec: PolicySpec{Rules: tc.rules}}
+		err := p.Validate()
+		if (err != nil) != tc.err {
+			t.Fatalf("Test Failed. rules: %v, expected error: %t, actual: %v", tc.rules, tc.err, err)
+		}
+		if err != nil && !errors.Is(err, ErrPolicyRuleInvalid) {
+			t.Fatalf("Test Failed. expected ErrPolicyRuleInvalid, actual: %v", err)
+		}
+	}
+}
+
+func TestPolicyRuleMatches(t *testing.T) {
+	type testCase struct {
+		match *PolicyMatch
+		group string
+		kind  string
+		exp   bool
+	}
+	for _, tc := range []testCase{
+		{match: nil, group: "", kind: "Service", exp: true},
+		{match: &PolicyMatch{}, group: "apps", kind: "Deployment", exp: true},
+		{match: &PolicyMatch{APIGroups: []string{""}}, group: "", kind: "Service", exp: true},
+		{match: &PolicyMatch{APIGroups: []string{""}}, group: "apps", kind: "Deployment"},
+		{match: &PolicyMatch{APIGroups: []string{"apps"}, Kinds: []string{"Deployment"}}, group: "apps", kind: "Deployment", exp: true},
+		{match: &PolicyMatch{APIGroups: []string{"apps"}, Kinds: []string{"Deployment"}}, group: "apps", kind: "DaemonSet"},
+	} {
+		rule := &PolicyRule{Match: tc.match}
+		if r := rule.Matches(tc.group, tc.kind); r != tc.exp {
+			t.Fatalf("Test Failed. match: %v, group: %s, kind: %s, expected: %t, actual: %t", tc.match, tc.group, tc.kind, tc.exp, r)
+		}
+	}
+}
+
+func TestComponentPlanIsPolicyViolated(t *testing.T) {
+	plan := &ComponentPlan{}
+	if plan.IsPolicyViolated() {
+		t.Fatalf("Test Failed. expected not violated without conditions")
+	}
+	plan.Status.SetConditions(ComponentPlanPolicyViolation(errors.New("violations")))
+	if !plan.IsPolicyViolated() {
+		t.Fatalf("Test Failed. expected violated")
+	}
+	plan.Status.SetConditions(ComponentPlanInstalling())
+	if plan.IsPolicyViolated() {
+		t.Fatalf("Test Failed. expected not violated after installing")
+	}
+}
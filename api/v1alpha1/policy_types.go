@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PolicySpec defines the desired state of Policy
+type PolicySpec struct {
+	// Rules are evaluated against every resource in the manifests of the ComponentPlans in the same namespace
+	// before they are installed or upgraded, a ComponentPlan is blocked if any resource violates any rule.
+	// +kubebuilder:validation:MinItems=1
+	Rules []PolicyRule `json:"rules"`
+
+	// Disabled skips all the rules of this Policy
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// PolicyRule is a CEL expression which must be true for every selected resource
+type PolicyRule struct {
+	// Name of the rule, unique in the Policy
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Match selects the resources to check, all resources are checked if it is not set
+	// +optional
+	Match *PolicyMatch `json:"match,omitempty"`
+
+	// Expression is a CEL expression returns bool, the resource is referred as `object`, like
+	// `!has(object.spec.hostNetwork) || !object.spec.hostNetwork`.
+	// A resource violates the rule if the expression returns false or fails to evaluate.
+	// +kubebuilder:validation:MinLength=1
+	Expression string `json:"expression"`
+
+	// Message is shown when a resource violates the rule, default is the expression
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// PolicyMatch selects the resources by the api groups and kinds, an empty list matches all
+type PolicyMatch struct {
+	// APIGroups of the resources, use "" for the core group, like `apps`
+	// +optional
+	APIGroups []string `json:"apiGroups,omitempty"`
+	// Kinds of the resources, like `Deployment`
+	// +optional
+	Kinds []string `json:"kinds,omitempty"`
+}
+
+// PolicyStatus defines the observed state of Policy
+type PolicyStatus struct {
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Namespaced
+
+// Policy is the Schema for the policies API
+type Policy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PolicySpec   `json:"spec,omitempty"`
+	Status PolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// PolicyList contains a list of Policy
+type PolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Policy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Policy{}, &PolicyList{})
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kubebb/core/pkg/utils"
+)
+
+const (
+	// PortalAllowConflictsAnnotation with value "true" allows the Portal to conflict with the existing Portals,
+	// it is used to take over the path or entry of another Portal in plan.
+	PortalAllowConflictsAnnotation = Group + "/allow-conflicts"
+
+	// ChartPortalPathAnnotation and ChartPortalEntryAnnotation in the Chart.yaml declare the Portal of the chart,
+	// the Portal is named after the release and generated when the ComponentPlan is installed.
+	ChartPortalPathAnnotation  = Group + "/portal-path"
+	ChartPortalEntryAnnotation = Group + "/portal-entry"
+	// ChartMenusAnnotation in the Chart.yaml declares the Menus of the chart, in the format of a yaml list of Menus
+	// with metadata.name and spec, they are generated when the ComponentPlan is installed.
+	ChartMenusAnnotation = Group + "/menus"
+
+	// GeneratedReleaseNameLabel and GeneratedReleaseNamespaceLabel mark the Portals and Menus generated by a release.
+	// They are cluster scoped, so they are owned by the labels instead of the ownerReferences.
+	// The owner is the release instead of the ComponentPlan, because a Subscription creates a new ComponentPlan
+	// for each version, and the ComponentPlan of the new version must take over the ones of the old version.
+	GeneratedReleaseNameLabel      = Group + "/release-name"
+	GeneratedReleaseNamespaceLabel = Group + "/release-namespace"
+)
+
+// AllowConflicts returns true if the Portal is allowed to conflict with the existing Portals
+func (p *Portal) AllowConflicts() bool {
+	return p.Annotations[PortalAllowConflictsAnnotation] == "true"
+}
+
+// PortalPathsOverlap returns true if a request path can be routed to both paths,
+// the paths overlap when they are the same or one is the parent of the other, like /a and /a/b,
+// but /a and /ab do not overlap. The trailing slashes are ignored.
+func PortalPathsOverlap(a, b string) bool {
+	a, b = strings.TrimRight(a, "/")+"/", strings.TrimRight(b, "/")+"/"
+	return strings.HasPrefix(a, b) || strings.HasPrefix(b, a)
+}
+
+// GetPortalConflicts returns the names of the portals which have the same entry or an overlapped path with the portal
+func GetPortalConflicts(portal *Portal, portals []Portal) (entryConflicts, pathConflicts []string) {
+	entryConflicts = make([]string, 0)
+	pathConflicts = make([]string, 0)
+	for _, p := range portals {
+		if portal.Name == p.Name {
+			continue
+		}
+		if portal.Spec.Entry == p.Spec.Entry {
+			entryConflicts = append(entryConflicts, p.Name)
+		}
+		if PortalPathsOverlap(portal.Spec.Path, p.Spec.Path) {
+			pathConflicts = append(pathConflicts, p.Name)
+		}
+	}
+	return entryConflicts, pathConflicts
+}
+
+// GeneratedLabels returns the labels of the Portals and Menus generated by the release of the ComponentPlan
+func (c *ComponentPlan) GeneratedLabels() map[string]string {
+	return map[string]string{
+		GeneratedReleaseNameLabel:      c.GetReleaseName(),
+		GeneratedReleaseNamespaceLabel: c.Namespace,
+	}
+}
+
+// IsGeneratedBy returns true if the Portal or Menu with the labels is generated by the release of the ComponentPlan
+func (c *ComponentPlan) IsGeneratedBy(labels map[string]string) bool {
+	return labels[GeneratedReleaseNameLabel] == c.GetReleaseName() && labels[GeneratedReleaseNamespaceLabel] == c.Namespace
+}
+
+// PortalAndMenusFromChart returns the Portal and Menus declared in the Chart.yaml annotations,
+// the Portal is nil if the chart declares no portal path.
+func PortalAndMenusFromChart(plan *ComponentPlan, annotations map[string]string) (portal *Portal, menus []Menu, err error) {
+	if path := annotations[ChartPortalPathAnnotation]; path != "" {
+		portal = &Portal{}
+		portal.SetName(plan.GetReleaseName())
+		portal.SetLabels(plan.GeneratedLabels())
+		portal.Spec.Path = path
+		portal.Spec.Entry = annotations[ChartPortalEntryAnnotation]
+	}
+	if data := annotations[ChartMenusAnnotation]; data != "" {
+		if err = yaml.Unmarshal([]byte(data), &menus); err != nil {
+			return nil, nil, fmt.Errorf("parse annotation %s: %w", ChartMenusAnnotation, err)
+		}
+		for i := range menus {
+			if menus[i].Name == "" {
+				return nil, nil, fmt.Errorf("parse annotation %s: menu %d has no name", ChartMenusAnnotation, i)
+			}
+			menus[i].SetLabels(plan.GeneratedLabels())
+		}
+	}
+	return portal, menus, nil
+}
+
+// PortalAndMenusFromManifest returns the Portal and Menus rendered by the chart,
+// the portal is empty if the chart renders no Portal.
+func PortalAndMenusFromManifest(data string) (portal Router, menus []MenuReference, err error) {
+	objs, err := utils.SplitYAML([]byte(data))
+	if err != nil {
+		return portal, nil, err
+	}
+	for _, obj := range objs {
+		if obj.GroupVersionKind().Group != GroupVersion.Group {
+			continue
+		}
+		switch obj.GetKind() {
+		case "Portal":
+			p := &Portal{}
+			if err = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, p); err != nil {
+				return portal, nil, err
+			}
+			portal = Router{Path: p.Spec.Path, Entry: p.Spec.Entry}
+		case "Menu":
+			menus = append(menus, MenuReference{Name: obj.GetName()})
+		}
+	}
+	return portal, menus, nil
+}
+
+// MergeMenuReferences returns the sorted and unique references of the menus
+func MergeMenuReferences(refs ...[]MenuReference) []MenuReference {
+	names := make(map[string]bool)
+	res := make([]MenuReference, 0)
+	for _, r := range refs {
+		for _, ref := range r {
+			if !names[ref.Name] {
+				names[ref.Name] = true
+				res = append(res, ref)
+			}
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPortalPathsOverlap(t *testing.T) {
+	type input struct {
+		a, b   string
+		expect bool
+	}
+	for _, tc := range []input{
+		{a: "/a", b: "/a", expect: true},
+		{a: "/a/", b: "/a", expect: true},
+		{a: "/a", b: "/a/b", expect: true},
+		{a: "/a/b/c", b: "/a/", expect: true},
+		{a: "/", b: "/a", expect: true},
+		{a: "/a", b: "/ab"},
+		{a: "/a/b", b: "/a/c"},
+	} {
+		if r := PortalPathsOverlap(tc.a, tc.b); r != tc.expect {
+			t.Fatalf("Test Failed. %s %s expect %v get %v", tc.a, tc.b, tc.expect, r)
+		}
+	}
+}
+
+func TestGetPortalConflicts(t *testing.T) {
+	portals := []Portal{
+		Portal{ObjectMeta: metav1.ObjectMeta{Name: "example"}, Spec: PortalSpec{Path: "/example", Entry: "/example/index.html"}},
+		Portal{ObjectMeta: metav1.ObjectMeta{Name: "sub"}, Spec: PortalSpec{Path: "/example/sub", Entry: "/sub/index.html"}},
+		Portal{ObjectMeta: metav1.ObjectMeta{Name: "another"}, Spec: PortalSpec{Path: "/another", Entry: "/example/index.html"}},
+		Portal{ObjectMeta: metav1.ObjectMeta{Name: "examples"}, Spec: PortalSpec{Path: "/examples", Entry: "/examples/index.html"}},
+	}
+	entry, path := GetPortalConflicts(&portals[0], portals)
+	if !reflect.DeepEqual(entry, []string{"another"}) || !reflect.DeepEqual(path, []string{"sub"}) {
+		t.Fatalf("Test Failed. get entry conflicts %v path conflicts %v", entry, path)
+	}
+	entry, path = GetPortalConflicts(&portals[3], portals)
+	if len(entry) != 0 || len(path) != 0 {
+		t.Fatalf("Test Failed. get entry conflicts %v path conflicts %v", entry, path)
+	}
+}
+
+func TestPortalCheckConflicts(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = AddToScheme(scheme)
+	existing := Portal{ObjectMeta: metav1.ObjectMeta{Name: "example"}, Spec: PortalSpec{Path: "/example", Entry: "/example/index.html"}}
+	validator := &portalValidator{client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(&existing).Build()}
+
+	type input struct {
+		name   string
+		portal Portal
+		expect error
+	}
+	for _, tc := range []input{
+		{name: "no conflicts", portal: Portal{ObjectMeta: metav1.ObjectMeta{Name: "another"}, Spec: PortalSpec{Path: "/another", Entry: "/another/index.html"}}},
+		{name: "itself", portal: existing},
+		{name: "entry conflicts", portal: Portal{ObjectMeta: metav1.ObjectMeta{Name: "another"}, Spec: PortalSpec{Path: "/another", Entry: "/example/index.html"}}, expect: ErrPortalConflict},
+		{name: "path overlaps", portal: Portal{ObjectMeta: metav1.ObjectMeta{Name: "sub"}, Spec: PortalSpec{Path: "/example/sub", Entry: "/sub/index.html"}}, expect: ErrPortalConflict},
+		{
+			name: "allow conflicts",
+			portal: Portal{
+				ObjectMeta: metav1.ObjectMeta{Name: "takeover", Annotations: map[string]string{PortalAllowConflictsAnnotation: "true"}},
+				Spec:       PortalSpec{Path: "/example", Entry: "/example/index.html"},
+			},
+		},
+	} {
+		if err := validator.checkConflicts(context.TODO(), &tc.portal); !errors.Is(err, tc.expect) {
+			t.Fatalf("Test Failed. %s expect %v get %v", tc.name, tc.expect, err)
+		}
+	}
+}
+
+func TestPortalAndMenusFromChart(t *testing.T) {
+	plan := &ComponentPlan{ObjectMeta: metav1.ObjectMeta{Name: "plan", Namespace: "default"}, Spec: ComponentPlanSpec{Config: Config{Name: "lowcode"}}}
+	type input struct {
+		name        string
+		annotations map[string]string
+		expectPath  string
+		expectMenus []string
+		expectErr   bool
+	}
+	for _, tc := range []input{
+		{name: "no annotations"},
+		{name: "portal", annotations: map[string]string{ChartPortalPathAnnotation: "/lowcode", ChartPortalEntryAnnotation: "/lowcode-public/index.html"}, expectPath: "/lowcode"},
+		{name: "menus", annotations: map[string]string{ChartMenusAnnotation: "- metadata:\n    name: lowcode\n  spec:\n    textEn: Lowcode\n- metadata:\n    name: lowcode-apps\n  spec:\n    textEn: Apps\n    parent: lowcode\n"}, expectMenus: []string{"lowcode", "lowcode-apps"}},
+		{name: "menu without name", annotations: map[string]string{ChartMenusAnnotation: "- spec:\n    textEn: Lowcode\n"}, expectErr: true},
+		{name: "invalid menus", annotations: map[string]string{ChartMenusAnnotation: "lowcode"}, expectErr: true},
+	} {
+		portal, menus, err := PortalAndMenusFromChart(plan, tc.annotations)
+		if (err != nil) != tc.expectErr {
+			t.Fatalf("Test Failed. %s expect error %v get %v", tc.name, tc.expectErr, err)
+		}
+		if tc.expectPath == "" && portal != nil {
+			t.Fatalf("Test Failed. %s expect no portal get %v", tc.name, portal)
+		}
+		if tc.expectPath != "" && (portal == nil || portal.Spec.Path != tc.expectPath || portal.Name != "lowcode" || !plan.IsGeneratedBy(portal.Labels)) {
+			t.Fatalf("Test Failed. %s expect portal path %s get %v", tc.name, tc.expectPath, portal)
+		}
+		names := make([]string, 0)
+		for _, m := range menus {
+			if !plan.IsGeneratedBy(m.Labels) {
+				t.Fatalf("Test Failed. %s menu %s is not labeled", tc.name, m.Name)
+			}
+			names = append(names, m.Name)
+		}
+		if len(tc.expectMenus) > 0 && !reflect.DeepEqual(names, tc.expectMenus) {
+			t.Fatalf("Test Failed. %s expect menus %v get %v", tc.name, tc.expectMenus, names)
+		}
+	}
+}
+
+func TestPortalAndMenusFromManifest(t *testing.T) {
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: lowcode
+---
+apiVersion: core.kubebb.k8s.com.cn/v1alpha1
+kind: Portal
+metadata:
+  name: lowcode
+spec:
+  entry: /lowcode-public/index.html
+  path: /lowcode
+---
+apiVersion: core.kubebb.k8s.com.cn/v1alpha1
+kind: Menu
+metadata:
+  name: lowcode-menu
+spec:
+  textEn: Lowcode
+`
+	portal, menus, err := PortalAndMenusFromManifest(manifest)
+	if err != nil {
+		t.Fatalf("Test Failed. get error %v", err)
+	}
+	if expect := (Router{Path: "/lowcode", Entry: "/lowcode-public/index.html"}); portal != expect {
+		t.Fatalf("Test Failed. expect %v get %v", expect, portal)
+	}
+	if expect := []MenuReference{{Name: "lowcode-menu"}}; !reflect.DeepEqual(menus, expect) {
+		t.Fatalf("Test Failed. expect %v get %v", expect, menus)
+	}
+}
+
+func TestMergeMenuReferences(t *testing.T) {
+	get := MergeMenuReferences([]MenuReference{{Name: "b"}, {Name: "a"}}, []MenuReference{{Name: "b"}, {Name: "c"}})
+	if expect := []MenuReference{{Name: "a"}, {Name: "b"}, {Name: "c"}}; !reflect.DeepEqual(get, expect) {
+		t.Fatalf("Test Failed. expect %v get %v", expect, get)
+	}
+}
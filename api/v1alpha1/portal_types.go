@@ -35,7 +35,7 @@ type PortalSpec struct {
 type PortalStatus struct {
 	// conflicted portals with same Entry
 	ConflictsInEntry []string `json:"conflictsInEntry,omitempty"`
-	// conflicted portals with same Path
+	// conflicted portals with same or overlapped Path, like /a and /a/b
 	ConflictsInPath []string `json:"conflictsInPath,omitempty"`
 }
 
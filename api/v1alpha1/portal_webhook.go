@@ -18,9 +18,11 @@ package v1alpha1
 
 import (
 	"context"
+	"fmt"
 
 	"k8s.io/apimachinery/pkg/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
@@ -32,7 +34,7 @@ func (p *Portal) SetupWebhookWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(p).
 		WithDefaulter(p).
-		WithValidator(p).
+		WithValidator(&portalValidator{client: mgr.GetClient()}).
 		Complete()
 }
 
@@ -48,29 +50,72 @@ func (p *Portal) Default(ctx context.Context, obj runtime.Object) error {
 
 //+kubebuilder:webhook:path=/validate-core-kubebb-k8s-com-cn-v1alpha1-portal,mutating=false,failurePolicy=fail,sideEffects=None,groups=core.kubebb.k8s.com.cn,resources=portals,verbs=create;update;delete,versions=v1alpha1,name=vportal.kb.io,admissionReviewVersions=v1
 
-var _ webhook.CustomValidator = &Portal{}
-
-// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
-func (p *Portal) ValidateCreate(ctx context.Context, obj runtime.Object) error {
-	portallog.Info("validate create", "name", p.Name)
+// portalValidator validates the Portals with the client to list the existing ones
+type portalValidator struct {
+	client client.Client
+}
 
-	// TODO: validate entry & path conflicts
+var _ webhook.CustomValidator = &portalValidator{}
 
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (v *portalValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	portal, ok := obj.(*Portal)
+	if !ok {
+		portallog.Error(ErrDecode, "obj "+ErrDecode.Error())
+		return ErrDecode
+	}
+	log := portallog.WithValues("name", portal.Name, "method", "ValidateCreate")
+	if err := v.checkConflicts(ctx, portal); err != nil {
+		log.Info(err.Error())
+		return err
+	}
+	log.Info("validate create done")
 	return nil
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
-func (p *Portal) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
-	portallog.Info("validate update", "name", p.Name)
-
-	// TODO: validate entry & path conflicts
+func (v *portalValidator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
+	oldPortal, ok := oldObj.(*Portal)
+	if !ok {
+		portallog.Error(ErrDecode, "oldObj "+ErrDecode.Error())
+		return ErrDecode
+	}
+	portal, ok := newObj.(*Portal)
+	if !ok {
+		portallog.Error(ErrDecode, "newObj "+ErrDecode.Error())
+		return ErrDecode
+	}
+	log := portallog.WithValues("name", portal.Name, "method", "ValidateUpdate")
+	// only check the conflicts when the path or entry changes, or the conflicts are not allowed any more
+	if oldPortal.Spec == portal.Spec && (!oldPortal.AllowConflicts() || portal.AllowConflicts()) {
+		return nil
+	}
+	if err := v.checkConflicts(ctx, portal); err != nil {
+		log.Info(err.Error())
+		return err
+	}
+	log.Info("validate update done")
 	return nil
 }
 
-// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
-func (p *Portal) ValidateDelete(ctx context.Context, obj runtime.Object) error {
-	portallog.Info("validate delete", "name", p.Name)
+// checkConflicts rejects the Portal with the same entry or an overlapped path with the existing Portals,
+// unless it allows conflicts by annotation.
+func (v *portalValidator) checkConflicts(ctx context.Context, p *Portal) error {
+	if p.AllowConflicts() {
+		return nil
+	}
+	list := &PortalList{}
+	if err := v.client.List(ctx, list); err != nil {
+		return err
+	}
+	entryConflicts, pathConflicts := GetPortalConflicts(p, list.Items)
+	if len(entryConflicts) == 0 && len(pathConflicts) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: entry %s conflicts with %v, path %s conflicts with %v", ErrPortalConflict, p.Spec.Entry, entryConflicts, p.Spec.Path, pathConflicts)
+}
 
-	// TODO(user): fill in your validation logic upon object deletion.
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (v *portalValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
 	return nil
 }
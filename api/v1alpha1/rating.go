@@ -17,11 +17,21 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"knative.dev/pkg/apis"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
@@ -33,12 +43,165 @@ const (
 	PipelineRun2ComponentLabel  = "rating.pipelinerun.component"
 	PipelineRun2RepositoryLabel = "rating.pipelinerun.repository"
 	PipelineRunDimensionLabel   = Group + "/dimension"
+	PipelineRun2VersionLabel    = "rating.pipelinerun.version"
+
+	// RatingRerunAnnotation re-runs the Rating when its value changes, like a timestamp.
+	RatingRerunAnnotation = Group + "/rerun"
+	// RatingCancelAnnotation cancels the running PipelineRuns of the Rating when its value changes.
+	RatingCancelAnnotation = Group + "/cancel"
+	// RepositoryLastRatingAnnotation records the time of the last Rating created from the RatingTemplate of the Repository
+	RepositoryLastRatingAnnotation = Group + "/last-rating-created"
+
+	DefaultRatingRetention = 3
+
+	DefaultPromptTemplateKey = "template"
+
+	// the params added to the Rating created from the RatingTemplate
+	RatingParamURL            = "URL"
+	RatingParamComponentName  = "COMPONENT_NAME"
+	RatingParamVersion        = "VERSION"
+	RatingParamRepositoryName = "REPOSITORY_NAME"
+
+	DefaultRatingTemplateInterval = 60 * time.Second
 )
 
+var ErrRatingPolicy = errors.New("rating policy is not satisfied")
+
+var invalidRatingNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// PipelineRunName returns the name prefix of the pipelineruns of the dimension of the Rating
 func PipelineRunName(ratingName, dimension string) string {
 	return fmt.Sprintf("%s.%s", ratingName, dimension)
 }
 
+// NumberedPipelineRunName returns the name of the pipelinerun created by the run of the Rating
+func NumberedPipelineRunName(ratingName, dimension string, run int) string {
+	return fmt.Sprintf("%s.%d", PipelineRunName(ratingName, dimension), run)
+}
+
+// GetRetention returns the number of runs kept for each dimension
+func (rating Rating) GetRetention() int {
+	if rating.Spec.Retention > 0 {
+		return rating.Spec.Retention
+	}
+	return DefaultRatingRetention
+}
+
+// NeedRerun returns true if the Rating has never run or the rerun annotation is not handled yet
+func (rating Rating) NeedRerun() bool {
+	return rating.Status.Run == 0 || rating.Annotations[RatingRerunAnnotation] != rating.Status.ObservedRerun
+}
+
+// NeedCancel returns true if the cancel annotation is not handled yet
+func (rating Rating) NeedCancel() bool {
+	return rating.Annotations[RatingCancelAnnotation] != rating.Status.ObservedCancel
+}
+
+// NextPipelineRunStatus returns the status of a new run of the dimension, the previous run is moved into the History.
+// At most retention runs are recorded, including the new one.
+func NextPipelineRunStatus(prev PipelineRunStatus, run int, pipelineRunName, pipelineName string, retention int) PipelineRunStatus {
+	status := PipelineRunStatus{
+		PipelineRunName: pipelineRunName,
+		PipelineName:    pipelineName,
+		Run:             run,
+	}
+	history := make([]PipelineRunRecord, 0, len(prev.History)+1)
+	if prev.PipelineRunName != "" {
+		history = append(history, PipelineRunRecord{Run: prev.Run, PipelineRunName: prev.PipelineRunName, ConditionedStatus: prev.ConditionedStatus})
+	}
+	history = append(history, prev.History...)
+	if keep := retention - 1; len(history) > keep {
+		if keep < 0 {
+			keep = 0
+		}
+		history = history[:keep]
+	}
+	if len(history) > 0 {
+		status.History = history
+	}
+	return status
+}
+
+// PipelineRunsToPrune returns the pipelineruns beyond the retention, the latest created ones are kept.
+// The pipelineruns created in the same second are ordered by the run number in their names, then by their names.
+func PipelineRunsToPrune(pipelineRuns []v1beta1.PipelineRun, retention int) []v1beta1.PipelineRun {
+	if len(pipelineRuns) <= retention {
+		return nil
+	}
+	sorted := make([]v1beta1.PipelineRun, len(pipelineRuns))
+	copy(sorted, pipelineRuns)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ti, tj := sorted[i].CreationTimestamp, sorted[j].CreationTimestamp
+		if !ti.Equal(&tj) {
+			return tj.Before(&ti)
+		}
+		if ri, rj := pipelineRunNumber(sorted[i].Name), pipelineRunNumber(sorted[j].Name); ri != rj {
+			return ri > rj
+		}
+		return sorted[i].Name > sorted[j].Name
+	})
+	return sorted[retention:]
+}
+
+// pipelineRunNumber returns the run number in the name generated by NumberedPipelineRunName,
+// 0 is returned for the name without a number.
+func pipelineRunNumber(name string) int {
+	i := strings.LastIndex(name, ".")
+	if i == -1 {
+		return 0
+	}
+	run, err := strconv.Atoi(name[i+1:])
+	if err != nil {
+		return 0
+	}
+	return run
+}
+
+// Succeeded returns true if the pipelinerun succeeded
+func (status PipelineRunStatus) Succeeded() bool {
+	for _, cond := range status.Conditions {
+		if cond.Reason == RatingSucceeded && cond.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPromptTemplate returns the prompt template of the dimension, nil means the built-in one
+func (e Evaluator) GetPromptTemplate(dimension string) *PromptTemplateRef {
+	if ref, ok := e.DimensionPromptTemplates[dimension]; ok {
+		return &ref
+	}
+	return e.PromptTemplate
+}
+
+// PromptTemplateConfigMaps returns the names of the ConfigMaps holding the prompt templates
+func (e Evaluator) PromptTemplateConfigMaps() []string {
+	names := make([]string, 0, len(e.DimensionPromptTemplates)+1)
+	if e.PromptTemplate != nil {
+		names = append(names, e.PromptTemplate.ConfigMap)
+	}
+	for _, ref := range e.DimensionPromptTemplates {
+		names = append(names, ref.ConfigMap)
+	}
+	sort.Strings(names)
+	result := make([]string, 0, len(names))
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			result = append(result, name)
+		}
+	}
+	return result
+}
+
+// GetKey returns the key of the template in the ConfigMap
+func (ref PromptTemplateRef) GetKey() string {
+	if ref.Key != "" {
+		return ref.Key
+	}
+	return DefaultPromptTemplateKey
+}
+
 func IsTaskSame(a, b Task) bool {
 	return a.Name == b.Name && a.TaskRunName == b.TaskRunName && a.Type == b.Type
 }
@@ -172,3 +335,178 @@ func WhenRunningOrSucceeded(pipelinerun *v1beta1.PipelineRun, instance *Rating,
 		instance.Status.PipelineRuns[dimension] = pipelineStatus
 	}
 }
+
+// AggregateScore returns the weighted average of the final ratings of the evaluations.
+// The dimension not in weights has the weight 1, the dimension with weight 0 or less is not counted,
+// neither is the dimension without a valid final rating. ok is false if no dimension is counted.
+func AggregateScore(evaluations map[string]EvaluatorStatus, weights map[string]int) (score float64, ok bool) {
+	total, sum := 0, 0.0
+	for dimension, evaluation := range evaluations {
+		weight, found := weights[dimension]
+		if !found {
+			weight = 1
+		}
+		if weight <= 0 {
+			continue
+		}
+		rating, err := strconv.ParseFloat(evaluation.FinalRating, 64)
+		if err != nil {
+			continue
+		}
+		total += weight
+		sum += rating * float64(weight)
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return sum / float64(total), true
+}
+
+// FormatScore formats the score with two decimal places, like 8.50
+func FormatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', 2, 64)
+}
+
+// Score returns the aggregate score of all dimensions with the weights of the rating
+func (rating Rating) Score() (string, bool) {
+	score, ok := AggregateScore(rating.Status.Evaluations, rating.Spec.Weights)
+	if !ok {
+		return "", false
+	}
+	return FormatScore(score), true
+}
+
+// Interval returns the minimum interval between two Ratings created in the repository
+func (t *RatingTemplate) Interval() time.Duration {
+	if t.IntervalSeconds <= 0 {
+		return DefaultRatingTemplateInterval
+	}
+	return time.Duration(t.IntervalSeconds) * time.Second
+}
+
+// ComponentRatingName returns the name of the Rating created from the RatingTemplate for the version of the component
+func ComponentRatingName(componentName, version string) string {
+	name := fmt.Sprintf("%s.%s", componentName, invalidRatingNameChars.ReplaceAllString(strings.ToLower(version), "-"))
+	return strings.Trim(name, ".-")
+}
+
+// NewRating creates a Rating for the version of the component from the template,
+// the component and version related params are added to each pipeline if they are not set.
+func (t *RatingTemplate) NewRating(component *Component, version, pullURL string) *Rating {
+	repoName := component.Labels[ComponentRepositoryLabel]
+	if repoName == "" && component.Status.RepositoryRef != nil {
+		repoName = component.Status.RepositoryRef.Name
+	}
+	defaults := []Param{
+		{Name: RatingParamURL, Value: ParamValue{Type: ParamTypeString, StringVal: pullURL}},
+		{Name: RatingParamComponentName, Value: ParamValue{Type: ParamTypeString, StringVal: component.Name}},
+		{Name: RatingParamVersion, Value: ParamValue{Type: ParamTypeString, StringVal: version}},
+		{Name: RatingParamRepositoryName, Value: ParamValue{Type: ParamTypeString, StringVal: repoName}},
+	}
+	pipelineParams := make([]PipelineParam, len(t.PipelineParams))
+	for i, p := range t.PipelineParams {
+		p.DeepCopyInto(&pipelineParams[i])
+		for _, d := range defaults {
+			found := false
+			for _, param := range p.Params {
+				if param.Name == d.Name {
+					found = true
+					break
+				}
+			}
+			if !found {
+				pipelineParams[i].Params = append(pipelineParams[i].Params, d)
+			}
+		}
+	}
+	rating := &Rating{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ComponentRatingName(component.Name, version),
+			Namespace: component.Namespace,
+			Labels: map[string]string{
+				RatingComponentLabel:   component.Name,
+				RatingRepositoryLabel:  repoName,
+				RatingComponentVersion: version,
+			},
+		},
+		Spec: RatingSpec{
+			ComponentName:  component.Name,
+			PipelineParams: pipelineParams,
+			Weights:        t.Weights,
+			Retention:      t.Retention,
+			Builtin:        t.Builtin,
+		},
+	}
+	t.Evaluator.DeepCopyInto(&rating.Spec.Evaluator)
+	return rating
+}
+
+// Check returns nil if the rating satisfies all the rules of the policy
+func (p *RatingPolicy) Check(rating *Rating) error {
+	for _, dimension := range p.RequiredPipelineRuns {
+		if !rating.Status.PipelineRuns[dimension].Succeeded() {
+			return fmt.Errorf("%w: pipelinerun of dimension %s in rating %s does not succeed", ErrRatingPolicy, dimension, rating.Name)
+		}
+	}
+	dimensions := make([]string, 0, len(p.MinDimensionScores))
+	for dimension := range p.MinDimensionScores {
+		dimensions = append(dimensions, dimension)
+	}
+	sort.Strings(dimensions)
+	for _, dimension := range dimensions {
+		score, err := strconv.ParseFloat(rating.Status.Evaluations[dimension].FinalRating, 64)
+		if err != nil {
+			return fmt.Errorf("%w: rating %s has no final rating of dimension %s", ErrRatingPolicy, rating.Name, dimension)
+		}
+		if min := p.MinDimensionScores[dimension]; score < float64(min) {
+			return fmt.Errorf("%w: rating %s of dimension %s is %s, lower than %d", ErrRatingPolicy, rating.Name, dimension, rating.Status.Evaluations[dimension].FinalRating, min)
+		}
+	}
+	if p.MinScore > 0 {
+		score, err := strconv.ParseFloat(rating.Status.Score, 64)
+		if err != nil {
+			return fmt.Errorf("%w: rating %s has no aggregate score", ErrRatingPolicy, rating.Name)
+		}
+		if score < float64(p.MinScore) {
+			return fmt.Errorf("%w: score of rating %s is %s, lower than %d", ErrRatingPolicy, rating.Name, rating.Status.Score, p.MinScore)
+		}
+	}
+	return nil
+}
+
+// CheckRatingPolicy checks the version of the component against the rating policy of its repository.
+// It returns nil if the repository has no rating policy, or any Rating of the version satisfies the policy.
+func CheckRatingPolicy(ctx context.Context, c client.Client, component *Component, version string) error {
+	repoName := component.Labels[ComponentRepositoryLabel]
+	if repoName == "" && component.Status.RepositoryRef != nil {
+		repoName = component.Status.RepositoryRef.Name
+	}
+	if repoName == "" {
+		return nil
+	}
+	repo := &Repository{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: component.Namespace, Name: repoName}, repo); err != nil {
+		return err
+	}
+	policy := repo.Spec.RatingPolicy
+	if policy == nil {
+		return nil
+	}
+	ratings := &RatingList{}
+	if err := c.List(ctx, ratings, client.InNamespace(component.Namespace), client.MatchingLabels{
+		RatingComponentLabel:   component.Name,
+		RatingComponentVersion: version,
+	}); err != nil {
+		return err
+	}
+	if len(ratings.Items) == 0 {
+		return fmt.Errorf("%w: no rating found for version %s of component %s", ErrRatingPolicy, version, component.Name)
+	}
+	var err error
+	for i := range ratings.Items {
+		if err = policy.Check(&ratings.Items[i]); err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -17,15 +17,20 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"context"
+	"errors"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/ghodss/yaml"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"knative.dev/pkg/apis"
 	duckv1beta1 "knative.dev/pkg/apis/duck/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestIsTaskSame(t *testing.T) {
@@ -113,6 +118,121 @@ func TestPipelineRunName(t *testing.T) {
 	}
 }
 
+func TestNumberedPipelineRunName(t *testing.T) {
+	if r := NumberedPipelineRunName("aa", "bb", 2); r != "aa.bb.2" {
+		t.Fatalf("Test Failed. expect %s get %s", "aa.bb.2", r)
+	}
+}
+
+func TestRatingNeedRerunAndCancel(t *testing.T) {
+	type input struct {
+		rating       Rating
+		expectRerun  bool
+		expectCancel bool
+	}
+	for _, tc := range []input{
+		{rating: Rating{}, expectRerun: true},
+		{rating: Rating{Status: RatingStatus{Run: 1}}},
+		{
+			rating: Rating{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{RatingRerunAnnotation: "1", RatingCancelAnnotation: "1"}},
+				Status:     RatingStatus{Run: 1},
+			},
+			expectRerun:  true,
+			expectCancel: true,
+		},
+		{
+			rating: Rating{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{RatingRerunAnnotation: "1", RatingCancelAnnotation: "1"}},
+				Status:     RatingStatus{Run: 2, ObservedRerun: "1", ObservedCancel: "1"},
+			},
+		},
+	} {
+		if r := tc.rating.NeedRerun(); r != tc.expectRerun {
+			t.Fatalf("Test Failed. expect %v get %v", tc.expectRerun, r)
+		}
+		if r := tc.rating.NeedCancel(); r != tc.expectCancel {
+			t.Fatalf("Test Failed. expect %v get %v", tc.expectCancel, r)
+		}
+	}
+}
+
+func TestNextPipelineRunStatus(t *testing.T) {
+	succeeded := ConditionedStatus{Conditions: []Condition{{Type: TypeReady, Reason: RatingSucceeded}}}
+	prev := PipelineRunStatus{
+		PipelineRunName:   "rating.dim.3",
+		PipelineName:      "pipeline",
+		Run:               3,
+		ConditionedStatus: succeeded,
+		History:           []PipelineRunRecord{{Run: 2, PipelineRunName: "rating.dim.2"}, {Run: 1, PipelineRunName: "rating.dim.1"}},
+	}
+	type input struct {
+		prev      PipelineRunStatus
+		retention int
+		expect    []PipelineRunRecord
+	}
+	for _, tc := range []input{
+		{prev: PipelineRunStatus{}, retention: 3},
+		{prev: prev, retention: 1},
+		{prev: prev, retention: 2, expect: []PipelineRunRecord{{Run: 3, PipelineRunName: "rating.dim.3", ConditionedStatus: succeeded}}},
+		{
+			prev:      prev,
+			retention: 5,
+			expect: []PipelineRunRecord{
+				{Run: 3, PipelineRunName: "rating.dim.3", ConditionedStatus: succeeded},
+				{Run: 2, PipelineRunName: "rating.dim.2"},
+				{Run: 1, PipelineRunName: "rating.dim.1"},
+			},
+		},
+	} {
+		r := NextPipelineRunStatus(tc.prev, 4, "rating.dim.4", "pipeline", tc.retention)
+		if r.PipelineRunName != "rating.dim.4" || r.Run != 4 || len(r.Conditions) != 0 {
+			t.Fatalf("Test Failed. get %v", r)
+		}
+		if !reflect.DeepEqual(r.History, tc.expect) {
+			t.Fatalf("Test Failed. expect %v get %v", tc.expect, r.History)
+		}
+	}
+}
+
+func TestPipelineRunsToPrune(t *testing.T) {
+	now := metav1.Now()
+	earlier := metav1.NewTime(now.Add(-time.Minute))
+	pipelineRuns := []v1beta1.PipelineRun{
+		{ObjectMeta: metav1.ObjectMeta{Name: "r.d.2", CreationTimestamp: earlier}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "r.d.4", CreationTimestamp: now}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "r.d", CreationTimestamp: earlier}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "r.d.3", CreationTimestamp: now}},
+	}
+	type input struct {
+		pipelineRuns []v1beta1.PipelineRun
+		retention    int
+		expect       []string
+	}
+	for _, tc := range []input{
+		{pipelineRuns: pipelineRuns, retention: 5},
+		{pipelineRuns: pipelineRuns, retention: 4},
+		{pipelineRuns: pipelineRuns, retention: 2, expect: []string{"r.d.2", "r.d"}},
+		{
+			pipelineRuns: []v1beta1.PipelineRun{
+				{ObjectMeta: metav1.ObjectMeta{Name: "r.d.9", CreationTimestamp: now}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "r.d.10", CreationTimestamp: now}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "r.d.8", CreationTimestamp: now}},
+			},
+			retention: 1,
+			expect:    []string{"r.d.9", "r.d.8"},
+		},
+	} {
+		var names []string
+		for _, pr := range PipelineRunsToPrune(tc.pipelineRuns, tc.retention) {
+			names = append(names, pr.Name)
+		}
+		if !reflect.DeepEqual(names, tc.expect) {
+			t.Fatalf("Test Failed. expect %v get %v", tc.expect, names)
+		}
+	}
+}
+
 func TestGetPipelineName(t *testing.T) {
 	type input struct {
 		pipelinerun *v1beta1.PipelineRun
@@ -506,3 +626,225 @@ func TestWhenRunningOrSucceeded(t *testing.T) {
 		t.Fatalf("Test Failed.expect %+v get %+v", expPipelineRunStatus, rating.Status.PipelineRuns)
 	}
 }
+
+func TestAggregateScore(t *testing.T) {
+	type input struct {
+		name        string
+		evaluations map[string]EvaluatorStatus
+		weights     map[string]int
+		expScore    string
+		expOK       bool
+	}
+	for _, tc := range []input{
+		{name: "no evaluations"},
+		{
+			name: "default weights",
+			evaluations: map[string]EvaluatorStatus{
+				"reliability": {FinalRating: "8"},
+				"security":    {FinalRating: "5"},
+			},
+			expScore: "6.50",
+			expOK:    true,
+		},
+		{
+			name: "custom weights",
+			evaluations: map[string]EvaluatorStatus{
+				"reliability": {FinalRating: "8"},
+				"security":    {FinalRating: "5"},
+				"docs":        {FinalRating: "1"},
+			},
+			weights:  map[string]int{"security": 2, "docs": 0},
+			expScore: "6.00",
+			expOK:    true,
+		},
+		{
+			name: "invalid final rating is not counted",
+			evaluations: map[string]EvaluatorStatus{
+				"reliability": {FinalRating: "9"},
+				"security":    {FinalRating: ""},
+			},
+			expScore: "9.00",
+			expOK:    true,
+		},
+		{
+			name: "all weights are zero",
+			evaluations: map[string]EvaluatorStatus{
+				"reliability": {FinalRating: "9"},
+			},
+			weights: map[string]int{"reliability": 0},
+		},
+	} {
+		rating := Rating{Spec: RatingSpec{Weights: tc.weights}, Status: RatingStatus{Evaluations: tc.evaluations}}
+		if r, ok := rating.Score(); r != tc.expScore || ok != tc.expOK {
+			t.Fatalf("Test Failed. %s expect %s %v get %s %v", tc.name, tc.expScore, tc.expOK, r, ok)
+		}
+	}
+}
+
+func TestComponentRatingName(t *testing.T) {
+	type input struct {
+		component, version string
+		expect             string
+	}
+	for _, tc := range []input{
+		{component: "repo.nginx", version: "0.1.0", expect: "repo.nginx.0.1.0"},
+		{component: "repo.nginx", version: "V1.0.0+Build_1", expect: "repo.nginx.v1.0.0-build-1"},
+	} {
+		if r := ComponentRatingName(tc.component, tc.version); r != tc.expect {
+			t.Fatalf("Test Failed. expect %s get %s", tc.expect, r)
+		}
+	}
+}
+
+func TestRatingTemplateNewRating(t *testing.T) {
+	template := &RatingTemplate{
+		PipelineParams: []PipelineParam{
+			{
+				Dimension:    "reliability",
+				PipelineName: "component-reliability",
+				Params:       []Param{{Name: RatingParamURL, Value: ParamValue{Type: ParamTypeString, StringVal: "http://mirror/nginx.tgz"}}},
+			},
+		},
+		Weights: map[string]int{"reliability": 2},
+	}
+	component := &Component{
+		ObjectMeta: metav1.ObjectMeta{Name: "repo.nginx", Namespace: "default", Labels: map[string]string{ComponentRepositoryLabel: "repo"}},
+	}
+	rating := template.NewRating(component, "0.1.0", "http://repo/nginx-0.1.0.tgz")
+	if rating.Name != "repo.nginx.0.1.0" || rating.Namespace != "default" || rating.Spec.ComponentName != "repo.nginx" {
+		t.Fatalf("Test Failed. unexpected rating %s/%s for %s", rating.Namespace, rating.Name, rating.Spec.ComponentName)
+	}
+	expLabels := map[string]string{RatingComponentLabel: "repo.nginx", RatingRepositoryLabel: "repo", RatingComponentVersion: "0.1.0"}
+	if !reflect.DeepEqual(rating.Labels, expLabels) {
+		t.Fatalf("Test Failed. expect %v get %v", expLabels, rating.Labels)
+	}
+	params := make(map[string]string)
+	for _, p := range rating.Spec.PipelineParams[0].Params {
+		params[p.Name] = p.Value.StringVal
+	}
+	expParams := map[string]string{
+		RatingParamURL:            "http://mirror/nginx.tgz",
+		RatingParamComponentName:  "repo.nginx",
+		RatingParamVersion:        "0.1.0",
+		RatingParamRepositoryName: "repo",
+	}
+	if !reflect.DeepEqual(params, expParams) {
+		t.Fatalf("Test Failed. expect %v get %v", expParams, params)
+	}
+	if len(template.PipelineParams[0].Params) != 1 {
+		t.Fatalf("Test Failed. the template should not be changed")
+	}
+	if rating.Spec.Weights["reliability"] != 2 {
+		t.Fatalf("Test Failed. expect weights %v get %v", template.Weights, rating.Spec.Weights)
+	}
+	if template.Interval() != DefaultRatingTemplateInterval {
+		t.Fatalf("Test Failed. expect %s get %s", DefaultRatingTemplateInterval, template.Interval())
+	}
+}
+
+func TestRatingPolicyCheck(t *testing.T) {
+	succeeded := PipelineRunStatus{ConditionedStatus: ConditionedStatus{Conditions: []Condition{{Reason: RatingSucceeded, Status: v1.ConditionTrue}}}}
+	failed := PipelineRunStatus{ConditionedStatus: ConditionedStatus{Conditions: []Condition{{Reason: "Failed", Status: v1.ConditionFalse}}}}
+	rating := &Rating{
+		ObjectMeta: metav1.ObjectMeta{Name: "rating"},
+		Status: RatingStatus{
+			PipelineRuns: map[string]PipelineRunStatus{"security": succeeded, "reliability": failed},
+			Evaluations:  map[string]EvaluatorStatus{"security": {FinalRating: "8"}, "reliability": {FinalRating: "5"}},
+			Score:        "6.50",
+		},
+	}
+	type input struct {
+		name   string
+		policy RatingPolicy
+		expErr bool
+	}
+	for _, tc := range []input{
+		{name: "empty policy"},
+		{name: "pipelinerun succeeded", policy: RatingPolicy{RequiredPipelineRuns: []string{"security"}}},
+		{name: "pipelinerun failed", policy: RatingPolicy{RequiredPipelineRuns: []string{"reliability"}}, expErr: true},
+		{name: "pipelinerun not found", policy: RatingPolicy{RequiredPipelineRuns: []string{"docs"}}, expErr: true},
+		{name: "dimension score satisfied", policy: RatingPolicy{MinDimensionScores: map[string]int{"security": 8}}},
+		{name: "dimension score too low", policy: RatingPolicy{MinDimensionScores: map[string]int{"security": 8, "reliability": 6}}, expErr: true},
+		{name: "dimension not evaluated", policy: RatingPolicy{MinDimensionScores: map[string]int{"docs": 1}}, expErr: true},
+		{name: "score satisfied", policy: RatingPolicy{MinScore: 6}},
+		{name: "score too low", policy: RatingPolicy{MinScore: 7}, expErr: true},
+	} {
+		err := tc.policy.Check(rating)
+		if (err != nil) != tc.expErr || (err != nil && !errors.Is(err, ErrRatingPolicy)) {
+			t.Fatalf("Test Failed. %s expect error %v get %v", tc.name, tc.expErr, err)
+		}
+	}
+}
+
+func TestCheckRatingPolicy(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = AddToScheme(scheme)
+	component := &Component{ObjectMeta: metav1.ObjectMeta{Name: "repo.nginx", Namespace: "default", Labels: map[string]string{ComponentRepositoryLabel: "repo"}}}
+	type input struct {
+		name    string
+		policy  *RatingPolicy
+		version string
+		expErr  error
+	}
+	for _, tc := range []input{
+		{name: "no policy", version: "0.1.0"},
+		{name: "no rating", policy: &RatingPolicy{MinScore: 6}, version: "0.3.0", expErr: ErrRatingPolicy},
+		{name: "one of ratings satisfied", policy: &RatingPolicy{MinScore: 6}, version: "0.2.0"},
+		{name: "score too low", policy: &RatingPolicy{MinScore: 6}, version: "0.1.0", expErr: ErrRatingPolicy},
+	} {
+		repo := &Repository{ObjectMeta: metav1.ObjectMeta{Name: "repo", Namespace: "default"}, Spec: RepositorySpec{RatingPolicy: tc.policy}}
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			repo,
+			&Rating{
+				ObjectMeta: metav1.ObjectMeta{Name: "r1", Namespace: "default", Labels: map[string]string{RatingComponentLabel: component.Name, RatingComponentVersion: "0.1.0"}},
+				Status:     RatingStatus{Score: "5.00"},
+			},
+			&Rating{
+				ObjectMeta: metav1.ObjectMeta{Name: "r2", Namespace: "default", Labels: map[string]string{RatingComponentLabel: component.Name, RatingComponentVersion: "0.2.0"}},
+				Status:     RatingStatus{Score: "5.00"},
+			},
+			&Rating{
+				ObjectMeta: metav1.ObjectMeta{Name: "r3", Namespace: "default", Labels: map[string]string{RatingComponentLabel: component.Name, RatingComponentVersion: "0.2.0"}},
+				Status:     RatingStatus{Score: "7.00"},
+			},
+		).Build()
+		if err := CheckRatingPolicy(context.TODO(), c, component, tc.version); !errors.Is(err, tc.expErr) {
+			t.Fatalf("Test Failed. %s expect %v get %v", tc.name, tc.expErr, err)
+		}
+	}
+}
+
+func TestEvaluatorPromptTemplate(t *testing.T) {
+	evaluator := Evaluator{
+		PromptTemplate: &PromptTemplateRef{ConfigMap: "prompts"},
+		DimensionPromptTemplates: map[string]PromptTemplateRef{
+			"security":    {ConfigMap: "security-prompts", Key: "security"},
+			"reliability": {ConfigMap: "prompts", Key: "reliability"},
+		},
+	}
+	type input struct {
+		dimension string
+		expect    *PromptTemplateRef
+		key       string
+	}
+	for _, tc := range []input{
+		{dimension: "security", expect: &PromptTemplateRef{ConfigMap: "security-prompts", Key: "security"}, key: "security"},
+		{dimension: "performance", expect: &PromptTemplateRef{ConfigMap: "prompts"}, key: DefaultPromptTemplateKey},
+	} {
+		r := evaluator.GetPromptTemplate(tc.dimension)
+		if !reflect.DeepEqual(r, tc.expect) {
+			t.Fatalf("Test Failed. expect %v get %v", tc.expect, r)
+		}
+		if r.GetKey() != tc.key {
+			t.Fatalf("Test Failed. expect %s get %s", tc.key, r.GetKey())
+		}
+	}
+	if r := (Evaluator{}).GetPromptTemplate("security"); r != nil {
+		t.Fatalf("Test Failed. expect nil get %v", r)
+	}
+
+	expect := []string{"prompts", "security-prompts"}
+	if r := evaluator.PromptTemplateConfigMaps(); !reflect.DeepEqual(r, expect) {
+		t.Fatalf("Test Failed. expect %v get %v", expect, r)
+	}
+}
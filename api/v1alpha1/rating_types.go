@@ -78,15 +78,53 @@ type RatingSpec struct {
 
 	// PipelineParams List of parameters defined in the pipeline
 	// If mulitple PipelineParams contains same dimension,only the 1st one shall be used
-	PipelineParams []PipelineParam `json:"pipelineParams"`
+	// +optional
+	PipelineParams []PipelineParam `json:"pipelineParams,omitempty"`
+
+	// Builtin runs the built-in static checks on the chart and its rendered manifests instead of the Tekton pipelines,
+	// the results are the security and reliability dimensions, PipelineParams are ignored.
+	// +optional
+	Builtin bool `json:"builtin,omitempty"`
 
 	// Evaluator defines the configuration when evaluating the component
 	Evaluator `json:"evaluator"`
+
+	// Weights of the dimensions when aggregating the score of the component.
+	// The dimension not in Weights has the weight 1, and the dimension with weight 0 is not counted.
+	// +optional
+	Weights map[string]int `json:"weights,omitempty"`
+
+	// Retention is the number of runs kept for each dimension of the rated component version,
+	// the older PipelineRuns and their Prompts are deleted when the Rating is re-run, the default is 3.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Retention int `json:"retention,omitempty"`
 }
 
 type Evaluator struct {
 	// LLM defines the LLM to be used when evaluating the component
 	LLM *arcadiav1.TypedObjectReference `json:"llm,omitempty"`
+
+	// PromptTemplate is the prompt template used by all dimensions, the built-in one is used if not set
+	// +optional
+	PromptTemplate *PromptTemplateRef `json:"promptTemplate,omitempty"`
+	// DimensionPromptTemplates are the prompt templates with the `Dimension` as the key, they take precedence over PromptTemplate
+	// +optional
+	DimensionPromptTemplates map[string]PromptTemplateRef `json:"dimensionPromptTemplates,omitempty"`
+}
+
+// PromptTemplateRef references a prompt template held in a ConfigMap in the namespace of the Rating.
+// The template is a go template which can only use the fields .Tasks, .Dimension and .OutputFormat.
+type PromptTemplateRef struct {
+	// ConfigMap is the name of the ConfigMap
+	ConfigMap string `json:"configMap"`
+	// Key of the template in the ConfigMap, the default is template
+	// +optional
+	Key string `json:"key,omitempty"`
+	// OutputFormatKey is the key of the output format in the ConfigMap, the built-in output format is used if not set.
+	// The answer is parsed with the chinese and english results of the score, suggestions and problems like the built-in one.
+	// +optional
+	OutputFormatKey string `json:"outputFormatKey,omitempty"`
 }
 
 type RatingStatus struct {
@@ -94,6 +132,14 @@ type RatingStatus struct {
 	PipelineRuns map[string]PipelineRunStatus `json:"pipelineRuns,omitempty"`
 	// Evaluations contains the evaluator status with the `Dimension` as the key
 	Evaluations map[string]EvaluatorStatus `json:"evaluations,omitempty"`
+	// Score is the weighted average of the final ratings of all dimensions, like 8.50
+	Score string `json:"score,omitempty"`
+	// Run is the sequence number of the current run, it increases every time the Rating is re-run
+	Run int `json:"run,omitempty"`
+	// ObservedRerun is the value of the rerun annotation handled last time
+	ObservedRerun string `json:"observedRerun,omitempty"`
+	// ObservedCancel is the value of the cancel annotation handled last time
+	ObservedCancel string `json:"observedCancel,omitempty"`
 
 	ConditionedStatus `json:",inline"`
 }
@@ -102,9 +148,23 @@ type EvaluatorStatus struct {
 	Prompt                      string `json:"prompt,omitempty"`
 	arcadiav1.ConditionedStatus `json:",inline"`
 
-	// FinalRating from this evaluation
-	// TODO: add the final rating
+	// FinalRating from this evaluation, it is the score of the english result, or the chinese one if no english result
 	FinalRating string `json:"finalRating,omitempty"`
+
+	// Chinese is the evaluation result in Chinese
+	Chinese *EvaluationResult `json:"chinese,omitempty"`
+	// English is the evaluation result in English
+	English *EvaluationResult `json:"english,omitempty"`
+}
+
+// EvaluationResult is the structured answer of the LLM
+type EvaluationResult struct {
+	// Score of the component in this dimension, from 1 to 10
+	Score int `json:"score"`
+	// Suggestions to improve the component
+	Suggestions []string `json:"suggestions,omitempty"`
+	// Problems found in the component
+	Problems []string `json:"problems,omitempty"`
 }
 
 type PipelineRunStatus struct {
@@ -113,6 +173,19 @@ type PipelineRunStatus struct {
 
 	Tasks             []Task `json:"tasks,omitempty"`
 	ConditionedStatus `json:",inline"`
+
+	// Run is the sequence number of the run which creates this pipelinerun
+	Run int `json:"run,omitempty"`
+	// History of the previous runs of this dimension, the latest first
+	History []PipelineRunRecord `json:"history,omitempty"`
+}
+
+// PipelineRunRecord is the result of a previous run of a dimension
+type PipelineRunRecord struct {
+	Run             int    `json:"run"`
+	PipelineRunName string `json:"pipelinerunName"`
+
+	ConditionedStatus `json:",inline"`
 }
 
 //+kubebuilder:object:root=true
@@ -228,6 +228,26 @@ func (r *Repository) IsOCI() bool {
 	return registry.IsOCI(r.Spec.URL)
 }
 
+// ChartPullURL returns the url to pull the chart of the component version, empty if the version has no url
+func (r *Repository) ChartPullURL(component *Component, urls []string) string {
+	u := strings.TrimSuffix(r.Spec.URL, "/")
+	if r.IsOCI() {
+		if v, ok := component.Annotations[OCIPullURLAnnotation]; ok {
+			return v
+		}
+		return u + "/" + component.Status.Name
+	}
+	if len(urls) == 0 {
+		return ""
+	}
+	if strings.HasPrefix(urls[0], "http") {
+		return urls[0]
+	}
+	// chartmuseum charts/weaviate-16.3.0.tgz
+	// github https://github.com/kubebb/components/releases/download/bc-apis-0.0.3/bc-apis-0.0.3.tgz
+	return u + "/" + urls[0]
+}
+
 func (r *Repository) GetRepoType(c client.Client) RepositoryType {
 	if r.IsOCI() {
 		return RepositoryTypeOCI
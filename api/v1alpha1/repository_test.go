@@ -268,6 +268,31 @@ func TestRepository_IsOCI(t *testing.T) {
 	}
 }
 
+func TestRepository_ChartPullURL(t *testing.T) {
+	tests := []struct {
+		name        string
+		url         string
+		annotations map[string]string
+		urls        []string
+		want        string
+	}{
+		{name: "oci", url: "oci://registry-1.docker.io/bitnamicharts/", want: "oci://registry-1.docker.io/bitnamicharts/nginx"},
+		{name: "oci annotation", url: "oci://registry-1.docker.io/bitnamicharts", annotations: map[string]string{OCIPullURLAnnotation: "oci://ghcr.io/nginx"}, want: "oci://ghcr.io/nginx"},
+		{name: "no urls", url: "http://chartmuseum"},
+		{name: "relative url", url: "http://chartmuseum/", urls: []string{"charts/nginx-0.1.0.tgz"}, want: "http://chartmuseum/charts/nginx-0.1.0.tgz"},
+		{name: "absolute url", url: "http://chartmuseum", urls: []string{"https://github.com/nginx-0.1.0.tgz"}, want: "https://github.com/nginx-0.1.0.tgz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &Repository{Spec: RepositorySpec{URL: tt.url}}
+			component := &Component{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}, Status: ComponentStatus{Name: "nginx"}}
+			if got := repo.ChartPullURL(component, tt.urls); got != tt.want {
+				t.Errorf("ChartPullURL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestRatingEnabled(t *testing.T) {
 	os.Setenv(RatingEnableEnv, "true")
 	if !RatingEnabled() {
@@ -88,11 +88,79 @@ type RepositorySpec struct {
 	// ImageOverride means replaced images rules for this repository
 	ImageOverride []ImageOverride `json:"imageOverride,omitempty"`
 
+	// ImageFieldSpecs locate the image fields to override besides the default ones,
+	// like the image fields of the custom resources.
+	// +optional
+	ImageFieldSpecs []ImageFieldSpec `json:"imageFieldSpecs,omitempty"`
+
+	// ImageFieldPresets are the names of the built-in ImageFieldSpecs of the common custom resources,
+	// like `argo-rollouts`, `argo-workflows` and `knative`.
+	// +optional
+	ImageFieldPresets []string `json:"imageFieldPresets,omitempty"`
+
+	// PinImageDigest pins every image of the components in this repository to the digest resolved
+	// when the ComponentPlan is planned, the install fails if any image can not be resolved.
+	// +optional
+	PinImageDigest bool `json:"pinImageDigest,omitempty"`
+
 	// KeywordLenLimit the keyword array length limit
 	KeywordLenLimit int `json:"keywordLenLimit,omitempty"`
 
 	// EnableRating enable component rating
 	EnableRating bool `json:"enableRating,omitempty"`
+
+	// RatingTemplate is used to create a Rating for each new version of the components automatically
+	// when EnableRating is true. No Rating is created automatically if it is not set.
+	RatingTemplate *RatingTemplate `json:"ratingTemplate,omitempty"`
+
+	// RatingPolicy is the rating requirement of a component version before a ComponentPlan of it can be approved.
+	// A Subscription with auto install only installs the versions which satisfy the policy.
+	RatingPolicy *RatingPolicy `json:"ratingPolicy,omitempty"`
+}
+
+// RatingPolicy requires at least one Rating of the component version satisfies all the rules
+type RatingPolicy struct {
+	// MinScore is the minimum aggregate score of all dimensions
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=10
+	// +optional
+	MinScore int `json:"minScore,omitempty"`
+
+	// MinDimensionScores is the minimum final rating of each dimension, with the dimension as the key
+	// +optional
+	MinDimensionScores map[string]int `json:"minDimensionScores,omitempty"`
+
+	// RequiredPipelineRuns are the dimensions whose PipelineRun must succeed, like security
+	// +optional
+	RequiredPipelineRuns []string `json:"requiredPipelineRuns,omitempty"`
+}
+
+// RatingTemplate describes the Rating created for the new version of the component
+type RatingTemplate struct {
+	// PipelineParams of the Rating, the params URL, COMPONENT_NAME, VERSION and REPOSITORY_NAME
+	// are added automatically if they are not set.
+	// +optional
+	PipelineParams []PipelineParam `json:"pipelineParams,omitempty"`
+
+	// Builtin runs the built-in static checks instead of the Tekton pipelines
+	// +optional
+	Builtin bool `json:"builtin,omitempty"`
+
+	// Evaluator defines the configuration when evaluating the component
+	Evaluator `json:"evaluator,omitempty"`
+
+	// Weights of the dimensions when aggregating the score of the component
+	// +optional
+	Weights map[string]int `json:"weights,omitempty"`
+
+	// IntervalSeconds is the minimum interval between two Ratings created in this repository,
+	// the default is 60 seconds.
+	// +optional
+	IntervalSeconds int `json:"intervalSeconds,omitempty"`
+	// Retention is the number of runs kept for each dimension of the rated component version
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Retention int `json:"retention,omitempty"`
 }
 
 type PathOverride struct {
@@ -115,6 +183,33 @@ type ImageOverride struct {
 	NewRegistry string `json:"newRegistry,omitempty"`
 	// PathOverride means replaced path
 	PathOverride *PathOverride `json:"pathOverride,omitempty"`
+	// Match selects the images by the glob or regex pattern instead of Registry and PathOverride.Path,
+	// PathOverride.Path is optional with Match, an empty one replaces the path of every matched image.
+	// +optional
+	Match *ImageMatch `json:"match,omitempty"`
+	// NewTag replaces the tag of the matched images, the digest of the image is removed too.
+	// +optional
+	NewTag string `json:"newTag,omitempty"`
+}
+
+// ImageFieldSpec locates the image fields of the resources, the resources of all kinds are selected if Kind is empty.
+type ImageFieldSpec struct {
+	Group   string `json:"group,omitempty"`
+	Version string `json:"version,omitempty"`
+	Kind    string `json:"kind,omitempty"`
+	// Path consists of slash-separated field names, the lists on the path are traversed, like `spec/template/spec/containers/image`
+	Path string `json:"path"`
+}
+
+// ImageMatch matches the image name without tag and digest, like `docker.io/library/nginx`.
+// An image is matched if both patterns match when both are set.
+type ImageMatch struct {
+	// Glob pattern in the syntax of path.Match, `*` does not match `/`, like `docker.io/library/*`
+	// +optional
+	Glob string `json:"glob,omitempty"`
+	// Regex pattern which must match the whole image name, like `docker\.io/(library|bitnami)/.*`
+	// +optional
+	Regex string `json:"regex,omitempty"`
 }
 
 // RepositoryStatus defines the observed state of Repository
@@ -33,6 +33,7 @@ func (r *Repository) SetupWebhookWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
 		WithDefaulter(r).
+		WithValidator(r).
 		Complete()
 }
 
@@ -54,3 +55,41 @@ func (r *Repository) Default(ctx context.Context, obj runtime.Object) error {
 	log.Info("set default value done")
 	return nil
 }
+
+//+kubebuilder:webhook:path=/validate-core-kubebb-k8s-com-cn-v1alpha1-repository,mutating=false,failurePolicy=fail,sideEffects=None,groups=core.kubebb.k8s.com.cn,resources=repositories,verbs=create;update,versions=v1alpha1,name=vrepository.kb.io,admissionReviewVersions=v1
+
+var _ webhook.CustomValidator = &Repository{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (r *Repository) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return r.validate(obj, "ValidateCreate")
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (r *Repository) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	return r.validate(newObj, "ValidateUpdate")
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (r *Repository) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// validate rejects the image override rules which fail to render the manifests
+func (r *Repository) validate(obj runtime.Object, method string) error {
+	log := repositorylog.WithValues("name", r.Name, "method", method)
+	p, ok := obj.(*Repository)
+	if !ok {
+		log.Error(ErrDecode, ErrDecode.Error())
+		return ErrDecode
+	}
+	if err := ValidateImageOverrides(p.Spec.ImageOverride); err != nil {
+		log.Error(err, "invalid image override")
+		return err
+	}
+	if err := ValidateImageFieldPresets(p.Spec.ImageFieldPresets); err != nil {
+		log.Error(err, "invalid image field presets")
+		return err
+	}
+	return nil
+}
@@ -36,6 +36,11 @@ var (
 	ErrComponentChange     = errors.New("component name and namespace (spec.component) should not change")
 	ErrComponentMissing    = errors.New("component name and namespace (spec.component) should have values")
 	ErrUnParseableSchedule = errors.New("unparseable subscription schedule")
+	ErrDeletionProtected   = errors.New("deletion is protected by annotation " + ComponentPlanDeletionProtectionAnnotation + ", remove it before deleting")
+	ErrPortalConflict      = errors.New("portal conflicts with existing portals, add annotation " + PortalAllowConflictsAnnotation + "=true to take over")
+	ErrPostRenderNamespace = errors.New("postRender should not move the resources out of the namespace of the ComponentPlan")
+	ErrPostRenderResource  = errors.New("postRender should not add the resources not in the manifests of the chart")
+	ErrTargetClusterDenied = errors.New("creator (spec.creator) can not get the kubeconfig Secret of the target cluster (spec.targetCluster)")
 )
 
 func getReqUserInfo(ctx context.Context) (authenticationv1.UserInfo, error) {
@@ -23,6 +23,7 @@ package v1alpha1
 
 import (
 	basev1alpha1 "github.com/kubeagi/arcadia/api/base/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -177,6 +178,11 @@ func (in *ComponentPlanStatus) DeepCopyInto(out *ComponentPlanStatus) {
 		**out = **in
 	}
 	out.Portal = in.Portal
+	if in.Menus != nil {
+		in, out := &in.Menus, &out.Menus
+		*out = make([]MenuReference, len(*in))
+		copy(*out, *in)
+	}
 	in.ConditionedStatus.DeepCopyInto(&out.ConditionedStatus)
 	if in.Resources != nil {
 		in, out := &in.Resources, &out.Resources
@@ -190,6 +196,20 @@ func (in *ComponentPlanStatus) DeepCopyInto(out *ComponentPlanStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ImageInventory != nil {
+		in, out := &in.ImageInventory, &out.ImageInventory
+		*out = make([]ImageInventory, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ImageDigests != nil {
+		in, out := &in.ImageDigests, &out.ImageDigests
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentPlanStatus.
@@ -247,6 +267,13 @@ func (in *ComponentStatus) DeepCopyInto(out *ComponentStatus) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Scores != nil {
+		in, out := &in.Scores, &out.Scores
+		*out = make([]ComponentVersionScore, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentStatus.
@@ -288,6 +315,29 @@ func (in *ComponentVersion) DeepCopy() *ComponentVersion {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentVersionScore) DeepCopyInto(out *ComponentVersionScore) {
+	*out = *in
+	if in.Dimensions != nil {
+		in, out := &in.Dimensions, &out.Dimensions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.RatedAt.DeepCopyInto(&out.RatedAt)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentVersionScore.
+func (in *ComponentVersionScore) DeepCopy() *ComponentVersionScore {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentVersionScore)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Condition) DeepCopyInto(out *Condition) {
 	*out = *in
@@ -341,6 +391,31 @@ func (in *Config) DeepCopyInto(out *Config) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.UninstallBackup != nil {
+		in, out := &in.UninstallBackup, &out.UninstallBackup
+		*out = new(UninstallBackup)
+		**out = **in
+	}
+	if in.Verification != nil {
+		in, out := &in.Verification, &out.Verification
+		*out = new(Verification)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TargetCluster != nil {
+		in, out := &in.TargetCluster, &out.TargetCluster
+		*out = new(TargetCluster)
+		**out = **in
+	}
+	if in.PrePull != nil {
+		in, out := &in.PrePull, &out.PrePull
+		*out = new(PrePull)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PostRender != nil {
+		in, out := &in.PostRender, &out.PostRender
+		*out = new(PostRender)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Config.
@@ -353,6 +428,31 @@ func (in *Config) DeepCopy() *Config {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EvaluationResult) DeepCopyInto(out *EvaluationResult) {
+	*out = *in
+	if in.Suggestions != nil {
+		in, out := &in.Suggestions, &out.Suggestions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Problems != nil {
+		in, out := &in.Problems, &out.Problems
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EvaluationResult.
+func (in *EvaluationResult) DeepCopy() *EvaluationResult {
+	if in == nil {
+		return nil
+	}
+	out := new(EvaluationResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Evaluator) DeepCopyInto(out *Evaluator) {
 	*out = *in
@@ -361,6 +461,18 @@ func (in *Evaluator) DeepCopyInto(out *Evaluator) {
 		*out = new(basev1alpha1.TypedObjectReference)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.PromptTemplate != nil {
+		in, out := &in.PromptTemplate, &out.PromptTemplate
+		*out = new(PromptTemplateRef)
+		**out = **in
+	}
+	if in.DimensionPromptTemplates != nil {
+		in, out := &in.DimensionPromptTemplates, &out.DimensionPromptTemplates
+		*out = make(map[string]PromptTemplateRef, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Evaluator.
@@ -377,6 +489,16 @@ func (in *Evaluator) DeepCopy() *Evaluator {
 func (in *EvaluatorStatus) DeepCopyInto(out *EvaluatorStatus) {
 	*out = *in
 	in.ConditionedStatus.DeepCopyInto(&out.ConditionedStatus)
+	if in.Chinese != nil {
+		in, out := &in.Chinese, &out.Chinese
+		*out = new(EvaluationResult)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.English != nil {
+		in, out := &in.English, &out.English
+		*out = new(EvaluationResult)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EvaluatorStatus.
@@ -429,6 +551,61 @@ func (in *GetTitleForReplaceSider) DeepCopy() *GetTitleForReplaceSider {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageFieldSpec) DeepCopyInto(out *ImageFieldSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageFieldSpec.
+func (in *ImageFieldSpec) DeepCopy() *ImageFieldSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageFieldSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageInventory) DeepCopyInto(out *ImageInventory) {
+	*out = *in
+	if in.Vulnerabilities != nil {
+		in, out := &in.Vulnerabilities, &out.Vulnerabilities
+		*out = new(VulnerabilitySummary)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Available != nil {
+		in, out := &in.Available, &out.Available
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageInventory.
+func (in *ImageInventory) DeepCopy() *ImageInventory {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageInventory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageMatch) DeepCopyInto(out *ImageMatch) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageMatch.
+func (in *ImageMatch) DeepCopy() *ImageMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ImageOverride) DeepCopyInto(out *ImageOverride) {
 	*out = *in
@@ -437,6 +614,11 @@ func (in *ImageOverride) DeepCopyInto(out *ImageOverride) {
 		*out = new(PathOverride)
 		**out = **in
 	}
+	if in.Match != nil {
+		in, out := &in.Match, &out.Match
+		*out = new(ImageMatch)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageOverride.
@@ -449,6 +631,38 @@ func (in *ImageOverride) DeepCopy() *ImageOverride {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageOverrideConfig) DeepCopyInto(out *ImageOverrideConfig) {
+	*out = *in
+	if in.ImageOverride != nil {
+		in, out := &in.ImageOverride, &out.ImageOverride
+		*out = make([]ImageOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ImageFieldSpecs != nil {
+		in, out := &in.ImageFieldSpecs, &out.ImageFieldSpecs
+		*out = make([]ImageFieldSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImageFieldPresets != nil {
+		in, out := &in.ImageFieldPresets, &out.ImageFieldPresets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageOverrideConfig.
+func (in *ImageOverrideConfig) DeepCopy() *ImageOverrideConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageOverrideConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Installed) DeepCopyInto(out *Installed) {
 	*out = *in
@@ -492,7 +706,7 @@ func (in *Menu) DeepCopyInto(out *Menu) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Menu.
@@ -590,6 +804,7 @@ func (in *MenuSpec) DeepCopy() *MenuSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MenuStatus) DeepCopyInto(out *MenuStatus) {
 	*out = *in
+	in.ConditionedStatus.DeepCopyInto(&out.ConditionedStatus)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MenuStatus.
@@ -612,7 +827,7 @@ func (in *Override) DeepCopyInto(out *Override) {
 			if (*in)[i] != nil {
 				in, out := &(*in)[i], &(*out)[i]
 				*out = new(ValuesReference)
-				**out = **in
+				(*in).DeepCopyInto(*out)
 			}
 		}
 	}
@@ -728,6 +943,22 @@ func (in *PipelineParam) DeepCopy() *PipelineParam {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PipelineRunRecord) DeepCopyInto(out *PipelineRunRecord) {
+	*out = *in
+	in.ConditionedStatus.DeepCopyInto(&out.ConditionedStatus)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineRunRecord.
+func (in *PipelineRunRecord) DeepCopy() *PipelineRunRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(PipelineRunRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PipelineRunStatus) DeepCopyInto(out *PipelineRunStatus) {
 	*out = *in
@@ -739,6 +970,13 @@ func (in *PipelineRunStatus) DeepCopyInto(out *PipelineRunStatus) {
 		}
 	}
 	in.ConditionedStatus.DeepCopyInto(&out.ConditionedStatus)
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]PipelineRunRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PipelineRunStatus.
@@ -751,6 +989,147 @@ func (in *PipelineRunStatus) DeepCopy() *PipelineRunStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Policy) DeepCopyInto(out *Policy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Policy.
+func (in *Policy) DeepCopy() *Policy {
+	if in == nil {
+		return nil
+	}
+	out := new(Policy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Policy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyList) DeepCopyInto(out *PolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Policy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyList.
+func (in *PolicyList) DeepCopy() *PolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyMatch) DeepCopyInto(out *PolicyMatch) {
+	*out = *in
+	if in.APIGroups != nil {
+		in, out := &in.APIGroups, &out.APIGroups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Kinds != nil {
+		in, out := &in.Kinds, &out.Kinds
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyMatch.
+func (in *PolicyMatch) DeepCopy() *PolicyMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyRule) DeepCopyInto(out *PolicyRule) {
+	*out = *in
+	if in.Match != nil {
+		in, out := &in.Match, &out.Match
+		*out = new(PolicyMatch)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyRule.
+func (in *PolicyRule) DeepCopy() *PolicyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicySpec) DeepCopyInto(out *PolicySpec) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]PolicyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicySpec.
+func (in *PolicySpec) DeepCopy() *PolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PolicyStatus) DeepCopyInto(out *PolicyStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PolicyStatus.
+func (in *PolicyStatus) DeepCopy() *PolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Portal) DeepCopyInto(out *Portal) {
 	*out = *in
@@ -850,6 +1229,141 @@ func (in *PortalStatus) DeepCopy() *PortalStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostRender) DeepCopyInto(out *PostRender) {
+	*out = *in
+	if in.Patches != nil {
+		in, out := &in.Patches, &out.Patches
+		*out = make([]PostRenderPatch, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CommonLabels != nil {
+		in, out := &in.CommonLabels, &out.CommonLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.CommonAnnotations != nil {
+		in, out := &in.CommonAnnotations, &out.CommonAnnotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Components != nil {
+		in, out := &in.Components, &out.Components
+		*out = make([]PostRenderComponent, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostRender.
+func (in *PostRender) DeepCopy() *PostRender {
+	if in == nil {
+		return nil
+	}
+	out := new(PostRender)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostRenderComponent) DeepCopyInto(out *PostRenderComponent) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostRenderComponent.
+func (in *PostRenderComponent) DeepCopy() *PostRenderComponent {
+	if in == nil {
+		return nil
+	}
+	out := new(PostRenderComponent)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostRenderPatch) DeepCopyInto(out *PostRenderPatch) {
+	*out = *in
+	if in.Target != nil {
+		in, out := &in.Target, &out.Target
+		*out = new(PostRenderPatchTarget)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostRenderPatch.
+func (in *PostRenderPatch) DeepCopy() *PostRenderPatch {
+	if in == nil {
+		return nil
+	}
+	out := new(PostRenderPatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PostRenderPatchTarget) DeepCopyInto(out *PostRenderPatchTarget) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PostRenderPatchTarget.
+func (in *PostRenderPatchTarget) DeepCopy() *PostRenderPatchTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(PostRenderPatchTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PrePull) DeepCopyInto(out *PrePull) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PrePull.
+func (in *PrePull) DeepCopy() *PrePull {
+	if in == nil {
+		return nil
+	}
+	out := new(PrePull)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PromptTemplateRef) DeepCopyInto(out *PromptTemplateRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PromptTemplateRef.
+func (in *PromptTemplateRef) DeepCopy() *PromptTemplateRef {
+	if in == nil {
+		return nil
+	}
+	out := new(PromptTemplateRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PullStategy) DeepCopyInto(out *PullStategy) {
 	*out = *in
@@ -924,6 +1438,33 @@ func (in *RatingList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RatingPolicy) DeepCopyInto(out *RatingPolicy) {
+	*out = *in
+	if in.MinDimensionScores != nil {
+		in, out := &in.MinDimensionScores, &out.MinDimensionScores
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.RequiredPipelineRuns != nil {
+		in, out := &in.RequiredPipelineRuns, &out.RequiredPipelineRuns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RatingPolicy.
+func (in *RatingPolicy) DeepCopy() *RatingPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(RatingPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RatingSpec) DeepCopyInto(out *RatingSpec) {
 	*out = *in
@@ -935,6 +1476,13 @@ func (in *RatingSpec) DeepCopyInto(out *RatingSpec) {
 		}
 	}
 	in.Evaluator.DeepCopyInto(&out.Evaluator)
+	if in.Weights != nil {
+		in, out := &in.Weights, &out.Weights
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RatingSpec.
@@ -977,6 +1525,36 @@ func (in *RatingStatus) DeepCopy() *RatingStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RatingTemplate) DeepCopyInto(out *RatingTemplate) {
+	*out = *in
+	if in.PipelineParams != nil {
+		in, out := &in.PipelineParams, &out.PipelineParams
+		*out = make([]PipelineParam, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	in.Evaluator.DeepCopyInto(&out.Evaluator)
+	if in.Weights != nil {
+		in, out := &in.Weights, &out.Weights
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RatingTemplate.
+func (in *RatingTemplate) DeepCopy() *RatingTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(RatingTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Repository) DeepCopyInto(out *Repository) {
 	*out = *in
@@ -1087,6 +1665,26 @@ func (in *RepositorySpec) DeepCopyInto(out *RepositorySpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ImageFieldSpecs != nil {
+		in, out := &in.ImageFieldSpecs, &out.ImageFieldSpecs
+		*out = make([]ImageFieldSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImageFieldPresets != nil {
+		in, out := &in.ImageFieldPresets, &out.ImageFieldPresets
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.RatingTemplate != nil {
+		in, out := &in.RatingTemplate, &out.RatingTemplate
+		*out = new(RatingTemplate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RatingPolicy != nil {
+		in, out := &in.RatingPolicy, &out.RatingPolicy
+		*out = new(RatingPolicy)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositorySpec.
@@ -1269,6 +1867,21 @@ func (in *SubscriptionStatus) DeepCopy() *SubscriptionStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetCluster) DeepCopyInto(out *TargetCluster) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetCluster.
+func (in *TargetCluster) DeepCopy() *TargetCluster {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetCluster)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Task) DeepCopyInto(out *Task) {
 	*out = *in
@@ -1285,9 +1898,44 @@ func (in *Task) DeepCopy() *Task {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UninstallBackup) DeepCopyInto(out *UninstallBackup) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UninstallBackup.
+func (in *UninstallBackup) DeepCopy() *UninstallBackup {
+	if in == nil {
+		return nil
+	}
+	out := new(UninstallBackup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ValuesDecryption) DeepCopyInto(out *ValuesDecryption) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValuesDecryption.
+func (in *ValuesDecryption) DeepCopy() *ValuesDecryption {
+	if in == nil {
+		return nil
+	}
+	out := new(ValuesDecryption)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ValuesReference) DeepCopyInto(out *ValuesReference) {
 	*out = *in
+	if in.Decryption != nil {
+		in, out := &in.Decryption, &out.Decryption
+		*out = new(ValuesDecryption)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ValuesReference.
@@ -1300,6 +1948,26 @@ func (in *ValuesReference) DeepCopy() *ValuesReference {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Verification) DeepCopyInto(out *Verification) {
+	*out = *in
+	if in.JobTemplate != nil {
+		in, out := &in.JobTemplate, &out.JobTemplate
+		*out = new(batchv1.JobTemplateSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Verification.
+func (in *Verification) DeepCopy() *Verification {
+	if in == nil {
+		return nil
+	}
+	out := new(Verification)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VersionedFilterCond) DeepCopyInto(out *VersionedFilterCond) {
 	*out = *in
@@ -1319,3 +1987,22 @@ func (in *VersionedFilterCond) DeepCopy() *VersionedFilterCond {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VulnerabilitySummary) DeepCopyInto(out *VulnerabilitySummary) {
+	*out = *in
+	if in.ScannedAt != nil {
+		in, out := &in.ScannedAt, &out.ScannedAt
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VulnerabilitySummary.
+func (in *VulnerabilitySummary) DeepCopy() *VulnerabilitySummary {
+	if in == nil {
+		return nil
+	}
+	out := new(VulnerabilitySummary)
+	in.DeepCopyInto(out)
+	return out
+}
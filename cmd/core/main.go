@@ -31,6 +31,25 @@ For more details, please refer to https://kubebb.github.io/website
 Examples:
     # install kubebb to a cluster
     corectl install --context ~/.kube/devconfig [--skip-component-store|--skip-u4a-component|--skip-cluster-component]
+
+    # write the effective profile of the flags into a file, then install with it
+    corectl install --core-version v0.1.0 --component-store=false --export profile.yaml
+    corectl install --profile profile.yaml
+
+    # upgrade core to a new version, keep the other components as they are
+    corectl upgrade kubebb-core --core-version v0.1.0
+
+    # show the status of the components
+    corectl status --namespace default
+
+    # check CRDs, webhook certs, operator pods and Repositories
+    corectl doctor --namespace default
+
+    # uninstall all components in reverse order
+    corectl uninstall --namespace default
+
+    # adopt releases installed by plain helm into ComponentPlans
+    corectl adopt --namespace default [RELEASE...]
 `
 
 func main() {
@@ -41,6 +60,11 @@ func main() {
 	}
 
 	rootCmd.AddCommand(cmd.NewInstallCmd())
+	rootCmd.AddCommand(cmd.NewUninstallCmd())
+	rootCmd.AddCommand(cmd.NewUpgradeCmd())
+	rootCmd.AddCommand(cmd.NewStatusCmd())
+	rootCmd.AddCommand(cmd.NewDoctorCmd())
+	rootCmd.AddCommand(cmd.NewAdoptCmd())
 	if err := rootCmd.Execute(); err != nil {
 		panic(err)
 	}
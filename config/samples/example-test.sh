@@ -669,6 +669,12 @@ info "8.1 create portal-example which has no conflicts"
 kubectl apply -f config/samples/core_v1alpha1_portal.yaml
 checkPortalStatus "portal-example" "" ""
 
+info "8.1.1 create a portal whose path overlaps with portal-example should be rejected"
+if kubectl apply -f config/samples/core_v1alpha1_portal_conflict.yaml; then
+	error "Portal portal-example-conflict should be rejected by webhook"
+	exit 1
+fi
+
 info "8.2 create portal-example-another which contains conflicts in entry"
 kubectl apply -f config/samples/core_v1alpha1_portal_another.yaml
 checkPortalStatus "portal-example" "portal-example-another" ""
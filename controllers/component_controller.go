@@ -20,7 +20,6 @@ import (
 	"context"
 	"fmt"
 	"reflect"
-	"strings"
 	"sync"
 	"time"
 
@@ -56,6 +55,8 @@ type ComponentReconciler struct {
 //+kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=components,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=components/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=components/finalizers,verbs=update
+//+kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=ratings,verbs=get;list;watch;create
+//+kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=repositories,verbs=get;list;watch;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -105,6 +106,13 @@ func (r *ComponentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		if err := r.UpdateValuesConfigmap(ctx, logger, instance, repo); err != nil {
 			return reconcile.Result{RequeueAfter: time.Second * 3}, nil
 		}
+		requeueAfter, err := r.CreateRating(ctx, logger, instance, repo)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if requeueAfter > 0 {
+			return reconcile.Result{RequeueAfter: requeueAfter}, nil
+		}
 	}
 
 	logger.Info("Synchronized component successfully")
@@ -201,26 +209,10 @@ func (r *ComponentReconciler) UpdateValuesConfigmap(ctx context.Context, logger
 				<-limit
 			}()
 			cmName := corev1alpha1.GetComponentChartValuesConfigmapName(component.Name, versionStr)
-			var pullURL string
-			u := strings.TrimSuffix(repo.Spec.URL, "/")
-			if repo.IsOCI() {
-				if v, ok := component.Annotations[corev1alpha1.OCIPullURLAnnotation]; ok {
-					pullURL = v
-				} else {
-					pullURL = u + "/" + component.Status.Name
-				}
-			} else {
-				if len(httpDonwloadURLs) == 0 {
-					logger.Error(fmt.Errorf("not found %s's urls", component.Status.Name), "")
-					return
-				}
-				if strings.HasPrefix(httpDonwloadURLs[0], "http") {
-					pullURL = httpDonwloadURLs[0]
-				} else {
-					// chartmuseum charts/weaviate-16.3.0.tgz
-					// github https://github.com/kubebb/components/releases/download/bc-apis-0.0.3/bc-apis-0.0.3.tgz
-					pullURL = u + "/" + httpDonwloadURLs[0]
-				}
+			pullURL := repo.ChartPullURL(component, httpDonwloadURLs)
+			if pullURL == "" {
+				logger.Error(fmt.Errorf("not found %s's urls", component.Status.Name), "")
+				return
 			}
 
 			h, err := helm.NewCoreHelmWrapper(getter, component.Namespace, logger, r.Client, nil, repo, component)
@@ -242,6 +234,63 @@ func (r *ComponentReconciler) UpdateValuesConfigmap(ctx context.Context, logger
 	return nil
 }
 
+// maxVersionsToRate is the number of the newest versions of a component to be rated automatically
+const maxVersionsToRate = 5
+
+// CreateRating creates Ratings from the RatingTemplate of the repository for the newest versions of the component,
+// see Component.NewVersionsToRate. Only one Rating is created for each version, and at most one Rating is created
+// in the repository in the interval of the template, the time of the last one is recorded in the annotation
+// of the repository. The returned duration is the time to wait for the next Rating.
+func (r *ComponentReconciler) CreateRating(ctx context.Context, logger logr.Logger, component *corev1alpha1.Component, repo *corev1alpha1.Repository) (time.Duration, error) {
+	template := repo.Spec.RatingTemplate
+	if !repo.Spec.EnableRating || template == nil {
+		return 0, nil
+	}
+	ratings := &corev1alpha1.RatingList{}
+	if err := r.Client.List(ctx, ratings, client.InNamespace(component.Namespace), client.MatchingLabels{
+		corev1alpha1.RatingComponentLabel: component.Name,
+	}); err != nil {
+		return 0, err
+	}
+	rated := make(map[string]bool, len(ratings.Items))
+	for _, rating := range ratings.Items {
+		rated[rating.Labels[corev1alpha1.RatingComponentVersion]] = true
+	}
+	versions := component.NewVersionsToRate(rated, maxVersionsToRate)
+	if len(versions) == 0 {
+		return 0, nil
+	}
+
+	repoKey := repo.Namespace + "/" + repo.Name
+	if last, err := time.Parse(time.RFC3339, repo.Annotations[corev1alpha1.RepositoryLastRatingAnnotation]); err == nil {
+		if wait := time.Until(last.Add(template.Interval())); wait > 0 {
+			logger.V(1).Info("Rating creation is limited", "Repository", repoKey, "Version", versions[0].Version, "Wait", wait)
+			return wait, nil
+		}
+	}
+
+	version := versions[0]
+	rating := template.NewRating(component, version.Version, repo.ChartPullURL(component, version.URLs))
+	if err := r.Client.Create(ctx, rating); err != nil && !errors.IsAlreadyExists(err) {
+		return 0, err
+	} else if err == nil {
+		logger.Info("Rating created", "Rating", rating.Name, "Version", version.Version)
+		r.Recorder.Event(component, corev1.EventTypeNormal, "RatingCreated", fmt.Sprintf("create rating %s for version %s", rating.Name, version.Version))
+		updated := repo.DeepCopy()
+		if updated.Annotations == nil {
+			updated.Annotations = make(map[string]string)
+		}
+		updated.Annotations[corev1alpha1.RepositoryLastRatingAnnotation] = time.Now().UTC().Format(time.RFC3339)
+		if err = r.Client.Patch(ctx, updated, client.MergeFrom(repo)); err != nil {
+			return 0, err
+		}
+	}
+	if len(versions) > 1 {
+		return template.Interval(), nil
+	}
+	return 0, nil
+}
+
 func (r *ComponentReconciler) DeleteComponentConfigMaps(ctx context.Context, name, namespace string, versions []string, logger logr.Logger) bool {
 	logger.Info(fmt.Sprintf("delete component %s/%s deleted versions..", namespace, name))
 
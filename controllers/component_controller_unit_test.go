@@ -0,0 +1,94 @@
+/*
+ * Copyright 2023 The Kubebb Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+func TestComponentCreateRating(t *testing.T) {
+	ctx := context.Background()
+	repo := &corev1alpha1.Repository{
+		ObjectMeta: metav1.ObjectMeta{Name: "repo", Namespace: "default"},
+		Spec: corev1alpha1.RepositorySpec{
+			EnableRating:   true,
+			RatingTemplate: &corev1alpha1.RatingTemplate{IntervalSeconds: 60},
+		},
+	}
+	component := &corev1alpha1.Component{
+		ObjectMeta: metav1.ObjectMeta{Name: "repo.nginx", Namespace: "default", Labels: map[string]string{corev1alpha1.ComponentRepositoryLabel: repo.Name}},
+		Status: corev1alpha1.ComponentStatus{
+			Versions: []corev1alpha1.ComponentVersion{{Version: "0.1.0"}, {Version: "0.2.0"}, {Version: "0.3.0"}},
+		},
+	}
+	rated := repo.Spec.RatingTemplate.NewRating(component, "0.1.0", "")
+	cli := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(repo, component, rated).Build()
+	r := &ComponentReconciler{Client: cli, Scheme: cli.Scheme(), Recorder: record.NewFakeRecorder(10)}
+
+	createRating := func() time.Duration {
+		t.Helper()
+		got := &corev1alpha1.Repository{}
+		if err := cli.Get(ctx, client.ObjectKeyFromObject(repo), got); err != nil {
+			t.Fatal(err)
+		}
+		wait, err := r.CreateRating(ctx, log.FromContext(ctx), component, got)
+		if err != nil {
+			t.Fatalf("CreateRating() error = %v", err)
+		}
+		return wait
+	}
+	hasRating := func(version string) bool {
+		t.Helper()
+		rating := &corev1alpha1.Rating{}
+		return cli.Get(ctx, client.ObjectKey{Namespace: "default", Name: corev1alpha1.ComponentRatingName(component.Name, version)}, rating) == nil
+	}
+
+	if wait := createRating(); wait != time.Minute {
+		t.Errorf("CreateRating() wait = %s, want the interval for the next version", wait)
+	}
+	if !hasRating("0.3.0") || hasRating("0.2.0") {
+		t.Fatalf("only the newest version should be rated first")
+	}
+	// the limit is kept in the repository annotation, so it works with a new reconciler after restart
+	r = &ComponentReconciler{Client: cli, Scheme: cli.Scheme(), Recorder: record.NewFakeRecorder(10)}
+	if wait := createRating(); wait <= 0 || hasRating("0.2.0") {
+		t.Fatalf("Rating creation should be limited, wait = %s", wait)
+	}
+
+	got := &corev1alpha1.Repository{}
+	_ = cli.Get(ctx, client.ObjectKeyFromObject(repo), got)
+	got.Annotations[corev1alpha1.RepositoryLastRatingAnnotation] = time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+	if err := cli.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if wait := createRating(); wait != 0 || !hasRating("0.2.0") {
+		t.Errorf("the next new version should be rated after the interval, wait = %s", wait)
+	}
+	if wait := createRating(); wait != 0 {
+		t.Errorf("no version left to rate, wait = %s", wait)
+	}
+}
@@ -20,27 +20,40 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
+	"github.com/google/go-containerregistry/pkg/authn"
 	"helm.sh/helm/v3/pkg/release"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sigs.k8s.io/yaml"
 
 	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
 	"github.com/kubebb/core/pkg/helm"
+	"github.com/kubebb/core/pkg/inventory"
+	"github.com/kubebb/core/pkg/policy"
 	"github.com/kubebb/core/pkg/utils"
 )
 
@@ -49,10 +62,15 @@ var (
 )
 
 const (
+	// ValuesFromIndexKey indexes ComponentPlans by the ConfigMaps and Secrets referenced in spec.override.valuesFrom, in the format of Kind/Name
+	ValuesFromIndexKey = "spec.override.valuesFrom"
+
 	revisionNoExist = -1
 	revisionInstall = 1
 	waitLonger      = time.Minute
 	waitSmaller     = time.Second * 3
+	// waitResync is the interval to restore the Portal and Menus in the target cluster, which can not be watched
+	waitResync = time.Minute * 10
 )
 
 // ComponentPlanReconciler reconciles a ComponentPlan object
@@ -61,13 +79,24 @@ type ComponentPlanReconciler struct {
 	Recorder   record.EventRecorder
 	Scheme     *runtime.Scheme
 	WorkerPool helm.ReleaseWorkerPool
+	// Inventory resolves the digests and vulnerabilities of the images, the RemoteResolver authenticates with
+	// the image pull secrets of the workloads. No inventory is built if it is nil.
+	Inventory *inventory.Builder
 }
 
 // +kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=componentplans,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=componentplans/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=componentplans/finalizers,verbs=update
+// +kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=policies,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=configmaps/status,verbs=get
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=serviceaccounts,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=portals,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=menus,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -107,6 +136,10 @@ func (r *ComponentPlanReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	isPlanMarkedToBeDeleted := plan.GetDeletionTimestamp() != nil
 	if isPlanMarkedToBeDeleted && controllerutil.ContainsFinalizer(plan, corev1alpha1.Finalizer) {
 		logger.Info("Performing Finalizer Operations for Plan before delete CR")
+		if err = r.deletePrePull(ctx, plan); err != nil {
+			logger.Error(err, "Failed to delete the pre-pull DaemonSet of ComponentPlan")
+			return ctrl.Result{}, err
+		}
 		// Note: In the original Helm source code, when helm is uninstalling, there is no check to
 		// see if the current helm release is in installing or any other state, just uninstall.
 		if plan.IsActionedReason(corev1alpha1.ComponentPlanReasonUninstallFailed) {
@@ -123,6 +156,23 @@ func (r *ComponentPlanReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 			logger.Info("Remove ComponentPlan done")
 			return ctrl.Result{}, nil
 		}
+		if plan.IsOrphanUninstall() {
+			logger.Info("Uninstall policy is Orphan, keep the release and remove Finalizer for ComponentPlan")
+			r.Recorder.Eventf(plan, corev1.EventTypeNormal, "Orphaned", "%s is kept after ComponentPlan deleted", plan.GetReleaseName())
+			controllerutil.RemoveFinalizer(plan, corev1alpha1.Finalizer)
+			if err = r.Update(ctx, plan); err != nil {
+				logger.Error(err, "Failed to remove finalizer for ComponentPlan")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, nil
+		}
+		if plan.Spec.UninstallBackup != nil && !plan.IsActionedReason(corev1alpha1.ComponentPlanReasonUninstalling) {
+			if err = r.backupRelease(ctx, logger, plan); err != nil {
+				logger.Error(err, fmt.Sprintf("Failed to backup release before uninstall, wait %s for another try", waitLonger))
+				r.Recorder.Eventf(plan, corev1.EventTypeWarning, "BackupFailure", "%s backup failed: %s", plan.GetReleaseName(), err)
+				return ctrl.Result{RequeueAfter: waitLonger}, r.PatchCondition(ctx, plan, logger, revisionNoExist, false, false, corev1alpha1.ComponentPlanWaitDo(fmt.Errorf("backup before uninstall: %w", err)))
+			}
+		}
 		doing, err := r.WorkerPool.Uninstall(ctx, plan)
 		if doing {
 			return ctrl.Result{RequeueAfter: waitSmaller}, r.PatchCondition(ctx, plan, logger, revisionNoExist, false, false, corev1alpha1.ComponentPlanUninstalling())
@@ -131,6 +181,10 @@ func (r *ComponentPlanReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 			logger.Error(err, "Failed to uninstall ComponentPlan")
 			return ctrl.Result{}, r.PatchCondition(ctx, plan, logger, revisionNoExist, true, true, corev1alpha1.ComponentPlanUninstallFailed(err))
 		}
+		if err = r.cleanupPortalAndMenus(ctx, logger, plan); err != nil {
+			logger.Error(err, "Failed to delete the Portal and Menus of ComponentPlan")
+			return ctrl.Result{}, err
+		}
 		logger.Info("Uninstall ComponentPlan succeeded")
 		return ctrl.Result{}, r.PatchCondition(ctx, plan, logger, revisionNoExist, true, false, corev1alpha1.ComponentPlanUninstallSuccess())
 	}
@@ -179,19 +233,53 @@ func (r *ComponentPlanReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 	// updateLatest try to update all componentplan's status.Latest
 	go r.updateLatest(ctx, logger, plan)
 
-	if plan.Status.GetCondition(corev1alpha1.ComponentPlanTypeSucceeded).Status == corev1.ConditionTrue {
+	isSucceeded := plan.Status.GetCondition(corev1alpha1.ComponentPlanTypeSucceeded).Status == corev1.ConditionTrue
+	valuesFromHash, err := corev1alpha1.ValuesFromHash(ctx, r.Client, plan.Namespace, plan.Spec.Override.ValuesFrom)
+	if err != nil {
+		logger.Error(err, fmt.Sprintf("Failed to get the content of ValuesFrom, wait %s for another try", waitLonger))
+		if isSucceeded {
+			r.Recorder.Eventf(plan, corev1.EventTypeWarning, "ValuesFromFailure", "failed to get the content of valuesFrom: %s", err)
+			return ctrl.Result{RequeueAfter: waitLonger}, nil
+		}
+		return ctrl.Result{RequeueAfter: waitLonger}, r.PatchCondition(ctx, plan, logger, revisionNoExist, false, false, corev1alpha1.ComponentPlanWaitDo(err))
+	}
+
+	if isSucceeded {
 		switch {
 		case r.isGenerationUpdate(plan):
 			logger.Info("ComponentPlan.Spec is changed, need to install or upgrade ...")
 			return ctrl.Result{}, r.PatchCondition(ctx, plan, logger, revisionNoExist, false, false, plan.InitCondition()...)
+		case plan.Status.ValuesFromHash == "" && valuesFromHash != "":
+			// plans installed before the content of ValuesFrom is tracked, record it instead of an upgrade
+			logger.Info("ComponentPlan has no ValuesFrom hash recorded, record the current one")
+			return ctrl.Result{}, r.patchValuesFromHash(ctx, plan, valuesFromHash)
+		case r.isValuesFromUpdate(plan, valuesFromHash):
+			return ctrl.Result{}, r.onValuesFromUpdate(ctx, logger, plan, valuesFromHash)
 		case r.needRetry(plan):
 			logger.Info("ComponentPlan need to retry...")
 		default:
-			logger.Info("ComponentPlan is unchanged and has been successful, no need to reconcile")
+			logger.Info("ComponentPlan is unchanged and has been successful, sync the Portal and Menus")
+			r.resyncPortalAndMenus(ctx, logger, plan)
+			if plan.Spec.TargetCluster != nil {
+				return ctrl.Result{RequeueAfter: waitResync}, nil
+			}
 			return ctrl.Result{}, nil
 		}
 	}
 
+	if plan.IsVerifiedReason(corev1alpha1.ComponentPlanReasonVerifyFailed) && r.isGenerationUpdate(plan) {
+		logger.Info("ComponentPlan.Spec is changed after verification failed, need to install or upgrade ...")
+		return ctrl.Result{}, r.PatchCondition(ctx, plan, logger, revisionNoExist, false, false, plan.InitCondition()...)
+	}
+
+	if plan.Status.ValuesFromHash != valuesFromHash {
+		// record the content used by the coming install or upgrade
+		if err = r.patchValuesFromHash(ctx, plan, valuesFromHash); err != nil {
+			logger.Error(err, "Failed to update ComponentPlan status.ValuesFromHash")
+			return ctrl.Result{}, err
+		}
+	}
+
 	repo := &corev1alpha1.Repository{}
 	if err = r.Get(ctx, types.NamespacedName{Name: component.Status.RepositoryRef.Name, Namespace: component.Status.RepositoryRef.Namespace}, repo); err != nil {
 		logger.Error(err, fmt.Sprintf("Failed to get Repository, wait %s for another try", waitSmaller))
@@ -211,37 +299,75 @@ func (r *ComponentPlanReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		}
 	}
 
+	imageCfg, err := corev1alpha1.GetImageOverrideConfig(ctx, r.Client)
+	if err != nil {
+		logger.Error(err, "Failed to get default image override config")
+		return ctrl.Result{}, r.PatchCondition(ctx, plan, logger, revisionNoExist, false, false, corev1alpha1.ComponentPlanWaitDo(err))
+	}
+	imageCfg = imageCfg.ForRepository(repo)
+	imageCfgHash := imageCfg.Hash()
+
 	// Check its helm template configmap exist
 	manifest := &corev1.ConfigMap{}
 	manifest.Name = corev1alpha1.GenerateComponentPlanManifestConfigMapName(plan)
 	manifest.Namespace = plan.Namespace
 	err = r.Get(ctx, types.NamespacedName{Name: manifest.Name, Namespace: manifest.Namespace}, manifest)
-	if (err != nil && apierrors.IsNotFound(err)) || r.isGenerationUpdate(plan) {
+	if (err != nil && apierrors.IsNotFound(err)) || r.isGenerationUpdate(plan) ||
+		(err == nil && manifest.Annotations[corev1alpha1.ComponentPlanValuesFromHashAnnotation] != plan.Status.ValuesFromHash) ||
+		(err == nil && manifest.Annotations[corev1alpha1.ComponentPlanImageOverrideHashAnnotation] != imageCfgHash) {
 		data, err := r.WorkerPool.GetManifests(ctx, plan, repo, chartName)
 		if err != nil {
 			logger.Error(err, "Failed to get manifest")
 			return ctrl.Result{}, r.PatchCondition(ctx, plan, logger, revisionNoExist, false, false, corev1alpha1.ComponentPlanWaitDo(err))
 		}
 
-		if err = r.GenerateManifestConfigMap(plan, manifest, data); err != nil {
+		if err = r.GenerateManifestConfigMap(plan, manifest, data, imageCfgHash); err != nil {
 			logger.Error(err, "Failed to generate manifest configmap")
 			return ctrl.Result{}, r.PatchCondition(ctx, plan, logger, revisionNoExist, false, false, corev1alpha1.ComponentPlanWaitDo(err))
 		}
 		logger.Info("Generate a new template Configmap", "ConfigMap", klog.KObj(manifest))
 
+		// resources diff is done against the cluster where the release is installed
+		targetCli, err := r.WorkerPool.GetClient(ctx, plan)
+		if err != nil {
+			logger.Error(err, "Failed to get client of target cluster")
+			return ctrl.Result{}, r.PatchCondition(ctx, plan, logger, revisionNoExist, false, false, corev1alpha1.ComponentPlanWaitDo(err))
+		}
+		fieldSpecs, err := imageCfg.FieldSpecs()
+		if err != nil {
+			logger.Error(err, "Failed to get image fields")
+			return ctrl.Result{}, r.PatchCondition(ctx, plan, logger, revisionNoExist, false, false, corev1alpha1.ComponentPlanWaitDo(err))
+		}
 		newPlan := plan.DeepCopy()
-		newPlan.Status.Resources, newPlan.Status.Images, err = corev1alpha1.GetResourcesAndImages(ctx, logger, r.Client, data, plan.GetNamespace())
+		newPlan.Status.Resources, newPlan.Status.Images, err = corev1alpha1.GetResourcesAndImages(ctx, logger, targetCli, data, plan.GetNamespace(), fieldSpecs)
 		if err != nil {
 			logger.Error(err, "Failed to get resources")
 			return ctrl.Result{}, r.PatchCondition(ctx, plan, logger, revisionNoExist, false, false, corev1alpha1.ComponentPlanWaitDo(err))
 		}
+		newPlan.Status.ImageInventory = r.buildImageInventory(ctx, logger, targetCli, plan, data, newPlan.Status.Images)
+		pinDigest := imageCfg.PinImageDigest
+		newPlan.Status.ImageDigests = nil
+		if pinDigest {
+			newPlan.Status.ImageDigests, err = r.resolveImageDigests(ctx, targetCli, plan, data, newPlan.Status.Images)
+			if err != nil {
+				logger.Error(err, "Failed to pin images to digests")
+				return ctrl.Result{}, r.PatchCondition(ctx, plan, logger, revisionNoExist, false, false, corev1alpha1.ComponentPlanWaitDo(err))
+			}
+		}
 		err = r.Status().Patch(ctx, newPlan, client.MergeFrom(plan))
 		if err != nil {
 			logger.Error(err, "Failed to update ComponentPlan status.Resources")
+			if pinDigest {
+				// never install the images without the pinned digests
+				return ctrl.Result{}, err
+			}
+		} else {
+			plan.Status.ImageDigests = newPlan.Status.ImageDigests
 		}
 		logger.Info("Update ComponentPlan status.Resources")
 		cm := manifest.DeepCopy()
 		res, err := controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+			cm.Annotations = manifest.Annotations
 			cm.Data = manifest.Data
 			return nil
 		})
@@ -293,6 +419,9 @@ func (r *ComponentPlanReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		}
 		return ctrl.Result{}, r.PatchCondition(ctx, plan, logger, revision, true, false, corev1alpha1.ComponentPlanRollBackSuccess())
 	}
+	if r.needVerify(plan) {
+		return r.verify(ctx, logger, plan)
+	}
 	if r.statusShowDone(plan) {
 		return ctrl.Result{}, nil
 	}
@@ -301,7 +430,11 @@ func (r *ComponentPlanReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		return ctrl.Result{}, r.PatchCondition(ctx, plan, logger, revisionNoExist, true, false, corev1alpha1.ComponentPlanFailed(errMaxRetry))
 	}
 
-	rel, err := r.WorkerPool.GetLastRelease(plan)
+	if blocked, err := r.checkPolicies(ctx, logger, plan, manifest.Data["manifest"]); err != nil || blocked {
+		return ctrl.Result{}, err
+	}
+
+	rel, err := r.WorkerPool.GetLastRelease(ctx, plan)
 	if err != nil {
 		logger.Error(err, "Failed to check if helm is doing")
 		return ctrl.Result{}, r.PatchCondition(ctx, plan, logger, revisionNoExist, false, false, corev1alpha1.ComponentPlanWaitDo(err))
@@ -314,8 +447,12 @@ func (r *ComponentPlanReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 			logger.Info(fmt.Sprintf("helm show another operation (install/upgrade/rollback/uninstall) is in progress...wait %s for another try", waitSmaller))
 			return ctrl.Result{RequeueAfter: waitSmaller}, nil
 		}
+		if r.needAdopt(plan, rel) {
+			return ctrl.Result{}, r.adoptRelease(ctx, logger, rel, plan, component)
+		}
 		_, _, uid, generation, _ := helm.ParseDescription(rel.Info.Description)
-		if uid == string(plan.GetUID()) && generation == plan.GetGeneration() && rel.Info.Status == release.StatusDeployed {
+		if uid == string(plan.GetUID()) && generation == plan.GetGeneration() && rel.Info.Status == release.StatusDeployed &&
+			helm.ParseDescriptionValuesFromHash(rel.Info.Description) == plan.Status.ValuesFromHash {
 			return ctrl.Result{}, r.updateReleaseStatus(ctx, logger, rel, nil, plan)
 		}
 
@@ -323,6 +460,18 @@ func (r *ComponentPlanReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 		_ = r.PatchCondition(ctx, plan, logger, rel.Version, false, false, corev1alpha1.ComponentPlanUpgrading())
 	}
 
+	if plan.NeedPrePull() {
+		done, err := r.prePull(ctx, logger, plan, manifest.Data["manifest"])
+		if err != nil {
+			logger.Error(err, "Failed to pre-pull images")
+			return ctrl.Result{}, err
+		}
+		if !done {
+			logger.Info(fmt.Sprintf("images are being pre-pulled...wait %s for another try", waitSmaller))
+			return ctrl.Result{RequeueAfter: waitSmaller}, nil
+		}
+	}
+
 	rel, doing, err := r.WorkerPool.InstallOrUpgrade(ctx, plan, repo, chartName)
 	if doing {
 		logger.Info(fmt.Sprintf("another operation (install/upgrade/rollback/uninstall) is in progress...wait %s for another try", waitSmaller))
@@ -355,22 +504,602 @@ func (r *ComponentPlanReconciler) updateReleaseStatus(ctx context.Context, logge
 			err = r.PatchCondition(ctx, plan, logger, revision, true, false, corev1alpha1.ComponentPlanUpgradeSuccess())
 			r.Recorder.Eventf(plan, corev1.EventTypeNormal, "UpgradeSuccess", "%s upgrade successfully", rel.Name)
 		}
+		if err != nil {
+			return err
+		}
+		// the release is installed already, a Portal or Menu failed to sync is only reported by an event,
+		// and does not make the release failed.
+		if syncErr := r.syncPortalAndMenus(ctx, logger, rel, plan); syncErr != nil {
+			logger.Error(syncErr, "Failed to sync the Portal and Menus of ComponentPlan")
+			r.Recorder.Eventf(plan, corev1.EventTypeWarning, "PortalFailure", "%s portal and menus sync failed: %s", rel.Name, syncErr)
+		}
 	}
 	return
 }
 
+// buildImageInventory builds the inventory of the images, the digests are resolved with the image pull secrets
+// of the workloads, so the unavailable images are found before the ComponentPlan is approved.
+// No inventory is built if the Inventory is not configured.
+func (r *ComponentPlanReconciler) buildImageInventory(ctx context.Context, logger logr.Logger, cli client.Client, plan *corev1alpha1.ComponentPlan, data string, images []string) []corev1alpha1.ImageInventory {
+	if r.Inventory == nil {
+		return nil
+	}
+	builder := *r.Inventory
+	if resolver, ok := builder.Resolver.(*inventory.RemoteResolver); ok {
+		keychain, err := r.pullSecretKeychain(ctx, cli, plan.Namespace, data)
+		if err != nil {
+			logger.Error(err, "Failed to get image pull secrets, resolve the images anonymously")
+		} else {
+			builder.Resolver = resolver.WithKeychain(keychain)
+		}
+	}
+	res := builder.Build(ctx, images)
+	unavailable := make([]string, 0)
+	for _, item := range res {
+		if item.Available != nil && !*item.Available {
+			unavailable = append(unavailable, item.Image)
+		}
+	}
+	if len(unavailable) > 0 {
+		r.Recorder.Eventf(plan, corev1.EventTypeWarning, "ImageUnavailable", "images are not available in the registries: %s", strings.Join(unavailable, ","))
+	}
+	return res
+}
+
+// resolveImageDigests resolves the digests to pin the images with the image pull secrets of the workloads
+func (r *ComponentPlanReconciler) resolveImageDigests(ctx context.Context, cli client.Client, plan *corev1alpha1.ComponentPlan, data string, images []string) (map[string]string, error) {
+	resolver := &inventory.RemoteResolver{}
+	if r.Inventory != nil {
+		if remoteResolver, ok := r.Inventory.Resolver.(*inventory.RemoteResolver); ok {
+			resolver = remoteResolver
+		}
+	}
+	keychain, err := r.pullSecretKeychain(ctx, cli, plan.Namespace, data)
+	if err != nil {
+		return nil, err
+	}
+	return inventory.ResolveDigests(ctx, resolver.WithKeychain(keychain), images)
+}
+
+func (r *ComponentPlanReconciler) pullSecretKeychain(ctx context.Context, cli client.Client, namespace, data string) (authn.Keychain, error) {
+	secrets, err := inventory.PullSecretNames(ctx, cli, namespace, data)
+	if err != nil {
+		return nil, err
+	}
+	return inventory.PullSecretKeychain(ctx, cli, namespace, secrets)
+}
+
+// checkPolicies blocks the ComponentPlan with the PolicyViolation condition if its manifest violates any Policy in the namespace
+func (r *ComponentPlanReconciler) checkPolicies(ctx context.Context, logger logr.Logger, plan *corev1alpha1.ComponentPlan, data string) (blocked bool, err error) {
+	policies := &corev1alpha1.PolicyList{}
+	if err = r.List(ctx, policies, client.InNamespace(plan.Namespace)); err != nil {
+		logger.Error(err, "Failed to list policies")
+		return true, err
+	}
+	violations, err := policy.Evaluate(ctx, policies.Items, data)
+	if err != nil {
+		logger.Error(err, "Failed to evaluate policies")
+		return true, err
+	}
+	if len(violations) == 0 {
+		return false, nil
+	}
+	err = policy.Error(violations)
+	if cond := plan.Status.GetCondition(corev1alpha1.ComponentPlanTypeActioned); cond.Reason == corev1alpha1.ComponentPlanReasonPolicyViolation && cond.Message == err.Error() {
+		return true, nil
+	}
+	logger.Info("ComponentPlan is blocked by policies", "violations", len(violations))
+	r.Recorder.Event(plan, corev1.EventTypeWarning, string(corev1alpha1.ComponentPlanReasonPolicyViolation), err.Error())
+	return true, r.PatchCondition(ctx, plan, logger, revisionNoExist, false, false, corev1alpha1.ComponentPlanPolicyViolation(err))
+}
+
+// prePull pulls the images on the nodes by a DaemonSet in the target cluster, and records the phases of the images in the status.
+// It is done when all the images are pulled or the timeout of the ComponentPlan is reached, the DaemonSet is deleted then.
+func (r *ComponentPlanReconciler) prePull(ctx context.Context, logger logr.Logger, plan *corev1alpha1.ComponentPlan, data string) (done bool, err error) {
+	cli, err := r.WorkerPool.GetClient(ctx, plan)
+	if err != nil {
+		return false, err
+	}
+	ds := &appsv1.DaemonSet{}
+	err = cli.Get(ctx, types.NamespacedName{Namespace: plan.Namespace, Name: corev1alpha1.GenerateComponentPlanPrePullName(plan)}, ds)
+	if apierrors.IsNotFound(err) {
+		secrets, err := inventory.PullSecretNames(ctx, cli, plan.Namespace, data)
+		if err != nil {
+			return false, err
+		}
+		ds = inventory.NewPrePullDaemonSet(plan, plan.Status.Images, secrets)
+		logger.Info("Create DaemonSet to pre-pull images", "DaemonSet", klog.KObj(ds))
+		r.Recorder.Eventf(plan, corev1.EventTypeNormal, "PrePulling", "pre-pulling %d images", len(plan.Status.Images))
+		return false, cli.Create(ctx, ds)
+	} else if err != nil {
+		return false, err
+	}
+	if ds.Annotations[inventory.PrePullGenerationAnnotation] != strconv.FormatInt(plan.Generation, 10) {
+		logger.Info("Delete DaemonSet to pre-pull the images of the new generation", "DaemonSet", klog.KObj(ds))
+		return false, client.IgnoreNotFound(cli.Delete(ctx, ds, client.PropagationPolicy(metav1.DeletePropagationBackground)))
+	}
+
+	pods := &corev1.PodList{}
+	if err = cli.List(ctx, pods, client.InNamespace(plan.Namespace), client.MatchingLabels(ds.Spec.Selector.MatchLabels)); err != nil {
+		return false, err
+	}
+	phases := inventory.PrePullPhases(plan.Status.Images, pods.Items)
+	allScheduled := ds.Status.ObservedGeneration == ds.Generation && int(ds.Status.DesiredNumberScheduled) == len(pods.Items)
+	done = allScheduled && ds.Status.NumberReady == ds.Status.DesiredNumberScheduled
+	if !done && time.Since(ds.CreationTimestamp.Time) > plan.Spec.Timeout() {
+		logger.Info("Pre-pulling images timed out, install anyway", "DaemonSet", klog.KObj(ds))
+		r.Recorder.Eventf(plan, corev1.EventTypeWarning, "PrePullTimeout", "pre-pulling images timed out after %s", plan.Spec.Timeout())
+		done = true
+	}
+
+	newPlan := plan.DeepCopy()
+	newPlan.Status.ImageInventory = corev1alpha1.SetImagePrePull(newPlan.Status.ImageInventory, phases)
+	if done {
+		newPlan.Status.PrePullGeneration = plan.Generation
+	}
+	if err = r.Status().Patch(ctx, newPlan, client.MergeFrom(plan)); err != nil {
+		return false, err
+	}
+	newPlan.DeepCopyInto(plan)
+	if done {
+		logger.Info("Pre-pulling images done, delete DaemonSet", "DaemonSet", klog.KObj(ds))
+		return true, client.IgnoreNotFound(cli.Delete(ctx, ds, client.PropagationPolicy(metav1.DeletePropagationBackground)))
+	}
+	return false, nil
+}
+
+// deletePrePull deletes the pre-pull DaemonSet of the ComponentPlan if it exists
+func (r *ComponentPlanReconciler) deletePrePull(ctx context.Context, plan *corev1alpha1.ComponentPlan) error {
+	if plan.Spec.PrePull == nil {
+		return nil
+	}
+	cli, err := r.WorkerPool.GetClient(ctx, plan)
+	if err != nil {
+		return err
+	}
+	ds := &appsv1.DaemonSet{}
+	ds.Name = corev1alpha1.GenerateComponentPlanPrePullName(plan)
+	ds.Namespace = plan.Namespace
+	return client.IgnoreNotFound(cli.Delete(ctx, ds, client.PropagationPolicy(metav1.DeletePropagationBackground)))
+}
+
+// resyncPortalAndMenus restores the Portal and Menus of the installed release, which may be edited or deleted after
+// the release is installed. A failure is only reported by an event, the same as syncPortalAndMenus after install.
+func (r *ComponentPlanReconciler) resyncPortalAndMenus(ctx context.Context, logger logr.Logger, plan *corev1alpha1.ComponentPlan) {
+	rel, err := r.WorkerPool.GetLastRelease(ctx, plan)
+	if err != nil {
+		logger.Error(err, "Failed to get the release to sync the Portal and Menus")
+		return
+	}
+	if rel == nil || rel.Info == nil || rel.Info.Status != release.StatusDeployed {
+		return
+	}
+	// the release may be upgraded by the ComponentPlan of another version, like the ones created by a Subscription
+	if _, _, uid, _, _ := helm.ParseDescription(rel.Info.Description); uid != string(plan.GetUID()) {
+		return
+	}
+	if err = r.syncPortalAndMenus(ctx, logger, rel, plan); err != nil {
+		logger.Error(err, "Failed to sync the Portal and Menus of ComponentPlan")
+		r.Recorder.Eventf(plan, corev1.EventTypeWarning, "PortalFailure", "%s portal and menus sync failed: %s", rel.Name, err)
+	}
+}
+
+// syncPortalAndMenus creates or updates the Portal and Menus declared in the Chart.yaml annotations of the release
+// in the cluster where the release is installed, deletes the generated ones which are no longer declared,
+// and records them with the ones rendered by the chart in the status.
+func (r *ComponentPlanReconciler) syncPortalAndMenus(ctx context.Context, logger logr.Logger, rel *release.Release, plan *corev1alpha1.ComponentPlan) error {
+	cli, err := r.WorkerPool.GetClient(ctx, plan)
+	if err != nil {
+		return err
+	}
+	var annotations map[string]string
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		annotations = rel.Chart.Metadata.Annotations
+	}
+	portal, menus, err := corev1alpha1.PortalAndMenusFromChart(plan, annotations)
+	if err != nil {
+		return err
+	}
+	router, menuRefs, err := corev1alpha1.PortalAndMenusFromManifest(rel.Manifest)
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[string]bool)
+	if portal != nil {
+		if err = r.applyGenerated(ctx, logger, cli, plan, portal, func(obj client.Object) {
+			obj.(*corev1alpha1.Portal).Spec = portal.Spec
+		}); err != nil {
+			return err
+		}
+		router = corev1alpha1.Router{Path: portal.Spec.Path, Entry: portal.Spec.Entry}
+		keep["Portal/"+portal.Name] = true
+	}
+	for i := range menus {
+		menu := &menus[i]
+		if err = r.applyGenerated(ctx, logger, cli, plan, menu, func(obj client.Object) {
+			obj.(*corev1alpha1.Menu).Spec = menu.Spec
+		}); err != nil {
+			return err
+		}
+		menuRefs = append(menuRefs, corev1alpha1.MenuReference{Name: menu.Name})
+		keep["Menu/"+menu.Name] = true
+	}
+	if err = r.deleteGenerated(ctx, cli, plan, keep); err != nil {
+		return err
+	}
+
+	newPlan := plan.DeepCopy()
+	newPlan.Status.Portal = router
+	newPlan.Status.Menus = corev1alpha1.MergeMenuReferences(menuRefs)
+	if len(newPlan.Status.Menus) == 0 {
+		newPlan.Status.Menus = nil
+	}
+	if reflect.DeepEqual(plan.Status, newPlan.Status) {
+		return nil
+	}
+	if err = r.Status().Patch(ctx, newPlan, client.MergeFrom(plan)); err != nil {
+		return err
+	}
+	newPlan.DeepCopyInto(plan)
+	return nil
+}
+
+// applyGenerated creates the Portal or Menu generated by the ComponentPlan, or updates it by mutate,
+// the existing one which is not generated by the release of the ComponentPlan is not taken over.
+func (r *ComponentPlanReconciler) applyGenerated(ctx context.Context, logger logr.Logger, cli client.Client, plan *corev1alpha1.ComponentPlan, obj client.Object, mutate func(obj client.Object)) error {
+	cur := obj.DeepCopyObject().(client.Object)
+	if err := cli.Get(ctx, client.ObjectKeyFromObject(obj), cur); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		logger.Info("Create generated object", "kind", fmt.Sprintf("%T", obj), "name", obj.GetName())
+		return cli.Create(ctx, obj)
+	}
+	if !plan.IsGeneratedBy(cur.GetLabels()) {
+		return fmt.Errorf("%T %s already exists and is not generated by the release", obj, obj.GetName())
+	}
+	updated := cur.DeepCopyObject().(client.Object)
+	mutate(updated)
+	if reflect.DeepEqual(cur, updated) {
+		return nil
+	}
+	return cli.Patch(ctx, updated, client.MergeFrom(cur))
+}
+
+// deleteGenerated deletes the Portals and Menus generated by the release of the ComponentPlan, except the ones in keep with the key Kind/Name
+func (r *ComponentPlanReconciler) deleteGenerated(ctx context.Context, cli client.Client, plan *corev1alpha1.ComponentPlan, keep map[string]bool) error {
+	selector := client.MatchingLabels(plan.GeneratedLabels())
+	portals := &corev1alpha1.PortalList{}
+	if err := cli.List(ctx, portals, selector); err != nil {
+		return err
+	}
+	for i := range portals.Items {
+		if !keep["Portal/"+portals.Items[i].Name] {
+			if err := cli.Delete(ctx, &portals.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	menus := &corev1alpha1.MenuList{}
+	if err := cli.List(ctx, menus, selector); err != nil {
+		return err
+	}
+	for i := range menus.Items {
+		if !keep["Menu/"+menus.Items[i].Name] {
+			if err := cli.Delete(ctx, &menus.Items[i]); err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cleanupPortalAndMenus deletes all the Portals and Menus generated by the release after it is uninstalled.
+// Uninstall skips the release owned by another ComponentPlan, like the one of a newer version created by a Subscription,
+// so they are kept when the release still exists.
+func (r *ComponentPlanReconciler) cleanupPortalAndMenus(ctx context.Context, logger logr.Logger, plan *corev1alpha1.ComponentPlan) error {
+	rel, err := r.WorkerPool.GetLastRelease(ctx, plan)
+	if err != nil {
+		return err
+	}
+	if rel != nil && (rel.Info == nil || rel.Info.Status != release.StatusUninstalled) {
+		logger.Info("The release is not uninstalled by the ComponentPlan, keep the Portal and Menus")
+		return nil
+	}
+	cli, err := r.WorkerPool.GetClient(ctx, plan)
+	if err != nil {
+		return err
+	}
+	return r.deleteGenerated(ctx, cli, plan, nil)
+}
+
+// isValuesFromUpdate checks whether the content of ValuesFrom is changed after the release is installed,
+// an empty recorded hash means the content is not tracked yet, which is not an update.
+func (r *ComponentPlanReconciler) isValuesFromUpdate(plan *corev1alpha1.ComponentPlan, valuesFromHash string) bool {
+	if plan.Spec.Override.GetValuesFromPolicy() == corev1alpha1.ValuesFromPolicyIgnore || plan.Status.ValuesFromHash == "" {
+		return false
+	}
+	return plan.Status.ValuesFromHash != valuesFromHash
+}
+
+// onValuesFromUpdate upgrades the release with the new content of ValuesFrom when the policy is Auto,
+// or just records an event once for each new content when the policy is Manual.
+func (r *ComponentPlanReconciler) onValuesFromUpdate(ctx context.Context, logger logr.Logger, plan *corev1alpha1.ComponentPlan, valuesFromHash string) error {
+	if plan.Spec.Override.GetValuesFromPolicy() == corev1alpha1.ValuesFromPolicyManual {
+		if plan.Status.PendingValuesFromHash == valuesFromHash {
+			logger.V(1).Info("ComponentPlan.Spec.Override.ValuesFrom content change is recorded, wait for manual upgrade")
+			return nil
+		}
+		logger.Info("ComponentPlan.Spec.Override.ValuesFrom content is changed, policy is Manual, skip upgrade")
+		r.Recorder.Eventf(plan, corev1.EventTypeNormal, "ValuesFromChanged", "content of valuesFrom is changed, update the ComponentPlan to upgrade %s", plan.GetReleaseName())
+		newPlan := plan.DeepCopy()
+		newPlan.Status.PendingValuesFromHash = valuesFromHash
+		return r.Status().Patch(ctx, newPlan, client.MergeFrom(plan))
+	}
+	logger.Info("ComponentPlan.Spec.Override.ValuesFrom content is changed, need to upgrade ...")
+	r.Recorder.Eventf(plan, corev1.EventTypeNormal, "ValuesFromChanged", "content of valuesFrom is changed, will upgrade %s", plan.GetReleaseName())
+	if err := r.patchValuesFromHash(ctx, plan, valuesFromHash); err != nil {
+		logger.Error(err, "Failed to update ComponentPlan status.ValuesFromHash")
+		return err
+	}
+	return r.PatchCondition(ctx, plan, logger, revisionNoExist, false, false, plan.InitCondition()...)
+}
+
+// patchValuesFromHash updates status.ValuesFromHash and clears the pending one, the plan is updated in place
+func (r *ComponentPlanReconciler) patchValuesFromHash(ctx context.Context, plan *corev1alpha1.ComponentPlan, valuesFromHash string) error {
+	newPlan := plan.DeepCopy()
+	newPlan.Status.ValuesFromHash = valuesFromHash
+	newPlan.Status.PendingValuesFromHash = ""
+	if err := r.Status().Patch(ctx, newPlan, client.MergeFrom(plan)); err != nil {
+		return err
+	}
+	newPlan.DeepCopyInto(plan)
+	return nil
+}
+
+// needVerify checks whether the installed or upgraded release is waiting for verification
+func (r *ComponentPlanReconciler) needVerify(plan *corev1alpha1.ComponentPlan) bool {
+	if plan.Spec.Verification == nil || plan.Status.GetCondition(corev1alpha1.ComponentPlanTypeActioned).Status != corev1.ConditionTrue {
+		return false
+	}
+	return plan.IsVerifiedReason(corev1alpha1.ComponentPlanReasonWaitDo) || plan.IsVerifiedReason(corev1alpha1.ComponentPlanReasonVerifying)
+}
+
+// verify runs the verification of the current release revision,
+// when the verification fails and the release is rolled back, the rolled back revision is recorded in status.
+func (r *ComponentPlanReconciler) verify(ctx context.Context, logger logr.Logger, plan *corev1alpha1.ComponentPlan) (ctrl.Result, error) {
+	rel, doing, err := r.WorkerPool.Verify(ctx, plan)
+	if doing {
+		logger.Info(fmt.Sprintf("verification is in progress...wait %s for another try", waitSmaller))
+		return ctrl.Result{RequeueAfter: waitSmaller}, r.PatchCondition(ctx, plan, logger, revisionNoExist, false, false, corev1alpha1.ComponentPlanVerifying())
+	}
+	if err != nil {
+		logger.Error(err, "componentplan verification failed")
+		revision := revisionNoExist
+		if rel != nil {
+			revision = rel.Version
+		}
+		r.Recorder.Eventf(plan, corev1.EventTypeWarning, "VerificationFailure", "%s verification failed: %s", plan.GetReleaseName(), err)
+		if rel != nil {
+			// the release is rolled back, the revision of the plan is not the one running anymore
+			return ctrl.Result{}, r.PatchCondition(ctx, plan, logger, revision, true, false, corev1alpha1.ComponentPlanVerifyFailed(err), corev1alpha1.ComponentPlanRollBackSuccess())
+		}
+		return ctrl.Result{}, r.PatchCondition(ctx, plan, logger, revision, true, false, corev1alpha1.ComponentPlanVerifyFailed(err))
+	}
+	logger.Info("componentplan verification successfully")
+	r.Recorder.Eventf(plan, corev1.EventTypeNormal, "VerificationSuccess", "%s verify successfully", plan.GetReleaseName())
+	return ctrl.Result{}, r.PatchCondition(ctx, plan, logger, revisionNoExist, true, false, corev1alpha1.ComponentPlanVerifySuccess())
+}
+
+// needAdopt checks whether the plan should take over a release installed by plain helm
+func (r *ComponentPlanReconciler) needAdopt(plan *corev1alpha1.ComponentPlan, rel *release.Release) bool {
+	if !plan.NeedAdoptRelease() || plan.Status.InstalledRevision != 0 {
+		return false
+	}
+	return rel.Info != nil && rel.Info.Status == release.StatusDeployed && !helm.IsKubebbRelease(rel)
+}
+
+// adoptRelease takes ownership of an existing release without reinstalling it.
+// After adoption, the release revision is recorded in status, so uninstall and rollback work as usual,
+// and the next spec change will upgrade the release with a kubebb description.
+func (r *ComponentPlanReconciler) adoptRelease(ctx context.Context, logger logr.Logger, rel *release.Release, plan *corev1alpha1.ComponentPlan, component *corev1alpha1.Component) error {
+	if err := helm.ReleaseMatchComponent(rel, component, plan.Spec.InstallVersion); err != nil {
+		logger.Info("release can not be adopted", "reason", err.Error())
+		r.Recorder.Eventf(plan, corev1.EventTypeWarning, "AdoptionFailure", "%s can not be adopted: %s", rel.Name, err)
+		return r.PatchCondition(ctx, plan, logger, revisionNoExist, false, false, corev1alpha1.ComponentPlanWaitDo(err))
+	}
+	logger.Info("componentplan adopt release successfully", helm.ReleaseLog(rel)...)
+	r.Recorder.Eventf(plan, corev1.EventTypeNormal, "AdoptionSuccess", "%s adopt successfully", rel.Name)
+	return r.PatchCondition(ctx, plan, logger, rel.Version, true, false, corev1alpha1.ComponentPlanAdoptSuccess())
+}
+
+// backupRelease exports the live resources, values and manifest of the release into a Secret or ConfigMap.
+// The bundle is not owned by the ComponentPlan, so it is kept after the ComponentPlan is deleted.
+func (r *ComponentPlanReconciler) backupRelease(ctx context.Context, logger logr.Logger, plan *corev1alpha1.ComponentPlan) error {
+	rel, err := r.WorkerPool.GetLastRelease(ctx, plan)
+	if err != nil {
+		return err
+	}
+	if rel == nil {
+		logger.Info("no release found, skip backup")
+		return nil
+	}
+	targetCli, err := r.WorkerPool.GetClient(ctx, plan)
+	if err != nil {
+		return err
+	}
+	resources, err := corev1alpha1.ExportReleaseResources(ctx, logger, targetCli, rel.Manifest, plan.Namespace)
+	if err != nil {
+		return err
+	}
+	values, err := yaml.Marshal(rel.Config)
+	if err != nil {
+		return err
+	}
+	data := map[string]string{
+		"resources.yaml": resources,
+		"values.yaml":    string(values),
+		"manifest.yaml":  rel.Manifest,
+	}
+	labels := map[string]string{corev1alpha1.ComponentPlanReleaseNameLabel: plan.GetReleaseName()}
+	var obj client.Object
+	if plan.Spec.UninstallBackup.Kind == "ConfigMap" {
+		cm := &corev1.ConfigMap{}
+		cm.Name, cm.Namespace = corev1alpha1.GenerateComponentPlanBackupName(plan), plan.Namespace
+		_, err = controllerutil.CreateOrUpdate(ctx, r.Client, cm, func() error {
+			cm.Labels = labels
+			cm.Data = data
+			return nil
+		})
+		obj = cm
+	} else {
+		secret := &corev1.Secret{}
+		secret.Name, secret.Namespace = corev1alpha1.GenerateComponentPlanBackupName(plan), plan.Namespace
+		_, err = controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+			secret.Labels = labels
+			secret.Data = make(map[string][]byte, len(data))
+			for k, v := range data {
+				secret.Data[k] = []byte(v)
+			}
+			return nil
+		})
+		obj = secret
+	}
+	if err != nil {
+		return err
+	}
+	logger.Info("Backup release done", "kind", plan.Spec.UninstallBackup.Kind, "obj", klog.KObj(obj), "release.Version", rel.Version)
+	r.Recorder.Eventf(plan, corev1.EventTypeNormal, "BackupSuccess", "%s revision %d is backed up to %s", rel.Name, rel.Version, obj.GetName())
+	return nil
+}
+
 // SetupWithManager sets up the controller with the Manager.
-func (r *ComponentPlanReconciler) SetupWithManager(mgr ctrl.Manager) error {
+func (r *ComponentPlanReconciler) SetupWithManager(ctx context.Context, mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(ctx, &corev1alpha1.ComponentPlan{}, ValuesFromIndexKey,
+		func(o client.Object) []string {
+			plan, ok := o.(*corev1alpha1.ComponentPlan)
+			if !ok {
+				return nil
+			}
+			return corev1alpha1.ValuesFromIndexValues(plan.Spec.Override.ValuesFrom)
+		},
+	); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&corev1alpha1.ComponentPlan{}).
 		Owns(&corev1.ConfigMap{}).
+		Owns(&batchv1.Job{}).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+				return r.GetValuesFromReqs(ctx, o, "ConfigMap")
+			})).
+		Watches(&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+				return r.GetImageOverrideReqs(ctx, o)
+			})).
+		Watches(&source.Kind{Type: &corev1.Secret{}},
+			handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+				return r.GetValuesFromReqs(ctx, o, "Secret")
+			})).
+		Watches(&source.Kind{Type: &corev1alpha1.Policy{}},
+			handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+				return r.GetPolicyViolatedReqs(ctx, o)
+			})).
+		Watches(&source.Kind{Type: &corev1alpha1.Portal{}},
+			handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+				return r.GetGeneratedReqs(ctx, o)
+			}), builder.WithPredicates(predicate.Funcs{
+				UpdateFunc: func(e event.UpdateEvent) bool { return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration() },
+			})).
+		Watches(&source.Kind{Type: &corev1alpha1.Menu{}},
+			handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+				return r.GetGeneratedReqs(ctx, o)
+			}), builder.WithPredicates(predicate.Funcs{
+				UpdateFunc: func(e event.UpdateEvent) bool { return e.ObjectOld.GetGeneration() != e.ObjectNew.GetGeneration() },
+			})).
 		Complete(r)
 }
 
-func (r *ComponentPlanReconciler) GenerateManifestConfigMap(plan *corev1alpha1.ComponentPlan, manifest *corev1.ConfigMap, data string) (err error) {
+// GetValuesFromReqs get the reqs of ComponentPlans which reference the ConfigMap or Secret in spec.override.valuesFrom
+func (r *ComponentPlanReconciler) GetValuesFromReqs(ctx context.Context, o client.Object, kind string) (reqs []reconcile.Request) {
+	var list corev1alpha1.ComponentPlanList
+	if err := r.List(ctx, &list, client.InNamespace(o.GetNamespace()), client.MatchingFields{ValuesFromIndexKey: kind + "/" + o.GetName()}); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to list ComponentPlan for ValuesFrom change", "kind", kind, "obj", klog.KObj(o))
+		return nil
+	}
+	for _, i := range list.Items {
+		reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&i)})
+	}
+	return reqs
+}
+
+// GetGeneratedReqs get the reqs of the succeeded ComponentPlans whose release generates the Portal or Menu,
+// so the changed or deleted one is restored.
+func (r *ComponentPlanReconciler) GetGeneratedReqs(ctx context.Context, o client.Object) (reqs []reconcile.Request) {
+	labels := o.GetLabels()
+	ns, name := labels[corev1alpha1.GeneratedReleaseNamespaceLabel], labels[corev1alpha1.GeneratedReleaseNameLabel]
+	if ns == "" || name == "" {
+		return nil
+	}
+	var list corev1alpha1.ComponentPlanList
+	if err := r.List(ctx, &list, client.InNamespace(ns)); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to list ComponentPlan for Portal or Menu change", "obj", klog.KObj(o))
+		return nil
+	}
+	for _, i := range list.Items {
+		if i.GetReleaseName() == name && i.Spec.TargetCluster == nil && i.Status.GetCondition(corev1alpha1.ComponentPlanTypeSucceeded).Status == corev1.ConditionTrue {
+			reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&i)})
+		}
+	}
+	return reqs
+}
+
+// GetImageOverrideReqs get the reqs of ComponentPlans not succeeded yet when the default image override rules change,
+// so their manifests are generated again with the new rules before they are installed.
+func (r *ComponentPlanReconciler) GetImageOverrideReqs(ctx context.Context, o client.Object) (reqs []reconcile.Request) {
+	if !corev1alpha1.IsImageOverrideConfigMap(o) {
+		return nil
+	}
+	var list corev1alpha1.ComponentPlanList
+	if err := r.List(ctx, &list); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to list ComponentPlan for image override change", "obj", klog.KObj(o))
+		return nil
+	}
+	for _, i := range list.Items {
+		if i.Status.GetCondition(corev1alpha1.ComponentPlanTypeSucceeded).Status != corev1.ConditionTrue {
+			reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&i)})
+		}
+	}
+	return reqs
+}
+
+// GetPolicyViolatedReqs get the reqs of ComponentPlans blocked by the Policies in the namespace of the changed Policy
+func (r *ComponentPlanReconciler) GetPolicyViolatedReqs(ctx context.Context, o client.Object) (reqs []reconcile.Request) {
+	var list corev1alpha1.ComponentPlanList
+	if err := r.List(ctx, &list, client.InNamespace(o.GetNamespace())); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "failed to list ComponentPlan for Policy change", "obj", klog.KObj(o))
+		return nil
+	}
+	for _, i := range list.Items {
+		if i.IsPolicyViolated() {
+			reqs = append(reqs, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&i)})
+		}
+	}
+	return reqs
+}
+
+func (r *ComponentPlanReconciler) GenerateManifestConfigMap(plan *corev1alpha1.ComponentPlan, manifest *corev1.ConfigMap, data, imageCfgHash string) (err error) {
 	if manifest.Labels == nil {
 		manifest.Labels = make(map[string]string)
 	}
+	if manifest.Annotations == nil {
+		manifest.Annotations = make(map[string]string)
+	}
+	manifest.Annotations[corev1alpha1.ComponentPlanValuesFromHashAnnotation] = plan.Status.ValuesFromHash
+	manifest.Annotations[corev1alpha1.ComponentPlanImageOverrideHashAnnotation] = imageCfgHash
 	manifest.Data = make(map[string]string)
 	manifest.Data["manifest"] = data
 	return controllerutil.SetOwnerReference(plan, manifest, r.Scheme)
@@ -405,6 +1134,9 @@ func (r *ComponentPlanReconciler) PatchCondition(ctx context.Context, plan *core
 func (r *ComponentPlanReconciler) setCondition(plan *corev1alpha1.ComponentPlan, condition ...corev1alpha1.Condition) (newPlan *corev1alpha1.ComponentPlan) {
 	newPlan = plan.DeepCopy()
 	newPlan.Status.SetConditions(condition...)
+	if newPlan.Spec.Verification == nil {
+		newPlan.Status.RemoveConditions(corev1alpha1.ComponentPlanTypeVerified)
+	}
 	ready := len(newPlan.Status.Conditions) > 0
 	for _, cond := range newPlan.Status.Conditions {
 		if cond.Type == corev1alpha1.ComponentPlanTypeSucceeded {
@@ -478,7 +1210,7 @@ func (r *ComponentPlanReconciler) updateLatest(ctx context.Context, logger logr.
 		logger.Error(err, "Failed to list ComponentPlan")
 		return
 	}
-	rel, err := r.WorkerPool.GetLastRelease(cpl)
+	rel, err := r.WorkerPool.GetLastRelease(ctx, cpl)
 	if err != nil {
 		logger.Error(err, "Failed to get last release")
 		return
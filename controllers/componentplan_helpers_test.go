@@ -0,0 +1,549 @@
+/*
+ * Copyright 2023 The Kubebb Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+	"github.com/kubebb/core/pkg/helm"
+)
+
+// fakeWorkerPool returns the preset results instead of running helm
+type fakeWorkerPool struct {
+	release *release.Release
+	doing   bool
+	err     error
+	cli     client.Client
+}
+
+var _ helm.ReleaseWorkerPool = &fakeWorkerPool{}
+
+func (f *fakeWorkerPool) GetManifests(context.Context, *corev1alpha1.ComponentPlan, *corev1alpha1.Repository, string) (string, error) {
+	return "", f.err
+}
+
+func (f *fakeWorkerPool) InstallOrUpgrade(context.Context, *corev1alpha1.ComponentPlan, *corev1alpha1.Repository, string) (*release.Release, bool, error) {
+	return f.release, f.doing, f.err
+}
+
+func (f *fakeWorkerPool) Uninstall(context.Context, *corev1alpha1.ComponentPlan) (bool, error) {
+	return f.doing, f.err
+}
+
+func (f *fakeWorkerPool) GetLastRelease(context.Context, *corev1alpha1.ComponentPlan) (*release.Release, error) {
+	return f.release, f.err
+}
+
+func (f *fakeWorkerPool) RollBack(context.Context, *corev1alpha1.ComponentPlan) (*release.Release, bool, error) {
+	return f.release, f.doing, f.err
+}
+
+func (f *fakeWorkerPool) GetClient(context.Context, *corev1alpha1.ComponentPlan) (client.Client, error) {
+	return f.cli, nil
+}
+
+func (f *fakeWorkerPool) Verify(context.Context, *corev1alpha1.ComponentPlan) (*release.Release, bool, error) {
+	return f.release, f.doing, f.err
+}
+
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+func newTestComponentPlanReconciler(t *testing.T, pool *fakeWorkerPool, objs ...client.Object) *ComponentPlanReconciler {
+	t.Helper()
+	scheme := newTestScheme(t)
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+	if pool.cli == nil {
+		pool.cli = cli
+	}
+	return &ComponentPlanReconciler{
+		Client:     cli,
+		Scheme:     scheme,
+		Recorder:   record.NewFakeRecorder(100),
+		WorkerPool: pool,
+	}
+}
+
+func TestComponentPlanVerifyRollback(t *testing.T) {
+	plan := &corev1alpha1.ComponentPlan{
+		ObjectMeta: metav1.ObjectMeta{Name: "plan", Namespace: "default", Generation: 2},
+		Spec: corev1alpha1.ComponentPlanSpec{
+			InstallVersion: "v2",
+			Config:         corev1alpha1.Config{Verification: &corev1alpha1.Verification{RollbackOnFailure: true}},
+		},
+		Status: corev1alpha1.ComponentPlanStatus{InstalledRevision: 3},
+	}
+	plan.Status.SetConditions(corev1alpha1.ComponentPlanUpgradeSuccess(), corev1alpha1.ComponentPlanVerifying())
+
+	tests := []struct {
+		name         string
+		pool         *fakeWorkerPool
+		wantReason   corev1alpha1.ConditionReason
+		wantRevision int
+	}{
+		{
+			name:         "rolled back",
+			pool:         &fakeWorkerPool{release: &release.Release{Version: 4}, err: errors.New("verify failed, rolled back to revision 1")},
+			wantReason:   corev1alpha1.ComponentPlanReasonRollBackSuccess,
+			wantRevision: 4,
+		},
+		{
+			name:         "not rolled back",
+			pool:         &fakeWorkerPool{err: errors.New("verify failed")},
+			wantReason:   corev1alpha1.ComponentPlanReasonUpgradeSuccess,
+			wantRevision: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			r := newTestComponentPlanReconciler(t, tt.pool, plan.DeepCopy())
+			got := plan.DeepCopy()
+			if err := r.Get(ctx, client.ObjectKeyFromObject(plan), got); err != nil {
+				t.Fatal(err)
+			}
+			if !r.needVerify(got) {
+				t.Fatalf("plan should wait for verification")
+			}
+			if _, err := r.verify(ctx, log.FromContext(ctx), got); err != nil {
+				t.Fatalf("verify() error = %v", err)
+			}
+			if err := r.Get(ctx, client.ObjectKeyFromObject(plan), got); err != nil {
+				t.Fatal(err)
+			}
+			if !got.IsVerifiedReason(corev1alpha1.ComponentPlanReasonVerifyFailed) {
+				t.Errorf("Verified = %v, want %s", got.Status.GetCondition(corev1alpha1.ComponentPlanTypeVerified), corev1alpha1.ComponentPlanReasonVerifyFailed)
+			}
+			if !got.IsActionedReason(tt.wantReason) {
+				t.Errorf("Actioned = %v, want %s", got.Status.GetCondition(corev1alpha1.ComponentPlanTypeActioned), tt.wantReason)
+			}
+			if got.Status.InstalledRevision != tt.wantRevision {
+				t.Errorf("InstalledRevision = %d, want %d", got.Status.InstalledRevision, tt.wantRevision)
+			}
+			if got.Status.GetCondition(corev1alpha1.ComponentPlanTypeSucceeded).Status == corev1.ConditionTrue {
+				t.Errorf("Succeeded should not be true after verification failed")
+			}
+			if r.needVerify(got) {
+				t.Errorf("plan should not be verified again")
+			}
+		})
+	}
+}
+
+func TestComponentPlanValuesFromManualEvent(t *testing.T) {
+	plan := &corev1alpha1.ComponentPlan{
+		ObjectMeta: metav1.ObjectMeta{Name: "plan", Namespace: "default"},
+		Spec: corev1alpha1.ComponentPlanSpec{
+			Config: corev1alpha1.Config{Override: corev1alpha1.Override{ValuesFromPolicy: corev1alpha1.ValuesFromPolicyManual}},
+		},
+		Status: corev1alpha1.ComponentPlanStatus{ValuesFromHash: "old"},
+	}
+	ctx := context.Background()
+	r := newTestComponentPlanReconciler(t, &fakeWorkerPool{}, plan.DeepCopy())
+	recorder := r.Recorder.(*record.FakeRecorder)
+	got := plan.DeepCopy()
+	for _, hash := range []string{"new", "new", "newer"} {
+		if err := r.Get(ctx, client.ObjectKeyFromObject(plan), got); err != nil {
+			t.Fatal(err)
+		}
+		if !r.isValuesFromUpdate(got, hash) {
+			t.Fatalf("hash %s should be an update", hash)
+		}
+		if err := r.onValuesFromUpdate(ctx, log.FromContext(ctx), got, hash); err != nil {
+			t.Fatalf("onValuesFromUpdate() error = %v", err)
+		}
+	}
+	if err := r.Get(ctx, client.ObjectKeyFromObject(plan), got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.ValuesFromHash != "old" || got.Status.PendingValuesFromHash != "newer" {
+		t.Errorf("ValuesFromHash = %s, PendingValuesFromHash = %s, want old and newer", got.Status.ValuesFromHash, got.Status.PendingValuesFromHash)
+	}
+	if n := len(recorder.Events); n != 2 {
+		t.Errorf("got %d events, want one event for each new hash", n)
+	}
+	if err := r.patchValuesFromHash(ctx, got, "newer"); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.PendingValuesFromHash != "" {
+		t.Errorf("PendingValuesFromHash should be cleared after upgrade, got %s", got.Status.PendingValuesFromHash)
+	}
+}
+
+func TestComponentPlanIsValuesFromUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy corev1alpha1.ValuesFromPolicy
+		status string
+		hash   string
+		want   bool
+	}{
+		{name: "unchanged", status: "old", hash: "old", want: false},
+		{name: "changed", status: "old", hash: "new", want: true},
+		{name: "not recorded before upgrade of operator", status: "", hash: "new", want: false},
+		{name: "changed but ignored", policy: corev1alpha1.ValuesFromPolicyIgnore, status: "old", hash: "new", want: false},
+	}
+	r := &ComponentPlanReconciler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &corev1alpha1.ComponentPlan{
+				Spec:   corev1alpha1.ComponentPlanSpec{Config: corev1alpha1.Config{Override: corev1alpha1.Override{ValuesFromPolicy: tt.policy}}},
+				Status: corev1alpha1.ComponentPlanStatus{ValuesFromHash: tt.status},
+			}
+			if got := r.isValuesFromUpdate(plan, tt.hash); got != tt.want {
+				t.Errorf("isValuesFromUpdate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestComponentPlanSyncPortalAndMenus(t *testing.T) {
+	plan := &corev1alpha1.ComponentPlan{
+		ObjectMeta: metav1.ObjectMeta{Name: "plan", Namespace: "default", Generation: 1},
+		Spec:       corev1alpha1.ComponentPlanSpec{InstallVersion: "v1", Config: corev1alpha1.Config{Name: "rel"}},
+	}
+	plan.Status.SetConditions(corev1alpha1.ComponentPlanInstalling())
+	foreignPortal := &corev1alpha1.Portal{ObjectMeta: metav1.ObjectMeta{Name: "rel"}}
+	staleMenu := &corev1alpha1.Menu{ObjectMeta: metav1.ObjectMeta{Name: "stale", Labels: plan.GeneratedLabels()}}
+	otherMenu := &corev1alpha1.Menu{ObjectMeta: metav1.ObjectMeta{Name: "other"}}
+
+	tests := []struct {
+		name        string
+		objs        []client.Object
+		annotations map[string]string
+		wantPortal  bool
+		wantMenus   []string
+		wantEvent   bool
+		wantRouter  corev1alpha1.Router
+		wantMenuRef []corev1alpha1.MenuReference
+	}{
+		{
+			name: "create the generated portal and menus, delete the stale ones",
+			objs: []client.Object{staleMenu, otherMenu},
+			annotations: map[string]string{
+				corev1alpha1.ChartPortalPathAnnotation:  "/rel",
+				corev1alpha1.ChartPortalEntryAnnotation: "/rel/index.html",
+				corev1alpha1.ChartMenusAnnotation:       "- metadata:\n    name: rel-menu\n  spec:\n    textEn: rel\n",
+			},
+			wantPortal:  true,
+			wantMenus:   []string{"other", "rel-menu"},
+			wantRouter:  corev1alpha1.Router{Path: "/rel", Entry: "/rel/index.html"},
+			wantMenuRef: []corev1alpha1.MenuReference{{Name: "rel-menu"}},
+		},
+		{
+			name: "portal generated by the plan of an older version is taken over",
+			objs: []client.Object{&corev1alpha1.Portal{
+				ObjectMeta: metav1.ObjectMeta{Name: "rel", Labels: plan.GeneratedLabels()},
+				Spec:       corev1alpha1.PortalSpec{Path: "/old", Entry: "/old/index.html"},
+			}},
+			annotations: map[string]string{corev1alpha1.ChartPortalPathAnnotation: "/rel"},
+			wantPortal:  true,
+			wantRouter:  corev1alpha1.Router{Path: "/rel"},
+		},
+		{
+			name:        "portal not generated by the release is not taken over",
+			objs:        []client.Object{foreignPortal},
+			annotations: map[string]string{corev1alpha1.ChartPortalPathAnnotation: "/rel"},
+			wantEvent:   true,
+		},
+		{
+			name:        "invalid menus annotation",
+			objs:        []client.Object{otherMenu},
+			annotations: map[string]string{corev1alpha1.ChartMenusAnnotation: "not a list"},
+			wantMenus:   []string{"other"},
+			wantEvent:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := append([]client.Object{plan.DeepCopy()}, tt.objs...)
+			for i := range objs {
+				objs[i] = objs[i].DeepCopyObject().(client.Object)
+			}
+			r := newTestComponentPlanReconciler(t, &fakeWorkerPool{}, objs...)
+			ctx := context.Background()
+			cur := &corev1alpha1.ComponentPlan{}
+			if err := r.Get(ctx, client.ObjectKeyFromObject(plan), cur); err != nil {
+				t.Fatal(err)
+			}
+			rel := &release.Release{Name: "rel", Namespace: "default", Version: 1, Chart: &chart.Chart{Metadata: &chart.Metadata{Annotations: tt.annotations}}}
+			if err := r.updateReleaseStatus(ctx, log.FromContext(ctx), rel, nil, cur); err != nil {
+				t.Fatalf("updateReleaseStatus() error = %v", err)
+			}
+
+			got := &corev1alpha1.ComponentPlan{}
+			if err := r.Get(ctx, client.ObjectKeyFromObject(plan), got); err != nil {
+				t.Fatal(err)
+			}
+			if reason := got.Status.GetCondition(corev1alpha1.ComponentPlanTypeActioned).Reason; reason != corev1alpha1.ComponentPlanReasonInstallSuccess {
+				t.Errorf("Actioned reason = %s, want %s", reason, corev1alpha1.ComponentPlanReasonInstallSuccess)
+			}
+			if got.Status.Portal != tt.wantRouter {
+				t.Errorf("status.portal = %v, want %v", got.Status.Portal, tt.wantRouter)
+			}
+			if !reflect.DeepEqual(got.Status.Menus, tt.wantMenuRef) {
+				t.Errorf("status.menus = %v, want %v", got.Status.Menus, tt.wantMenuRef)
+			}
+
+			portal := &corev1alpha1.Portal{}
+			err := r.Get(ctx, client.ObjectKey{Name: "rel"}, portal)
+			if tt.wantPortal {
+				if err != nil {
+					t.Fatalf("get portal error = %v", err)
+				}
+				if !plan.IsGeneratedBy(portal.GetLabels()) || portal.Spec.Path != tt.wantRouter.Path {
+					t.Errorf("portal = %v, want generated with path %s", portal, tt.wantRouter.Path)
+				}
+			} else if err == nil && plan.IsGeneratedBy(portal.GetLabels()) {
+				t.Errorf("portal %s should not be generated", portal.Name)
+			}
+			menus := &corev1alpha1.MenuList{}
+			if err := r.List(ctx, menus); err != nil {
+				t.Fatal(err)
+			}
+			names := make([]string, 0)
+			for _, m := range menus.Items {
+				names = append(names, m.Name)
+			}
+			if len(names) != len(tt.wantMenus) || (len(names) > 0 && !reflect.DeepEqual(names, tt.wantMenus)) {
+				t.Errorf("menus = %v, want %v", names, tt.wantMenus)
+			}
+
+			recorder := r.Recorder.(*record.FakeRecorder)
+			hasWarning := false
+			for len(recorder.Events) > 0 {
+				if e := <-recorder.Events; strings.HasPrefix(e, corev1.EventTypeWarning+" PortalFailure") {
+					hasWarning = true
+				}
+			}
+			if hasWarning != tt.wantEvent {
+				t.Errorf("PortalFailure event = %v, want %v", hasWarning, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestComponentPlanResyncPortalAndMenus(t *testing.T) {
+	plan := &corev1alpha1.ComponentPlan{
+		ObjectMeta: metav1.ObjectMeta{Name: "plan", Namespace: "default", UID: "uid"},
+		Spec:       corev1alpha1.ComponentPlanSpec{Config: corev1alpha1.Config{Name: "rel"}},
+	}
+	plan.Spec.TargetCluster = &corev1alpha1.TargetCluster{SecretRef: "member"}
+	plan.Status.SetConditions(corev1alpha1.ComponentPlanInstallSuccess(), corev1alpha1.ComponentPlanSucceeded())
+	annotations := map[string]string{
+		corev1alpha1.ChartPortalPathAnnotation: "/rel",
+		corev1alpha1.ChartMenusAnnotation:      "- metadata:\n    name: rel-menu\n  spec:\n    textEn: rel\n",
+	}
+	tests := []struct {
+		name       string
+		uid        string
+		status     release.Status
+		wantSynced bool
+	}{
+		{name: "restored in the target cluster", uid: "uid", status: release.StatusDeployed, wantSynced: true},
+		{name: "release upgraded by another plan", uid: "other", status: release.StatusDeployed},
+		{name: "release not deployed", uid: "uid", status: release.StatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := fake.NewClientBuilder().WithScheme(newTestScheme(t)).Build()
+			rel := &release.Release{
+				Name:  "rel",
+				Info:  &release.Info{Status: tt.status, Description: "core:default/plan/" + tt.uid + "/1 Install complete"},
+				Chart: &chart.Chart{Metadata: &chart.Metadata{Annotations: annotations}},
+			}
+			r := newTestComponentPlanReconciler(t, &fakeWorkerPool{release: rel, cli: target}, plan.DeepCopy())
+			ctx := context.Background()
+			cur := &corev1alpha1.ComponentPlan{}
+			if err := r.Get(ctx, client.ObjectKeyFromObject(plan), cur); err != nil {
+				t.Fatal(err)
+			}
+			r.resyncPortalAndMenus(ctx, log.FromContext(ctx), cur)
+
+			err := target.Get(ctx, client.ObjectKey{Name: "rel"}, &corev1alpha1.Portal{})
+			if tt.wantSynced != (err == nil) {
+				t.Errorf("portal in the target cluster: synced %t, got error %v", tt.wantSynced, err)
+			}
+			err = target.Get(ctx, client.ObjectKey{Name: "rel-menu"}, &corev1alpha1.Menu{})
+			if tt.wantSynced != (err == nil) {
+				t.Errorf("menu in the target cluster: synced %t, got error %v", tt.wantSynced, err)
+			}
+			if err = r.Get(ctx, client.ObjectKey{Name: "rel"}, &corev1alpha1.Portal{}); err == nil {
+				t.Errorf("portal should not be created in the management cluster")
+			}
+		})
+	}
+}
+
+func TestComponentPlanGetGeneratedReqs(t *testing.T) {
+	succeeded := &corev1alpha1.ComponentPlan{
+		ObjectMeta: metav1.ObjectMeta{Name: "succeeded", Namespace: "default"},
+		Spec:       corev1alpha1.ComponentPlanSpec{Config: corev1alpha1.Config{Name: "rel"}},
+	}
+	succeeded.Status.SetConditions(corev1alpha1.ComponentPlanInstallSuccess(), corev1alpha1.ComponentPlanSucceeded())
+	installing := succeeded.DeepCopy()
+	installing.Name = "installing"
+	installing.Status.Conditions = nil
+	installing.Status.SetConditions(corev1alpha1.ComponentPlanInstalling())
+	other := succeeded.DeepCopy()
+	other.Name = "other"
+	other.Spec.Name = "other"
+	r := newTestComponentPlanReconciler(t, &fakeWorkerPool{}, succeeded, installing, other)
+
+	menu := &corev1alpha1.Menu{ObjectMeta: metav1.ObjectMeta{Name: "rel-menu", Labels: succeeded.GeneratedLabels()}}
+	want := []reconcile.Request{{NamespacedName: client.ObjectKeyFromObject(succeeded)}}
+	if got := r.GetGeneratedReqs(context.Background(), menu); !reflect.DeepEqual(got, want) {
+		t.Errorf("GetGeneratedReqs() = %v, want %v", got, want)
+	}
+	if got := r.GetGeneratedReqs(context.Background(), &corev1alpha1.Menu{ObjectMeta: metav1.ObjectMeta{Name: "manual"}}); len(got) != 0 {
+		t.Errorf("GetGeneratedReqs() = %v, want none for the menu not generated", got)
+	}
+}
+
+func TestComponentPlanCleanupPortalAndMenus(t *testing.T) {
+	plan := &corev1alpha1.ComponentPlan{
+		ObjectMeta: metav1.ObjectMeta{Name: "sub.rel.v1", Namespace: "default"},
+		Spec:       corev1alpha1.ComponentPlanSpec{Config: corev1alpha1.Config{Name: "rel"}},
+	}
+	otherPlan := &corev1alpha1.ComponentPlan{
+		ObjectMeta: metav1.ObjectMeta{Name: "sub.rel.v1", Namespace: "other"},
+		Spec:       corev1alpha1.ComponentPlanSpec{Config: corev1alpha1.Config{Name: "rel"}},
+	}
+
+	tests := []struct {
+		name        string
+		release     *release.Release
+		wantPortals []string
+		wantMenus   []string
+	}{
+		{
+			name:        "release is uninstalled",
+			wantPortals: []string{"foreign"},
+			wantMenus:   []string{"manual"},
+		},
+		{
+			name:        "release is uninstalled with history kept",
+			release:     &release.Release{Name: "rel", Info: &release.Info{Status: release.StatusUninstalled}},
+			wantPortals: []string{"foreign"},
+			wantMenus:   []string{"manual"},
+		},
+		{
+			name:        "release is owned by the plan of a newer version",
+			release:     &release.Release{Name: "rel", Info: &release.Info{Status: release.StatusDeployed}},
+			wantPortals: []string{"foreign", "generated"},
+			wantMenus:   []string{"generated", "manual"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestComponentPlanReconciler(t, &fakeWorkerPool{release: tt.release},
+				&corev1alpha1.Portal{ObjectMeta: metav1.ObjectMeta{Name: "generated", Labels: plan.GeneratedLabels()}},
+				&corev1alpha1.Portal{ObjectMeta: metav1.ObjectMeta{Name: "foreign", Labels: otherPlan.GeneratedLabels()}},
+				&corev1alpha1.Menu{ObjectMeta: metav1.ObjectMeta{Name: "generated", Labels: plan.GeneratedLabels()}},
+				&corev1alpha1.Menu{ObjectMeta: metav1.ObjectMeta{Name: "manual"}},
+			)
+			ctx := context.Background()
+			if err := r.cleanupPortalAndMenus(ctx, log.FromContext(ctx), plan); err != nil {
+				t.Fatalf("cleanupPortalAndMenus() error = %v", err)
+			}
+			portals := &corev1alpha1.PortalList{}
+			if err := r.List(ctx, portals); err != nil {
+				t.Fatal(err)
+			}
+			names := make([]string, 0)
+			for _, p := range portals.Items {
+				names = append(names, p.Name)
+			}
+			if !reflect.DeepEqual(names, tt.wantPortals) {
+				t.Errorf("portals = %v, want %v", names, tt.wantPortals)
+			}
+			menus := &corev1alpha1.MenuList{}
+			if err := r.List(ctx, menus); err != nil {
+				t.Fatal(err)
+			}
+			names = make([]string, 0)
+			for _, m := range menus.Items {
+				names = append(names, m.Name)
+			}
+			if !reflect.DeepEqual(names, tt.wantMenus) {
+				t.Errorf("menus = %v, want %v", names, tt.wantMenus)
+			}
+		})
+	}
+}
+
+func TestComponentPlanGetImageOverrideReqs(t *testing.T) {
+	t.Setenv("POD_NAMESPACE", "kubebb-system")
+	succeeded := &corev1alpha1.ComponentPlan{ObjectMeta: metav1.ObjectMeta{Name: "succeeded", Namespace: "default"}}
+	succeeded.Status.SetConditions(corev1alpha1.ComponentPlanSucceeded())
+	pending := &corev1alpha1.ComponentPlan{ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "default"}}
+	pending.Status.SetConditions(corev1alpha1.ComponentPlanInitSucceeded())
+	r := newTestComponentPlanReconciler(t, &fakeWorkerPool{}, succeeded, pending)
+
+	tests := []struct {
+		name string
+		cm   *corev1.ConfigMap
+		want []string
+	}{
+		{
+			name: "image override configmap",
+			cm:   &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: corev1alpha1.ImageOverrideConfigMapName, Namespace: "kubebb-system"}},
+			want: []string{"pending"},
+		},
+		{
+			name: "configmap in other namespace",
+			cm:   &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: corev1alpha1.ImageOverrideConfigMapName, Namespace: "default"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0)
+			for _, req := range r.GetImageOverrideReqs(context.Background(), tt.cm) {
+				got = append(got, req.Name)
+			}
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("GetImageOverrideReqs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
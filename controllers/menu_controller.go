@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+// MenuReconciler reconciles a Menu object
+type MenuReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=menus,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=menus/status,verbs=get;update;patch
+
+// Reconcile validates the whole menu tree whenever a Menu changes,
+// because a change of one Menu may make its children or siblings valid or invalid.
+func (r *MenuReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.V(1).Info("Starting menu reconcile")
+
+	menus := &corev1alpha1.MenuList{}
+	if err := r.List(ctx, menus); err != nil {
+		logger.Error(err, "Failed to list menus")
+		return ctrl.Result{}, err
+	}
+	conditions := corev1alpha1.ValidateMenuTree(menus.Items)
+	for i := range menus.Items {
+		menu := &menus.Items[i]
+		cond := conditions[menu.Name]
+		cur := menu.Status.GetCondition(corev1alpha1.TypeReady)
+		if cur.Status == cond.Status && cur.Reason == cond.Reason && cur.Message == cond.Message {
+			continue
+		}
+		newMenu := menu.DeepCopy()
+		newMenu.Status.SetConditions(cond)
+		if err := r.Status().Patch(ctx, newMenu, client.MergeFrom(menu)); err != nil {
+			logger.Error(err, "Failed to patch menu status", "menu", menu.Name)
+			return ctrl.Result{}, err
+		}
+		if cond.Status != cur.Status && !newMenu.IsValid() {
+			r.Recorder.Event(newMenu, corev1.EventTypeWarning, string(cond.Reason), cond.Message)
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *MenuReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&corev1alpha1.Menu{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Complete(r)
+}
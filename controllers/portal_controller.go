@@ -140,23 +140,6 @@ func GetConflicts(c client.Client, ctx context.Context, portal *corev1alpha1.Por
 		return nil, nil, err
 	}
 
-	entryConflicts := make([]string, 0)
-	pathConflicts := make([]string, 0)
-	for _, p := range list.Items {
-		if portal.Name == p.Name {
-			continue
-		}
-
-		// entry conflicts
-		if portal.Spec.Entry == p.Spec.Entry {
-			entryConflicts = append(entryConflicts, p.Name)
-		}
-
-		// path conflicts
-		if portal.Spec.Path == p.Spec.Path {
-			pathConflicts = append(pathConflicts, p.Name)
-		}
-	}
-
+	entryConflicts, pathConflicts := corev1alpha1.GetPortalConflicts(portal, list.Items)
 	return entryConflicts, pathConflicts, nil
 }
@@ -19,15 +19,21 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	arcadiav1 "github.com/kubeagi/arcadia/api/base/v1alpha1"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/util/workqueue"
 	"knative.dev/pkg/apis"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -43,19 +49,28 @@ import (
 
 	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
 	"github.com/kubebb/core/pkg/evaluator"
+	"github.com/kubebb/core/pkg/helm"
 	"github.com/kubebb/core/pkg/utils"
 )
 
 // RatingReconciler reconciles a Rating object
 type RatingReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme           *runtime.Scheme
+	PromptCaller     *evaluator.PromptCaller
+	BuiltinCheckPool *evaluator.BuiltinCheckPool
 }
 
+// builtinCheckInterval is the interval to check whether the built-in checks running in the background are done
+const builtinCheckInterval = 5 * time.Second
+
 //+kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=ratings,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=ratings/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=ratings/finalizers,verbs=update
 
+//+kubebuilder:rbac:groups=tekton.dev,resources=pipelines,verbs=get;list;watch
+//+kubebuilder:rbac:groups=tekton.dev,resources=pipelineruns,verbs=get;list;watch;create;update;patch;delete
+
 //+kubebuilder:rbac:groups=arcadia.kubeagi.k8s.com.cn,resources=llms,verbs=get;list;watch
 //+kubebuilder:rbac:groups=arcadia.kubeagi.k8s.com.cn,resources=llms/status,verbs=get
 //+kubebuilder:rbac:groups=arcadia.kubeagi.k8s.com.cn,resources=prompts,verbs=get;list;watch;create;update;patch;delete
@@ -103,6 +118,13 @@ func (r *RatingReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		return reconcile.Result{Requeue: requeue}, nil
 	}
 
+	if instance.NeedCancel() {
+		if err := r.CancelPipelineRuns(ctx, &instance); err != nil {
+			logger.Error(err, "failed to cancel pipelineruns")
+			return reconcile.Result{}, err
+		}
+	}
+
 	// update status to false when rating is disabled
 	if !repository.Spec.EnableRating {
 		instanceDeepCopy := instance.DeepCopy()
@@ -123,11 +145,38 @@ func (r *RatingReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 				return reconcile.Result{}, err
 			}
 		}
-	} else {
+	} else if instance.NeedRerun() && instance.Spec.Builtin {
+		results, doing, err := r.BuiltinCheckPool.Run(ctx, &instance, r.builtinChecks(logger, &instance, &component, &repository))
+		if err != nil {
+			logger.Error(err, "failed to run builtin checks")
+			return reconcile.Result{}, err
+		}
+		if doing {
+			return reconcile.Result{RequeueAfter: builtinCheckInterval}, nil
+		}
+		if err = r.RecordBuiltinChecks(logger, ctx, &instance, results); err != nil {
+			logger.Error(err, "failed to record builtin checks")
+			return reconcile.Result{}, err
+		}
+	} else if instance.NeedRerun() {
 		if err := r.CreatePipelineRun(logger, ctx, &instance); err != nil {
 			logger.Error(err, "")
 			return reconcile.Result{}, err
 		}
+	} else {
+		if err := evaluator.ReevaluateOutdatedDimensions(ctx, logger, r.Client, r.Scheme, &instance); err != nil {
+			logger.Error(err, "failed to re-evaluate with the updated prompt template")
+			return reconcile.Result{}, err
+		}
+		doing, err := r.PromptCaller.CallPendingPrompts(ctx, &instance)
+		if err != nil {
+			logger.Error(err, "failed to call llm for the pending prompts")
+			return reconcile.Result{}, err
+		}
+		if doing {
+			// the Prompt is updated after the call, requeue in case the update is missed
+			return reconcile.Result{RequeueAfter: evaluator.DefaultCallTimeout}, nil
+		}
 	}
 
 	return ctrl.Result{}, nil
@@ -199,20 +248,25 @@ func (r RatingReconciler) updateLabels(ctx context.Context, instance *corev1alph
 	return false, nil
 }
 
+// CreatePipelineRun starts a new run of the Rating, the unfinished pipelineruns of the previous run are cancelled,
+// and the pipelineruns beyond the retention are deleted together with their Prompts.
 func (r *RatingReconciler) CreatePipelineRun(logger logr.Logger, ctx context.Context, instance *corev1alpha1.Rating) error {
-	if err := r.DeletePipelineRun(ctx, instance); err != nil {
+	if _, err := r.cancelPipelineRuns(ctx, instance); err != nil {
 		return err
 	}
 	component := instance.Labels[corev1alpha1.RatingComponentLabel]
 	repository := instance.Labels[corev1alpha1.RatingRepositoryLabel]
+	version := instance.Labels[corev1alpha1.RatingComponentVersion]
 	namespace, err := utils.GetNamespace()
 	if err != nil {
 		return err
 	}
-	nextCreate := make([]v1beta1.PipelineRun, len(instance.Spec.PipelineParams))
+	run := instance.Status.Run + 1
+	retention := instance.GetRetention()
+	nextCreate := make([]v1beta1.PipelineRun, 0, len(instance.Spec.PipelineParams))
 	pipelineRunStatus := make(map[string]corev1alpha1.PipelineRunStatus)
 
-	for idx, pipelineDef := range instance.Spec.PipelineParams {
+	for _, pipelineDef := range instance.Spec.PipelineParams {
 		if _, ok := pipelineRunStatus[pipelineDef.Dimension]; ok {
 			logger.Error(fmt.Errorf("repeatedly defined pipeline %s", pipelineDef.PipelineName), "")
 			continue
@@ -221,23 +275,25 @@ func (r *RatingReconciler) CreatePipelineRun(logger logr.Logger, ctx context.Con
 		if err := r.Client.Get(ctx, types.NamespacedName{Name: pipelineDef.PipelineName, Namespace: namespace}, &pipeline); err != nil {
 			return err
 		}
-		pipelineRunName := corev1alpha1.PipelineRunName(instance.Name, pipelineDef.Dimension)
+		pipelineRunName := corev1alpha1.NumberedPipelineRunName(instance.Name, pipelineDef.Dimension, run)
 
-		pipelineRunStatus[pipelineDef.Dimension] = corev1alpha1.PipelineRunStatus{
-			PipelineRunName: pipelineRunName,
-			PipelineName:    pipelineDef.PipelineName,
-		}
+		pipelineRunStatus[pipelineDef.Dimension] = corev1alpha1.NextPipelineRunStatus(instance.Status.PipelineRuns[pipelineDef.Dimension],
+			run, pipelineRunName, pipelineDef.PipelineName, retention)
 
+		labels := map[string]string{
+			corev1alpha1.PipelineRun2RatingLabel:     instance.Name,
+			corev1alpha1.PipelineRun2ComponentLabel:  component,
+			corev1alpha1.PipelineRun2RepositoryLabel: repository,
+			corev1alpha1.PipelineRunDimensionLabel:   pipelineDef.Dimension,
+		}
+		if version != "" {
+			labels[corev1alpha1.PipelineRun2VersionLabel] = version
+		}
 		ppr := v1beta1.PipelineRun{
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: instance.GetNamespace(),
 				Name:      pipelineRunName,
-				Labels: map[string]string{
-					corev1alpha1.PipelineRun2RatingLabel:     instance.Name,
-					corev1alpha1.PipelineRun2ComponentLabel:  component,
-					corev1alpha1.PipelineRun2RepositoryLabel: repository,
-					corev1alpha1.PipelineRunDimensionLabel:   pipelineDef.Dimension,
-				},
+				Labels:    labels,
 			},
 			Spec: v1beta1.PipelineRunSpec{
 				ServiceAccountName: corev1alpha1.GetRatingServiceAccount(),
@@ -263,18 +319,22 @@ func (r *RatingReconciler) CreatePipelineRun(logger logr.Logger, ctx context.Con
 				Params: corev1alpha1.Params2PipelinrunParams(pipelineDef.Params),
 			},
 		}
-		nextCreate[idx] = ppr
+		nextCreate = append(nextCreate, ppr)
 	}
 
 	instanceDeepCopy := instance.DeepCopy()
 	instanceDeepCopy.Status.PipelineRuns = pipelineRunStatus
+	instanceDeepCopy.Status.Evaluations = nil
+	instanceDeepCopy.Status.Score = ""
+	instanceDeepCopy.Status.Run = run
+	instanceDeepCopy.Status.ObservedRerun = instance.Annotations[corev1alpha1.RatingRerunAnnotation]
 	instanceDeepCopy.Status.ConditionedStatus = corev1alpha1.ConditionedStatus{
 		Conditions: []corev1alpha1.Condition{
 			{
 				Status:             v1.ConditionFalse,
 				LastTransitionTime: metav1.Now(),
 				Reason:             corev1alpha1.PipelineRunning,
-				Message:            "Rating enabled in component's repository",
+				Message:            fmt.Sprintf("Rating enabled in component's repository, run %d", run),
 				Type:               corev1alpha1.TypeReady,
 			},
 		},
@@ -287,28 +347,205 @@ func (r *RatingReconciler) CreatePipelineRun(logger logr.Logger, ctx context.Con
 		_ = controllerutil.SetOwnerReference(instance, &nextCreate[idx], r.Scheme)
 		if err := r.Client.Create(ctx, &nextCreate[idx]); err != nil {
 			for i := idx - 1; i >= 0; i-- {
-				_ = r.Client.Delete(ctx, &nextCreate[idx])
+				_ = r.Client.Delete(ctx, &nextCreate[i])
 			}
 			return err
 		}
 	}
 
+	for dimension := range pipelineRunStatus {
+		if err := r.prunePipelineRuns(ctx, instance, dimension, retention); err != nil {
+			logger.Error(err, "failed to prune pipelineruns", "dimension", dimension)
+		}
+	}
 	return nil
 }
 
-// Before creating pipelinerun, we shoulde delete all the existing pipelineruns.
-func (r *RatingReconciler) DeletePipelineRun(ctx context.Context, instance *corev1alpha1.Rating) error {
-	for _, pipelineDef := range instance.Spec.PipelineParams {
-		name := pipelineDef.PipelineName
-		pipelineRun := v1beta1.PipelineRun{
-			ObjectMeta: metav1.ObjectMeta{
-				Namespace: instance.Namespace,
-				Name:      corev1alpha1.PipelineRunName(instance.Name, name),
+// builtinChecks returns the check which pulls the chart of the rated version and runs the built-in checks
+// on its rendered manifests, it is run by the BuiltinCheckPool in the background.
+func (r *RatingReconciler) builtinChecks(logger logr.Logger, instance *corev1alpha1.Rating, component *corev1alpha1.Component, repo *corev1alpha1.Repository) evaluator.BuiltinCheckFunc {
+	ratedVersion := instance.Labels[corev1alpha1.RatingComponentVersion]
+	component, repo = component.DeepCopy(), repo.DeepCopy()
+	return func(ctx context.Context) (map[string][]corev1alpha1.Task, error) {
+		version, ok := component.LatestVersion()
+		if ratedVersion != "" {
+			version, ok = component.GetVersion(ratedVersion)
+		}
+		if !ok {
+			return nil, fmt.Errorf("no version %s found in component %s", ratedVersion, component.Name)
+		}
+		pullURL := repo.ChartPullURL(component, version.URLs)
+		if pullURL == "" {
+			return nil, fmt.Errorf("not found %s's urls", component.Status.Name)
+		}
+		cfg, err := ctrl.GetConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config for in-cluster REST client: %w", err)
+		}
+		getter := &genericclioptions.ConfigFlags{
+			APIServer:   &cfg.Host,
+			CAFile:      &cfg.CAFile,
+			BearerToken: &cfg.BearerToken,
+			Namespace:   &component.Namespace,
+		}
+		h, err := helm.NewCoreHelmWrapper(getter, component.Namespace, logger, r.Client, nil, repo, component)
+		if err != nil {
+			return nil, err
+		}
+		_, dir, entryName, err := h.Pull(ctx, pullURL, version.Version)
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+		rel, err := h.Template(ctx, version.Version, dir+"/"+entryName)
+		if err != nil {
+			return nil, err
+		}
+		return evaluator.RunBuiltinChecks(rel.Manifest)
+	}
+}
+
+// RecordBuiltinChecks records the results of the built-in checks as the pipelineruns of the security and reliability
+// dimensions without Tekton. The results are evaluated by the LLM if it is set, otherwise scored by the checks directly.
+func (r *RatingReconciler) RecordBuiltinChecks(logger logr.Logger, ctx context.Context, instance *corev1alpha1.Rating, results map[string][]corev1alpha1.Task) error {
+	run := instance.Status.Run + 1
+	now := metav1.Now()
+	instanceDeepCopy := instance.DeepCopy()
+	instanceDeepCopy.Status.PipelineRuns = make(map[string]corev1alpha1.PipelineRunStatus, len(results))
+	instanceDeepCopy.Status.Evaluations = nil
+	instanceDeepCopy.Status.Score = ""
+	instanceDeepCopy.Status.Run = run
+	instanceDeepCopy.Status.ObservedRerun = instance.Annotations[corev1alpha1.RatingRerunAnnotation]
+	for dimension, tasks := range results {
+		status := corev1alpha1.NextPipelineRunStatus(instance.Status.PipelineRuns[dimension], run,
+			corev1alpha1.NumberedPipelineRunName(instance.Name, dimension, run), evaluator.BuiltinPipelineName, instance.GetRetention())
+		status.Tasks = tasks
+		status.Conditions = []corev1alpha1.Condition{{
+			Type:               corev1alpha1.ConditionType(apis.ConditionSucceeded),
+			Status:             v1.ConditionTrue,
+			LastTransitionTime: now,
+			Reason:             corev1alpha1.RatingSucceeded,
+			Message:            "Builtin checks completed",
+		}}
+		instanceDeepCopy.Status.PipelineRuns[dimension] = status
+	}
+	cond := corev1alpha1.Condition{
+		Status:             v1.ConditionFalse,
+		LastTransitionTime: now,
+		Reason:             corev1alpha1.EvaluationRunning,
+		Message:            "Builtin checks completed, evaluation is running",
+		Type:               corev1alpha1.TypeReady,
+	}
+	if instance.Spec.LLM == nil {
+		instanceDeepCopy.Status.Evaluations = make(map[string]corev1alpha1.EvaluatorStatus, len(results))
+		for dimension, tasks := range results {
+			instanceDeepCopy.Status.Evaluations[dimension] = evaluator.BuiltinEvaluation(tasks)
+		}
+		instanceDeepCopy.Status.Score, _ = instanceDeepCopy.Score()
+		cond.Status = v1.ConditionTrue
+		cond.Reason = corev1alpha1.EvaluationSucceeded
+		cond.Message = "Builtin checks completed"
+	}
+	instanceDeepCopy.Status.ConditionedStatus = corev1alpha1.ConditionedStatus{Conditions: []corev1alpha1.Condition{cond}}
+	if err := r.Client.Status().Patch(ctx, instanceDeepCopy, client.MergeFrom(instance)); err != nil {
+		return err
+	}
+
+	if instance.Spec.LLM == nil {
+		return evaluator.RecordComponentScore(ctx, r.Client, instanceDeepCopy)
+	}
+	for dimension, tasks := range results {
+		if err := evaluator.EvaluateDimension(ctx, logger, r.Client, r.Scheme, instanceDeepCopy, dimension, tasks); err != nil {
+			logger.Error(err, "failed to evaluate builtin checks", "dimension", dimension)
+		}
+	}
+	return nil
+}
+
+// CancelPipelineRuns cancels the unfinished pipelineruns of the current run when the cancel annotation changes.
+func (r *RatingReconciler) CancelPipelineRuns(ctx context.Context, instance *corev1alpha1.Rating) error {
+	cancelled, err := r.cancelPipelineRuns(ctx, instance)
+	if err != nil {
+		return err
+	}
+	instanceDeepCopy := instance.DeepCopy()
+	instanceDeepCopy.Status.ObservedCancel = instance.Annotations[corev1alpha1.RatingCancelAnnotation]
+	if len(cancelled) > 0 {
+		instanceDeepCopy.Status.ConditionedStatus = corev1alpha1.ConditionedStatus{
+			Conditions: []corev1alpha1.Condition{
+				{
+					Status:             v1.ConditionFalse,
+					LastTransitionTime: metav1.Now(),
+					Reason:             corev1alpha1.RatingCancelled,
+					Message:            fmt.Sprintf("PipelineRuns %s are cancelled", strings.Join(cancelled, ",")),
+					Type:               corev1alpha1.TypeReady,
+				},
 			},
 		}
+	}
+	if err := r.Client.Status().Patch(ctx, instanceDeepCopy, client.MergeFrom(instance)); err != nil {
+		return err
+	}
+	*instance = *instanceDeepCopy
+	return nil
+}
+
+// cancelPipelineRuns sets the unfinished pipelineruns of the current run to cancelled, returns the names of them.
+func (r *RatingReconciler) cancelPipelineRuns(ctx context.Context, instance *corev1alpha1.Rating) ([]string, error) {
+	cancelled := make([]string, 0)
+	for _, status := range instance.Status.PipelineRuns {
+		if status.PipelineName == evaluator.BuiltinPipelineName {
+			continue
+		}
+		pipelineRun := &v1beta1.PipelineRun{}
+		if err := r.Client.Get(ctx, types.NamespacedName{Namespace: instance.Namespace, Name: status.PipelineRunName}, pipelineRun); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return cancelled, err
+		}
+		if pipelineRun.IsDone() || pipelineRun.IsCancelled() {
+			continue
+		}
+		pipelineRunDeepCopy := pipelineRun.DeepCopy()
+		pipelineRunDeepCopy.Spec.Status = v1beta1.PipelineRunSpecStatusCancelled
+		if err := r.Client.Patch(ctx, pipelineRunDeepCopy, client.MergeFrom(pipelineRun)); err != nil {
+			return cancelled, err
+		}
+		cancelled = append(cancelled, pipelineRun.Name)
+	}
+	sort.Strings(cancelled)
+	return cancelled, nil
+}
+
+// prunePipelineRuns deletes the pipelineruns of the dimension beyond the retention and the Prompts evaluating them.
+// The pipelineruns of the same component version are counted together, or the ones of the Rating if it has no version.
+func (r *RatingReconciler) prunePipelineRuns(ctx context.Context, instance *corev1alpha1.Rating, dimension string, retention int) error {
+	selector := client.MatchingLabels{corev1alpha1.PipelineRunDimensionLabel: dimension}
+	if version := instance.Labels[corev1alpha1.RatingComponentVersion]; version != "" {
+		selector[corev1alpha1.PipelineRun2ComponentLabel] = instance.Labels[corev1alpha1.RatingComponentLabel]
+		selector[corev1alpha1.PipelineRun2VersionLabel] = version
+	} else {
+		selector[corev1alpha1.PipelineRun2RatingLabel] = instance.Name
+	}
+	pipelineRuns := &v1beta1.PipelineRunList{}
+	if err := r.Client.List(ctx, pipelineRuns, client.InNamespace(instance.Namespace), selector); err != nil {
+		return err
+	}
+	for _, pipelineRun := range corev1alpha1.PipelineRunsToPrune(pipelineRuns.Items, retention) {
+		pipelineRun := pipelineRun
 		if err := r.Client.Delete(ctx, &pipelineRun); err != nil && !errors.IsNotFound(err) {
 			return err
 		}
+		prompts := &arcadiav1.PromptList{}
+		if err := r.Client.List(ctx, prompts, client.MatchingLabels{evaluator.EvaluatePipelineRunLabel: pipelineRun.Name}); err != nil {
+			return err
+		}
+		for idx := range prompts.Items {
+			if err := r.Client.Delete(ctx, &prompts.Items[idx]); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
 	}
 	return nil
 }
@@ -354,6 +591,20 @@ func (r *RatingReconciler) PipelineRunUpdate(logger logr.Logger) func(event.Upda
 		dimension := corev1alpha1.GetPipelineRunDimension(pipelinerun)
 
 		pipelineRunStatus := deepCopyRating.Status.PipelineRuns[dimension]
+		if pipelineRunStatus.PipelineRunName != pipelinerun.Name {
+			// the pipelinerun of a previous run, only its record in the history is updated
+			for idx := range pipelineRunStatus.History {
+				if pipelineRunStatus.History[idx].PipelineRunName == pipelinerun.Name {
+					pipelineRunStatus.History[idx].Conditions = corev1alpha1.ConvertPipelineRunCondition(pipelinerun)
+					deepCopyRating.Status.PipelineRuns[dimension] = pipelineRunStatus
+					if err := r.Client.Status().Patch(context.TODO(), deepCopyRating, client.MergeFrom(rating)); err != nil {
+						logger.Error(err, "")
+					}
+					break
+				}
+			}
+			return
+		}
 		pipelineRunStatus.Conditions = corev1alpha1.ConvertPipelineRunCondition(pipelinerun)
 		deepCopyRating.Status.PipelineRuns[dimension] = pipelineRunStatus
 
@@ -367,21 +618,21 @@ func (r *RatingReconciler) PipelineRunUpdate(logger logr.Logger) func(event.Upda
 
 		// When pipelinerun succeeded and llm is set,evaluate this Rating status
 		if curCond.Reason == string(corev1alpha1.RatingSucceeded) && rating.Spec.LLM != nil {
-			llmNamespace := rating.Spec.LLM.GetNamespace(rating.Namespace)
-			arcEval, err := evaluator.NewEvaluator(logger, context.TODO(), r.Client, r.Scheme,
-				types.NamespacedName{Namespace: llmNamespace, Name: rating.Spec.LLM.Name})
+			err := evaluator.EvaluateDimension(context.TODO(), logger, r.Client, r.Scheme, rating, dimension, deepCopyRating.GetPipelineRunStatus(dimension).Tasks)
 			if err != nil {
-				logger.Error(err, "failed to create arcadia evaluator")
-			} else {
-				data := &evaluator.Data{
-					Owner:     rating.DeepCopy(),
-					FromRun:   pipelinerun.Name,
-					Dimension: dimension,
-					Tasks:     deepCopyRating.GetPipelineRunStatus(dimension).Tasks,
-				}
-				err = arcEval.EvaluateWithData(context.TODO(), data)
-				if err != nil {
-					logger.Error(err, "")
+				logger.Error(err, "")
+				if evaluator.IsInvalidTemplate(err) {
+					deepCopyRating.Status.ConditionedStatus = corev1alpha1.ConditionedStatus{
+						Conditions: []corev1alpha1.Condition{
+							{
+								Status:             v1.ConditionFalse,
+								LastTransitionTime: metav1.Now(),
+								Reason:             corev1alpha1.EvaluationInvalidTemplate,
+								Message:            fmt.Sprintf("failed to load the prompt template of dimension %s: %s", dimension, err),
+								Type:               corev1alpha1.TypeReady,
+							},
+						},
+					}
 				}
 			}
 		}
@@ -395,10 +646,25 @@ func (r *RatingReconciler) PipelineRunUpdate(logger logr.Logger) func(event.Upda
 // SetupWithManager sets up the controller with the Manager.
 func (r *RatingReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	logger := log.FromContext(context.TODO())
-	return ctrl.NewControllerManagedBy(mgr).
+	if err := mgr.GetFieldIndexer().IndexField(context.TODO(), &corev1alpha1.Rating{}, evaluator.PromptTemplateIndexKey,
+		func(o client.Object) []string {
+			rating, ok := o.(*corev1alpha1.Rating)
+			if !ok {
+				return nil
+			}
+			return rating.Spec.PromptTemplateConfigMaps()
+		},
+	); err != nil {
+		return err
+	}
+
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&corev1alpha1.Rating{}, builder.WithPredicates(predicate.Funcs{
 			UpdateFunc: func(ue event.UpdateEvent) bool {
-				return false
+				// only the rerun and cancel actions trigger the reconcile
+				oldAnnotations, newAnnotations := ue.ObjectOld.GetAnnotations(), ue.ObjectNew.GetAnnotations()
+				return oldAnnotations[corev1alpha1.RatingRerunAnnotation] != newAnnotations[corev1alpha1.RatingRerunAnnotation] ||
+					oldAnnotations[corev1alpha1.RatingCancelAnnotation] != newAnnotations[corev1alpha1.RatingCancelAnnotation]
 			},
 			DeleteFunc: func(event.DeleteEvent) bool {
 				return false
@@ -406,15 +672,31 @@ func (r *RatingReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		})).
 		Watches(
 			&source.Kind{
-				Type: &v1beta1.PipelineRun{},
+				Type: &arcadiav1.Prompt{},
 			}, handler.Funcs{
-				UpdateFunc: r.PipelineRunUpdate(logger),
+				CreateFunc: evaluator.OnPromptCreate,
+				UpdateFunc: evaluator.OnPromptUpdate(logger, r.Client),
 			}).
 		Watches(
 			&source.Kind{
-				Type: &arcadiav1.Prompt{},
+				Type: &v1.ConfigMap{},
 			}, handler.Funcs{
-				UpdateFunc: evaluator.OnPromptUpdate(logger, r.Client),
-			}).
-		Complete(r)
+				UpdateFunc: evaluator.OnTemplateUpdate(logger, r.Client),
+			}, builder.WithPredicates(evaluator.TemplateDataChanged(logger, r.Client)))
+
+	// without Tekton, only the builtin Ratings can run
+	if _, err := mgr.GetRESTMapper().RESTMapping(v1beta1.SchemeGroupVersion.WithKind("PipelineRun").GroupKind(), v1beta1.SchemeGroupVersion.Version); err != nil {
+		if !meta.IsNoMatchError(err) {
+			return err
+		}
+		logger.Info("Tekton PipelineRun is not installed, only the builtin Ratings are supported")
+	} else {
+		b = b.Watches(
+			&source.Kind{
+				Type: &v1beta1.PipelineRun{},
+			}, handler.Funcs{
+				UpdateFunc: r.PipelineRunUpdate(logger),
+			})
+	}
+	return b.Complete(r)
 }
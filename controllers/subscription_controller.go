@@ -69,6 +69,7 @@ type SubscriptionReconciler struct {
 //+kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=repositorys,verbs=get;list;watch
 //+kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=repositorys/status,verbs=get
 //+kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=componentplans,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=core.kubebb.k8s.com.cn,resources=ratings,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -155,6 +156,18 @@ func (r *SubscriptionReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		}
 	}
 	logger.V(1).Info("get component latest installed version")
+	if sub.Spec.ComponentPlanInstallMethod.IsAuto() {
+		latestVersionFetch, err = r.LatestRatedVersion(ctx, component, latestVersionInstalled.Version)
+		if errors.Is(err, corev1alpha1.ErrRatingPolicy) {
+			msg := fmt.Sprintf("no version satisfies the rating policy, skip: %s", err)
+			logger.Info(msg)
+			return ctrl.Result{}, r.PatchCondition(ctx, sub, corev1alpha1.SubscriptionReconcileSuccess(corev1alpha1.SubscriptionTypeReady).WithMessage(msg))
+		}
+		if err != nil {
+			logger.Error(err, "Failed to check rating policy")
+			return ctrl.Result{}, r.PatchCondition(ctx, sub, corev1alpha1.SubscriptionReconcileError(corev1alpha1.SubscriptionTypeReady, err))
+		}
+	}
 	// If component's the latest version is the same as installed and sub's approved is same with the latest plan, skip
 	if latestVersionFetch.Equal(&latestVersionInstalled) && (latestPlanApproved != nil && sub.Spec.ComponentPlanInstallMethod.IsAuto() == *latestPlanApproved) {
 		msg := "component latest version is the same as installed, skip"
@@ -269,6 +282,16 @@ func (r *SubscriptionReconciler) SetupWithManager(ctx context.Context, mgr ctrl.
 			handler.EnqueueRequestsFromMapFunc(func(o client.Object) (reqs []reconcile.Request) {
 				return r.GetReqs(ctx, o, false)
 			})).
+		Watches(&source.Kind{Type: &corev1alpha1.Rating{}},
+			handler.EnqueueRequestsFromMapFunc(func(o client.Object) []reconcile.Request {
+				component := &corev1alpha1.Component{}
+				component.Namespace = o.GetNamespace()
+				component.Name = o.GetLabels()[corev1alpha1.RatingComponentLabel]
+				if component.Name == "" {
+					return nil
+				}
+				return r.GetReqs(ctx, component, true)
+			})).
 		Complete(r)
 }
 
@@ -289,6 +312,23 @@ func (r *SubscriptionReconciler) GetReqs(ctx context.Context, o client.Object, w
 	return reqs
 }
 
+// LatestRatedVersion returns the latest version of the component which satisfies the rating policy of its repository.
+// The installed version is returned when no newer version satisfies the policy, so the subscription never downgrades.
+func (r *SubscriptionReconciler) LatestRatedVersion(ctx context.Context, component *corev1alpha1.Component, installed string) (version corev1alpha1.ComponentVersion, err error) {
+	for _, v := range component.Status.Versions {
+		if v.Version == installed {
+			return v, nil
+		}
+		if err = corev1alpha1.CheckRatingPolicy(ctx, r.Client, component, v.Version); err == nil {
+			return v, nil
+		}
+		if !errors.Is(err, corev1alpha1.ErrRatingPolicy) {
+			return version, err
+		}
+	}
+	return version, err
+}
+
 // UpdateStatusRepositoryHealth get repository CR, check if the repository is healthy and updates subscription status.RepositoryHealth
 func (r *SubscriptionReconciler) UpdateStatusRepositoryHealth(ctx context.Context, logger logr.Logger, sub *corev1alpha1.Subscription) (err error) {
 	repo := &corev1alpha1.Repository{}
@@ -28,6 +28,7 @@ import (
 	arcadiav1 "github.com/kubeagi/arcadia/api/base/v1alpha1"
 	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -36,7 +37,11 @@ import (
 
 	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
 	"github.com/kubebb/core/controllers"
+	"github.com/kubebb/core/pkg/evaluator"
 	"github.com/kubebb/core/pkg/helm"
+	"github.com/kubebb/core/pkg/inventory"
+	"github.com/kubebb/core/pkg/menu"
+	"github.com/kubebb/core/pkg/policy"
 	"github.com/kubebb/core/pkg/repository"
 	"github.com/kubebb/core/pkg/utils"
 
@@ -68,6 +73,10 @@ func main() {
 		configFile      string
 		enableProfiling bool
 		probeAddr       string
+		menuAddr        string
+		menuModuleBits  string
+		resolveDigest   bool
+		reportDir       string
 	)
 	flag.StringVar(&configFile, "config", "",
 		"The controller will load its initial configuration from this file. "+
@@ -76,6 +85,21 @@ func main() {
 	flag.BoolVar(&enableProfiling, "profiling", true,
 		"Enable profiling via web interface host:port/debug/pprof/")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.StringVar(&menuAddr, "menu-bind-address", "127.0.0.1:8082",
+		"The address the menu tree endpoint binds to, it reads the user from the headers of the auth proxy, "+
+			"so it should only be reachable through the proxy. Set 0 to disable it.")
+	flag.StringVar(&menuModuleBits, "menu-module-bits-configmap", "",
+		"The ConfigMap in the namespace of the manager mapping the users and groups to their module bits for the menu tree endpoint, "+
+			"no user has module bits if it is not set.")
+	flag.BoolVar(&resolveDigest, "resolve-image-digest", false,
+		"Resolve the digests of the images in ComponentPlans from the registries with the image pull secrets, "+
+			"which checks whether the images are available before the ComponentPlans are approved.")
+	flag.StringVar(&reportDir, "trivy-report-dir", "",
+		"The directory of trivy json reports to show the vulnerabilities of the images in ComponentPlans.")
+	flag.StringVar(&inventory.PrePullHelperImage, "prepull-helper-image", inventory.PrePullHelperImage,
+		"The image providing the static busybox binary to pre-pull the images of ComponentPlans.")
+	flag.StringVar(&inventory.PrePullPauseImage, "prepull-pause-image", inventory.PrePullPauseImage,
+		"The image keeping the pods to pre-pull the images of ComponentPlans running.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -148,12 +172,23 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "Subscription")
 		os.Exit(1)
 	}
+	var imageInventory *inventory.Builder
+	if resolveDigest || reportDir != "" {
+		imageInventory = &inventory.Builder{}
+		if resolveDigest {
+			imageInventory.Resolver = &inventory.RemoteResolver{}
+		}
+		if reportDir != "" {
+			imageInventory.Source = &inventory.TrivyReportSource{Dir: reportDir}
+		}
+	}
 	if err = (&controllers.ComponentPlanReconciler{
 		Client:     mgr.GetClient(),
 		Scheme:     mgr.GetScheme(),
 		Recorder:   mgr.GetEventRecorderFor("componentplan-reconcile"),
-		WorkerPool: helm.NewWorkerPool(mgr.GetLogger(), mgr.GetClient()),
-	}).SetupWithManager(mgr); err != nil {
+		WorkerPool: helm.NewWorkerPool(mgr.GetLogger(), mgr.GetClient(), mgr.GetScheme()),
+		Inventory:  imageInventory,
+	}).SetupWithManager(ctx, mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "ComponentPlan")
 		os.Exit(1)
 	}
@@ -174,6 +209,32 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "Portal")
 		os.Exit(1)
 	}
+	if err = (&controllers.MenuReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("menu-reconcile"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Menu")
+		os.Exit(1)
+	}
+	if menuAddr != "0" {
+		menuHandler := &menu.Handler{Client: mgr.GetClient(), Logger: ctrl.Log.WithName("menu")}
+		if menuModuleBits != "" {
+			namespace, err := utils.GetNamespace()
+			if err != nil {
+				setupLog.Error(err, "unable to get the namespace of the menu module bits configmap")
+				os.Exit(1)
+			}
+			menuHandler.ModuleBits = types.NamespacedName{Namespace: namespace, Name: menuModuleBits}
+		}
+		if err = mgr.Add(&menu.Server{
+			Addr:    menuAddr,
+			Handler: menuHandler,
+		}); err != nil {
+			setupLog.Error(err, "unable to add menu server")
+			os.Exit(1)
+		}
+	}
 	if enableWebhooks {
 		if err = (&corev1alpha1.ComponentPlan{}).SetupWebhookWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "ComponentPlan")
@@ -195,11 +256,17 @@ func main() {
 			setupLog.Error(err, "unable to create webhook", "webhook", "Repository")
 			os.Exit(1)
 		}
+		if err = (&policy.Validator{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Policy")
+			os.Exit(1)
+		}
 	}
 	if corev1alpha1.RatingEnabled() {
 		if err = (&controllers.RatingReconciler{
-			Client: mgr.GetClient(),
-			Scheme: mgr.GetScheme(),
+			Client:           mgr.GetClient(),
+			Scheme:           mgr.GetScheme(),
+			PromptCaller:     evaluator.NewPromptCaller(mgr.GetLogger(), mgr.GetClient()),
+			BuiltinCheckPool: evaluator.NewBuiltinCheckPool(),
 		}).SetupWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create controller", "controller", "Rating")
 			os.Exit(1)
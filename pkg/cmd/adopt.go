@@ -0,0 +1,142 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/kubebb/core/api/v1alpha1"
+	"github.com/kubebb/core/pkg/helm"
+)
+
+func NewAdoptCmd() *cobra.Command {
+	var (
+		namespace          string
+		componentNamespace string
+		dryRun             bool
+	)
+	cmd := &cobra.Command{
+		Use:   "adopt [RELEASE...]",
+		Short: "adopt releases installed by plain helm into ComponentPlans",
+		Long: `adopt releases installed by plain helm into ComponentPlans.
+
+For each release, find the Component with the same chart name and version, then create a ComponentPlan
+with the current release values as override values. The ComponentPlan takes ownership of the release
+without reinstalling it. If no release is specified, all deployed releases in the namespace will be adopted.
+Exit with code 1 if any release fails to be adopted.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			hl, err := newHelmWrapper(namespace)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "new helm wrapper error %s\n", err)
+				os.Exit(1)
+			}
+			var rels []*release.Release
+			if len(args) == 0 {
+				if rels, err = hl.ListDeployedReleases(); err != nil {
+					fmt.Fprintf(os.Stderr, "failed to list releases in namespace %s with error %s\n", namespace, err)
+					os.Exit(1)
+				}
+			}
+			for _, name := range args {
+				rel, err := hl.GetLastRelease(name)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "failed to get release %s with error %s\n", name, err)
+					os.Exit(1)
+				}
+				if rel == nil {
+					fmt.Fprintf(os.Stderr, "release %s not found in namespace %s\n", name, namespace)
+					os.Exit(1)
+				}
+				rels = append(rels, rel)
+			}
+
+			once.Do(initCli)
+			components := &v1alpha1.ComponentList{}
+			if err = cc.List(ctx, components, client.InNamespace(componentNamespace)); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to list components with error %s\n", err)
+				os.Exit(1)
+			}
+			failed := 0
+			for _, rel := range rels {
+				if err = adoptRelease(ctx, rel, components.Items, dryRun); err != nil {
+					fmt.Fprintf(os.Stderr, "failed to adopt release %s with error %s\n", rel.Name, err)
+					failed++
+				}
+			}
+			if failed > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&namespace, "namespace", "default", "namespace of the releases")
+	cmd.Flags().StringVar(&componentNamespace, "component-namespace", "", "only search components in this namespace, default all namespaces")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only print the generated ComponentPlans")
+	return cmd
+}
+
+func adoptRelease(ctx context.Context, rel *release.Release, components []v1alpha1.Component, dryRun bool) error {
+	if helm.IsKubebbRelease(rel) {
+		fmt.Printf("skip %s, it is already managed by a ComponentPlan\n", rel.Name)
+		return nil
+	}
+	component, err := helm.FindComponentForRelease(rel, components)
+	if err != nil {
+		return err
+	}
+	plan, err := helm.GenerateComponentPlanForRelease(rel, component)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		out, err := yaml.Marshal(plan)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("---\n%s", out)
+		return nil
+	}
+	if err = cc.Create(ctx, plan); err != nil {
+		return err
+	}
+	fmt.Printf("release %s is adopted by ComponentPlan %s/%s with component %s/%s\n", rel.Name, plan.Namespace, plan.Name, component.Namespace, component.Name)
+	return nil
+}
+
+func newHelmWrapper(namespace string) (*helm.HelmWrapper, error) {
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	getter := genericclioptions.ConfigFlags{
+		APIServer:   &cfg.Host,
+		CAFile:      &cfg.CAFile,
+		BearerToken: &cfg.BearerToken,
+		Namespace:   &namespace,
+	}
+	return helm.NewHelmWrapper(&getter, namespace, logr.Logger{})
+}
@@ -18,18 +18,24 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"sync"
 
-	"github.com/ghodss/yaml"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
 
 	"github.com/kubebb/core/api/v1alpha1"
 )
@@ -41,6 +47,7 @@ var (
 
 const (
 	mustAddForClusterComponent = "ingress-nginx.controller.nodeSelector.kubernetes\\.io/hostname"
+	ingressNginxDeployment     = "cluster-component-ingress-nginx-controller"
 	clusterComponentName       = "cluster-component"
 	installTemplate            = `apiVersion: core.kubebb.k8s.com.cn/v1alpha1
 kind: ComponentPlan
@@ -241,6 +248,8 @@ func initCli() {
 	utilruntime.Must(v1alpha1.AddToScheme(scheme))
 	utilruntime.Must(appsv1.AddToScheme(scheme))
 	utilruntime.Must(corev1.AddToScheme(scheme))
+	utilruntime.Must(admissionregistrationv1.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
 	cc, err = client.New(cfg, client.Options{Scheme: scheme})
 	if err != nil {
 		panic(err)
@@ -295,23 +304,71 @@ func (c *ClusterComponent) Install(ctx context.Context) error {
 				ValuesKey: "values.yaml",
 			},
 		}
-	} else {
-		componentPlan.Spec.Override.Set = c.cfg.Args.Values
 	}
-	err := cc.Create(ctx, &componentPlan)
+	// the set values override the generated values of u4a as well
+	componentPlan.Spec.Override.Set = c.cfg.Args.Values
+	if err := c.setValues(&componentPlan); err != nil {
+		return err
+	}
+	if err := cc.Create(ctx, &componentPlan); err != nil {
+		return err
+	}
+	if err := WaitComponentPlanSucceeded(ctx, cc, componentPlan.Namespace, componentPlan.Name); err != nil {
+		return err
+	}
 	if c.cfg.RegisterName == CLUSTERCOMPONENT {
 		// here need to wait for cert-manager to update the core to ensure that the webhook will work.
-		return WaitDeployment(ctx, cc, c.cfg.Namespace, []string{"cert-manager", "cert-manager-cainjector", "cert-manager-webhook", "cluster-component-ingress-nginx-controller"}, 300)
+		return WaitDeployment(ctx, cc, c.cfg.Namespace, []string{"cert-manager", "cert-manager-cainjector", "cert-manager-webhook", ingressNginxDeployment})
 	}
-	return err
+	return nil
 }
 
-func (c *ClusterComponent) Upgrade(ctx context.Context) error {
-	// not support
+// setValues sets the merged values files and inline values as the values of the componentplan.
+func (c *ClusterComponent) setValues(componentPlan *v1alpha1.ComponentPlan) error {
+	if len(c.cfg.Args.ValueFiles) == 0 && len(c.cfg.Values) == 0 {
+		return nil
+	}
+	vals, err := c.cfg.mergedValues()
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(vals)
+	if err != nil {
+		return err
+	}
+	componentPlan.Spec.Override.Values = &apiextensionsv1.JSON{Raw: raw}
 	return nil
 }
 
-func (c *ClusterComponent) Uninstall(ctx context.Context) {
+func (c *ClusterComponent) Upgrade(ctx context.Context) error {
+	once.Do(initCli)
+	componentPlan := v1alpha1.ComponentPlan{}
+	if err := cc.Get(ctx, types.NamespacedName{Namespace: c.cfg.Namespace, Name: c.cfg.RegisterName}, &componentPlan); err != nil {
+		return err
+	}
+	origin := componentPlan.DeepCopy()
+	if c.cfg.Version != "" {
+		componentPlan.Spec.InstallVersion = c.cfg.Version
+	}
+	// the set values of u4a override the values from the u4acm configmap
+	if len(c.cfg.Args.Values) > 0 {
+		componentPlan.Spec.Override.Set = mergeSetValues(componentPlan.Spec.Override.Set, c.cfg.Args.Values)
+	}
+	if err := c.setValues(&componentPlan); err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(origin.Spec, componentPlan.Spec) {
+		fmt.Printf("\t[%s] nothing to upgrade\n", c.cfg.RegisterName)
+		return nil
+	}
+	fmt.Printf("\t[%s] upgrade componentplan to version %s\n", c.cfg.RegisterName, componentPlan.Spec.InstallVersion)
+	if err := cc.Patch(ctx, &componentPlan, client.MergeFrom(origin)); err != nil {
+		return err
+	}
+	return WaitComponentPlanSucceeded(ctx, cc, componentPlan.Namespace, componentPlan.Name)
+}
+
+func (c *ClusterComponent) Uninstall(ctx context.Context) error {
 	once.Do(initCli)
 	componentPlan := v1alpha1.ComponentPlan{
 		ObjectMeta: v1.ObjectMeta{
@@ -319,5 +376,65 @@ func (c *ClusterComponent) Uninstall(ctx context.Context) {
 			Namespace: c.cfg.Namespace,
 		},
 	}
-	_ = cc.Delete(ctx, &componentPlan)
+	if err := cc.Delete(ctx, &componentPlan); err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete componentplan: %w", err)
+	}
+	if c.cfg.RegisterName == U4A {
+		cm := corev1.ConfigMap{ObjectMeta: v1.ObjectMeta{Name: "u4acm", Namespace: c.cfg.Namespace}}
+		if err := cc.Delete(ctx, &cm); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete configmap u4acm: %w", err)
+		}
+	}
+	// the release is uninstalled by core when the finalizer of the componentplan is handled,
+	// so wait for it to be gone before core itself is uninstalled.
+	return WaitFor(ctx, fmt.Sprintf("componentplan %s/%s to be deleted", componentPlan.Namespace, componentPlan.Name), func(ctx context.Context) (bool, string, error) {
+		plan := v1alpha1.ComponentPlan{}
+		if err := cc.Get(ctx, client.ObjectKeyFromObject(&componentPlan), &plan); err != nil {
+			return apierrors.IsNotFound(err), getErrorState(err), nil
+		}
+		return false, string(plan.Status.GetCondition(v1alpha1.ComponentPlanTypeSucceeded).Reason), nil
+	})
+}
+
+func (c *ClusterComponent) Status(ctx context.Context) (Status, error) {
+	once.Do(initCli)
+	status := Status{Name: c.cfg.RegisterName}
+	componentPlan := v1alpha1.ComponentPlan{}
+	if err := cc.Get(ctx, types.NamespacedName{Namespace: c.cfg.Namespace, Name: c.cfg.RegisterName}, &componentPlan); err != nil {
+		if apierrors.IsNotFound(err) {
+			return status, nil
+		}
+		return status, err
+	}
+	status.Installed = true
+	status.Version = componentPlan.Spec.InstallVersion
+	cond := componentPlan.Status.GetCondition(v1alpha1.ComponentPlanTypeSucceeded)
+	status.Ready = cond.Status == corev1.ConditionTrue
+	status.Message = string(cond.Reason)
+	if cond.Message != "" {
+		status.Message += ": " + cond.Message
+	}
+	return status, nil
+}
+
+// mergeSetValues merges the set values into the existing ones of the componentplan.
+// The values are applied in order like the repeated --set flags of helm, so the existing values
+// with the same keys are dropped and the new ones are appended.
+func mergeSetValues(existing, values []string) []string {
+	keys := make(map[string]bool, len(values))
+	for _, v := range values {
+		keys[setValueKey(v)] = true
+	}
+	merged := make([]string, 0, len(existing)+len(values))
+	for _, v := range existing {
+		if !keys[setValueKey(v)] {
+			merged = append(merged, v)
+		}
+	}
+	return append(merged, values...)
+}
+
+func setValueKey(value string) string {
+	key, _, _ := strings.Cut(value, "=")
+	return key
 }
@@ -0,0 +1,44 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeSetValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		values   []string
+		want     []string
+	}{
+		{name: "no existing", existing: nil, values: []string{"a=1"}, want: []string{"a=1"}},
+		{name: "no values", existing: []string{"a=1"}, values: nil, want: []string{"a=1"}},
+		{name: "append new keys", existing: []string{"a=1", "b=2"}, values: []string{"c=3"}, want: []string{"a=1", "b=2", "c=3"}},
+		{name: "override existing keys", existing: []string{"a=1", "b=2"}, values: []string{"a=3"}, want: []string{"b=2", "a=3"}},
+		{name: "nested keys", existing: []string{"a.b=1", "a.c=2"}, values: []string{"a.b=3"}, want: []string{"a.c=2", "a.b=3"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeSetValues(tt.existing, tt.values); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeSetValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -23,8 +23,11 @@ import (
 	"time"
 
 	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
 	"helm.sh/helm/v3/pkg/repo"
+	"sigs.k8s.io/yaml"
 
 	"github.com/kubebb/core/pkg/helm"
 )
@@ -42,6 +45,10 @@ type Config struct {
 	NodeName     string // for ingress
 	NodeIP       string // for ingress
 	Args         values.Options
+	// Values are the inline values, which override the values files in Args and are overridden by the set values in Args.
+	Values map[string]interface{}
+	// Timeout limits the time of the step, including waiting for the component to be ready
+	Timeout time.Duration
 }
 
 func NewConf(registerName string) Config {
@@ -50,9 +57,77 @@ func NewConf(registerName string) Config {
 		Upgrade:      false,
 		RegisterName: registerName,
 		Args:         values.Options{},
+		Timeout:      DefaultStepTimeout,
 	}
 }
 
+// valueOptions returns the helm value options of the config, the inline values are written into a temporary
+// values file after the others. The cleanup func removes the temporary file.
+func (c *Config) valueOptions() (opts *values.Options, cleanup func(), err error) {
+	opts = &values.Options{}
+	*opts = c.Args
+	cleanup = func() {}
+	if len(c.Values) == 0 {
+		return opts, cleanup, nil
+	}
+	name, err := writeValuesFile(c.RegisterName+"-values-*.yaml", c.Values)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	opts.ValueFiles = append(append([]string{}, c.Args.ValueFiles...), name)
+	return opts, func() { _ = os.Remove(name) }, nil
+}
+
+// upgradeValueOptions returns the helm value options of the config over the values of the current release,
+// so the values set by install are kept unless the config overrides them. helm --reuse-values is not used,
+// because it renders the new chart with the default values of the old chart and misses the new keys.
+func (c *Config) upgradeValueOptions(current map[string]interface{}) (opts *values.Options, cleanup func(), err error) {
+	opts, cleanup, err = c.valueOptions()
+	if err != nil || len(current) == 0 {
+		return opts, cleanup, err
+	}
+	name, err := writeValuesFile(c.RegisterName+"-current-*.yaml", current)
+	if err != nil {
+		cleanup()
+		return nil, func() {}, err
+	}
+	opts.ValueFiles = append([]string{name}, opts.ValueFiles...)
+	configCleanup := cleanup
+	return opts, func() {
+		configCleanup()
+		_ = os.Remove(name)
+	}, nil
+}
+
+// writeValuesFile writes the values into a temporary values file and returns its name
+func writeValuesFile(pattern string, vals map[string]interface{}) (string, error) {
+	data, err := yaml.Marshal(vals)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err = f.Write(data); err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// mergedValues merges the values files and the inline values of the config, the set values are not included.
+func (c *Config) mergedValues() (map[string]interface{}, error) {
+	opts, cleanup, err := c.valueOptions()
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	files := values.Options{ValueFiles: opts.ValueFiles}
+	return files.MergeValues(getter.All(cli.New()))
+}
+
 var (
 	store = map[string]InstallFunc{}
 )
@@ -84,9 +159,22 @@ type Installer interface {
 	Description() string
 	Install(context.Context) error
 	Upgrade(context.Context) error
-	Uninstall(context.Context)
+	Uninstall(context.Context) error
+	Status(context.Context) (Status, error)
+}
+
+// Status is the state of a component installed by an Installer
+type Status struct {
+	Name      string
+	Installed bool
+	Version   string
+	Ready     bool
+	Message   string
 }
 
+// Components lists the components in the order of installation, uninstallation follows the reverse order.
+var Components = []string{CORE, CLUSTERCOMPONENT, U4A, COMPONENTSTORE}
+
 func EnsureRepo() {
 	entry := repo.Entry{
 		Name: KUBEBBOFFICIALREPO,
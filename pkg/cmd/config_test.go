@@ -0,0 +1,75 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
+)
+
+func TestUpgradeValueOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		current map[string]interface{}
+		want    map[string]interface{}
+	}{
+		{
+			name:    "no values of the current release",
+			cfg:     Config{RegisterName: CORE, Args: values.Options{Values: []string{"a=set"}}},
+			current: nil,
+			want:    map[string]interface{}{"a": "set"},
+		},
+		{
+			name: "config overrides the values of the current release",
+			cfg: Config{
+				RegisterName: CORE,
+				Args:         values.Options{Values: []string{"b=set"}},
+				Values:       map[string]interface{}{"a": "inline"},
+			},
+			current: map[string]interface{}{"a": "old", "b": "old", "c": "old"},
+			want:    map[string]interface{}{"a": "inline", "b": "set", "c": "old"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, cleanup, err := tt.cfg.upgradeValueOptions(tt.current)
+			if err != nil {
+				t.Fatalf("upgradeValueOptions() error = %v", err)
+			}
+			got, err := opts.MergeValues(getter.All(cli.New()))
+			files := opts.ValueFiles
+			cleanup()
+			if err != nil {
+				t.Fatalf("MergeValues() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("values = %v, want %v", got, tt.want)
+			}
+			for _, f := range files {
+				if _, err = os.Stat(f); !os.IsNotExist(err) {
+					t.Errorf("values file %s is not removed", f)
+				}
+			}
+		})
+	}
+}
@@ -18,11 +18,13 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
-	"time"
 
 	"github.com/go-logr/logr"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	ctrl "sigs.k8s.io/controller-runtime"
 
@@ -65,18 +67,28 @@ func (c *Core) Install(ctx context.Context) error {
 		return err
 	}
 
+	valueOpts, cleanup, err := c.cfg.valueOptions()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
 	actionClient := hl.GetDefaultInstallCfg()
 	actionClient.Version = c.cfg.Version
 	fmt.Printf("\t[%s] helm install %s %s/kubebb-core\n", CORE, CORE, KUBEBBOFFICIALREPO)
-	_, _, err = hl.Install(ctx, logger, actionClient, &c.cfg.Args, "kubebb-core", fmt.Sprintf("%s/kubebb-core", KUBEBBOFFICIALREPO))
+	rel, _, err := hl.Install(ctx, logger, actionClient, valueOpts, "kubebb-core", fmt.Sprintf("%s/kubebb-core", KUBEBBOFFICIALREPO))
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "faile to install %s with error: %s", CORE, err)
 		return err
 	}
+	kinds, err := releaseCRDKinds(rel)
+	if err != nil {
+		return err
+	}
 	once.Do(initCli)
-	fmt.Printf("\t[%s] wait %s's deployments\n", CORE, CORE)
-
-	return WaitDeployment(ctx, cc, c.cfg.Namespace, []string{CORE}, 60)
+	if err = WaitDeployment(ctx, cc, c.cfg.Namespace, []string{CORE}); err != nil {
+		return err
+	}
+	return WaitCRDsEstablished(ctx, cc, kinds)
 }
 
 func (c *Core) Upgrade(ctx context.Context) error {
@@ -99,24 +111,40 @@ func (c *Core) Upgrade(ctx context.Context) error {
 		return err
 	}
 
+	rel, err := hl.GetLastRelease(CORE)
+	if err != nil {
+		return err
+	}
+	var current map[string]interface{}
+	if rel != nil {
+		current = rel.Config
+	}
+	// keep the values set by install, the args only add or override some of them
+	valueOpts, cleanup, err := c.cfg.upgradeValueOptions(current)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
 	actionClient, createNs := hl.GetDefaultUpgradeCfg()
+	actionClient.Version = c.cfg.Version
 	fmt.Printf("\t[%s] helm upgrade %s\n", CORE, CORE)
 
-	_, _, err = hl.Upgrade(ctx, logger, actionClient, &c.cfg.Args, CORE, fmt.Sprintf("%s/kubebb-core", KUBEBBOFFICIALREPO), createNs)
+	_, _, err = hl.Upgrade(ctx, logger, actionClient, valueOpts, CORE, fmt.Sprintf("%s/kubebb-core", KUBEBBOFFICIALREPO), createNs)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to upgrade %s with error: %s", CORE, err)
 		return err
 	}
-	fmt.Printf("\t sleep 30s for %s pod running\n", CORE)
-	time.Sleep(30 * time.Second)
-
-	return WaitDeployment(ctx, cc, c.cfg.Namespace, []string{CORE}, 30)
+	once.Do(initCli)
+	if err = WaitDeployment(ctx, cc, c.cfg.Namespace, []string{CORE}); err != nil {
+		return err
+	}
+	return WaitWebhookServing(ctx, cc, c.cfg.Namespace)
 }
 
-func (c *Core) Uninstall(ctx context.Context) {
+func (c *Core) Uninstall(ctx context.Context) error {
 	cfg, err := ctrl.GetConfig()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "[core operator] can't get cluster config %s", err)
+		return fmt.Errorf("can't get cluster config: %w", err)
 	}
 	getter := genericclioptions.ConfigFlags{
 		APIServer:   &cfg.Host,
@@ -128,10 +156,34 @@ func (c *Core) Uninstall(ctx context.Context) {
 	logger := logr.Logger{}
 	hl, err := helm.NewHelmWrapper(&getter, c.cfg.Namespace, logger)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "new helm wrapper error %s", err)
-		return
+		return fmt.Errorf("new helm wrapper error: %w", err)
 	}
 
 	actionClient := hl.GetDefaultUninstallCfg()
-	_, _ = hl.Uninstall(ctx, logger, actionClient, CORE)
+	// core may be partially installed when it is rolled back, a missing release is already uninstalled.
+	if _, err = hl.Uninstall(ctx, logger, actionClient, CORE); err != nil && !errors.Is(err, driver.ErrReleaseNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (c *Core) Status(ctx context.Context) (Status, error) {
+	status := Status{Name: CORE}
+	hl, err := newHelmWrapper(c.cfg.Namespace)
+	if err != nil {
+		return status, err
+	}
+	rel, err := hl.GetLastRelease(CORE)
+	if err != nil || rel == nil {
+		return status, err
+	}
+	status.Installed = true
+	if rel.Chart != nil && rel.Chart.Metadata != nil {
+		status.Version = rel.Chart.Metadata.Version
+	}
+	if rel.Info != nil {
+		status.Ready = rel.Info.Status == release.StatusDeployed
+		status.Message = fmt.Sprintf("release revision %d is %s", rel.Version, rel.Info.Status)
+	}
+	return status, nil
 }
@@ -0,0 +1,386 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/release"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/strings/slices"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubebb/core/api/v1alpha1"
+	"github.com/kubebb/core/pkg/utils"
+)
+
+const (
+	checkOK   = "OK"
+	checkWarn = "WARN"
+	checkFail = "FAIL"
+
+	// certExpireWarning is how long before the expiration of a webhook cert to warn
+	certExpireWarning = 30 * 24 * time.Hour
+)
+
+type checkResult struct {
+	Check   string
+	Result  string
+	Message string
+}
+
+func NewDoctorCmd() *cobra.Command {
+	var profileFile string
+	cmd := &cobra.Command{
+		Use:   "doctor",
+		Short: "check whether kubebb works well in the cluster",
+		Long: `check whether kubebb works well in the cluster.
+
+The checks include:
+  - the CRDs shipped by the core release are established
+  - the CA bundles and the serving certs of the kubebb webhooks are valid and not expired
+  - the pods of the core operator are ready
+  - the Repositories are ready
+
+Exit with code 1 if any check fails.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			profile, err := loadProfile(profileFile, cmd.Flags())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to load profile %s\n", err)
+				os.Exit(1)
+			}
+			namespace := profile.Config(CORE).Namespace
+			once.Do(initCli)
+			var results []checkResult
+			if kinds, err := coreCRDKinds(namespace); err != nil {
+				results = append(results, checkResult{Check: "crds", Result: checkFail, Message: err.Error()})
+			} else {
+				results = append(results, checkCRDs(ctx, cc, kinds)...)
+			}
+			results = append(results, checkWebhookCerts(ctx, cc, namespace, time.Now())...)
+			results = append(results, checkOperatorPods(ctx, cc, namespace)...)
+			results = append(results, checkRepositories(ctx, cc)...)
+			failed := 0
+			for _, r := range results {
+				fmt.Printf("[%s] %s: %s\n", r.Result, r.Check, r.Message)
+				if r.Result == checkFail {
+					failed++
+				}
+			}
+			if failed > 0 {
+				fmt.Fprintf(os.Stderr, "%d checks failed\n", failed)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&profileFile, "profile", "", "the yaml profile used to install the components")
+	cmd.Flags().String("namespace", "default", "install namespace")
+	return cmd
+}
+
+// releaseCRDKinds returns the kinds of the kubebb CRDs shipped by the release, from the crds directory of the chart
+// and the rendered manifest. The released charts may not ship all the kinds compiled into corectl,
+// so the expected CRDs are not derived from the scheme.
+func releaseCRDKinds(rel *release.Release) ([]string, error) {
+	docs := []string{rel.Manifest}
+	if rel.Chart != nil {
+		for _, crd := range rel.Chart.CRDObjects() {
+			docs = append(docs, string(crd.File.Data))
+		}
+	}
+	kinds := sets.NewString()
+	for _, doc := range docs {
+		objs, err := utils.SplitYAML([]byte(doc))
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objs {
+			if obj.GetKind() != "CustomResourceDefinition" {
+				continue
+			}
+			group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+			kind, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "kind")
+			if group == v1alpha1.Group && kind != "" {
+				kinds.Insert(kind)
+			}
+		}
+	}
+	return kinds.List(), nil
+}
+
+// coreCRDKinds returns the kinds of the kubebb CRDs shipped by the core release in the namespace
+func coreCRDKinds(namespace string) ([]string, error) {
+	hl, err := newHelmWrapper(namespace)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := hl.GetLastRelease(CORE)
+	if err != nil {
+		return nil, err
+	}
+	if rel == nil {
+		return nil, fmt.Errorf("release %s is not found in namespace %s", CORE, namespace)
+	}
+	return releaseCRDKinds(rel)
+}
+
+func checkCRDs(ctx context.Context, c client.Client, kinds []string) []checkResult {
+	crds := &apiextensionsv1.CustomResourceDefinitionList{}
+	if err := c.List(ctx, crds); err != nil {
+		return []checkResult{{Check: "crds", Result: checkFail, Message: err.Error()}}
+	}
+	found := make(map[string]apiextensionsv1.CustomResourceDefinition)
+	for _, crd := range crds.Items {
+		if crd.Spec.Group == v1alpha1.Group {
+			found[crd.Spec.Names.Kind] = crd
+		}
+	}
+	results := make([]checkResult, 0)
+	for _, kind := range kinds {
+		check := "crd " + kind
+		crd, ok := found[kind]
+		if !ok {
+			results = append(results, checkResult{Check: check, Result: checkFail, Message: "not found"})
+			continue
+		}
+		established := crdEstablished(&crd)
+		served := false
+		for _, v := range crd.Spec.Versions {
+			if v.Name == v1alpha1.Version && v.Served {
+				served = true
+			}
+		}
+		switch {
+		case !established:
+			results = append(results, checkResult{Check: check, Result: checkFail, Message: fmt.Sprintf("%s is not established", crd.Name)})
+		case !served:
+			results = append(results, checkResult{Check: check, Result: checkFail, Message: fmt.Sprintf("%s does not serve %s", crd.Name, v1alpha1.Version)})
+		default:
+			results = append(results, checkResult{Check: check, Result: checkOK, Message: crd.Name})
+		}
+	}
+	return results
+}
+
+// kubebbWebhook is a validating or mutating webhook served in the namespace for the kubebb resources
+type kubebbWebhook struct {
+	Name         string
+	ClientConfig admissionregistrationv1.WebhookClientConfig
+}
+
+func isKubebbWebhook(cfg admissionregistrationv1.WebhookClientConfig, rules []admissionregistrationv1.RuleWithOperations, namespace string) bool {
+	if cfg.Service == nil || cfg.Service.Namespace != namespace {
+		return false
+	}
+	for _, rule := range rules {
+		if slices.Contains(rule.APIGroups, v1alpha1.Group) {
+			return true
+		}
+	}
+	return false
+}
+
+func listKubebbWebhooks(ctx context.Context, c client.Client, namespace string) ([]kubebbWebhook, error) {
+	webhooks := make([]kubebbWebhook, 0)
+	validatings := &admissionregistrationv1.ValidatingWebhookConfigurationList{}
+	if err := c.List(ctx, validatings); err != nil {
+		return nil, err
+	}
+	for _, cfg := range validatings.Items {
+		for _, w := range cfg.Webhooks {
+			if isKubebbWebhook(w.ClientConfig, w.Rules, namespace) {
+				webhooks = append(webhooks, kubebbWebhook{Name: w.Name, ClientConfig: w.ClientConfig})
+			}
+		}
+	}
+	mutatings := &admissionregistrationv1.MutatingWebhookConfigurationList{}
+	if err := c.List(ctx, mutatings); err != nil {
+		return nil, err
+	}
+	for _, cfg := range mutatings.Items {
+		for _, w := range cfg.Webhooks {
+			if isKubebbWebhook(w.ClientConfig, w.Rules, namespace) {
+				webhooks = append(webhooks, kubebbWebhook{Name: w.Name, ClientConfig: w.ClientConfig})
+			}
+		}
+	}
+	return webhooks, nil
+}
+
+func checkCABundle(caBundle []byte, now time.Time) (result, message string) {
+	return checkCertExpiration("caBundle", caBundle, now)
+}
+
+// checkCertExpiration checks the pem encoded certificates in data are valid now and not expiring soon,
+// what is the name of data in the messages.
+func checkCertExpiration(what string, data []byte, now time.Time) (result, message string) {
+	if len(data) == 0 {
+		return checkFail, what + " is empty"
+	}
+	var expire time.Time
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return checkFail, fmt.Sprintf("invalid %s: %s", what, err)
+		}
+		if now.Before(cert.NotBefore) {
+			return checkFail, fmt.Sprintf("cert %s is not valid before %s", cert.Subject.CommonName, cert.NotBefore)
+		}
+		if expire.IsZero() || cert.NotAfter.Before(expire) {
+			expire = cert.NotAfter
+		}
+	}
+	switch {
+	case expire.IsZero():
+		return checkFail, "no certificate found in " + what
+	case now.After(expire):
+		return checkFail, fmt.Sprintf("cert expired at %s", expire)
+	case now.Add(certExpireWarning).After(expire):
+		return checkWarn, fmt.Sprintf("cert will expire at %s", expire)
+	}
+	return checkOK, fmt.Sprintf("cert expires at %s", expire)
+}
+
+func checkWebhookCerts(ctx context.Context, c client.Client, namespace string, now time.Time) []checkResult {
+	webhooks, err := listKubebbWebhooks(ctx, c, namespace)
+	if err != nil {
+		return []checkResult{{Check: "webhooks", Result: checkFail, Message: err.Error()}}
+	}
+	if len(webhooks) == 0 {
+		// webhook is only enabled after cluster-component is installed
+		return []checkResult{{Check: "webhooks", Result: checkWarn, Message: fmt.Sprintf("no kubebb webhook found in namespace %s", namespace)}}
+	}
+	results := make([]checkResult, 0, len(webhooks)+1)
+	for _, w := range webhooks {
+		result, message := checkCABundle(w.ClientConfig.CABundle, now)
+		results = append(results, checkResult{Check: "webhook " + w.Name, Result: result, Message: message})
+	}
+	return append(results, checkServingCerts(ctx, c, namespace, now)...)
+}
+
+// checkServingCerts checks the serving certs of the webhook server in the Secrets mounted by the core operator,
+// the caBundle may be renewed while the operator still serves with an expired cert.
+func checkServingCerts(ctx context.Context, c client.Client, namespace string, now time.Time) []checkResult {
+	check := "webhook serving cert"
+	deploy := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: CORE}, deploy); err != nil {
+		return []checkResult{{Check: check, Result: checkFail, Message: err.Error()}}
+	}
+	results := make([]checkResult, 0)
+	for _, v := range deploy.Spec.Template.Spec.Volumes {
+		if v.Secret == nil {
+			continue
+		}
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: v.Secret.SecretName}, secret); err != nil {
+			results = append(results, checkResult{Check: check + " " + v.Secret.SecretName, Result: checkFail, Message: err.Error()})
+			continue
+		}
+		cert, ok := secret.Data[corev1.TLSCertKey]
+		if !ok {
+			continue
+		}
+		result, message := checkCertExpiration(corev1.TLSCertKey, cert, now)
+		results = append(results, checkResult{Check: check + " " + secret.Name, Result: result, Message: message})
+	}
+	if len(results) == 0 {
+		return []checkResult{{Check: check, Result: checkWarn, Message: fmt.Sprintf("no Secret with %s mounted by %s", corev1.TLSCertKey, CORE)}}
+	}
+	return results
+}
+
+func checkOperatorPods(ctx context.Context, c client.Client, namespace string) []checkResult {
+	check := "operator " + CORE
+	deploy := &appsv1.Deployment{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: CORE}, deploy); err != nil {
+		return []checkResult{{Check: check, Result: checkFail, Message: err.Error()}}
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deploy.Spec.Selector)
+	if err != nil {
+		return []checkResult{{Check: check, Result: checkFail, Message: err.Error()}}
+	}
+	pods := &corev1.PodList{}
+	if err = c.List(ctx, pods, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return []checkResult{{Check: check, Result: checkFail, Message: err.Error()}}
+	}
+	if len(pods.Items) == 0 {
+		return []checkResult{{Check: check, Result: checkFail, Message: "no pod found"}}
+	}
+	results := make([]checkResult, 0, len(pods.Items))
+	for _, pod := range pods.Items {
+		var restarts int32
+		reasons := make([]string, 0)
+		for _, status := range pod.Status.ContainerStatuses {
+			restarts += status.RestartCount
+			if status.State.Waiting != nil && status.State.Waiting.Reason != "" {
+				reasons = append(reasons, fmt.Sprintf("%s is %s", status.Name, status.State.Waiting.Reason))
+			}
+		}
+		message := fmt.Sprintf("pod %s is %s with %d restarts", pod.Name, pod.Status.Phase, restarts)
+		if len(reasons) > 0 {
+			message += ", " + strings.Join(reasons, ", ")
+		}
+		result := checkOK
+		if !podReady(&pod) {
+			result = checkFail
+		}
+		results = append(results, checkResult{Check: check, Result: result, Message: message})
+	}
+	return results
+}
+
+func checkRepositories(ctx context.Context, c client.Client) []checkResult {
+	repos := &v1alpha1.RepositoryList{}
+	if err := c.List(ctx, repos); err != nil {
+		return []checkResult{{Check: "repositories", Result: checkFail, Message: err.Error()}}
+	}
+	results := make([]checkResult, 0, len(repos.Items))
+	for _, repo := range repos.Items {
+		check := fmt.Sprintf("repository %s/%s", repo.Namespace, repo.Name)
+		cond := repo.Status.GetCondition(v1alpha1.TypeReady)
+		if cond.Status != corev1.ConditionTrue {
+			results = append(results, checkResult{Check: check, Result: checkFail, Message: fmt.Sprintf("not ready: %s %s", cond.Reason, cond.Message)})
+			continue
+		}
+		if synced := repo.Status.GetCondition(v1alpha1.TypeSynced); synced.Status == corev1.ConditionFalse {
+			results = append(results, checkResult{Check: check, Result: checkWarn, Message: fmt.Sprintf("ready but failed to sync: %s", synced.Message)})
+			continue
+		}
+		results = append(results, checkResult{Check: check, Result: checkOK, Message: repo.Spec.URL})
+	}
+	return results
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kubebb/core/api/v1alpha1"
+)
+
+func newTestCert(t *testing.T, notBefore, notAfter time.Time) []byte {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "kubebb-webhook"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestCheckCABundle(t *testing.T) {
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	valid := newTestCert(t, now.AddDate(-1, 0, 0), now.AddDate(1, 0, 0))
+	expiring := newTestCert(t, now.AddDate(-1, 0, 0), now.AddDate(0, 0, 10))
+	tests := []struct {
+		name       string
+		caBundle   []byte
+		want       string
+		wantPrefix string
+	}{
+		{name: "empty", caBundle: nil, want: checkFail, wantPrefix: "caBundle is empty"},
+		{name: "not pem", caBundle: []byte("not a cert"), want: checkFail, wantPrefix: "no certificate found"},
+		{name: "invalid cert", caBundle: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("bad")}), want: checkFail, wantPrefix: "invalid caBundle"},
+		{name: "valid", caBundle: valid, want: checkOK, wantPrefix: "cert expires at"},
+		{name: "expiring soon", caBundle: expiring, want: checkWarn, wantPrefix: "cert will expire at"},
+		{name: "expired", caBundle: newTestCert(t, now.AddDate(-1, 0, 0), now.AddDate(0, 0, -1)), want: checkFail, wantPrefix: "cert expired at"},
+		{name: "not yet valid", caBundle: newTestCert(t, now.AddDate(0, 0, 1), now.AddDate(1, 0, 0)), want: checkFail, wantPrefix: "cert kubebb-webhook is not valid before"},
+		{name: "earliest expiration in the bundle", caBundle: append(append([]byte{}, valid...), expiring...), want: checkWarn, wantPrefix: "cert will expire at"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, message := checkCABundle(tt.caBundle, now)
+			if result != tt.want {
+				t.Errorf("checkCABundle() result = %s, want %s, message %s", result, tt.want, message)
+			}
+			if !strings.HasPrefix(message, tt.wantPrefix) {
+				t.Errorf("checkCABundle() message = %s, want prefix %s", message, tt.wantPrefix)
+			}
+		})
+	}
+}
+
+func TestCheckServingCerts(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	deploy := func(secrets ...string) *appsv1.Deployment {
+		d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: CORE, Namespace: "kubebb-system"}}
+		for _, s := range secrets {
+			d.Spec.Template.Spec.Volumes = append(d.Spec.Template.Spec.Volumes, corev1.Volume{
+				Name:         s,
+				VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: s}},
+			})
+		}
+		return d
+	}
+	secret := func(name string, data map[string][]byte) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kubebb-system"}, Data: data}
+	}
+	tests := []struct {
+		name string
+		objs []client.Object
+		// want is the expected result of each check
+		want map[string]string
+	}{
+		{
+			name: "no deployment",
+			want: map[string]string{"webhook serving cert": checkFail},
+		},
+		{
+			name: "no cert secret",
+			objs: []client.Object{deploy("config"), secret("config", map[string][]byte{"config.yaml": nil})},
+			want: map[string]string{"webhook serving cert": checkWarn},
+		},
+		{
+			name: "valid cert",
+			objs: []client.Object{deploy("webhook-server-cert"), secret("webhook-server-cert", map[string][]byte{corev1.TLSCertKey: newTestCert(t, now.AddDate(-1, 0, 0), now.AddDate(1, 0, 0))})},
+			want: map[string]string{"webhook serving cert webhook-server-cert": checkOK},
+		},
+		{
+			name: "expired cert",
+			objs: []client.Object{deploy("webhook-server-cert"), secret("webhook-server-cert", map[string][]byte{corev1.TLSCertKey: newTestCert(t, now.AddDate(-1, 0, 0), now.AddDate(0, 0, -1))})},
+			want: map[string]string{"webhook serving cert webhook-server-cert": checkFail},
+		},
+		{
+			name: "missing secret",
+			objs: []client.Object{deploy("webhook-server-cert")},
+			want: map[string]string{"webhook serving cert webhook-server-cert": checkFail},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.objs...).Build()
+			got := make(map[string]string)
+			for _, r := range checkServingCerts(context.TODO(), c, "kubebb-system", now) {
+				got[r.Check] = r.Result
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("checkServingCerts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckCRDs(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := apiextensionsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	established := apiextensionsv1.CustomResourceDefinitionStatus{
+		Conditions: []apiextensionsv1.CustomResourceDefinitionCondition{{Type: apiextensionsv1.Established, Status: apiextensionsv1.ConditionTrue}},
+	}
+	tests := []struct {
+		name  string
+		kinds []string
+		objs  []client.Object
+		// want is the expected result of each kind, the omitted kinds are expected to be OK
+		want map[string]string
+	}{
+		{
+			name:  "established",
+			kinds: []string{"Portal"},
+			objs: []client.Object{&apiextensionsv1.CustomResourceDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: "portals." + v1alpha1.Group},
+				Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+					Group:    v1alpha1.Group,
+					Names:    apiextensionsv1.CustomResourceDefinitionNames{Kind: "Portal"},
+					Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{Name: v1alpha1.Version, Served: true}},
+				},
+				Status: established,
+			}},
+		},
+		{
+			name:  "missing, not established and not served",
+			kinds: []string{"Component", "ComponentPlan", "Repository"},
+			objs: []client.Object{
+				&apiextensionsv1.CustomResourceDefinition{
+					ObjectMeta: metav1.ObjectMeta{Name: "componentplans." + v1alpha1.Group},
+					Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+						Group:    v1alpha1.Group,
+						Names:    apiextensionsv1.CustomResourceDefinitionNames{Kind: "ComponentPlan"},
+						Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{Name: v1alpha1.Version}},
+					},
+					Status: established,
+				},
+				&apiextensionsv1.CustomResourceDefinition{
+					ObjectMeta: metav1.ObjectMeta{Name: "repositories." + v1alpha1.Group},
+					Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+						Group:    v1alpha1.Group,
+						Names:    apiextensionsv1.CustomResourceDefinitionNames{Kind: "Repository"},
+						Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{Name: v1alpha1.Version, Served: true}},
+					},
+				},
+			},
+			want: map[string]string{"Component": checkFail, "ComponentPlan": checkFail, "Repository": checkFail},
+		},
+		{
+			name:  "crd of another group is ignored",
+			kinds: []string{"Portal"},
+			objs: []client.Object{&apiextensionsv1.CustomResourceDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: "portals.example.com"},
+				Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+					Group:    "example.com",
+					Names:    apiextensionsv1.CustomResourceDefinitionNames{Kind: "Portal"},
+					Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{Name: v1alpha1.Version, Served: true}},
+				},
+				Status: established,
+			}},
+			want: map[string]string{"Portal": checkFail},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.objs...).Build()
+			results := checkCRDs(context.Background(), c, tt.kinds)
+			if len(results) != len(tt.kinds) {
+				t.Fatalf("checkCRDs() returns %d results, want %d", len(results), len(tt.kinds))
+			}
+			for _, r := range results {
+				kind := strings.TrimPrefix(r.Check, "crd ")
+				want, ok := tt.want[kind]
+				if !ok {
+					want = checkOK
+				}
+				if r.Result != want {
+					t.Errorf("checkCRDs() %s = %s (%s), want %s", r.Check, r.Result, r.Message, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReleaseCRDKinds(t *testing.T) {
+	crd := func(group, kind string) string {
+		return "apiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nspec:\n  group: " + group + "\n  names:\n    kind: " + kind + "\n"
+	}
+	tests := []struct {
+		name string
+		rel  *release.Release
+		want []string
+	}{
+		{
+			name: "crds directory of the chart",
+			rel: &release.Release{Chart: &chart.Chart{Files: []*chart.File{
+				{Name: "crds/core.yaml", Data: []byte(crd(v1alpha1.Group, "Repository") + "---\n" + crd(v1alpha1.Group, "Component"))},
+				{Name: "crds/other.yaml", Data: []byte(crd("example.com", "Other"))},
+				{Name: "README.md", Data: []byte(crd(v1alpha1.Group, "Menu"))},
+			}}},
+			want: []string{"Component", "Repository"},
+		},
+		{
+			name: "crds rendered in the manifest",
+			rel: &release.Release{
+				Chart:    &chart.Chart{},
+				Manifest: crd(v1alpha1.Group, "ComponentPlan") + "---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n",
+			},
+			want: []string{"ComponentPlan"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := releaseCRDKinds(tt.rel)
+			if err != nil {
+				t.Fatalf("releaseCRDKinds() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("releaseCRDKinds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"k8s.io/utils/strings/slices"
+)
+
+func NewUninstallCmd() *cobra.Command {
+	var (
+		profileFile string
+		components  []string
+	)
+	cmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: "uninstall core, cluster-component, u4a and component-store",
+		Long: `uninstall core, cluster-component, u4a and component-store.
+
+The components are uninstalled in the reverse order of installation, core is the last one
+because it is responsible for uninstalling the releases of the other components.
+
+Exit with code 1 if any component fails to uninstall.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			profile, err := loadProfile(profileFile, cmd.Flags())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to load profile %s\n", err)
+				os.Exit(1)
+			}
+			failed := 0
+			for i := len(Components) - 1; i >= 0; i-- {
+				name := Components[i]
+				conf := profile.Config(name)
+				if !conf.Install || !slices.Contains(components, name) {
+					fmt.Printf("skip %s\n", name)
+					continue
+				}
+				fmt.Printf("uninstall %s, timeout %s\n", name, conf.Timeout)
+				stepCtx, cancel := context.WithTimeout(ctx, conf.Timeout)
+				err = GetInstaller(name)(&conf).Uninstall(stepCtx)
+				cancel()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "failed to uninstall %s with error %s\n", name, err)
+					failed++
+				}
+			}
+			if failed > 0 {
+				fmt.Fprintf(os.Stderr, "%d components failed to uninstall\n", failed)
+				os.Exit(1)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&profileFile, "profile", "", "the yaml profile used to install the components")
+	cmd.Flags().String("namespace", "default", "install namespace")
+	cmd.Flags().StringSliceVar(&components, "components", Components, "components to uninstall")
+	cmd.Flags().Duration("timeout", DefaultStepTimeout, "timeout of uninstalling each component")
+	return cmd
+}
+
+func NewUpgradeCmd() *cobra.Command {
+	var profileFile string
+	cmd := &cobra.Command{
+		Use:   "upgrade [COMPONENT...]",
+		Short: "upgrade the installed components",
+		Long: `upgrade the installed components.
+
+Core is upgraded to the specified version, or the latest one, with the values of the installation kept.
+The other components are upgraded by updating the version and values of their ComponentPlans.
+The versions and values can be described by a profile file with --profile, the flags set in the command line
+override the profile. If no component is specified, all installed components will be upgraded.
+
+Exit with code 1 if any component fails to upgrade, the remaining components are not upgraded.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			for _, name := range args {
+				if !slices.Contains(Components, name) {
+					fmt.Fprintf(os.Stderr, "unknown component %s, must be one of %s\n", name, strings.Join(Components, ","))
+					os.Exit(1)
+				}
+			}
+			profile, err := loadProfile(profileFile, cmd.Flags())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to load profile %s\n", err)
+				os.Exit(1)
+			}
+			for _, name := range Components {
+				if len(args) > 0 && !slices.Contains(args, name) {
+					continue
+				}
+				conf := profile.Config(name)
+				installer := GetInstaller(name)(&conf)
+				status, err := installer.Status(ctx)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "failed to get status of %s with error %s\n", name, err)
+					os.Exit(1)
+				}
+				if !status.Installed {
+					fmt.Printf("skip %s, it is not installed\n", name)
+					continue
+				}
+				fmt.Printf("upgrade %s, timeout %s\n", name, conf.Timeout)
+				stepCtx, cancel := context.WithTimeout(ctx, conf.Timeout)
+				err = installer.Upgrade(stepCtx)
+				cancel()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "failed to upgrade %s with error %s\n", name, err)
+					os.Exit(1)
+				}
+				fmt.Printf("\t%s upgrade done\n", name)
+			}
+		},
+	}
+	cmd.Flags().StringVar(&profileFile, "profile", "", "the yaml profile describing the version and values of each component")
+	cmd.Flags().String("namespace", "default", "install namespace")
+	cmd.Flags().String("core-version", "", "")
+	cmd.Flags().String("cc-version", "", "")
+	cmd.Flags().String("u4a-version", "", "")
+	cmd.Flags().String("cs-version", "", "")
+	cmd.Flags().Duration("timeout", DefaultStepTimeout, "timeout of upgrading each component, including waiting for it to be ready")
+	cmd.Flags().StringArray("core-extra-conf", nil, "values of core like helm --set, can be repeated")
+	cmd.Flags().StringArray("cluster-component-extra-conf", nil, "values of cluster-component like helm --set, can be repeated")
+	cmd.Flags().StringArray("u4a-extra-conf", nil, "values of u4a like helm --set, can be repeated")
+	cmd.Flags().StringArray("component-store-extra-conf", nil, "values of component-store like helm --set, can be repeated")
+	return cmd
+}
+
+func NewStatusCmd() *cobra.Command {
+	var profileFile string
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "show the status of core, cluster-component, u4a and component-store",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			profile, err := loadProfile(profileFile, cmd.Flags())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to load profile %s\n", err)
+				os.Exit(1)
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "NAME\tINSTALLED\tVERSION\tREADY\tMESSAGE")
+			for _, name := range Components {
+				conf := profile.Config(name)
+				status, err := GetInstaller(name)(&conf).Status(ctx)
+				if err != nil {
+					status.Message = err.Error()
+				}
+				fmt.Fprintf(w, "%s\t%t\t%s\t%t\t%s\n", name, status.Installed, status.Version, status.Ready, status.Message)
+			}
+			_ = w.Flush()
+		},
+	}
+	cmd.Flags().StringVar(&profileFile, "profile", "", "the yaml profile used to install the components")
+	cmd.Flags().String("namespace", "default", "install namespace")
+	return cmd
+}
@@ -0,0 +1,230 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/strings/slices"
+	"sigs.k8s.io/yaml"
+)
+
+// Profile describes how to install the components declaratively, so the same installation can be reproduced
+// by checking the profile file into git.
+//
+//	namespace: kubebb-system
+//	nodeName: kubebb-control-plane
+//	timeout: 10m
+//	components:
+//	  kubebb-core:
+//	    version: v0.1.0
+//	    valuesFiles:
+//	    - core-values.yaml
+//	    values:
+//	      image:
+//	        tag: v0.1.0
+//	  component-store:
+//	    enabled: false
+type Profile struct {
+	// Namespace is the namespace of the components without their own namespace
+	Namespace string `json:"namespace,omitempty"`
+	// NodeName is the node to run ingress-nginx of cluster-component and expose u4a
+	NodeName string `json:"nodeName,omitempty"`
+	// Timeout limits the time of each step, including waiting for the component to be ready
+	Timeout    *metav1.Duration             `json:"timeout,omitempty"`
+	Components map[string]*ComponentProfile `json:"components,omitempty"`
+
+	// dir is the directory of the profile file, which the relative values files are relative to
+	dir string
+}
+
+type ComponentProfile struct {
+	// Enabled defaults to true
+	Enabled *bool `json:"enabled,omitempty"`
+	// Version is the chart version, default is the latest one
+	Version string `json:"version,omitempty"`
+	// Namespace overrides the namespace of the profile
+	Namespace string `json:"namespace,omitempty"`
+	// ValuesFiles are the values files like helm --values, relative paths are relative to the profile file
+	ValuesFiles []string `json:"valuesFiles,omitempty"`
+	// Values are the inline values, which override the values files
+	Values map[string]interface{} `json:"values,omitempty"`
+	// Set are the values like helm --set, which override the values files and inline values
+	Set []string `json:"set,omitempty"`
+}
+
+// Flags of the install command which can override the profile
+var (
+	enableFlags = map[string]string{
+		"cluster-component": CLUSTERCOMPONENT,
+		"u4a":               U4A,
+		"component-store":   COMPONENTSTORE,
+	}
+	versionFlags = map[string]string{
+		"core-version": CORE,
+		"cc-version":   CLUSTERCOMPONENT,
+		"u4a-version":  U4A,
+		"cs-version":   COMPONENTSTORE,
+	}
+	extraConfFlags = map[string]string{
+		"core-extra-conf":              CORE,
+		"cluster-component-extra-conf": CLUSTERCOMPONENT,
+		"u4a-extra-conf":               U4A,
+		"component-store-extra-conf":   COMPONENTSTORE,
+	}
+)
+
+// DefaultProfile enables all components in the default namespace
+func DefaultProfile() *Profile {
+	p := &Profile{Namespace: "default"}
+	p.complete()
+	return p
+}
+
+// Export writes the effective profile to the file, or stdout if the path is "-".
+func (p *Profile) Export(path string) error {
+	out, err := yaml.Marshal(p)
+	if err != nil {
+		return err
+	}
+	if path == "-" {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	return os.WriteFile(path, out, 0o644)
+}
+
+// LoadProfile reads the profile file, unknown fields and components are rejected.
+func LoadProfile(path string) (*Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := &Profile{}
+	if err = yaml.UnmarshalStrict(data, p); err != nil {
+		return nil, fmt.Errorf("invalid profile %s: %w", path, err)
+	}
+	for name := range p.Components {
+		if !slices.Contains(Components, name) {
+			return nil, fmt.Errorf("invalid profile %s: unknown component %s, must be one of %s", path, name, strings.Join(Components, ","))
+		}
+	}
+	p.dir = filepath.Dir(path)
+	if p.Namespace == "" {
+		p.Namespace = "default"
+	}
+	p.complete()
+	return p, nil
+}
+
+// complete adds the missing components and sets the defaults, so the profile is the effective one.
+func (p *Profile) complete() {
+	if p.Timeout == nil {
+		p.Timeout = &metav1.Duration{Duration: DefaultStepTimeout}
+	}
+	if p.Components == nil {
+		p.Components = make(map[string]*ComponentProfile)
+	}
+	for _, name := range Components {
+		comp := p.Components[name]
+		if comp == nil {
+			comp = &ComponentProfile{}
+			p.Components[name] = comp
+		}
+		if comp.Enabled == nil {
+			enabled := true
+			comp.Enabled = &enabled
+		}
+	}
+}
+
+// ApplyFlags overrides the profile with the flags set in the command line
+func (p *Profile) ApplyFlags(flags *pflag.FlagSet) {
+	flags.Visit(func(f *pflag.Flag) {
+		value := f.Value.String()
+		switch f.Name {
+		case "namespace":
+			p.Namespace = value
+		case "node-name":
+			p.NodeName = value
+		case "timeout":
+			if d, err := time.ParseDuration(value); err == nil {
+				p.Timeout = &metav1.Duration{Duration: d}
+			}
+		}
+		if name, ok := enableFlags[f.Name]; ok {
+			enabled := value == "true"
+			p.Components[name].Enabled = &enabled
+		}
+		if name, ok := versionFlags[f.Name]; ok {
+			p.Components[name].Version = value
+		}
+		if name, ok := extraConfFlags[f.Name]; ok {
+			// each flag is kept as a whole like helm --set, so the values with commas, such as lists, are not broken
+			set, _ := flags.GetStringArray(f.Name)
+			p.Components[name].Set = append(p.Components[name].Set, set...)
+		}
+	})
+}
+
+// Config returns the config of the component to install
+func (p *Profile) Config(name string) Config {
+	conf := NewConf(name)
+	conf.Namespace = p.Namespace
+	conf.NodeName = p.NodeName
+	if p.Timeout != nil {
+		conf.Timeout = p.Timeout.Duration
+	}
+	comp := p.Components[name]
+	if comp == nil {
+		return conf
+	}
+	conf.Install = comp.Enabled == nil || *comp.Enabled
+	conf.Version = comp.Version
+	if comp.Namespace != "" {
+		conf.Namespace = comp.Namespace
+	}
+	for _, f := range comp.ValuesFiles {
+		if p.dir != "" && !filepath.IsAbs(f) && !strings.Contains(f, "://") {
+			f = filepath.Join(p.dir, f)
+		}
+		conf.Args.ValueFiles = append(conf.Args.ValueFiles, f)
+	}
+	// copy the set values, the callers append their own values to the config
+	conf.Args.Values = append([]string(nil), comp.Set...)
+	conf.Values = comp.Values
+	return conf
+}
+
+// loadProfile loads the profile file if specified, then applies the flags set in the command line.
+func loadProfile(path string, flags *pflag.FlagSet) (*Profile, error) {
+	p := DefaultProfile()
+	if path != "" {
+		var err error
+		if p, err = LoadProfile(path); err != nil {
+			return nil, err
+		}
+	}
+	p.ApplyFlags(flags)
+	return p, nil
+}
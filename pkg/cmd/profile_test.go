@@ -0,0 +1,254 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+func writeTestProfile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "profile.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+		check   func(t *testing.T, p *Profile, dir string)
+	}{
+		{
+			name: "defaults",
+			content: `
+components:
+  kubebb-core:
+    version: v0.1.0
+`,
+			check: func(t *testing.T, p *Profile, dir string) {
+				if p.Namespace != "default" {
+					t.Errorf("Namespace = %s, want default", p.Namespace)
+				}
+				if p.Timeout == nil || p.Timeout.Duration != DefaultStepTimeout {
+					t.Errorf("Timeout = %v, want %s", p.Timeout, DefaultStepTimeout)
+				}
+				for _, name := range Components {
+					comp := p.Components[name]
+					if comp == nil || comp.Enabled == nil || !*comp.Enabled {
+						t.Errorf("component %s is not enabled by default", name)
+					}
+				}
+				if p.Components[CORE].Version != "v0.1.0" {
+					t.Errorf("core version = %s, want v0.1.0", p.Components[CORE].Version)
+				}
+			},
+		},
+		{
+			name: "relative values files",
+			content: `
+namespace: kubebb-system
+components:
+  kubebb-core:
+    valuesFiles:
+    - core-values.yaml
+    - /etc/kubebb/values.yaml
+    - https://example.com/values.yaml
+`,
+			check: func(t *testing.T, p *Profile, dir string) {
+				want := []string{filepath.Join(dir, "core-values.yaml"), "/etc/kubebb/values.yaml", "https://example.com/values.yaml"}
+				if got := p.Config(CORE).Args.ValueFiles; !reflect.DeepEqual(got, want) {
+					t.Errorf("ValueFiles = %v, want %v", got, want)
+				}
+			},
+		},
+		{
+			name:    "unknown field",
+			content: "namespace: kubebb-system\nnodeIP: 172.18.0.2\n",
+			wantErr: "unknown field",
+		},
+		{
+			name: "unknown component field",
+			content: `
+components:
+  kubebb-core:
+    enable: false
+`,
+			wantErr: "unknown field",
+		},
+		{
+			name: "unknown component",
+			content: `
+components:
+  nginx:
+    version: v1.0.0
+`,
+			wantErr: "unknown component nginx",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTestProfile(t, tt.content)
+			p, err := LoadProfile(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("LoadProfile() error = %v, want to contain %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadProfile() error = %v", err)
+			}
+			tt.check(t, p, filepath.Dir(path))
+		})
+	}
+	if _, err := LoadProfile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Errorf("LoadProfile() of missing file returns no error")
+	}
+}
+
+func TestApplyFlags(t *testing.T) {
+	profile := `
+namespace: kubebb-system
+nodeName: node1
+timeout: 10m
+components:
+  kubebb-core:
+    version: v0.1.0
+    set:
+    - image.tag=v0.1.0
+  component-store:
+    enabled: false
+`
+	tests := []struct {
+		name  string
+		args  []string
+		check func(t *testing.T, p *Profile)
+	}{
+		{
+			name: "no flags keep the profile",
+			check: func(t *testing.T, p *Profile) {
+				conf := p.Config(CORE)
+				if conf.Namespace != "kubebb-system" || conf.NodeName != "node1" || conf.Timeout != 10*time.Minute || conf.Version != "v0.1.0" {
+					t.Errorf("Config() = %+v, want the profile", conf)
+				}
+				if p.Config(COMPONENTSTORE).Install {
+					t.Errorf("component-store is installed, want disabled by profile")
+				}
+			},
+		},
+		{
+			name: "flags override the profile",
+			args: []string{"--namespace", "kubebb", "--node-name", "node2", "--timeout", "5m", "--core-version", "v0.2.0", "--component-store=true", "--u4a=false"},
+			check: func(t *testing.T, p *Profile) {
+				conf := p.Config(CORE)
+				if conf.Namespace != "kubebb" || conf.NodeName != "node2" || conf.Timeout != 5*time.Minute || conf.Version != "v0.2.0" {
+					t.Errorf("Config() = %+v, want the flags", conf)
+				}
+				if !p.Config(COMPONENTSTORE).Install {
+					t.Errorf("component-store is not installed, want enabled by flag")
+				}
+				if p.Config(U4A).Install {
+					t.Errorf("u4a is installed, want disabled by flag")
+				}
+			},
+		},
+		{
+			name: "extra conf is appended after the profile and kept as a whole",
+			args: []string{"--core-extra-conf", "image.tag=v0.2.0", "--core-extra-conf", "args={a,b}", "--u4a-extra-conf", "a=b,c=d"},
+			check: func(t *testing.T, p *Profile) {
+				want := []string{"image.tag=v0.1.0", "image.tag=v0.2.0", "args={a,b}"}
+				if got := p.Config(CORE).Args.Values; !reflect.DeepEqual(got, want) {
+					t.Errorf("core set values = %v, want %v", got, want)
+				}
+				if got := p.Config(U4A).Args.Values; !reflect.DeepEqual(got, []string{"a=b,c=d"}) {
+					t.Errorf("u4a set values = %v, want [a=b,c=d]", got)
+				}
+				out, err := yaml.Marshal(p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				exported := &Profile{}
+				if err = yaml.UnmarshalStrict(out, exported); err != nil {
+					t.Fatal(err)
+				}
+				if got := exported.Components[CORE].Set; !reflect.DeepEqual(got, want) {
+					t.Errorf("exported core set values = %v, want %v", got, want)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := NewInstallCmd()
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			p, err := loadProfile(writeTestProfile(t, profile), cmd.Flags())
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, p)
+		})
+	}
+}
+
+func TestProfileConfig(t *testing.T) {
+	enabled := false
+	p := &Profile{
+		Namespace: "kubebb-system",
+		NodeName:  "node1",
+		Components: map[string]*ComponentProfile{
+			CORE: {Version: "v0.1.0", Set: []string{"a=b"}, Values: map[string]interface{}{"c": "d"}},
+			U4A:  {Namespace: "u4a-system", Enabled: &enabled},
+		},
+	}
+	p.complete()
+
+	core := p.Config(CORE)
+	if !core.Install || core.Namespace != "kubebb-system" || core.NodeName != "node1" || core.Version != "v0.1.0" || core.Timeout != DefaultStepTimeout {
+		t.Errorf("Config(%s) = %+v", CORE, core)
+	}
+	if !reflect.DeepEqual(core.Values, map[string]interface{}{"c": "d"}) {
+		t.Errorf("Config(%s).Values = %v", CORE, core.Values)
+	}
+	// the callers append to the set values, which must not change the profile
+	core.Args.Values = append(core.Args.Values, "e=f")
+	if got := p.Config(CORE).Args.Values; !reflect.DeepEqual(got, []string{"a=b"}) {
+		t.Errorf("Config(%s).Args.Values = %v, want [a=b]", CORE, got)
+	}
+
+	u4a := p.Config(U4A)
+	if u4a.Install || u4a.Namespace != "u4a-system" {
+		t.Errorf("Config(%s) = %+v, want disabled in u4a-system", U4A, u4a)
+	}
+
+	unknown := p.Config("unknown")
+	if !unknown.Install || unknown.Namespace != "kubebb-system" {
+		t.Errorf("Config(unknown) = %+v, want the defaults of the profile", unknown)
+	}
+}
@@ -18,143 +18,175 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
-	corev1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/types"
 )
 
 func NewInstallCmd() *cobra.Command {
 	var (
-		coreConf             = NewConf(CORE)
-		upgradeCoreConf      = NewConf(CORE)
-		clusterComponentConf = NewConf(CLUSTERCOMPONENT)
-		componentStoreConf   = NewConf(COMPONENTSTORE)
-		u4aConf              = NewConf(U4A)
-		namespace            string
-	)
-	var (
-		coreExtraConfString           string
-		clusterCompnentExtrConfString string
-		u4aExtraConfString            string
-		componentStroeExtraConfString string
-		kubeconfig                    string
-		nodeName                      string
+		profileFile string
+		export      string
 	)
 	cmd := &cobra.Command{
-		Use:  "install",
-		Long: "install core, cluster-component",
+		Use:   "install",
+		Short: "install core, cluster-component, u4a and component-store",
+		Long: `install core, cluster-component, u4a and component-store.
+
+The installation can be described by a profile file with --profile, the flags set in the command line
+override the profile. Use --export to write the effective profile without installing, for example:
+
+    corectl install --core-version v0.1.0 --component-store=false --export profile.yaml
+    corectl install --profile profile.yaml`,
 		Run: func(cmd *cobra.Command, args []string) {
 			background := context.Background()
-			if len(coreExtraConfString) > 0 {
-				coreConf.Args.Values = append(coreConf.Args.Values, strings.Split(coreExtraConfString, ",")...)
+			profile, err := loadProfile(profileFile, cmd.Flags())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to load profile %s\n", err)
+				os.Exit(1)
 			}
-			if len(clusterCompnentExtrConfString) > 0 {
-				clusterComponentConf.Args.Values = append(clusterComponentConf.Args.Values, strings.Split(clusterCompnentExtrConfString, ",")...)
-			}
-			if len(u4aExtraConfString) > 0 {
-				u4aConf.Args.Values = append(u4aConf.Args.Values, strings.Split(u4aExtraConfString, ",")...)
-			}
-			if len(componentStroeExtraConfString) > 0 {
-				componentStoreConf.Args.Values = append(componentStoreConf.Args.Values, strings.Split(componentStroeExtraConfString, ",")...)
+			if export != "" {
+				if err = profile.Export(export); err != nil {
+					fmt.Fprintf(os.Stderr, "failed to export profile %s\n", err)
+					os.Exit(1)
+				}
+				return
 			}
 
+			coreConf := profile.Config(CORE)
+			clusterComponentConf := profile.Config(CLUSTERCOMPONENT)
+			u4aConf := profile.Config(U4A)
+			componentStoreConf := profile.Config(COMPONENTSTORE)
+			// the upgrade of core only enables the webhook, the other values of the installation are reused.
+			upgradeCoreConf := NewConf(CORE)
+			upgradeCoreConf.Namespace = coreConf.Namespace
+			upgradeCoreConf.Version = coreConf.Version
+			upgradeCoreConf.Timeout = coreConf.Timeout
+
 			upgradeCoreConf.Install = false
 			upgradeCoreConf.Upgrade = false
 			// install cluster-component
 			if !clusterComponentConf.Install {
 				u4aConf.Install = false
 			} else {
-				clusterComponentConf.Args.Values = append(clusterComponentConf.Args.Values, fmt.Sprintf("%s=%s", mustAddForClusterComponent, nodeName))
+				clusterComponentConf.Args.Values = append(clusterComponentConf.Args.Values, fmt.Sprintf("%s=%s", mustAddForClusterComponent, profile.NodeName))
 				upgradeCoreConf.Upgrade = true
 				upgradeCoreConf.Args.Values = append(upgradeCoreConf.Args.Values, "webhook.enable=true")
 			}
 
-			var nodeIP string
+			// the ip to expose u4a is the one of the node running ingress-nginx, which is waited in installStep.
 			if !u4aConf.Install {
 				componentStoreConf.Install = false
-			} else {
-				once.Do(initCli)
-				node := corev1.Node{}
-				if err := cc.Get(background, types.NamespacedName{Name: nodeName}, &node); err != nil {
-					fmt.Fprintf(os.Stderr, "Failed to get node %s", nodeName)
-					return
-				}
+			}
 
-				for _, address := range node.Status.Addresses {
-					if address.Type == corev1.NodeInternalIP {
-						nodeIP = address.Address
-						break
-					}
+			taskConfs := []Config{coreConf, clusterComponentConf, upgradeCoreConf, u4aConf, componentStoreConf}
+			total := 0
+			for _, taskConf := range taskConfs {
+				if taskConf.Install || taskConf.Upgrade {
+					total++
 				}
 			}
-
 			step := 0
-			taskConfs := []Config{coreConf, clusterComponentConf, upgradeCoreConf, u4aConf, componentStoreConf}
+			var installed []installedStep
 			for idx := range taskConfs {
 				taskConf := taskConfs[idx]
-				taskConf.Namespace = namespace
-				taskConf.NodeName = nodeName
-				taskConf.NodeIP = nodeIP
+				taskConf.NodeName = profile.NodeName
 
 				if !taskConf.Install && !taskConf.Upgrade {
-					fmt.Printf("skip %s", taskConf.RegisterName)
+					fmt.Printf("skip %s\n", taskConf.RegisterName)
 					continue
 				}
 				instance := GetInstaller(taskConf.RegisterName)
-				if taskConf.Install && instance != nil {
-					installer := instance(&taskConf)
-					fmt.Printf("[%d] install %s", step, taskConf.RegisterName)
-					step++
-
-					if err := installer.Install(background); err != nil {
+				if instance == nil {
+					continue
+				}
+				step++
+				start := time.Now()
+				ctx, cancel := context.WithTimeout(background, taskConf.Timeout)
+				installer := instance(&taskConf)
+				if taskConf.Install {
+					fmt.Printf("[%d/%d] install %s, timeout %s\n", step, total, taskConf.RegisterName, taskConf.Timeout)
+					err = installStep(ctx, &taskConf, installer, clusterComponentConf.Namespace)
+					cancel()
+					if err != nil {
 						fmt.Fprintf(os.Stderr, "failed to install %s, with error %s. start to uninstall\n", taskConf.RegisterName, err)
-						// TODO uninstall
-						return
-					}
-					fmt.Printf("\t%s install done\n", taskConf.RegisterName)
-					if idx == 0 {
-						fmt.Println("\twait components")
-						// TODO: check components
-						time.Sleep(20 * time.Second)
+						// the failed step may be partially installed, so clean it up as well
+						if err = rollback(background, append(installed, installedStep{name: taskConf.RegisterName, installer: installer, timeout: taskConf.Timeout})); err != nil {
+							fmt.Fprintf(os.Stderr, "failed to rollback with error %s\n", err)
+						}
+						os.Exit(1)
 					}
+					installed = append(installed, installedStep{name: taskConf.RegisterName, installer: installer, timeout: taskConf.Timeout})
+					fmt.Printf("\t%s install done in %s\n", taskConf.RegisterName, time.Since(start).Round(time.Second))
 					continue
 				}
 
-				if taskConf.Upgrade && instance != nil {
-					installer := instance(&taskConf)
-					fmt.Printf("[%d] upgrade %s", step, taskConf.RegisterName)
-					step++
-
-					if err := installer.Upgrade(background); err != nil {
-						fmt.Fprintf(os.Stderr, "failed to upgrade %s, with error %s. start to uninstall\n", taskConf.RegisterName, err)
-						// TODO uninstall
-						return
+				fmt.Printf("[%d/%d] upgrade %s, timeout %s\n", step, total, taskConf.RegisterName, taskConf.Timeout)
+				err = installer.Upgrade(ctx)
+				cancel()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "failed to upgrade %s, with error %s. start to uninstall\n", taskConf.RegisterName, err)
+					if err = rollback(background, installed); err != nil {
+						fmt.Fprintf(os.Stderr, "failed to rollback with error %s\n", err)
 					}
-					fmt.Printf("\t%s upgrade done\n", taskConf.RegisterName)
+					os.Exit(1)
 				}
+				fmt.Printf("\t%s upgrade done in %s\n", taskConf.RegisterName, time.Since(start).Round(time.Second))
 			}
 		},
 	}
 
-	cmd.Flags().BoolVar(&clusterComponentConf.Install, "cluster-component", true, "install cluster-component?")
-	cmd.Flags().BoolVar(&u4aConf.Install, "u4a", true, "install u4a")
-	cmd.Flags().BoolVar(&componentStoreConf.Install, "component-store", true, "install component-store?")
-	cmd.Flags().StringVar(&namespace, "namespace", "default", "install namespace")
-	cmd.Flags().StringVar(&kubeconfig, "kubeconfig", "", "")
-	cmd.Flags().StringVar(&coreConf.Version, "core-version", "", "")
-	cmd.Flags().StringVar(&clusterComponentConf.Version, "cc-version", "", "")
-	cmd.Flags().StringVar(&u4aConf.Version, "u4a-version", "", "")
-	cmd.Flags().StringVar(&componentStoreConf.Version, "cs-version", "", "")
-	cmd.Flags().StringVar(&nodeName, "node-name", "", "")
-
-	cmd.Flags().StringVar(&coreExtraConfString, "core-extra-conf", "", "a=b,c=d")
-	cmd.Flags().StringVar(&clusterCompnentExtrConfString, "cluster-component-extra-conf", "", "a=b,c=d")
-	cmd.Flags().StringVar(&u4aExtraConfString, "u4a-extra-conf", "", "a=b,c=d")
-	cmd.Flags().StringVar(&componentStroeExtraConfString, "component-store-extra-conf", "", "a=b,c=d")
+	cmd.Flags().StringVar(&profileFile, "profile", "", "the yaml profile describing the installation of each component")
+	cmd.Flags().StringVar(&export, "export", "", "write the effective profile to the file instead of installing, - for stdout")
+	cmd.Flags().Bool("cluster-component", true, "install cluster-component?")
+	cmd.Flags().Bool("u4a", true, "install u4a")
+	cmd.Flags().Bool("component-store", true, "install component-store?")
+	cmd.Flags().String("namespace", "default", "install namespace")
+	cmd.Flags().String("kubeconfig", "", "")
+	cmd.Flags().String("core-version", "", "")
+	cmd.Flags().String("cc-version", "", "")
+	cmd.Flags().String("u4a-version", "", "")
+	cmd.Flags().String("cs-version", "", "")
+	cmd.Flags().String("node-name", "", "")
+	cmd.Flags().Duration("timeout", DefaultStepTimeout, "timeout of each step, including waiting for the component to be ready")
+
+	cmd.Flags().StringArray("core-extra-conf", nil, "values of core like helm --set, can be repeated")
+	cmd.Flags().StringArray("cluster-component-extra-conf", nil, "values of cluster-component like helm --set, can be repeated")
+	cmd.Flags().StringArray("u4a-extra-conf", nil, "values of u4a like helm --set, can be repeated")
+	cmd.Flags().StringArray("component-store-extra-conf", nil, "values of component-store like helm --set, can be repeated")
 	return cmd
 }
+
+// installStep installs the component, u4a is exposed by the ip of ingress-nginx installed by cluster-component
+// in ingressNamespace, so it waits for the ip first.
+func installStep(ctx context.Context, conf *Config, installer Installer, ingressNamespace string) (err error) {
+	if conf.RegisterName == U4A {
+		if conf.NodeIP, err = WaitIngressIP(ctx, cc, ingressNamespace); err != nil {
+			return err
+		}
+	}
+	return installer.Install(ctx)
+}
+
+type installedStep struct {
+	name      string
+	installer Installer
+	timeout   time.Duration
+}
+
+// rollback uninstalls the installed steps in reverse order, a failed step doesn't stop the others.
+func rollback(ctx context.Context, installed []installedStep) error {
+	var errs []error
+	for i := len(installed) - 1; i >= 0; i-- {
+		fmt.Printf("[rollback] uninstall %s, timeout %s\n", installed[i].name, installed[i].timeout)
+		stepCtx, cancel := context.WithTimeout(ctx, installed[i].timeout)
+		if err := installed[i].installer.Uninstall(stepCtx); err != nil {
+			errs = append(errs, fmt.Errorf("uninstall %s: %w", installed[i].name, err))
+		}
+		cancel()
+	}
+	return errors.Join(errs...)
+}
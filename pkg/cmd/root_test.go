@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeInstaller records the uninstallation of the component to check the order of rollback.
+type fakeInstaller struct {
+	name         string
+	uninstalled  *[]string
+	uninstallErr error
+}
+
+func (f *fakeInstaller) Description() string                    { return f.name }
+func (f *fakeInstaller) Install(context.Context) error          { return nil }
+func (f *fakeInstaller) Upgrade(context.Context) error          { return nil }
+func (f *fakeInstaller) Status(context.Context) (Status, error) { return Status{Name: f.name}, nil }
+func (f *fakeInstaller) Uninstall(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return errors.New("uninstall without timeout")
+	}
+	*f.uninstalled = append(*f.uninstalled, f.name)
+	return f.uninstallErr
+}
+
+func TestRollback(t *testing.T) {
+	tests := []struct {
+		name            string
+		failed          map[string]bool
+		wantUninstalled []string
+		wantErr         []string
+	}{
+		{
+			name:            "reverse order",
+			wantUninstalled: []string{U4A, CLUSTERCOMPONENT, CORE},
+		},
+		{
+			name:            "failed step doesn't stop the others",
+			failed:          map[string]bool{CLUSTERCOMPONENT: true},
+			wantUninstalled: []string{U4A, CLUSTERCOMPONENT, CORE},
+			wantErr:         []string{"uninstall " + CLUSTERCOMPONENT},
+		},
+		{
+			name:            "all errors are returned",
+			failed:          map[string]bool{U4A: true, CORE: true},
+			wantUninstalled: []string{U4A, CLUSTERCOMPONENT, CORE},
+			wantErr:         []string{"uninstall " + U4A, "uninstall " + CORE},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uninstalled := make([]string, 0)
+			installed := make([]installedStep, 0)
+			for _, name := range []string{CORE, CLUSTERCOMPONENT, U4A} {
+				f := &fakeInstaller{name: name, uninstalled: &uninstalled}
+				if tt.failed[name] {
+					f.uninstallErr = errors.New("failed")
+				}
+				installed = append(installed, installedStep{name: name, installer: f, timeout: time.Minute})
+			}
+			err := rollback(context.Background(), installed)
+			if !reflect.DeepEqual(uninstalled, tt.wantUninstalled) {
+				t.Errorf("rollback() uninstalled %v, want %v", uninstalled, tt.wantUninstalled)
+			}
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Errorf("rollback() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("rollback() error = nil, want %v", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("rollback() error = %v, want to contain %s", err, want)
+				}
+			}
+		})
+	}
+}
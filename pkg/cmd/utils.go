@@ -18,61 +18,279 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"sync"
+	"sort"
+	"strings"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/wait"
-	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubebb/core/api/v1alpha1"
+)
+
+const (
+	// DefaultStepTimeout is the default timeout of each step of install, upgrade and uninstall
+	DefaultStepTimeout = 5 * time.Minute
+
+	waitInterval = 5 * time.Second
 )
 
-func WaitDeployment(ctx context.Context, c client.Client, namespace string, deployNames []string, timeourSeconds int) error {
+// CheckFunc returns whether the waited resources are ready, and the current state of them for the progress output.
+// An error stops the waiting, so it should only be returned when the resources can never be ready.
+type CheckFunc func(ctx context.Context) (done bool, state string, err error)
+
+// WaitFor polls check until it is done or ctx is done, the state is printed every time it changes.
+func WaitFor(ctx context.Context, what string, check CheckFunc) error {
+	start := time.Now()
+	last := ""
+	fmt.Printf("\twait for %s\n", what)
+	err := wait.PollImmediateUntilWithContext(ctx, waitInterval, func(ctx context.Context) (bool, error) {
+		done, state, err := check(ctx)
+		if err != nil {
+			return false, err
+		}
+		if state != last {
+			fmt.Printf("\t  [%s] %s\n", time.Since(start).Round(time.Second), state)
+			last = state
+		}
+		return done, nil
+	})
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		return fmt.Errorf("timed out after %s waiting for %s, last state: %s", time.Since(start).Round(time.Second), what, last)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to wait for %s: %w", what, err)
+	}
+	fmt.Printf("\t%s ready after %s\n", what, time.Since(start).Round(time.Second))
+	return nil
+}
+
+// deploymentRolledOut returns true if all replicas of the latest generation are updated and available,
+// like kubectl rollout status.
+func deploymentRolledOut(dep *appsv1.Deployment) (bool, string) {
+	replicas := int32(1)
+	if dep.Spec.Replicas != nil {
+		replicas = *dep.Spec.Replicas
+	}
+	switch {
+	case dep.Status.ObservedGeneration < dep.Generation:
+		return false, "waiting for the rollout to be observed"
+	case dep.Status.UpdatedReplicas < replicas:
+		return false, fmt.Sprintf("%d/%d replicas updated", dep.Status.UpdatedReplicas, replicas)
+	case dep.Status.Replicas > dep.Status.UpdatedReplicas:
+		return false, fmt.Sprintf("%d old replicas pending termination", dep.Status.Replicas-dep.Status.UpdatedReplicas)
+	case dep.Status.AvailableReplicas < dep.Status.UpdatedReplicas:
+		return false, fmt.Sprintf("%d/%d replicas available", dep.Status.AvailableReplicas, dep.Status.UpdatedReplicas)
+	}
+	return true, fmt.Sprintf("%d/%d replicas available", dep.Status.AvailableReplicas, replicas)
+}
+
+// WaitDeployment waits for the rollout of the deployments to complete.
+func WaitDeployment(ctx context.Context, c client.Client, namespace string, deployNames []string) error {
 	if len(deployNames) == 0 {
 		return nil
 	}
+	return WaitFor(ctx, fmt.Sprintf("deployments %s in namespace %s", strings.Join(deployNames, ","), namespace), func(ctx context.Context) (bool, string, error) {
+		allDone := true
+		states := make([]string, 0, len(deployNames))
+		for _, name := range deployNames {
+			dep := appsv1.Deployment{}
+			if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &dep); err != nil {
+				allDone = false
+				states = append(states, fmt.Sprintf("%s: %s", name, getErrorState(err)))
+				continue
+			}
+			done, state := deploymentRolledOut(&dep)
+			allDone = allDone && done
+			states = append(states, fmt.Sprintf("%s: %s", name, state))
+		}
+		return allDone, strings.Join(states, "; "), nil
+	})
+}
+
+// WaitCRDsEstablished waits for the kubebb CRDs of the kinds to be established, so the resources can be created.
+func WaitCRDsEstablished(ctx context.Context, c client.Client, kinds []string) error {
+	return WaitFor(ctx, "kubebb CRDs", func(ctx context.Context) (bool, string, error) {
+		crds := &apiextensionsv1.CustomResourceDefinitionList{}
+		if err := c.List(ctx, crds); err != nil {
+			return false, getErrorState(err), nil
+		}
+		established := make(map[string]bool)
+		for _, crd := range crds.Items {
+			if crd.Spec.Group == v1alpha1.Group {
+				established[crd.Spec.Names.Kind] = crdEstablished(&crd)
+			}
+		}
+		pending := make([]string, 0)
+		for _, kind := range kinds {
+			if !established[kind] {
+				pending = append(pending, kind)
+			}
+		}
+		if len(pending) > 0 {
+			return false, fmt.Sprintf("not established: %s", strings.Join(pending, ",")), nil
+		}
+		return true, "all established", nil
+	})
+}
+
+// WaitWebhookServing waits for the CA bundles of the kubebb webhooks to be injected and the endpoints of
+// the webhook services to be ready. It returns immediately if the webhooks are not enabled.
+func WaitWebhookServing(ctx context.Context, c client.Client, namespace string) error {
+	return WaitFor(ctx, fmt.Sprintf("kubebb webhooks in namespace %s", namespace), func(ctx context.Context) (bool, string, error) {
+		webhooks, err := listKubebbWebhooks(ctx, c, namespace)
+		if err != nil {
+			return false, getErrorState(err), nil
+		}
+		if len(webhooks) == 0 {
+			return true, "no webhook enabled", nil
+		}
+		services := make(map[string]bool)
+		for _, w := range webhooks {
+			if len(w.ClientConfig.CABundle) == 0 {
+				return false, fmt.Sprintf("caBundle of %s is not injected", w.Name), nil
+			}
+			services[w.ClientConfig.Service.Name] = true
+		}
+		names := make([]string, 0, len(services))
+		for name := range services {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			endpoints := corev1.Endpoints{}
+			if err = c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &endpoints); err != nil {
+				return false, fmt.Sprintf("service %s: %s", name, getErrorState(err)), nil
+			}
+			ready := 0
+			for _, subset := range endpoints.Subsets {
+				ready += len(subset.Addresses)
+			}
+			if ready == 0 {
+				return false, fmt.Sprintf("service %s has no ready endpoints", name), nil
+			}
+		}
+		return true, fmt.Sprintf("services %s are serving", strings.Join(names, ",")), nil
+	})
+}
 
-	steps := timeourSeconds / 5
-	if timeourSeconds%5 != 0 {
-		steps++
+// WaitComponentPlanSucceeded waits for the ComponentPlan to be done with the current generation,
+// an error is returned if it fails.
+func WaitComponentPlanSucceeded(ctx context.Context, c client.Client, namespace, name string) error {
+	return WaitFor(ctx, fmt.Sprintf("componentplan %s/%s", namespace, name), func(ctx context.Context) (bool, string, error) {
+		plan := v1alpha1.ComponentPlan{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &plan); err != nil {
+			return false, getErrorState(err), nil
+		}
+		return componentPlanDone(&plan)
+	})
+}
+
+// componentPlanDone checks whether the ComponentPlan is done with the current generation. The observed generation
+// is updated once the release is installed or upgraded, so the plan is not done until both the action and
+// the verification are finished.
+func componentPlanDone(plan *v1alpha1.ComponentPlan) (bool, string, error) {
+	actioned := plan.Status.GetCondition(v1alpha1.ComponentPlanTypeActioned)
+	if plan.Status.ObservedGeneration < plan.Generation {
+		if len(plan.Status.Conditions) == 0 {
+			return false, "waiting for the controller", nil
+		}
+		return false, conditionState(actioned), nil
+	}
+	switch actioned.Reason {
+	case v1alpha1.ComponentPlanReasonWaitDo, v1alpha1.ComponentPlanReasonInstalling,
+		v1alpha1.ComponentPlanReasonUpgrading, v1alpha1.ComponentPlanReasonRollingBack:
+		return false, conditionState(actioned), nil
+	}
+	verified := plan.Status.GetCondition(v1alpha1.ComponentPlanTypeVerified)
+	switch verified.Reason {
+	case v1alpha1.ComponentPlanReasonWaitDo, v1alpha1.ComponentPlanReasonVerifying:
+		return false, conditionState(verified), nil
+	}
+	succeeded := plan.Status.GetCondition(v1alpha1.ComponentPlanTypeSucceeded)
+	if succeeded.Status != corev1.ConditionTrue {
+		// the reason of Succeeded is empty, the failed conditions tell why
+		failed := make([]string, 0)
+		for _, cond := range plan.Status.Conditions {
+			if cond.Type != v1alpha1.ComponentPlanTypeSucceeded && cond.Status != corev1.ConditionTrue {
+				failed = append(failed, conditionState(cond))
+			}
+		}
+		state := strings.Join(failed, "; ")
+		return false, state, fmt.Errorf("componentplan failed, %s", state)
 	}
+	return true, conditionState(actioned), nil
+}
 
-	backOff := wait.Backoff{
-		Steps:    steps,
-		Duration: time.Second * 5,
+// conditionState returns the type, reason and message of the condition for the progress output,
+// the status is used if the reason is empty.
+func conditionState(cond v1alpha1.Condition) string {
+	reason := string(cond.Reason)
+	if reason == "" {
+		reason = string(cond.Status)
+	}
+	state := fmt.Sprintf("%s: %s", cond.Type, reason)
+	if cond.Message != "" {
+		state += ", " + cond.Message
 	}
+	return state
+}
 
-	result := make(chan struct{}, len(deployNames))
-	var wg sync.WaitGroup
-	for _, name := range deployNames {
-		wg.Add(1)
-		go func(name string) {
-			defer wg.Done()
-			dep := appsv1.Deployment{}
-			if err := retry.OnError(backOff, func(error) bool {
-				return true
-			}, func() error {
-				if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, &dep); err != nil {
-					fmt.Printf("\t in namespace: %s not found deployment %s, wait\n", namespace, name)
-					return err
-				}
-				fmt.Printf("\tname: %s availableReplicas: %d --- replicas: %d\n", name, dep.Status.AvailableReplicas, *dep.Spec.Replicas)
-				if dep.Status.AvailableReplicas == *dep.Spec.Replicas {
-					return nil
-				}
-				return fmt.Errorf("deployment %s doest not match condition. availableReplicas: %d, replicas: %d",
-					name, dep.Status.AvailableReplicas, *dep.Spec.Replicas)
-			}); err != nil {
-				result <- struct{}{}
+// WaitIngressIP waits for a pod of the ingress-nginx controller to be ready and returns the ip of its node.
+func WaitIngressIP(ctx context.Context, c client.Client, namespace string) (ip string, err error) {
+	err = WaitFor(ctx, "ingress ip", func(ctx context.Context) (bool, string, error) {
+		dep := appsv1.Deployment{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ingressNginxDeployment}, &dep); err != nil {
+			return false, fmt.Sprintf("%s: %s", ingressNginxDeployment, getErrorState(err)), nil
+		}
+		selector, err := metav1.LabelSelectorAsSelector(dep.Spec.Selector)
+		if err != nil {
+			return false, "", err
+		}
+		pods := corev1.PodList{}
+		if err = c.List(ctx, &pods, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return false, getErrorState(err), nil
+		}
+		for _, pod := range pods.Items {
+			if pod.Status.HostIP != "" && podReady(&pod) {
+				ip = pod.Status.HostIP
+				return true, fmt.Sprintf("pod %s is ready on %s", pod.Name, ip), nil
 			}
-		}(name)
+		}
+		return false, fmt.Sprintf("no ready pod of %s", ingressNginxDeployment), nil
+	})
+	return ip, err
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+			return true
+		}
 	}
-	wg.Wait()
-	if len(result) > 0 {
-		return fmt.Errorf("not all deployment match condition")
+	return false
+}
+
+func crdEstablished(crd *apiextensionsv1.CustomResourceDefinition) bool {
+	for _, cond := range crd.Status.Conditions {
+		if cond.Type == apiextensionsv1.Established && cond.Status == apiextensionsv1.ConditionTrue {
+			return true
+		}
 	}
+	return false
+}
 
-	return nil
+func getErrorState(err error) string {
+	if apierrors.IsNotFound(err) {
+		return "not found"
+	}
+	return err.Error()
 }
@@ -0,0 +1,377 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/kubebb/core/api/v1alpha1"
+)
+
+func TestDeploymentRolledOut(t *testing.T) {
+	tests := []struct {
+		name      string
+		dep       *appsv1.Deployment
+		want      bool
+		wantState string
+	}{
+		{
+			name: "not observed",
+			dep: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32(1)},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+			},
+			wantState: "waiting for the rollout to be observed",
+		},
+		{
+			name: "not updated",
+			dep: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32(2)},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 1, AvailableReplicas: 2},
+			},
+			wantState: "1/2 replicas updated",
+		},
+		{
+			name: "old replicas pending termination",
+			dep: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32(1)},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 1, AvailableReplicas: 1},
+			},
+			wantState: "1 old replicas pending termination",
+		},
+		{
+			name: "not available",
+			dep: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32(2)},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 1},
+			},
+			wantState: "1/2 replicas available",
+		},
+		{
+			name: "rolled out",
+			dep: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32(2)},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: 2, UpdatedReplicas: 2, AvailableReplicas: 2},
+			},
+			want:      true,
+			wantState: "2/2 replicas available",
+		},
+		{
+			name: "replicas default to 1",
+			dep: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+			},
+			want:      true,
+			wantState: "1/1 replicas available",
+		},
+		{
+			name: "scaled to zero",
+			dep: &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+				Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32(0)},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 1},
+			},
+			want:      true,
+			wantState: "0/0 replicas available",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, state := deploymentRolledOut(tt.dep)
+			if got != tt.want || state != tt.wantState {
+				t.Errorf("deploymentRolledOut() = %v, %s, want %v, %s", got, state, tt.want, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestComponentPlanDone(t *testing.T) {
+	tests := []struct {
+		name      string
+		plan      *v1alpha1.ComponentPlan
+		want      bool
+		wantErr   bool
+		wantState string
+	}{
+		{
+			name: "not reconciled",
+			plan: &v1alpha1.ComponentPlan{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+			},
+			wantState: "waiting for the controller",
+		},
+		{
+			name: "generation not observed",
+			plan: &v1alpha1.ComponentPlan{
+				ObjectMeta: metav1.ObjectMeta{Generation: 2},
+				Status: v1alpha1.ComponentPlanStatus{ObservedGeneration: 1, ConditionedStatus: v1alpha1.ConditionedStatus{Conditions: []v1alpha1.Condition{
+					v1alpha1.ComponentPlanApproved(),
+					v1alpha1.ComponentPlanInstallSuccess(),
+					v1alpha1.ComponentPlanSucceeded(),
+				}}},
+			},
+			wantState: "Actioned: InstallSuccess",
+		},
+		{
+			name: "installing",
+			plan: &v1alpha1.ComponentPlan{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+				Status: v1alpha1.ComponentPlanStatus{ObservedGeneration: 1, ConditionedStatus: v1alpha1.ConditionedStatus{Conditions: []v1alpha1.Condition{
+					v1alpha1.ComponentPlanApproved(),
+					v1alpha1.ComponentPlanInstalling(),
+					v1alpha1.ComponentPlanFailed(nil),
+				}}},
+			},
+			wantState: "Actioned: Installing",
+		},
+		{
+			name: "installed and waiting for verification",
+			plan: &v1alpha1.ComponentPlan{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+				Status: v1alpha1.ComponentPlanStatus{ObservedGeneration: 1, ConditionedStatus: v1alpha1.ConditionedStatus{Conditions: []v1alpha1.Condition{
+					v1alpha1.ComponentPlanApproved(),
+					v1alpha1.ComponentPlanInstallSuccess(),
+					v1alpha1.ComponentPlanWaitVerify(),
+					v1alpha1.ComponentPlanFailed(nil),
+				}}},
+			},
+			wantState: "Verified: WaitDo",
+		},
+		{
+			name: "verifying",
+			plan: &v1alpha1.ComponentPlan{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+				Status: v1alpha1.ComponentPlanStatus{ObservedGeneration: 1, ConditionedStatus: v1alpha1.ConditionedStatus{Conditions: []v1alpha1.Condition{
+					v1alpha1.ComponentPlanApproved(),
+					v1alpha1.ComponentPlanUpgradeSuccess(),
+					v1alpha1.ComponentPlanVerifying(),
+					v1alpha1.ComponentPlanFailed(nil),
+				}}},
+			},
+			wantState: "Verified: Verifying",
+		},
+		{
+			name: "verified",
+			plan: &v1alpha1.ComponentPlan{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+				Status: v1alpha1.ComponentPlanStatus{ObservedGeneration: 1, ConditionedStatus: v1alpha1.ConditionedStatus{Conditions: []v1alpha1.Condition{
+					v1alpha1.ComponentPlanApproved(),
+					v1alpha1.ComponentPlanInstallSuccess(),
+					v1alpha1.ComponentPlanVerifySuccess(),
+					v1alpha1.ComponentPlanSucceeded(),
+				}}},
+			},
+			want:      true,
+			wantState: "Actioned: InstallSuccess",
+		},
+		{
+			name: "installed without verification",
+			plan: &v1alpha1.ComponentPlan{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+				Status: v1alpha1.ComponentPlanStatus{ObservedGeneration: 1, ConditionedStatus: v1alpha1.ConditionedStatus{Conditions: []v1alpha1.Condition{
+					v1alpha1.ComponentPlanApproved(),
+					v1alpha1.ComponentPlanInstallSuccess(),
+					v1alpha1.ComponentPlanSucceeded(),
+				}}},
+			},
+			want:      true,
+			wantState: "Actioned: InstallSuccess",
+		},
+		{
+			name: "install failed",
+			plan: &v1alpha1.ComponentPlan{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+				Status: v1alpha1.ComponentPlanStatus{ObservedGeneration: 1, ConditionedStatus: v1alpha1.ConditionedStatus{Conditions: []v1alpha1.Condition{
+					v1alpha1.ComponentPlanApproved(),
+					v1alpha1.ComponentPlanInstallFailed(errors.New("timed out")),
+					v1alpha1.ComponentPlanFailed(nil),
+				}}},
+			},
+			wantErr:   true,
+			wantState: "Actioned: InstallFailed, timed out",
+		},
+		{
+			name: "verify failed",
+			plan: &v1alpha1.ComponentPlan{
+				ObjectMeta: metav1.ObjectMeta{Generation: 1},
+				Status: v1alpha1.ComponentPlanStatus{ObservedGeneration: 1, ConditionedStatus: v1alpha1.ConditionedStatus{Conditions: []v1alpha1.Condition{
+					v1alpha1.ComponentPlanApproved(),
+					v1alpha1.ComponentPlanInstallSuccess(),
+					v1alpha1.ComponentPlanVerifyFailed(errors.New("pod not ready")),
+					v1alpha1.ComponentPlanFailed(nil),
+				}}},
+			},
+			wantErr:   true,
+			wantState: "Verified: VerifyFailed, pod not ready",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, state, err := componentPlanDone(tt.plan)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("componentPlanDone() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || state != tt.wantState {
+				t.Errorf("componentPlanDone() = %v, %s, want %v, %s", got, state, tt.want, tt.wantState)
+			}
+		})
+	}
+}
+
+func newTestClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func TestWaitComponentPlanSucceeded(t *testing.T) {
+	tests := []struct {
+		name    string
+		objs    []client.Object
+		wantErr string
+	}{
+		{
+			name: "succeeded",
+			objs: []client.Object{&v1alpha1.ComponentPlan{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: CLUSTERCOMPONENT, Generation: 1},
+				Status: v1alpha1.ComponentPlanStatus{ObservedGeneration: 1, ConditionedStatus: v1alpha1.ConditionedStatus{Conditions: []v1alpha1.Condition{
+					v1alpha1.ComponentPlanApproved(),
+					v1alpha1.ComponentPlanInstallSuccess(),
+					v1alpha1.ComponentPlanSucceeded(),
+				}}},
+			}},
+		},
+		{
+			name: "failed",
+			objs: []client.Object{&v1alpha1.ComponentPlan{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: CLUSTERCOMPONENT, Generation: 1},
+				Status: v1alpha1.ComponentPlanStatus{ObservedGeneration: 1, ConditionedStatus: v1alpha1.ConditionedStatus{Conditions: []v1alpha1.Condition{
+					v1alpha1.ComponentPlanApproved(),
+					v1alpha1.ComponentPlanInstallFailed(errors.New("timed out")),
+					v1alpha1.ComponentPlanFailed(nil),
+				}}},
+			}},
+			wantErr: "componentplan failed, Actioned: InstallFailed, timed out",
+		},
+		{
+			name: "pending verification times out",
+			objs: []client.Object{&v1alpha1.ComponentPlan{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: CLUSTERCOMPONENT, Generation: 1},
+				Status: v1alpha1.ComponentPlanStatus{ObservedGeneration: 1, ConditionedStatus: v1alpha1.ConditionedStatus{Conditions: []v1alpha1.Condition{
+					v1alpha1.ComponentPlanApproved(),
+					v1alpha1.ComponentPlanInstallSuccess(),
+					v1alpha1.ComponentPlanVerifying(),
+					v1alpha1.ComponentPlanFailed(nil),
+				}}},
+			}},
+			wantErr: "last state: Verified: Verifying",
+		},
+		{
+			name:    "not found times out",
+			wantErr: "last state: not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			err := WaitComponentPlanSucceeded(ctx, newTestClient(t, tt.objs...), "default", CLUSTERCOMPONENT)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("WaitComponentPlanSucceeded() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("WaitComponentPlanSucceeded() error = %v, want to contain %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWaitDeployment(t *testing.T) {
+	tests := []struct {
+		name    string
+		objs    []client.Object
+		wantErr string
+	}{
+		{
+			name: "rolled out",
+			objs: []client.Object{&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: CORE, Generation: 1},
+				Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32(1)},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+			}},
+		},
+		{
+			name: "not available",
+			objs: []client.Object{&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: CORE, Generation: 1},
+				Spec:       appsv1.DeploymentSpec{Replicas: pointer.Int32(1)},
+				Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 1, UpdatedReplicas: 1},
+			}},
+			wantErr: "last state: kubebb-core: 0/1 replicas available",
+		},
+		{
+			name:    "not found",
+			wantErr: "last state: kubebb-core: not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			err := WaitDeployment(ctx, newTestClient(t, tt.objs...), "default", []string{CORE})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("WaitDeployment() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("WaitDeployment() error = %v, want to contain %s", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+/*
+Copyright 2023 KubeAGI.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evaluator
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"knative.dev/pkg/apis"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+	"github.com/kubebb/core/pkg/utils"
+)
+
+const (
+	// BuiltinPipelineName is the pipeline name recorded for the dimensions checked by the built-in checks
+	BuiltinPipelineName = "builtin"
+	// BuiltinTaskType is the type of the Tasks produced by the built-in checks
+	BuiltinTaskType = "builtin"
+
+	DimensionSecurity    = "security"
+	DimensionReliability = "reliability"
+
+	// builtinPenalty is the score deducted for each failed check
+	builtinPenalty = 3
+)
+
+// BuiltinCheck checks the objects rendered from a chart
+type BuiltinCheck struct {
+	Name        string
+	Description string
+	Dimension   string
+	// Check returns the problems found in the object
+	Check func(obj *unstructured.Unstructured) []string
+}
+
+// BuiltinChecks are the static checks run when the Rating is Builtin
+var BuiltinChecks = []BuiltinCheck{
+	{
+		Name:        "privileged-containers",
+		Description: "containers should not run in privileged mode",
+		Dimension:   DimensionSecurity,
+		Check:       checkPrivileged,
+	},
+	{
+		Name:        "host-path-volumes",
+		Description: "pods should not mount hostPath volumes",
+		Dimension:   DimensionSecurity,
+		Check:       checkHostPath,
+	},
+	{
+		Name:        "rbac-wildcards",
+		Description: "roles should not grant wildcard apiGroups, resources or verbs",
+		Dimension:   DimensionSecurity,
+		Check:       checkRBACWildcards,
+	},
+	{
+		Name:        "resource-limits",
+		Description: "containers should set cpu and memory limits",
+		Dimension:   DimensionReliability,
+		Check:       checkResourceLimits,
+	},
+	{
+		Name:        "probes",
+		Description: "containers of long-running workloads should set liveness and readiness probes",
+		Dimension:   DimensionReliability,
+		Check:       checkProbes,
+	},
+	{
+		Name:        "latest-image-tags",
+		Description: "images should be pinned to a tag other than latest or a digest",
+		Dimension:   DimensionReliability,
+		Check:       checkLatestTag,
+	},
+}
+
+// RunBuiltinChecks runs the BuiltinChecks on the rendered manifest of a chart,
+// returns the Tasks with the `Dimension` as the key, each check is a Task.
+func RunBuiltinChecks(manifest string) (map[string][]corev1alpha1.Task, error) {
+	objs, err := utils.SplitYAML([]byte(manifest))
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]corev1alpha1.Task)
+	for _, check := range BuiltinChecks {
+		problems := make([]string, 0)
+		for _, obj := range objs {
+			for _, problem := range check.Check(obj) {
+				problems = append(problems, fmt.Sprintf("%s %s: %s", obj.GetKind(), obj.GetName(), problem))
+			}
+		}
+		cond := corev1alpha1.Condition{
+			Type:   corev1alpha1.ConditionType(apis.ConditionSucceeded),
+			Status: corev1.ConditionTrue,
+			Reason: corev1alpha1.RatingSucceeded,
+		}
+		if len(problems) > 0 {
+			cond.Status = corev1.ConditionFalse
+			cond.Reason = "Failed"
+			cond.Message = strings.Join(problems, "; ")
+		}
+		result[check.Dimension] = append(result[check.Dimension], corev1alpha1.Task{
+			Name:              check.Name,
+			Description:       check.Description,
+			Type:              BuiltinTaskType,
+			ConditionedStatus: corev1alpha1.ConditionedStatus{Conditions: []corev1alpha1.Condition{cond}},
+		})
+	}
+	return result, nil
+}
+
+// BuiltinEvaluation evaluates the Tasks of the built-in checks without LLM,
+// the score is 10 with builtinPenalty deducted for each failed check, and at least 1.
+func BuiltinEvaluation(tasks []corev1alpha1.Task) corev1alpha1.EvaluatorStatus {
+	result := &corev1alpha1.EvaluationResult{Score: 10}
+	for _, task := range tasks {
+		for _, cond := range task.Conditions {
+			if cond.Status == corev1.ConditionFalse {
+				result.Score -= builtinPenalty
+				result.Problems = append(result.Problems, fmt.Sprintf("%s: %s", task.Name, cond.Message))
+				result.Suggestions = append(result.Suggestions, task.Description)
+			}
+		}
+	}
+	if result.Score < 1 {
+		result.Score = 1
+	}
+	return corev1alpha1.EvaluatorStatus{
+		FinalRating: strconv.Itoa(result.Score),
+		English:     result,
+	}
+}
+
+// podSpec returns the pod spec of the workload, ok is false if the object is not a workload
+func podSpec(obj *unstructured.Unstructured) (spec corev1.PodSpec, ok bool) {
+	var fields []string
+	switch obj.GetKind() {
+	case "Pod":
+		fields = []string{"spec"}
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Job":
+		fields = []string{"spec", "template", "spec"}
+	case "CronJob":
+		fields = []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	default:
+		return spec, false
+	}
+	m, found, err := unstructured.NestedMap(obj.Object, fields...)
+	if err != nil || !found {
+		return spec, false
+	}
+	if err = runtime.DefaultUnstructuredConverter.FromUnstructured(m, &spec); err != nil {
+		return spec, false
+	}
+	return spec, true
+}
+
+func allContainers(spec corev1.PodSpec) []corev1.Container {
+	return append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+}
+
+func checkPrivileged(obj *unstructured.Unstructured) (problems []string) {
+	spec, ok := podSpec(obj)
+	if !ok {
+		return nil
+	}
+	for _, c := range allContainers(spec) {
+		if c.SecurityContext != nil && c.SecurityContext.Privileged != nil && *c.SecurityContext.Privileged {
+			problems = append(problems, fmt.Sprintf("container %s is privileged", c.Name))
+		}
+	}
+	return problems
+}
+
+func checkHostPath(obj *unstructured.Unstructured) (problems []string) {
+	spec, ok := podSpec(obj)
+	if !ok {
+		return nil
+	}
+	for _, v := range spec.Volumes {
+		if v.HostPath != nil {
+			problems = append(problems, fmt.Sprintf("volume %s mounts host path %s", v.Name, v.HostPath.Path))
+		}
+	}
+	return problems
+}
+
+func checkRBACWildcards(obj *unstructured.Unstructured) (problems []string) {
+	if obj.GetKind() != "Role" && obj.GetKind() != "ClusterRole" {
+		return nil
+	}
+	role := rbacv1.ClusterRole{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &role); err != nil {
+		return nil
+	}
+	for i, rule := range role.Rules {
+		fields := []struct {
+			name   string
+			values []string
+		}{{"apiGroups", rule.APIGroups}, {"resources", rule.Resources}, {"verbs", rule.Verbs}}
+		for _, field := range fields {
+			for _, v := range field.values {
+				if v == rbacv1.ResourceAll {
+					problems = append(problems, fmt.Sprintf("rule %d grants all %s", i, field.name))
+					break
+				}
+			}
+		}
+	}
+	return problems
+}
+
+func checkResourceLimits(obj *unstructured.Unstructured) (problems []string) {
+	spec, ok := podSpec(obj)
+	if !ok {
+		return nil
+	}
+	for _, c := range spec.Containers {
+		for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+			if _, ok := c.Resources.Limits[name]; !ok {
+				problems = append(problems, fmt.Sprintf("container %s has no %s limit", c.Name, name))
+			}
+		}
+	}
+	return problems
+}
+
+func checkProbes(obj *unstructured.Unstructured) (problems []string) {
+	if obj.GetKind() == "Job" || obj.GetKind() == "CronJob" {
+		return nil
+	}
+	spec, ok := podSpec(obj)
+	if !ok {
+		return nil
+	}
+	for _, c := range spec.Containers {
+		if c.LivenessProbe == nil {
+			problems = append(problems, fmt.Sprintf("container %s has no liveness probe", c.Name))
+		}
+		if c.ReadinessProbe == nil {
+			problems = append(problems, fmt.Sprintf("container %s has no readiness probe", c.Name))
+		}
+	}
+	return problems
+}
+
+func checkLatestTag(obj *unstructured.Unstructured) (problems []string) {
+	spec, ok := podSpec(obj)
+	if !ok {
+		return nil
+	}
+	for _, c := range allContainers(spec) {
+		if isLatestImage(c.Image) {
+			problems = append(problems, fmt.Sprintf("container %s uses image %s", c.Name, c.Image))
+		}
+	}
+	return problems
+}
+
+// isLatestImage returns true if the image has no digest and its tag is latest or empty
+func isLatestImage(image string) bool {
+	if strings.Contains(image, "@") {
+		return false
+	}
+	name := image[strings.LastIndex(image, "/")+1:]
+	idx := strings.LastIndex(name, ":")
+	return idx < 0 || name[idx+1:] == "latest"
+}
+
+// BuiltinCheckFunc pulls the chart of the rated version and runs the built-in checks on its rendered manifests
+type BuiltinCheckFunc func(ctx context.Context) (map[string][]corev1alpha1.Task, error)
+
+// BuiltinCheckPool runs the built-in checks of the Ratings in the background like the WorkerPool of helm,
+// because pulling and rendering the chart may take long and the reconcile of the Rating should not be blocked.
+type BuiltinCheckPool struct {
+	sync.Mutex
+	jobs map[string]*builtinCheckJob // key: namespace/name of the Rating
+}
+
+type builtinCheckJob struct {
+	uid     types.UID
+	rerun   string
+	cancel  context.CancelFunc
+	done    bool
+	results map[string][]corev1alpha1.Task
+	err     error
+}
+
+func NewBuiltinCheckPool() *BuiltinCheckPool {
+	return &BuiltinCheckPool{jobs: make(map[string]*builtinCheckJob)}
+}
+
+// Run starts the checks for the rerun of the Rating if they are not started, the checks of an old rerun are canceled.
+// It returns doing=true until the checks are done, then the results are returned once and the job is removed.
+func (p *BuiltinCheckPool) Run(ctx context.Context, rating *corev1alpha1.Rating, check BuiltinCheckFunc) (results map[string][]corev1alpha1.Task, doing bool, err error) {
+	key := rating.Namespace + "/" + rating.Name
+	rerun := rating.Annotations[corev1alpha1.RatingRerunAnnotation]
+	p.Lock()
+	defer p.Unlock()
+	job, ok := p.jobs[key]
+	if !ok || job.uid != rating.UID || job.rerun != rerun {
+		if ok {
+			job.cancel()
+		}
+		subCtx, cancel := context.WithCancel(ctx)
+		job = &builtinCheckJob{uid: rating.UID, rerun: rerun, cancel: cancel}
+		p.jobs[key] = job
+		go func() {
+			defer cancel()
+			results, err := check(subCtx)
+			p.Lock()
+			defer p.Unlock()
+			job.results, job.err, job.done = results, err, true
+		}()
+		return nil, true, nil
+	}
+	if !job.done {
+		return nil, true, nil
+	}
+	delete(p.jobs, key)
+	return job.results, false, job.err
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2023 KubeAGI.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evaluator
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+const builtinManifest = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: good
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: nginx:1.25
+        resources:
+          limits:
+            cpu: 100m
+            memory: 128Mi
+        livenessProbe:
+          tcpSocket:
+            port: 80
+        readinessProbe:
+          tcpSocket:
+            port: 80
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+spec:
+  template:
+    spec:
+      volumes:
+      - name: root
+        hostPath:
+          path: /
+      containers:
+      - name: agent
+        image: registry.io:5000/agent
+        securityContext:
+          privileged: true
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: backup
+            image: backup@sha256:0000000000000000000000000000000000000000000000000000000000000000
+            resources:
+              limits:
+                cpu: 100m
+                memory: 128Mi
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: admin
+rules:
+- apiGroups: [""]
+  resources: ["*"]
+  verbs: ["*"]
+`
+
+func TestRunBuiltinChecks(t *testing.T) {
+	results, err := RunBuiltinChecks(builtinManifest)
+	if err != nil {
+		t.Fatalf("RunBuiltinChecks() error = %v", err)
+	}
+	got := make(map[string]string)
+	for _, tasks := range results {
+		for _, task := range tasks {
+			if task.Type != BuiltinTaskType || len(task.Conditions) != 1 {
+				t.Fatalf("RunBuiltinChecks() got task %v", task)
+			}
+			got[task.Name] = task.Conditions[0].Message
+		}
+	}
+	want := map[string]string{
+		"privileged-containers": "DaemonSet agent: container agent is privileged",
+		"host-path-volumes":     "DaemonSet agent: volume root mounts host path /",
+		"rbac-wildcards":        "ClusterRole admin: rule 0 grants all resources; ClusterRole admin: rule 0 grants all verbs",
+		"resource-limits":       "DaemonSet agent: container agent has no cpu limit; DaemonSet agent: container agent has no memory limit",
+		"probes":                "DaemonSet agent: container agent has no liveness probe; DaemonSet agent: container agent has no readiness probe",
+		"latest-image-tags":     "DaemonSet agent: container agent uses image registry.io:5000/agent",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RunBuiltinChecks() got %v, want %v", got, want)
+	}
+	if len(results[DimensionSecurity]) != 3 || len(results[DimensionReliability]) != 3 {
+		t.Errorf("RunBuiltinChecks() got %d security and %d reliability tasks", len(results[DimensionSecurity]), len(results[DimensionReliability]))
+	}
+}
+
+func TestBuiltinEvaluation(t *testing.T) {
+	passed := corev1alpha1.Task{Name: "check", ConditionedStatus: corev1alpha1.ConditionedStatus{Conditions: []corev1alpha1.Condition{{Status: v1.ConditionTrue}}}}
+	failed := corev1alpha1.Task{Name: "check", ConditionedStatus: corev1alpha1.ConditionedStatus{Conditions: []corev1alpha1.Condition{{Status: v1.ConditionFalse}}}}
+	tests := []struct {
+		name  string
+		tasks []corev1alpha1.Task
+		want  string
+	}{
+		{name: "all passed", tasks: []corev1alpha1.Task{passed, passed}, want: "10"},
+		{name: "one failed", tasks: []corev1alpha1.Task{passed, failed}, want: "7"},
+		{name: "all failed", tasks: []corev1alpha1.Task{failed, failed, failed, failed}, want: "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuiltinEvaluation(tt.tasks); got.FinalRating != tt.want {
+				t.Errorf("BuiltinEvaluation() = %v, want %v", got.FinalRating, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsLatestImage(t *testing.T) {
+	tests := []struct {
+		image string
+		want  bool
+	}{
+		{image: "nginx", want: true},
+		{image: "nginx:latest", want: true},
+		{image: "registry.io:5000/nginx", want: true},
+		{image: "registry.io:5000/nginx:1.25"},
+		{image: "nginx@sha256:abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := isLatestImage(tt.image); got != tt.want {
+				t.Errorf("isLatestImage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuiltinCheckPool(t *testing.T) {
+	pool := NewBuiltinCheckPool()
+	rating := &corev1alpha1.Rating{ObjectMeta: metav1.ObjectMeta{Name: "rating", Namespace: "default", UID: "uid"}}
+	want := map[string][]corev1alpha1.Task{DimensionSecurity: {{Name: "privileged-containers"}}}
+	release := make(chan struct{})
+	canceled := make(chan struct{})
+	blocked := func(ctx context.Context) (map[string][]corev1alpha1.Task, error) {
+		<-ctx.Done()
+		close(canceled)
+		return nil, ctx.Err()
+	}
+	check := func(ctx context.Context) (map[string][]corev1alpha1.Task, error) {
+		<-release
+		return want, nil
+	}
+
+	if _, doing, _ := pool.Run(context.TODO(), rating, blocked); !doing {
+		t.Fatalf("Run() got doing false, want the checks running")
+	}
+	// a new rerun cancels the checks of the old one
+	rating.Annotations = map[string]string{corev1alpha1.RatingRerunAnnotation: "1"}
+	if _, doing, _ := pool.Run(context.TODO(), rating, check); !doing {
+		t.Fatalf("Run() got doing false, want the checks of the new rerun running")
+	}
+	<-canceled
+	if _, doing, _ := pool.Run(context.TODO(), rating, check); !doing {
+		t.Fatalf("Run() got doing false, want the checks still running")
+	}
+	close(release)
+	var got map[string][]corev1alpha1.Task
+	if err := wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		results, doing, err := pool.Run(context.TODO(), rating, check)
+		got = results
+		return !doing, err
+	}); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Run() got %v, want %v", got, want)
+	}
+}
@@ -20,22 +20,25 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"html/template"
+	"reflect"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/go-logr/logr"
 	arcadiav1 "github.com/kubeagi/arcadia/api/base/v1alpha1"
-	"github.com/kubeagi/arcadia/pkg/llms"
-	"github.com/kubeagi/arcadia/pkg/llms/zhipuai"
 	v1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
 	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
 )
@@ -44,6 +47,9 @@ const (
 	EvaluateRatingLabel      = corev1alpha1.Group + "/rating"
 	EvaluatePipelineRunLabel = corev1alpha1.Group + "/pipelinerun"
 	EvaluateDimensionLabel   = corev1alpha1.Group + "/dimension"
+	// PromptContentAnnotation keeps the content of the Prompt waiting for the evaluator to call the LLM by a Caller,
+	// it is removed after the response is saved into the Prompt status.
+	PromptContentAnnotation = corev1alpha1.Group + "/prompt-content"
 )
 
 var (
@@ -57,6 +63,18 @@ type EvaluateOptions struct {
 
 type EvaluateOptionsFunc func(*EvaluateOptions)
 
+// newEvaluateOptions returns the options with the default prompt template and output format
+func newEvaluateOptions(opts ...EvaluateOptionsFunc) *EvaluateOptions {
+	options := &EvaluateOptions{
+		template:     DefaultPromptTemplate,
+		outputFormat: DefaultOutputFormat,
+	}
+	for _, optFunc := range opts {
+		optFunc(options)
+	}
+	return options
+}
+
 func WithTemplate(template string) EvaluateOptionsFunc {
 	return func(o *EvaluateOptions) {
 		o.template = template
@@ -130,34 +148,33 @@ func (evaluator *Evaluator) EvaluateWithData(ctx context.Context, data *Data, op
 	if data.Dimension == "" {
 		return ErrEmptyDimension
 	}
-	// process options
-	options := &EvaluateOptions{
-		template:     DefaultPromptTemplate,
-		outputFormat: DefaultOutputFormat,
-	}
-	for _, optFunc := range opts {
-		optFunc(options)
-	}
+	options := newEvaluateOptions(opts...)
 	if data.OutputFormat == "" {
 		data.OutputFormat = options.outputFormat
 	}
 
 	// initialize a arcadia Prompt
-	tmpl := template.Must(template.New("Evaluate").Parse(options.template))
+	tmpl, err := ParseTemplate(options.template)
+	if err != nil {
+		return err
+	}
 	var output strings.Builder
-	err := tmpl.Execute(&output, data)
+	err = tmpl.Execute(&output, &TemplateData{Dimension: data.Dimension, Tasks: data.Tasks, OutputFormat: data.OutputFormat})
 	if err != nil {
-		return fmt.Errorf("invalid evaluate template: %s", err.Error())
+		return fmt.Errorf("%w: %s", ErrInvalidTemplate, err)
 	}
 	prompt := &arcadiav1.Prompt{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: evaluator.llm.Namespace,
-			Name:      data.Owner.Name + "-" + data.Dimension,
+			Name:      PromptName(data.Owner, data.Dimension),
 			Labels: map[string]string{
 				EvaluateRatingLabel:      data.Owner.Name,
 				EvaluatePipelineRunLabel: data.FromRun,
 				EvaluateDimensionLabel:   data.Dimension,
 			},
+			Annotations: map[string]string{
+				PromptTemplateHashAnnotation: templateHash(options.template, data.OutputFormat),
+			},
 		},
 		Spec: arcadiav1.PromptSpec{
 			LLM: &arcadiav1.TypedObjectReference{
@@ -170,24 +187,207 @@ func (evaluator *Evaluator) EvaluateWithData(ctx context.Context, data *Data, op
 	_ = controllerutil.SetOwnerReference(data.Owner, prompt, evaluator.scheme)
 
 	// build prompt params
-	switch evaluator.llm.Spec.Type {
-	case llms.ZhiPuAI:
-		params := zhipuai.DefaultModelParams()
-		params.Model = llms.ZhiPuAIPro
-		params.Prompt = []zhipuai.Prompt{
-			{Role: zhipuai.User, Content: output.String()},
-		}
-		prompt.Spec.ZhiPuAIParams = &params
-	default:
-		return fmt.Errorf("unsupported LLM type: %s", evaluator.llm.Spec.Type)
+	provider, err := GetProvider(evaluator.llm.Spec.Type)
+	if err != nil {
+		return err
+	}
+	if err = provider.BuildPrompt(evaluator.llm, prompt, output.String()); err != nil {
+		return err
+	}
+	_, isCaller := provider.(Caller)
+	if isCaller {
+		// the LLM is called by the PromptCaller when the Rating is reconciled, see CallPendingPrompts
+		prompt.Annotations[PromptContentAnnotation] = output.String()
 	}
 
 	// create or update a arcadia Prompt
 	err = evaluator.c.Create(ctx, prompt)
-	if err != nil && !k8serrors.IsAlreadyExists(err) {
+	if err == nil || !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	existing := &arcadiav1.Prompt{}
+	if err = evaluator.c.Get(ctx, client.ObjectKeyFromObject(prompt), existing); err != nil {
+		return err
+	}
+	prompt.ResourceVersion = existing.ResourceVersion
+	if err = evaluator.c.Update(ctx, prompt); err != nil {
+		return err
+	}
+	if isCaller && len(prompt.Status.Conditions) > 0 {
+		// the Prompt is evaluated again, drop the response of the last call
+		prompt.Status = arcadiav1.PromptStatus{}
+		return evaluator.c.Status().Update(ctx, prompt)
+	}
+	return nil
+}
+
+// EvaluateDimension evaluates the pipelinerun of the dimension in the current run of the Rating
+// with the prompt template of the dimension.
+func EvaluateDimension(ctx context.Context, logger logr.Logger, c client.Client, scheme *runtime.Scheme, rating *corev1alpha1.Rating, dimension string, tasks []corev1alpha1.Task) error {
+	if rating.Spec.LLM == nil {
+		return nil
+	}
+	opts, err := LoadPromptTemplate(ctx, c, rating.Namespace, rating.Spec.GetPromptTemplate(dimension))
+	if err != nil {
 		return err
 	}
-	return evaluator.c.Update(ctx, prompt)
+	llmNamespace := rating.Spec.LLM.GetNamespace(rating.Namespace)
+	arcEval, err := NewEvaluator(logger, ctx, c, scheme, types.NamespacedName{Namespace: llmNamespace, Name: rating.Spec.LLM.Name})
+	if err != nil {
+		return fmt.Errorf("failed to create arcadia evaluator: %w", err)
+	}
+	data := &Data{
+		Owner:     rating.DeepCopy(),
+		FromRun:   rating.Status.PipelineRuns[dimension].PipelineRunName,
+		Dimension: dimension,
+		Tasks:     tasks,
+	}
+	return arcEval.EvaluateWithData(ctx, data, opts...)
+}
+
+// PromptName returns the name of the Prompt evaluating the dimension in the current run of the Rating,
+// each run has its own Prompt so that arcadia calls the LLM again when the Rating is re-run.
+func PromptName(rating *corev1alpha1.Rating, dimension string) string {
+	if rating.Status.Run == 0 {
+		return rating.Name + "-" + dimension
+	}
+	return fmt.Sprintf("%s-%s-%d", rating.Name, dimension, rating.Status.Run)
+}
+
+// PromptCaller calls the LLM for the Prompts which wait for the evaluator in the background, like the WorkerPool of helm,
+// because an LLM may take minutes to answer and the reconcile of the Rating should not be blocked.
+type PromptCaller struct {
+	sync.Mutex
+	c       client.Client
+	logger  logr.Logger
+	running map[string]bool // key: namespace/name of the Prompt
+}
+
+func NewPromptCaller(logger logr.Logger, c client.Client) *PromptCaller {
+	return &PromptCaller{
+		c:       c,
+		logger:  logger,
+		running: make(map[string]bool),
+	}
+}
+
+// CallPendingPrompts starts calling the LLM for the Prompts of the current run of the Rating which wait for the evaluator,
+// because arcadia can not call the LLM of their type. The pending Prompts are recorded by PromptContentAnnotation,
+// so they are called again after restart. It returns true if any call is still running.
+func (p *PromptCaller) CallPendingPrompts(ctx context.Context, rating *corev1alpha1.Rating) (doing bool, err error) {
+	if rating.Spec.LLM == nil {
+		return false, nil
+	}
+	llmNamespace := rating.Spec.LLM.GetNamespace(rating.Namespace)
+	prompts := &arcadiav1.PromptList{}
+	if err = p.c.List(ctx, prompts, client.InNamespace(llmNamespace), client.MatchingLabels{EvaluateRatingLabel: rating.Name}); err != nil {
+		return false, err
+	}
+	var evaluator *Evaluator
+	var caller Caller
+	p.Lock()
+	defer p.Unlock()
+	for idx := range prompts.Items {
+		prompt := &prompts.Items[idx]
+		if _, ok := prompt.Annotations[PromptContentAnnotation]; !ok || !IsCurrentPrompt(rating, prompt.Labels[EvaluateDimensionLabel], prompt) {
+			continue
+		}
+		key := prompt.Namespace + "/" + prompt.Name
+		if p.running[key] {
+			doing = true
+			continue
+		}
+		if evaluator == nil {
+			llm := &arcadiav1.LLM{}
+			if err = p.c.Get(ctx, types.NamespacedName{Namespace: llmNamespace, Name: rating.Spec.LLM.Name}, llm); err != nil {
+				return doing, err
+			}
+			provider, err := GetProvider(llm.Spec.Type)
+			if err != nil {
+				return doing, err
+			}
+			var ok bool
+			if caller, ok = provider.(Caller); !ok {
+				return doing, nil
+			}
+			evaluator = &Evaluator{c: p.c, logger: p.logger, llm: llm}
+		}
+		p.running[key] = true
+		doing = true
+		go func(prompt *arcadiav1.Prompt) {
+			defer func() {
+				p.Lock()
+				delete(p.running, key)
+				p.Unlock()
+			}()
+			if err := evaluator.call(ctx, caller, prompt); err != nil {
+				p.logger.Error(err, "failed to call llm for the pending prompt", "prompt", key)
+			}
+		}(prompt)
+	}
+	return doing, nil
+}
+
+// call asks the LLM by the Caller and saves the response into the Prompt status like arcadia does,
+// then removes PromptContentAnnotation to mark the Prompt as called. The response is dropped
+// if the content of the Prompt changes during the call, so the new content is called again.
+func (evaluator *Evaluator) call(ctx context.Context, caller Caller, prompt *arcadiav1.Prompt) error {
+	logger := evaluator.logger.WithValues("prompt", prompt.Name, "llm", evaluator.llm.Name)
+	content := prompt.Annotations[PromptContentAnnotation]
+	callCtx, cancel := context.WithTimeout(ctx, DefaultCallTimeout)
+	defer cancel()
+	data, callErr := caller.Call(callCtx, evaluator.c, evaluator.llm, content)
+	cond := arcadiav1.Condition{
+		Type:               arcadiav1.TypeDone,
+		Status:             v1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             arcadiav1.ReasonReconcileSuccess,
+		Message:            "Finished CallLLM",
+	}
+	if callErr != nil {
+		logger.Error(callErr, "failed to call llm")
+		cond.Status = v1.ConditionFalse
+		cond.Reason = arcadiav1.ReasonReconcileError
+		cond.Message = callErr.Error()
+	}
+	outdated := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := evaluator.c.Get(ctx, client.ObjectKeyFromObject(prompt), prompt); err != nil {
+			return err
+		}
+		if outdated = prompt.Annotations[PromptContentAnnotation] != content; outdated {
+			return nil
+		}
+		prompt.Status.SetConditions(cond)
+		prompt.Status.Data = data
+		return evaluator.c.Status().Update(ctx, prompt)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update status of prompt %s: %w", prompt.Name, err)
+	}
+	if outdated {
+		logger.Info("prompt is changed during the call, drop the response")
+		return nil
+	}
+	called := prompt.DeepCopy()
+	delete(called.Annotations, PromptContentAnnotation)
+	return evaluator.c.Patch(ctx, called, client.MergeFrom(prompt))
+}
+
+// OnPromptCreate enqueues the Rating of the Prompt which waits for the evaluator to call the LLM.
+func OnPromptCreate(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	enqueuePendingPrompt(e.Object, q)
+}
+
+func enqueuePendingPrompt(obj client.Object, q workqueue.RateLimitingInterface) {
+	ratingName, ok := obj.GetLabels()[EvaluateRatingLabel]
+	if !ok {
+		return
+	}
+	if _, ok = obj.GetAnnotations()[PromptContentAnnotation]; !ok {
+		return
+	}
+	q.Add(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: ratingName}})
 }
 
 func OnPromptUpdate(logger logr.Logger, c client.Client) func(event.UpdateEvent, workqueue.RateLimitingInterface) {
@@ -201,6 +401,7 @@ func OnPromptUpdate(logger logr.Logger, c client.Client) func(event.UpdateEvent,
 			// not a rating prompt,do nothing
 			return
 		}
+		enqueuePendingPrompt(newPrompt, q)
 		// check rating
 		rating := &corev1alpha1.Rating{}
 		err = c.Get(context.TODO(), types.NamespacedName{Name: ratingName, Namespace: newPrompt.Namespace}, rating)
@@ -214,55 +415,275 @@ func OnPromptUpdate(logger logr.Logger, c client.Client) func(event.UpdateEvent,
 			// dimension is empty.not a rating prompt,do nothing
 			return
 		}
+		if !IsCurrentPrompt(rating, dimension, newPrompt) {
+			// the prompt of a previous run, do nothing
+			return
+		}
 
 		deepCopyRating := rating.DeepCopy()
 		// update rating
 		if deepCopyRating.Status.Evaluations == nil {
 			deepCopyRating.Status.Evaluations = make(map[string]corev1alpha1.EvaluatorStatus)
 		}
-		evaluationStatus := corev1alpha1.EvaluatorStatus{
-			Prompt:            newPrompt.Name,
-			ConditionedStatus: newPrompt.Status.ConditionedStatus,
+		evaluationStatus, parseErr := ParsePrompt(newPrompt)
+		if parseErr != nil {
+			logger.Error(parseErr, "failed to parse evaluation output", "rating", ratingName, "dimension", dimension)
 		}
-		// TODO: get the final rating when succ
-		// condition := newPrompt.Status.ConditionedStatus.GetCondition(arcadiav1.TypeDone)
-		// if condition.Status == corev1.ConditionTrue {
-		// 	switch rating.Spec.LLM {
-		// 	case llms.ZhiPuAI:
-		// 		resp := &zhipuai.Response{}
-		// 		err = json.Unmarshal(newPrompt.Status.Data, resp)
-		// 		if err != nil {
-		// 			logger.Error(err, "failed to unmarshal evaluation response")
-		// 			break
-		// 		}
-		// 		if resp.Data != nil && len(resp.Data.Choices) != 0 {
-		// 			output := &Output{}
-		// 			err = json.Unmarshal([]byte(resp.Data.Choices[0].Content), output)
-		// 			if err != nil {
-		// 				logger.Error(err, "failed to unmarshal evaluation response")
-		// 			}
-		// 			evaluationStatus.FinalRating = output.Rating
-		// 		}
-		// 	default:
-		// 	}
-		// }
-
+		deepCopyRating.Status.Evaluations[dimension] = evaluationStatus
+		cond := evaluationCondition(deepCopyRating, dimension, newPrompt, parseErr)
 		deepCopyRating.Status.ConditionedStatus = corev1alpha1.ConditionedStatus{
-			Conditions: []corev1alpha1.Condition{
-				{
-					Status:             v1.ConditionTrue,
-					LastTransitionTime: metav1.Now(),
-					Reason:             corev1alpha1.EvaluationSucceeded,
-					Message:            "Evaluation succeeded",
-					Type:               corev1alpha1.TypeReady,
-				},
-			},
+			Conditions: []corev1alpha1.Condition{cond},
+		}
+		deepCopyRating.Status.Score = ""
+		if cond.Status == v1.ConditionTrue {
+			deepCopyRating.Status.Score, _ = deepCopyRating.Score()
 		}
-		deepCopyRating.Status.Evaluations[dimension] = evaluationStatus
 		err = c.Status().Patch(context.TODO(), deepCopyRating, client.MergeFrom(rating))
 		if err != nil {
 			logger.Error(err, "failed to update rating status", "rating", ratingName)
 			return
 		}
+		if deepCopyRating.Status.Score != "" {
+			if err = RecordComponentScore(context.TODO(), c, deepCopyRating); err != nil {
+				logger.Error(err, "failed to record the score into component", "rating", ratingName)
+			}
+		}
+	}
+}
+
+// IsCurrentPrompt returns true if the Prompt evaluates the pipelinerun of the current run of the dimension.
+func IsCurrentPrompt(rating *corev1alpha1.Rating, dimension string, prompt *arcadiav1.Prompt) bool {
+	pipelineRun, ok := prompt.Labels[EvaluatePipelineRunLabel]
+	return !ok || pipelineRun == rating.Status.PipelineRuns[dimension].PipelineRunName
+}
+
+// OnTemplateUpdate enqueues the Ratings whose prompt template in the ConfigMap changes,
+// the succeeded pipelineruns are re-evaluated by ReevaluateOutdatedDimensions when the Ratings are reconciled.
+func OnTemplateUpdate(logger logr.Logger, c client.Client) func(event.UpdateEvent, workqueue.RateLimitingInterface) {
+	return func(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+		oldCM, ok := e.ObjectOld.(*v1.ConfigMap)
+		if !ok {
+			return
+		}
+		newCM := e.ObjectNew.(*v1.ConfigMap)
+		ratings := &corev1alpha1.RatingList{}
+		if err := c.List(context.TODO(), ratings, client.InNamespace(newCM.Namespace), client.MatchingFields{PromptTemplateIndexKey: newCM.Name}); err != nil {
+			logger.Error(err, "failed to list ratings for prompt template change", "configmap", newCM.Name)
+			return
+		}
+		for idx := range ratings.Items {
+			rating := &ratings.Items[idx]
+			for dimension := range rating.Status.PipelineRuns {
+				ref := rating.Spec.GetPromptTemplate(dimension)
+				if ref != nil && ref.ConfigMap == newCM.Name && templateChanged(ref, oldCM, newCM) {
+					q.Add(reconcile.Request{NamespacedName: types.NamespacedName{Namespace: rating.Namespace, Name: rating.Name}})
+					break
+				}
+			}
+		}
+	}
+}
+
+// TemplateDataChanged is the predicate of the ConfigMap watch, only the data changes of the ConfigMaps
+// holding the prompt templates of Ratings are handled.
+func TemplateDataChanged(logger logr.Logger, c client.Client) predicate.Funcs {
+	return predicate.Funcs{
+		CreateFunc:  func(event.CreateEvent) bool { return false },
+		DeleteFunc:  func(event.DeleteEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldCM, ok := e.ObjectOld.(*v1.ConfigMap)
+			if !ok {
+				return false
+			}
+			newCM, ok := e.ObjectNew.(*v1.ConfigMap)
+			if !ok || reflect.DeepEqual(oldCM.Data, newCM.Data) {
+				return false
+			}
+			ratings := &corev1alpha1.RatingList{}
+			if err := c.List(context.TODO(), ratings, client.InNamespace(newCM.Namespace), client.MatchingFields{PromptTemplateIndexKey: newCM.Name}, client.Limit(1)); err != nil {
+				logger.Error(err, "failed to list ratings for prompt template change", "configmap", newCM.Name)
+				return false
+			}
+			return len(ratings.Items) > 0
+		},
+	}
+}
+
+// ReevaluateOutdatedDimensions re-evaluates the succeeded pipelineruns of the dimensions whose Prompt is built with
+// a different prompt template from the one in the ConfigMap now. The evaluations of the dimensions are cleared
+// before re-evaluating, so the result of the new Prompt is not overwritten.
+func ReevaluateOutdatedDimensions(ctx context.Context, logger logr.Logger, c client.Client, scheme *runtime.Scheme, rating *corev1alpha1.Rating) error {
+	if rating.Spec.LLM == nil {
+		return nil
+	}
+	llmNamespace := rating.Spec.LLM.GetNamespace(rating.Namespace)
+	dimensions := make([]string, 0)
+	for dimension, runStatus := range rating.Status.PipelineRuns {
+		ref := rating.Spec.GetPromptTemplate(dimension)
+		if ref == nil || !runStatus.Succeeded() {
+			continue
+		}
+		prompt := &arcadiav1.Prompt{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: llmNamespace, Name: PromptName(rating, dimension)}, prompt); err != nil {
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		hash, ok := prompt.Annotations[PromptTemplateHashAnnotation]
+		if !ok {
+			// built before the hash is recorded
+			continue
+		}
+		opts, err := LoadPromptTemplate(ctx, c, rating.Namespace, ref)
+		if err != nil {
+			if IsInvalidTemplate(err) {
+				return invalidTemplate(ctx, c, rating, dimension, err)
+			}
+			return err
+		}
+		options := newEvaluateOptions(opts...)
+		if hash != templateHash(options.template, options.outputFormat) {
+			dimensions = append(dimensions, dimension)
+		}
+	}
+	if len(dimensions) == 0 {
+		return nil
+	}
+	sort.Strings(dimensions)
+	deepCopyRating := rating.DeepCopy()
+	for _, dimension := range dimensions {
+		delete(deepCopyRating.Status.Evaluations, dimension)
+	}
+	deepCopyRating.Status.Score = ""
+	deepCopyRating.Status.ConditionedStatus = corev1alpha1.ConditionedStatus{
+		Conditions: []corev1alpha1.Condition{{
+			Status:             v1.ConditionFalse,
+			LastTransitionTime: metav1.Now(),
+			Reason:             corev1alpha1.EvaluationRunning,
+			Message:            fmt.Sprintf("Prompt template of %s is updated, evaluation is running", strings.Join(dimensions, ",")),
+			Type:               corev1alpha1.TypeReady,
+		}},
+	}
+	if err := c.Status().Patch(ctx, deepCopyRating, client.MergeFrom(rating)); err != nil {
+		return err
+	}
+	for _, dimension := range dimensions {
+		err := EvaluateDimension(ctx, logger, c, scheme, deepCopyRating, dimension, rating.Status.PipelineRuns[dimension].Tasks)
+		if err == nil {
+			continue
+		}
+		if IsInvalidTemplate(err) {
+			return invalidTemplate(ctx, c, deepCopyRating, dimension, err)
+		}
+		return fmt.Errorf("failed to re-evaluate dimension %s with the updated prompt template: %w", dimension, err)
+	}
+	return nil
+}
+
+// invalidTemplate records the invalid prompt template of the dimension into the Rating status
+func invalidTemplate(ctx context.Context, c client.Client, rating *corev1alpha1.Rating, dimension string, err error) error {
+	cond := corev1alpha1.Condition{
+		Status:             v1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             corev1alpha1.EvaluationInvalidTemplate,
+		Message:            fmt.Sprintf("failed to load the prompt template of dimension %s: %s", dimension, err),
+		Type:               corev1alpha1.TypeReady,
+	}
+	if current := rating.Status.GetCondition(corev1alpha1.TypeReady); current.Reason == cond.Reason && current.Message == cond.Message {
+		return nil
+	}
+	invalidRating := rating.DeepCopy()
+	invalidRating.Status.ConditionedStatus = corev1alpha1.ConditionedStatus{Conditions: []corev1alpha1.Condition{cond}}
+	return c.Status().Patch(ctx, invalidRating, client.MergeFrom(rating))
+}
+
+func templateChanged(ref *corev1alpha1.PromptTemplateRef, oldCM, newCM *v1.ConfigMap) bool {
+	if oldCM.Data[ref.GetKey()] != newCM.Data[ref.GetKey()] {
+		return true
+	}
+	return ref.OutputFormatKey != "" && oldCM.Data[ref.OutputFormatKey] != newCM.Data[ref.OutputFormatKey]
+}
+
+// RecordComponentScore records the score of the Rating into the Component status for the rated version.
+// The Rating without a version label is ignored because we don't know which version it rates.
+func RecordComponentScore(ctx context.Context, c client.Client, rating *corev1alpha1.Rating) error {
+	version := rating.Labels[corev1alpha1.RatingComponentVersion]
+	if version == "" || rating.Status.Score == "" {
+		return nil
+	}
+	score := corev1alpha1.ComponentVersionScore{
+		Version:    version,
+		Score:      rating.Status.Score,
+		Dimensions: make(map[string]string, len(rating.Status.Evaluations)),
+		Rating:     rating.Name,
+		RatedAt:    metav1.Now(),
+	}
+	for dimension, evaluation := range rating.Status.Evaluations {
+		score.Dimensions[dimension] = evaluation.FinalRating
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		component := &corev1alpha1.Component{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: rating.Namespace, Name: rating.Spec.ComponentName}, component); err != nil {
+			return err
+		}
+		component.Status.SetVersionScore(score)
+		return c.Status().Update(ctx, component)
+	})
+}
+
+// ParsePrompt builds the EvaluatorStatus from the Prompt, the final rating is only set when the Prompt is done
+// and its output is parsed successfully, the error is not nil when the output can not be parsed.
+func ParsePrompt(prompt *arcadiav1.Prompt) (corev1alpha1.EvaluatorStatus, error) {
+	status := corev1alpha1.EvaluatorStatus{
+		Prompt:            prompt.Name,
+		ConditionedStatus: prompt.Status.ConditionedStatus,
+	}
+	if prompt.Status.GetCondition(arcadiav1.TypeDone).Status != v1.ConditionTrue {
+		return status, nil
+	}
+	content, err := GetPromptContent(prompt.Status.Data)
+	if err != nil {
+		return status, err
+	}
+	output, err := ParseOutput(content)
+	if err != nil {
+		return status, err
+	}
+	status.Chinese = output.Chinese
+	status.English = output.English
+	status.FinalRating = output.FinalRating()
+	return status, nil
+}
+
+// evaluationCondition returns the Ready condition of the Rating after the Prompt of the dimension is updated.
+// The Rating is succeeded only when all dimensions have a final rating.
+func evaluationCondition(rating *corev1alpha1.Rating, dimension string, prompt *arcadiav1.Prompt, parseErr error) corev1alpha1.Condition {
+	cond := corev1alpha1.Condition{
+		Status:             v1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             corev1alpha1.EvaluationRunning,
+		Message:            "Evaluation is running",
+		Type:               corev1alpha1.TypeReady,
+	}
+	done := prompt.Status.GetCondition(arcadiav1.TypeDone)
+	switch {
+	case parseErr != nil:
+		cond.Reason = corev1alpha1.EvaluationParseError
+		cond.Message = fmt.Sprintf("failed to parse the evaluation of dimension %s: %s", dimension, parseErr)
+	case done.Status == v1.ConditionFalse:
+		cond.Reason = corev1alpha1.EvaluationFailed
+		cond.Message = fmt.Sprintf("evaluation of dimension %s failed: %s", dimension, done.Message)
+	case done.Status == v1.ConditionTrue:
+		for d := range rating.Status.PipelineRuns {
+			if rating.Status.Evaluations[d].FinalRating == "" {
+				return cond
+			}
+		}
+		cond.Status = v1.ConditionTrue
+		cond.Reason = corev1alpha1.EvaluationSucceeded
+		cond.Message = "Evaluation succeeded"
 	}
+	return cond
 }
@@ -0,0 +1,412 @@
+/*
+Copyright 2023 KubeAGI.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	arcadiav1 "github.com/kubeagi/arcadia/api/base/v1alpha1"
+	"github.com/kubeagi/arcadia/pkg/llms"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+func TestParsePrompt(t *testing.T) {
+	tests := []struct {
+		name            string
+		prompt          *arcadiav1.Prompt
+		wantFinalRating string
+		wantErr         bool
+	}{
+		{
+			name:   "not done",
+			prompt: &arcadiav1.Prompt{ObjectMeta: metav1.ObjectMeta{Name: "rating-reliability"}},
+		},
+		{
+			name: "failed",
+			prompt: &arcadiav1.Prompt{
+				ObjectMeta: metav1.ObjectMeta{Name: "rating-reliability"},
+				Status:     arcadiav1.PromptStatus{ConditionedStatus: arcadiav1.ConditionedStatus{Conditions: []arcadiav1.Condition{{Type: arcadiav1.TypeDone, Status: v1.ConditionFalse, Message: "call llm failed"}}}},
+			},
+		},
+		{
+			name: "done",
+			prompt: &arcadiav1.Prompt{
+				ObjectMeta: metav1.ObjectMeta{Name: "rating-reliability"},
+				Status: arcadiav1.PromptStatus{
+					ConditionedStatus: arcadiav1.ConditionedStatus{Conditions: []arcadiav1.Condition{{Type: arcadiav1.TypeDone, Status: v1.ConditionTrue}}},
+					Data:              []byte(`{"english":{"score":9}}`),
+				},
+			},
+			wantFinalRating: "9",
+		},
+		{
+			name: "done but invalid output",
+			prompt: &arcadiav1.Prompt{
+				ObjectMeta: metav1.ObjectMeta{Name: "rating-reliability"},
+				Status: arcadiav1.PromptStatus{
+					ConditionedStatus: arcadiav1.ConditionedStatus{Conditions: []arcadiav1.Condition{{Type: arcadiav1.TypeDone, Status: v1.ConditionTrue}}},
+					Data:              []byte(`no json`),
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePrompt(tt.prompt)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParsePrompt() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got.Prompt != tt.prompt.Name || got.FinalRating != tt.wantFinalRating {
+				t.Errorf("ParsePrompt() = %v, want final rating %v", got, tt.wantFinalRating)
+			}
+		})
+	}
+}
+
+func TestEvaluationCondition(t *testing.T) {
+	rating := &corev1alpha1.Rating{
+		Status: corev1alpha1.RatingStatus{
+			PipelineRuns: map[string]corev1alpha1.PipelineRunStatus{"reliability": {}, "security": {}},
+			Evaluations: map[string]corev1alpha1.EvaluatorStatus{
+				"reliability": {FinalRating: "8"},
+			},
+		},
+	}
+	allDone := rating.DeepCopy()
+	allDone.Status.Evaluations["security"] = corev1alpha1.EvaluatorStatus{FinalRating: "7"}
+	running := &arcadiav1.Prompt{ObjectMeta: metav1.ObjectMeta{Name: "rating-reliability"}}
+	failed := running.DeepCopy()
+	failed.Status.SetConditions(arcadiav1.Condition{Type: arcadiav1.TypeDone, Status: v1.ConditionFalse, Message: "call llm failed"})
+	done := running.DeepCopy()
+	done.Status.SetConditions(arcadiav1.Condition{Type: arcadiav1.TypeDone, Status: v1.ConditionTrue})
+
+	tests := []struct {
+		name       string
+		rating     *corev1alpha1.Rating
+		prompt     *arcadiav1.Prompt
+		parseErr   error
+		wantReason corev1alpha1.ConditionReason
+		wantStatus v1.ConditionStatus
+	}{
+		{
+			name:       "prompt running",
+			rating:     rating,
+			prompt:     running,
+			wantReason: corev1alpha1.EvaluationRunning,
+			wantStatus: v1.ConditionFalse,
+		},
+		{
+			name:       "prompt failed",
+			rating:     rating,
+			prompt:     failed,
+			wantReason: corev1alpha1.EvaluationFailed,
+			wantStatus: v1.ConditionFalse,
+		},
+		{
+			name:       "parse error",
+			rating:     allDone,
+			prompt:     done,
+			parseErr:   errors.New("bad output"),
+			wantReason: corev1alpha1.EvaluationParseError,
+			wantStatus: v1.ConditionFalse,
+		},
+		{
+			name:       "other dimension not done",
+			rating:     rating,
+			prompt:     done,
+			wantReason: corev1alpha1.EvaluationRunning,
+			wantStatus: v1.ConditionFalse,
+		},
+		{
+			name:       "all dimensions done",
+			rating:     allDone,
+			prompt:     done,
+			wantReason: corev1alpha1.EvaluationSucceeded,
+			wantStatus: v1.ConditionTrue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := evaluationCondition(tt.rating, "reliability", tt.prompt, tt.parseErr)
+			if got.Reason != tt.wantReason || got.Status != tt.wantStatus || got.Type != corev1alpha1.TypeReady {
+				t.Errorf("evaluationCondition() = %v, want %v %v", got, tt.wantReason, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRecordComponentScore(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1alpha1.AddToScheme(scheme)
+	component := &corev1alpha1.Component{
+		ObjectMeta: metav1.ObjectMeta{Name: "repo.nginx", Namespace: "default"},
+		Status: corev1alpha1.ComponentStatus{
+			Scores: []corev1alpha1.ComponentVersionScore{{Version: "0.1.0", Score: "8.00"}},
+		},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(component).Build()
+
+	tests := []struct {
+		name      string
+		version   string
+		wantScore []string
+	}{
+		{name: "no version", wantScore: []string{"8.00"}},
+		{name: "new version", version: "0.2.0", wantScore: []string{"6.50", "8.00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rating := &corev1alpha1.Rating{
+				ObjectMeta: metav1.ObjectMeta{Name: "rating", Namespace: "default", Labels: map[string]string{}},
+				Spec:       corev1alpha1.RatingSpec{ComponentName: component.Name},
+				Status: corev1alpha1.RatingStatus{
+					Evaluations: map[string]corev1alpha1.EvaluatorStatus{"reliability": {FinalRating: "8"}, "security": {FinalRating: "5"}},
+					Score:       "6.50",
+				},
+			}
+			if tt.version != "" {
+				rating.Labels[corev1alpha1.RatingComponentVersion] = tt.version
+			}
+			if err := RecordComponentScore(context.TODO(), c, rating); err != nil {
+				t.Fatalf("RecordComponentScore() error = %v", err)
+			}
+			got := &corev1alpha1.Component{}
+			_ = c.Get(context.TODO(), client.ObjectKeyFromObject(component), got)
+			scores := make([]string, 0)
+			for _, s := range got.Status.Scores {
+				scores = append(scores, s.Score)
+			}
+			if !reflect.DeepEqual(scores, tt.wantScore) {
+				t.Errorf("RecordComponentScore() got scores %v, want %v", scores, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestPromptName(t *testing.T) {
+	rating := &corev1alpha1.Rating{ObjectMeta: metav1.ObjectMeta{Name: "rating"}}
+	if got := PromptName(rating, "reliability"); got != "rating-reliability" {
+		t.Errorf("PromptName() = %v, want rating-reliability", got)
+	}
+	rating.Status.Run = 2
+	if got := PromptName(rating, "reliability"); got != "rating-reliability-2" {
+		t.Errorf("PromptName() = %v, want rating-reliability-2", got)
+	}
+}
+
+func TestIsCurrentPrompt(t *testing.T) {
+	rating := &corev1alpha1.Rating{
+		Status: corev1alpha1.RatingStatus{
+			PipelineRuns: map[string]corev1alpha1.PipelineRunStatus{"reliability": {PipelineRunName: "rating.reliability.2"}},
+		},
+	}
+	tests := []struct {
+		name        string
+		pipelineRun string
+		want        bool
+	}{
+		{name: "no pipelinerun label", want: true},
+		{name: "current run", pipelineRun: "rating.reliability.2", want: true},
+		{name: "previous run", pipelineRun: "rating.reliability.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt := &arcadiav1.Prompt{ObjectMeta: metav1.ObjectMeta{Name: "rating-reliability"}}
+			if tt.pipelineRun != "" {
+				prompt.Labels = map[string]string{EvaluatePipelineRunLabel: tt.pipelineRun}
+			}
+			if got := IsCurrentPrompt(rating, "reliability", prompt); got != tt.want {
+				t.Errorf("IsCurrentPrompt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCallPendingPrompts(t *testing.T) {
+	typ := llms.LLMType("pending-test")
+	RegisterProvider(typ, &FakeProvider{Score: 7})
+	scheme := runtime.NewScheme()
+	_ = corev1alpha1.AddToScheme(scheme)
+	_ = arcadiav1.AddToScheme(scheme)
+	llm := &arcadiav1.LLM{ObjectMeta: metav1.ObjectMeta{Name: "llm", Namespace: "default"}, Spec: arcadiav1.LLMSpec{Type: typ}}
+	rating := &corev1alpha1.Rating{
+		ObjectMeta: metav1.ObjectMeta{Name: "rating", Namespace: "default"},
+		Spec:       corev1alpha1.RatingSpec{Evaluator: corev1alpha1.Evaluator{LLM: &arcadiav1.TypedObjectReference{Kind: "LLM", Name: llm.Name}}},
+		Status: corev1alpha1.RatingStatus{
+			PipelineRuns: map[string]corev1alpha1.PipelineRunStatus{"reliability": {PipelineRunName: "rating.reliability.2"}},
+		},
+	}
+	current := &arcadiav1.Prompt{ObjectMeta: metav1.ObjectMeta{
+		Name:      "rating-reliability-2",
+		Namespace: "default",
+		Labels: map[string]string{
+			EvaluateRatingLabel:      rating.Name,
+			EvaluatePipelineRunLabel: "rating.reliability.2",
+			EvaluateDimensionLabel:   "reliability",
+		},
+		Annotations: map[string]string{PromptContentAnnotation: "rate it"},
+	}}
+	previous := &arcadiav1.Prompt{ObjectMeta: metav1.ObjectMeta{
+		Name:      "rating-reliability-1",
+		Namespace: "default",
+		Labels: map[string]string{
+			EvaluateRatingLabel:      rating.Name,
+			EvaluatePipelineRunLabel: "rating.reliability.1",
+			EvaluateDimensionLabel:   "reliability",
+		},
+		Annotations: map[string]string{PromptContentAnnotation: "rate it"},
+	}}
+	called := &arcadiav1.Prompt{ObjectMeta: metav1.ObjectMeta{
+		Name:      "other",
+		Namespace: "default",
+		Labels: map[string]string{
+			EvaluateRatingLabel:      rating.Name,
+			EvaluatePipelineRunLabel: "rating.reliability.2",
+			EvaluateDimensionLabel:   "reliability",
+		},
+	}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(llm, current, previous, called).Build()
+
+	caller := NewPromptCaller(logr.Discard(), c)
+	doing, err := caller.CallPendingPrompts(context.TODO(), rating)
+	if err != nil || !doing {
+		t.Fatalf("CallPendingPrompts() = %v %v, want the call running", doing, err)
+	}
+	// the call runs in the background, the prompt is not pending after it is done
+	if err = wait.PollImmediate(10*time.Millisecond, 5*time.Second, func() (bool, error) {
+		doing, err := caller.CallPendingPrompts(context.TODO(), rating)
+		return !doing, err
+	}); err != nil {
+		t.Fatalf("CallPendingPrompts() error = %v", err)
+	}
+	got := &arcadiav1.Prompt{}
+	_ = c.Get(context.TODO(), client.ObjectKeyFromObject(current), got)
+	if _, ok := got.Annotations[PromptContentAnnotation]; ok {
+		t.Errorf("pending annotation should be removed after the call")
+	}
+	if status, err := ParsePrompt(got); err != nil || status.FinalRating != "7" {
+		t.Errorf("ParsePrompt() = %v %v, want final rating 7", status, err)
+	}
+	for _, p := range []*arcadiav1.Prompt{previous, called} {
+		_ = c.Get(context.TODO(), client.ObjectKeyFromObject(p), got)
+		if len(got.Status.Conditions) != 0 {
+			t.Errorf("prompt %s should not be called", p.Name)
+		}
+	}
+}
+
+func TestReevaluateOutdatedDimensions(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1alpha1.AddToScheme(scheme)
+	_ = arcadiav1.AddToScheme(scheme)
+	_ = v1.AddToScheme(scheme)
+	template := "rate {{.Dimension}}"
+	tests := []struct {
+		name       string
+		hash       map[string]string
+		data       map[string]string
+		wantReason corev1alpha1.ConditionReason
+	}{
+		{
+			name:       "template not changed",
+			hash:       map[string]string{PromptTemplateHashAnnotation: templateHash(template, DefaultOutputFormat)},
+			data:       map[string]string{"template": template},
+			wantReason: corev1alpha1.EvaluationSucceeded,
+		},
+		{
+			name:       "no hash recorded",
+			data:       map[string]string{"template": "rate it again"},
+			wantReason: corev1alpha1.EvaluationSucceeded,
+		},
+		{
+			name:       "template removed",
+			hash:       map[string]string{PromptTemplateHashAnnotation: templateHash(template, DefaultOutputFormat)},
+			data:       map[string]string{},
+			wantReason: corev1alpha1.EvaluationInvalidTemplate,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rating := &corev1alpha1.Rating{
+				ObjectMeta: metav1.ObjectMeta{Name: "rating", Namespace: "default"},
+				Spec: corev1alpha1.RatingSpec{Evaluator: corev1alpha1.Evaluator{
+					LLM:            &arcadiav1.TypedObjectReference{Kind: "LLM", Name: "llm"},
+					PromptTemplate: &corev1alpha1.PromptTemplateRef{ConfigMap: "templates"},
+				}},
+				Status: corev1alpha1.RatingStatus{
+					Run: 1,
+					PipelineRuns: map[string]corev1alpha1.PipelineRunStatus{"reliability": {
+						PipelineRunName:   "rating.reliability.1",
+						ConditionedStatus: corev1alpha1.ConditionedStatus{Conditions: []corev1alpha1.Condition{{Status: v1.ConditionTrue, Reason: corev1alpha1.RatingSucceeded}}},
+					}},
+					ConditionedStatus: corev1alpha1.ConditionedStatus{Conditions: []corev1alpha1.Condition{
+						{Type: corev1alpha1.TypeReady, Status: v1.ConditionTrue, Reason: corev1alpha1.EvaluationSucceeded},
+					}},
+				},
+			}
+			prompt := &arcadiav1.Prompt{ObjectMeta: metav1.ObjectMeta{Name: "rating-reliability-1", Namespace: "default", Annotations: tt.hash}}
+			cm := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "templates", Namespace: "default"}, Data: tt.data}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(rating, prompt, cm).Build()
+			if err := ReevaluateOutdatedDimensions(context.TODO(), logr.Discard(), c, scheme, rating); err != nil {
+				t.Fatalf("ReevaluateOutdatedDimensions() error = %v", err)
+			}
+			got := &corev1alpha1.Rating{}
+			_ = c.Get(context.TODO(), client.ObjectKeyFromObject(rating), got)
+			if reason := got.Status.GetCondition(corev1alpha1.TypeReady).Reason; reason != tt.wantReason {
+				t.Errorf("ReevaluateOutdatedDimensions() got reason %s, want %s", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestEnqueuePendingPrompt(t *testing.T) {
+	q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer q.ShutDown()
+	pending := &arcadiav1.Prompt{ObjectMeta: metav1.ObjectMeta{
+		Name:        "rating-reliability",
+		Namespace:   "default",
+		Labels:      map[string]string{EvaluateRatingLabel: "rating"},
+		Annotations: map[string]string{PromptContentAnnotation: "rate it"},
+	}}
+	OnPromptCreate(event.CreateEvent{Object: &arcadiav1.Prompt{ObjectMeta: metav1.ObjectMeta{Name: "called", Labels: pending.Labels}}}, q)
+	if q.Len() != 0 {
+		t.Fatalf("called prompt should not be enqueued")
+	}
+	OnPromptCreate(event.CreateEvent{Object: pending}, q)
+	item, _ := q.Get()
+	if want := (reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "rating"}}); item != want {
+		t.Errorf("enqueued %v, want %v", item, want)
+	}
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2023 KubeAGI.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evaluator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/kubeagi/arcadia/pkg/llms/zhipuai"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+var (
+	ErrEmptyPromptData = errors.New("empty prompt data")
+	ErrNoJSONObject    = errors.New("no json object found in the answer")
+	ErrEmptyOutput     = errors.New("neither chinese nor english result found in the answer")
+	ErrInvalidScore    = errors.New("score must be an integer from 1 to 10")
+)
+
+// Output is the answer of the LLM described by DefaultPromptTemplate
+type Output struct {
+	Chinese *corev1alpha1.EvaluationResult `json:"chinese,omitempty"`
+	English *corev1alpha1.EvaluationResult `json:"english,omitempty"`
+}
+
+// FinalRating returns the english score, or the chinese score if no english result
+func (o *Output) FinalRating() string {
+	if o.English != nil {
+		return strconv.Itoa(o.English.Score)
+	}
+	if o.Chinese != nil {
+		return strconv.Itoa(o.Chinese.Score)
+	}
+	return ""
+}
+
+// rawResult is more tolerant than EvaluationResult, LLMs may answer a score like "8", 8.0 or "8/10",
+// or a single string instead of a string array.
+type rawResult struct {
+	Score       json.RawMessage `json:"score"`
+	Suggestions json.RawMessage `json:"suggestions"`
+	Problems    json.RawMessage `json:"problems"`
+}
+
+// chatCompletionResponse is the response of the OpenAI-compatible chat completions API
+type chatCompletionResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+type rawOutput struct {
+	Chinese *rawResult `json:"chinese"`
+	English *rawResult `json:"english"`
+}
+
+// GetPromptContent gets the answer text of the LLM from the Prompt status data.
+// The data is the response of the LLM, if it is not a known response, the data is treated as the answer.
+func GetPromptContent(data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", ErrEmptyPromptData
+	}
+	resp := &zhipuai.Response{}
+	if err := json.Unmarshal(data, resp); err == nil && resp.Data != nil {
+		if len(resp.Data.Choices) == 0 {
+			return "", ErrEmptyPromptData
+		}
+		return resp.Data.Choices[0].Content, nil
+	}
+	chat := &chatCompletionResponse{}
+	if err := json.Unmarshal(data, chat); err == nil && chat.Choices != nil {
+		if len(chat.Choices) == 0 {
+			return "", ErrEmptyPromptData
+		}
+		return chat.Choices[0].Message.Content, nil
+	}
+	return string(data), nil
+}
+
+// ParseOutput parses the answer of the LLM. The answer may be wrapped in markdown code fences,
+// surrounded by explanations, or json encoded as a string again, like the content of zhipuai.
+func ParseOutput(content string) (*Output, error) {
+	content = strings.TrimSpace(content)
+	// zhipuai returns the content as a json string, like "\"{\\\"chinese\\\": ...}\""
+	if strings.HasPrefix(content, `"`) {
+		var unquoted string
+		if err := json.Unmarshal([]byte(content), &unquoted); err == nil {
+			content = unquoted
+		}
+	}
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end <= start {
+		return nil, ErrNoJSONObject
+	}
+	raw := &rawOutput{}
+	if err := json.Unmarshal([]byte(content[start:end+1]), raw); err != nil {
+		return nil, fmt.Errorf("invalid json object in the answer: %w", err)
+	}
+	if raw.Chinese == nil && raw.English == nil {
+		return nil, ErrEmptyOutput
+	}
+	output := &Output{}
+	var err error
+	if raw.Chinese != nil {
+		if output.Chinese, err = raw.Chinese.parse(); err != nil {
+			return nil, fmt.Errorf("chinese: %w", err)
+		}
+	}
+	if raw.English != nil {
+		if output.English, err = raw.English.parse(); err != nil {
+			return nil, fmt.Errorf("english: %w", err)
+		}
+	}
+	return output, nil
+}
+
+func (r *rawResult) parse() (*corev1alpha1.EvaluationResult, error) {
+	score, err := parseScore(r.Score)
+	if err != nil {
+		return nil, err
+	}
+	res := &corev1alpha1.EvaluationResult{Score: score}
+	if res.Suggestions, err = parseStrings(r.Suggestions); err != nil {
+		return nil, fmt.Errorf("suggestions: %w", err)
+	}
+	if res.Problems, err = parseStrings(r.Problems); err != nil {
+		return nil, fmt.Errorf("problems: %w", err)
+	}
+	return res, nil
+}
+
+func parseScore(raw json.RawMessage) (int, error) {
+	if len(raw) == 0 {
+		return 0, ErrInvalidScore
+	}
+	var f float64
+	if err := json.Unmarshal(raw, &f); err != nil {
+		var s string
+		if err = json.Unmarshal(raw, &s); err != nil {
+			return 0, ErrInvalidScore
+		}
+		// answers like "8/10"
+		s, _, _ = strings.Cut(strings.TrimSpace(s), "/")
+		if f, err = strconv.ParseFloat(strings.TrimSpace(s), 64); err != nil {
+			return 0, ErrInvalidScore
+		}
+	}
+	score := int(math.Round(f))
+	if score < 1 || score > 10 {
+		return 0, ErrInvalidScore
+	}
+	return score, nil
+}
+
+func parseStrings(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		var s string
+		if err = json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		list = []string{s}
+	}
+	res := make([]string, 0, len(list))
+	for _, s := range list {
+		if s = strings.TrimSpace(s); s != "" {
+			res = append(res, s)
+		}
+	}
+	return res, nil
+}
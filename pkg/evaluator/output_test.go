@@ -0,0 +1,167 @@
+/*
+Copyright 2023 KubeAGI.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evaluator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+func TestGetPromptContent(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "empty",
+			data:    "",
+			wantErr: ErrEmptyPromptData,
+		},
+		{
+			name: "zhipuai response",
+			data: `{"code":200,"data":{"choices":[{"content":"{\"english\":{}}","role":"assistant"}]},"success":true}`,
+			want: `{"english":{}}`,
+		},
+		{
+			name:    "zhipuai response without choices",
+			data:    `{"code":200,"data":{"choices":[]},"success":true}`,
+			wantErr: ErrEmptyPromptData,
+		},
+		{
+			name: "raw answer",
+			data: `the answer is {"english":{}}`,
+			want: `the answer is {"english":{}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetPromptContent([]byte(tt.data))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetPromptContent() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("GetPromptContent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *Output
+		wantErr bool
+	}{
+		{
+			name:    "standard answer",
+			content: `{"chinese": {"score":8, "suggestions":["增加探针"], "problems":["没有资源限制"]}, "english":{"score":8, "suggestions":["add probes"], "problems":["no resource limits"]}}`,
+			want: &Output{
+				Chinese: &corev1alpha1.EvaluationResult{Score: 8, Suggestions: []string{"增加探针"}, Problems: []string{"没有资源限制"}},
+				English: &corev1alpha1.EvaluationResult{Score: 8, Suggestions: []string{"add probes"}, Problems: []string{"no resource limits"}},
+			},
+		},
+		{
+			name:    "markdown code fence and explanations",
+			content: "Here is my answer:\n```json\n{\"english\":{\"score\":\"7/10\", \"suggestions\":\"add probes\", \"problems\":[\"\"]}}\n```\nThanks.",
+			want: &Output{
+				English: &corev1alpha1.EvaluationResult{Score: 7, Suggestions: []string{"add probes"}, Problems: []string{}},
+			},
+		},
+		{
+			name:    "json encoded string",
+			content: `"{\"english\":{\"score\":6.6}}"`,
+			want: &Output{
+				English: &corev1alpha1.EvaluationResult{Score: 7},
+			},
+		},
+		{
+			name:    "no json",
+			content: "I can not rate this component",
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			content: `{"english":{"score":8,}}`,
+			wantErr: true,
+		},
+		{
+			name:    "no result",
+			content: `{"rating": 8}`,
+			wantErr: true,
+		},
+		{
+			name:    "score out of range",
+			content: `{"english":{"score":100}}`,
+			wantErr: true,
+		},
+		{
+			name:    "missing score",
+			content: `{"english":{"suggestions":["add probes"]}}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseOutput(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseOutput() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseOutput() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputFinalRating(t *testing.T) {
+	tests := []struct {
+		name   string
+		output Output
+		want   string
+	}{
+		{
+			name:   "empty",
+			output: Output{},
+			want:   "",
+		},
+		{
+			name:   "english first",
+			output: Output{Chinese: &corev1alpha1.EvaluationResult{Score: 6}, English: &corev1alpha1.EvaluationResult{Score: 8}},
+			want:   "8",
+		},
+		{
+			name:   "only chinese",
+			output: Output{Chinese: &corev1alpha1.EvaluationResult{Score: 6}},
+			want:   "6",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.output.FinalRating(); got != tt.want {
+				t.Errorf("FinalRating() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -16,9 +16,102 @@ limitations under the License.
 
 package evaluator
 
-const DefaultOutputFormat = `json
-{"rating": "xxx","assesement": "xxx"}
-`
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"text/template"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+const (
+	// PromptTemplateIndexKey indexes Ratings by the ConfigMaps holding their prompt templates
+	PromptTemplateIndexKey = "spec.evaluator.promptTemplates"
+	// PromptTemplateHashAnnotation records the hash of the prompt template and output format which the Prompt is built with,
+	// the dimension is re-evaluated when the hash of the template in the ConfigMap is different.
+	PromptTemplateHashAnnotation = corev1alpha1.Group + "/prompt-template-hash"
+)
+
+var ErrInvalidTemplate = errors.New("invalid prompt template")
+
+// IsInvalidTemplate returns true if the error is caused by a missing or invalid prompt template
+func IsInvalidTemplate(err error) bool {
+	return errors.Is(err, ErrInvalidTemplate)
+}
+
+// TemplateData is the data which a prompt template can use
+type TemplateData struct {
+	Dimension    string
+	Tasks        []corev1alpha1.Task
+	OutputFormat string
+}
+
+// ParseTemplate parses the prompt template and checks it only uses the fields of TemplateData
+func ParseTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("Evaluate").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTemplate, err)
+	}
+	sample := &TemplateData{
+		Dimension:    "reliability",
+		Tasks:        []corev1alpha1.Task{{Name: "lint", Description: "lint the helm chart"}},
+		OutputFormat: DefaultOutputFormat,
+	}
+	if err = tmpl.Execute(io.Discard, sample); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTemplate, err)
+	}
+	return tmpl, nil
+}
+
+// templateHash returns the hash of the prompt template and output format
+func templateHash(template, outputFormat string) string {
+	sum := sha256.Sum256([]byte(template + "\x00" + outputFormat))
+	return hex.EncodeToString(sum[:8])
+}
+
+// LoadPromptTemplate returns the options to evaluate with the prompt template held in the ConfigMap,
+// the template is validated by ParseTemplate. No option is returned if ref is nil.
+func LoadPromptTemplate(ctx context.Context, c client.Client, namespace string, ref *corev1alpha1.PromptTemplateRef) ([]EvaluateOptionsFunc, error) {
+	if ref == nil {
+		return nil, nil
+	}
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.ConfigMap}, cm); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, fmt.Errorf("%w: configmap %s not found", ErrInvalidTemplate, ref.ConfigMap)
+		}
+		return nil, err
+	}
+	text, ok := cm.Data[ref.GetKey()]
+	if !ok {
+		return nil, fmt.Errorf("%w: no key %s in configmap %s", ErrInvalidTemplate, ref.GetKey(), ref.ConfigMap)
+	}
+	if _, err := ParseTemplate(text); err != nil {
+		return nil, fmt.Errorf("configmap %s: %w", ref.ConfigMap, err)
+	}
+	opts := []EvaluateOptionsFunc{WithTemplate(text)}
+	if ref.OutputFormatKey != "" {
+		format, ok := cm.Data[ref.OutputFormatKey]
+		if !ok {
+			return nil, fmt.Errorf("%w: no key %s in configmap %s", ErrInvalidTemplate, ref.OutputFormatKey, ref.ConfigMap)
+		}
+		opts = append(opts, WithOutputFormat(format))
+	}
+	return opts, nil
+}
+
+// DefaultOutputFormat is the json schema of the answer which ParseOutput parses,
+// a custom output format must keep the chinese and english results with the score, suggestions and problems.
+const DefaultOutputFormat = `{"chinese": {"score":1, "suggestions":[""], "problems":[""]}, "english":{"score":1, "suggestions":[""], "problems":[""]}}`
 
 const (
 	DefaultPromptTemplate = `I have a component in my repository and I want to evaluate the impact of the component's {{.Dimension}} dimension.
@@ -32,7 +125,7 @@ You need to rate the component (1-10, out of 10), give recommendations and point
 The scores are integer types, and the suggestions and problems are string arrays.
 At least one of the suggestions and problems must be given. Answers are output in json format:
 
-{"chinese": {"score":1, "suggestions":[""], "problems":[""]}, "english":{"score":1, "suggestions":[""], "problems":[""]}}
+{{.OutputFormat}}
 
 Note that the data corresponding to the chinese field in the json should be output in Chinese, and the data corresponding to the english field should be output in English.`
 
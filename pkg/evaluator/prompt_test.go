@@ -0,0 +1,117 @@
+/*
+Copyright 2023 KubeAGI.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evaluator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+func TestParseTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantErr bool
+	}{
+		{name: "default", text: DefaultPromptTemplate},
+		{name: "all fields", text: "rate {{.Dimension}} of {{range .Tasks}}{{.Name}}{{end}} as {{.OutputFormat}}"},
+		{name: "syntax error", text: "rate {{.Dimension}", wantErr: true},
+		{name: "unknown field", text: "rate {{.Owner.Name}}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTemplate(tt.text)
+			if (err != nil) != tt.wantErr || (err != nil && !IsInvalidTemplate(err)) {
+				t.Errorf("ParseTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadPromptTemplate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "prompts", Namespace: "default"},
+		Data: map[string]string{
+			"template":    "rate {{.Dimension}}, answer in {{.OutputFormat}}",
+			"security":    "rate {{.Owner}}",
+			"format":      "yaml",
+			"emptyformat": "",
+		},
+	}).Build()
+
+	tests := []struct {
+		name        string
+		ref         *corev1alpha1.PromptTemplateRef
+		wantOptions int
+		wantPrompt  string
+		wantErr     bool
+	}{
+		{name: "built-in template"},
+		{name: "configmap not found", ref: &corev1alpha1.PromptTemplateRef{ConfigMap: "none"}, wantErr: true},
+		{name: "key not found", ref: &corev1alpha1.PromptTemplateRef{ConfigMap: "prompts", Key: "none"}, wantErr: true},
+		{name: "invalid template", ref: &corev1alpha1.PromptTemplateRef{ConfigMap: "prompts", Key: "security"}, wantErr: true},
+		{name: "output format not found", ref: &corev1alpha1.PromptTemplateRef{ConfigMap: "prompts", OutputFormatKey: "none"}, wantErr: true},
+		{
+			name:        "default key",
+			ref:         &corev1alpha1.PromptTemplateRef{ConfigMap: "prompts"},
+			wantOptions: 1,
+			wantPrompt:  "rate reliability, answer in " + DefaultOutputFormat,
+		},
+		{
+			name:        "output format",
+			ref:         &corev1alpha1.PromptTemplateRef{ConfigMap: "prompts", OutputFormatKey: "format"},
+			wantOptions: 2,
+			wantPrompt:  "rate reliability, answer in yaml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := LoadPromptTemplate(context.TODO(), c, "default", tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadPromptTemplate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(opts) != tt.wantOptions {
+				t.Errorf("LoadPromptTemplate() got %d options, want %d", len(opts), tt.wantOptions)
+				return
+			}
+			if tt.wantOptions == 0 {
+				return
+			}
+			options := &EvaluateOptions{outputFormat: DefaultOutputFormat}
+			for _, opt := range opts {
+				opt(options)
+			}
+			tmpl, _ := ParseTemplate(options.template)
+			var output strings.Builder
+			_ = tmpl.Execute(&output, &TemplateData{Dimension: "reliability", OutputFormat: options.outputFormat})
+			if output.String() != tt.wantPrompt {
+				t.Errorf("LoadPromptTemplate() got prompt %q, want %q", output.String(), tt.wantPrompt)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2023 KubeAGI.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evaluator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	arcadiav1 "github.com/kubeagi/arcadia/api/base/v1alpha1"
+	"github.com/kubeagi/arcadia/pkg/llms"
+	"github.com/kubeagi/arcadia/pkg/llms/zhipuai"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// LocalLLM is a model server deployed by ourselves which serves the OpenAI-compatible API,
+	// like vLLM, FastChat or Ollama, no API key is required.
+	LocalLLM llms.LLMType = "local"
+	// FakeLLM answers a fixed rating without calling any LLM, for the tests without a real LLM.
+	FakeLLM llms.LLMType = "fake"
+
+	DefaultOpenAIModel = "gpt-3.5-turbo"
+	DefaultCallTimeout = 5 * time.Minute
+	DefaultFakeScore   = 8
+)
+
+var (
+	ErrUnsupportedLLM = errors.New("unsupported LLM type")
+	ErrNoEndpoint     = errors.New("no endpoint url provided by the LLM")
+)
+
+// Provider builds the arcadia Prompt to ask a kind of LLM.
+type Provider interface {
+	// BuildPrompt sets the params of the Prompt to ask the LLM with content.
+	BuildPrompt(llm *arcadiav1.LLM, prompt *arcadiav1.Prompt, content string) error
+}
+
+// Caller is implemented by the Providers whose LLM can not be called by arcadia.
+// The evaluator calls the LLM by itself and saves the response into the Prompt status,
+// so the response is handled the same as the Prompts called by arcadia.
+type Caller interface {
+	Call(ctx context.Context, c client.Client, llm *arcadiav1.LLM, content string) ([]byte, error)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[llms.LLMType]Provider{}
+)
+
+func init() {
+	RegisterProvider(llms.ZhiPuAI, &ZhiPuAIProvider{Model: llms.ZhiPuAIPro})
+	RegisterProvider(llms.OpenAI, &OpenAIProvider{Model: DefaultOpenAIModel, RequireAPIKey: true})
+	RegisterProvider(LocalLLM, &OpenAIProvider{})
+	RegisterProvider(FakeLLM, &FakeProvider{Score: DefaultFakeScore})
+}
+
+// RegisterProvider registers the Provider for the LLM type, the registered one with the same type is replaced.
+func RegisterProvider(typ llms.LLMType, provider Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[typ] = provider
+}
+
+// GetProvider returns the Provider registered for the LLM type.
+func GetProvider(typ llms.LLMType) (Provider, error) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	provider, ok := providers[typ]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedLLM, typ)
+	}
+	return provider, nil
+}
+
+// ZhiPuAIProvider asks ZhiPuAI by arcadia with the ZhiPuAIParams of the Prompt.
+type ZhiPuAIProvider struct {
+	Model string
+}
+
+func (p *ZhiPuAIProvider) BuildPrompt(_ *arcadiav1.LLM, prompt *arcadiav1.Prompt, content string) error {
+	params := zhipuai.DefaultModelParams()
+	if p.Model != "" {
+		params.Model = p.Model
+	}
+	params.Prompt = []zhipuai.Prompt{
+		{Role: zhipuai.User, Content: content},
+	}
+	prompt.Spec.ZhiPuAIParams = &params
+	return nil
+}
+
+// OpenAIProvider asks the OpenAI-compatible chat completions API of the LLM endpoint.
+// The model is the first model of the LLM, or Model if the LLM provides none.
+type OpenAIProvider struct {
+	Model         string
+	Temperature   float32
+	RequireAPIKey bool
+	HTTPClient    *http.Client
+}
+
+var _ Caller = (*OpenAIProvider)(nil)
+
+// BuildPrompt does nothing, arcadia Prompt has no params for OpenAI, the content is sent by Call.
+func (p *OpenAIProvider) BuildPrompt(_ *arcadiav1.LLM, _ *arcadiav1.Prompt, _ string) error {
+	return nil
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatCompletionRequest struct {
+	Model       string        `json:"model"`
+	Messages    []chatMessage `json:"messages"`
+	Temperature float32       `json:"temperature,omitempty"`
+}
+
+func (p *OpenAIProvider) Call(ctx context.Context, c client.Client, llm *arcadiav1.LLM, content string) ([]byte, error) {
+	if llm.Spec.Endpoint == nil {
+		return nil, ErrNoEndpoint
+	}
+	url := llm.Spec.Endpoint.InternalURL
+	if url == "" {
+		url = llm.Spec.Endpoint.URL
+	}
+	if url == "" {
+		return nil, ErrNoEndpoint
+	}
+	apiKey, err := llm.AuthAPIKey(ctx, c, nil)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey == "" && p.RequireAPIKey {
+		return nil, errors.New("no api key provided by the LLM")
+	}
+
+	body, err := json.Marshal(chatCompletionRequest{
+		Model:       p.model(llm),
+		Messages:    []chatMessage{{Role: "user", Content: content}},
+		Temperature: p.Temperature,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(url, "/")+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	httpClient := p.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("call %s failed with status %d: %s", req.URL, resp.StatusCode, data)
+	}
+	return data, nil
+}
+
+func (p *OpenAIProvider) model(llm *arcadiav1.LLM) string {
+	if len(llm.Spec.Models) > 0 {
+		return llm.Spec.Models[0]
+	}
+	return p.Model
+}
+
+// FakeProvider answers the same Score for every evaluation, the answer is in the format of DefaultPromptTemplate.
+type FakeProvider struct {
+	Score int
+}
+
+var _ Caller = (*FakeProvider)(nil)
+
+// BuildPrompt does nothing, the answer is made by Call.
+func (p *FakeProvider) BuildPrompt(_ *arcadiav1.LLM, _ *arcadiav1.Prompt, _ string) error {
+	return nil
+}
+
+func (p *FakeProvider) Call(_ context.Context, _ client.Client, _ *arcadiav1.LLM, _ string) ([]byte, error) {
+	result := map[string]interface{}{"score": p.Score, "suggestions": []string{}, "problems": []string{}}
+	return json.Marshal(map[string]interface{}{"chinese": result, "english": result})
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2023 KubeAGI.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package evaluator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	arcadiav1 "github.com/kubeagi/arcadia/api/base/v1alpha1"
+	"github.com/kubeagi/arcadia/pkg/llms"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		typ     llms.LLMType
+		wantErr error
+	}{
+		{name: "zhipuai", typ: llms.ZhiPuAI},
+		{name: "openai", typ: llms.OpenAI},
+		{name: "local", typ: LocalLLM},
+		{name: "fake", typ: FakeLLM},
+		{name: "unknown", typ: llms.DashScope, wantErr: ErrUnsupportedLLM},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetProvider(tt.typ)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetProvider() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr == nil && got == nil {
+				t.Errorf("GetProvider() got nil provider")
+			}
+		})
+	}
+}
+
+func TestRegisterProvider(t *testing.T) {
+	typ := llms.LLMType("test")
+	provider := &FakeProvider{Score: 3}
+	RegisterProvider(typ, provider)
+	got, err := GetProvider(typ)
+	if err != nil || got != provider {
+		t.Errorf("GetProvider() = %v %v, want %v", got, err, provider)
+	}
+}
+
+func TestZhiPuAIProviderBuildPrompt(t *testing.T) {
+	prompt := &arcadiav1.Prompt{}
+	if err := (&ZhiPuAIProvider{Model: llms.ZhiPuAIPro}).BuildPrompt(nil, prompt, "rate it"); err != nil {
+		t.Fatalf("BuildPrompt() error = %v", err)
+	}
+	params := prompt.Spec.ZhiPuAIParams
+	if params == nil || params.Model != llms.ZhiPuAIPro || len(params.Prompt) != 1 || params.Prompt[0].Content != "rate it" {
+		t.Errorf("BuildPrompt() got params %v", params)
+	}
+}
+
+func TestOpenAIProviderCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		req := chatCompletionRequest{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if r.Header.Get("Authorization") != "Bearer secret" || req.Model != "gateway-model" || req.Messages[0].Content != "rate it" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{\"english\":{\"score\":9}}"}}]}`))
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	_ = v1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "gateway-auth", Namespace: "default"},
+		Data:       map[string][]byte{"apiKey": []byte("secret")},
+	}).Build()
+
+	tests := []struct {
+		name     string
+		provider *OpenAIProvider
+		llm      *arcadiav1.LLM
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "no endpoint",
+			provider: &OpenAIProvider{},
+			llm:      &arcadiav1.LLM{ObjectMeta: metav1.ObjectMeta{Name: "gateway", Namespace: "default"}},
+			wantErr:  true,
+		},
+		{
+			name:     "api key required",
+			provider: &OpenAIProvider{RequireAPIKey: true},
+			llm: &arcadiav1.LLM{
+				ObjectMeta: metav1.ObjectMeta{Name: "gateway", Namespace: "default"},
+				Spec:       arcadiav1.LLMSpec{Provider: arcadiav1.Provider{Endpoint: &arcadiav1.Endpoint{URL: server.URL + "/v1"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name:     "unauthorized",
+			provider: &OpenAIProvider{Model: "gateway-model"},
+			llm: &arcadiav1.LLM{
+				ObjectMeta: metav1.ObjectMeta{Name: "gateway", Namespace: "default"},
+				Spec:       arcadiav1.LLMSpec{Provider: arcadiav1.Provider{Endpoint: &arcadiav1.Endpoint{URL: server.URL + "/v1/"}}},
+			},
+			wantErr: true,
+		},
+		{
+			name:     "model of the llm",
+			provider: &OpenAIProvider{Model: DefaultOpenAIModel, RequireAPIKey: true},
+			llm: &arcadiav1.LLM{
+				ObjectMeta: metav1.ObjectMeta{Name: "gateway", Namespace: "default"},
+				Spec: arcadiav1.LLMSpec{
+					Provider: arcadiav1.Provider{Endpoint: &arcadiav1.Endpoint{
+						URL:        server.URL + "/v1/",
+						AuthSecret: &arcadiav1.TypedObjectReference{Kind: "Secret", Name: "gateway-auth"},
+					}},
+					Models: []string{"gateway-model"},
+				},
+			},
+			want: `{"english":{"score":9}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.provider.Call(context.TODO(), c, tt.llm, "rate it")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Call() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := GetPromptContent(data)
+			if err != nil || got != tt.want {
+				t.Errorf("GetPromptContent() = %v %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFakeProviderCall(t *testing.T) {
+	data, err := (&FakeProvider{Score: 6}).Call(context.TODO(), nil, nil, "")
+	if err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	content, err := GetPromptContent(data)
+	if err != nil {
+		t.Fatalf("GetPromptContent() error = %v", err)
+	}
+	output, err := ParseOutput(content)
+	if err != nil {
+		t.Fatalf("ParseOutput() error = %v", err)
+	}
+	if got := output.FinalRating(); got != "6" {
+		t.Errorf("FinalRating() = %v, want 6", got)
+	}
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"helm.sh/helm/v3/pkg/release"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+var (
+	ErrReleaseNoChart       = errors.New("release has no chart metadata")
+	ErrNoComponentMatched   = errors.New("no component matches the chart name and version of the release")
+	ErrManyComponentMatched = errors.New("more than one component matches the chart name and version of the release")
+)
+
+// IsKubebbRelease returns true if the release is installed or upgraded by a ComponentPlan
+func IsKubebbRelease(rel *release.Release) bool {
+	if rel == nil || rel.Info == nil {
+		return false
+	}
+	ns, name, uid, _, _ := ParseDescription(rel.Info.Description)
+	return ns != "" && name != "" && uid != ""
+}
+
+// ReleaseMatchComponent checks whether the chart of the release is the given version of the component
+func ReleaseMatchComponent(rel *release.Release, component *corev1alpha1.Component, version string) error {
+	if rel == nil || rel.Chart == nil || rel.Chart.Metadata == nil {
+		return ErrReleaseNoChart
+	}
+	if rel.Chart.Metadata.Name != component.Status.Name {
+		return fmt.Errorf("release chart name %q does not match component chart name %q", rel.Chart.Metadata.Name, component.Status.Name)
+	}
+	if rel.Chart.Metadata.Version != version {
+		return fmt.Errorf("release chart version %q does not match version %q", rel.Chart.Metadata.Version, version)
+	}
+	return nil
+}
+
+// FindComponentForRelease finds the component whose chart name and versions match the release
+func FindComponentForRelease(rel *release.Release, components []corev1alpha1.Component) (*corev1alpha1.Component, error) {
+	if rel == nil || rel.Chart == nil || rel.Chart.Metadata == nil {
+		return nil, ErrReleaseNoChart
+	}
+	var matched *corev1alpha1.Component
+	for i := range components {
+		component := &components[i]
+		if component.Status.Name != rel.Chart.Metadata.Name {
+			continue
+		}
+		for _, v := range component.Status.Versions {
+			if v.Version != rel.Chart.Metadata.Version {
+				continue
+			}
+			if matched != nil {
+				return nil, fmt.Errorf("%w: %s/%s and %s/%s", ErrManyComponentMatched, matched.Namespace, matched.Name, component.Namespace, component.Name)
+			}
+			matched = component
+			break
+		}
+	}
+	if matched == nil {
+		return nil, fmt.Errorf("%w: chart %s version %s", ErrNoComponentMatched, rel.Chart.Metadata.Name, rel.Chart.Metadata.Version)
+	}
+	return matched, nil
+}
+
+// GenerateComponentPlanForRelease generates a ComponentPlan which adopts the existing release,
+// the values of the release are kept as Override.Values, so the next upgrade will not lose them.
+func GenerateComponentPlanForRelease(rel *release.Release, component *corev1alpha1.Component) (*corev1alpha1.ComponentPlan, error) {
+	if rel == nil || rel.Chart == nil || rel.Chart.Metadata == nil {
+		return nil, ErrReleaseNoChart
+	}
+	if err := ReleaseMatchComponent(rel, component, rel.Chart.Metadata.Version); err != nil {
+		return nil, err
+	}
+	plan := &corev1alpha1.ComponentPlan{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1alpha1.GroupVersion.String(),
+			Kind:       "ComponentPlan",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      rel.Name,
+			Namespace: rel.Namespace,
+			Annotations: map[string]string{
+				corev1alpha1.ComponentPlanAdoptReleaseAnnotation: "true",
+			},
+		},
+		Spec: corev1alpha1.ComponentPlanSpec{
+			ComponentRef: &corev1.ObjectReference{
+				Namespace: component.Namespace,
+				Name:      component.Name,
+			},
+			InstallVersion: rel.Chart.Metadata.Version,
+			Approved:       true,
+			Config: corev1alpha1.Config{
+				Name: rel.Name,
+			},
+		},
+	}
+	if len(rel.Config) != 0 {
+		raw, err := json.Marshal(rel.Config)
+		if err != nil {
+			return nil, err
+		}
+		plan.Spec.Override.Values = &apiextensionsv1.JSON{Raw: raw}
+	}
+	return plan, nil
+}
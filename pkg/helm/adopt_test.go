@@ -0,0 +1,145 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"errors"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+func TestIsKubebbRelease(t *testing.T) {
+	tests := []struct {
+		name string
+		rel  *release.Release
+		want bool
+	}{
+		{name: "nil", rel: nil, want: false},
+		{name: "plain helm", rel: &release.Release{Name: "nginx", Info: &release.Info{Description: "Install complete"}}, want: false},
+		{name: "kubebb", rel: &release.Release{Name: "nginx", Info: &release.Info{Description: "core:default/nginx/894d23c7-a177-4e6c-9d5c-bd8efe1e6df8/1 "}}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsKubebbRelease(tt.rel); got != tt.want {
+				t.Errorf("IsKubebbRelease() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindComponentForRelease(t *testing.T) {
+	rel := &release.Release{Name: "my-nginx", Chart: &chart.Chart{Metadata: &chart.Metadata{Name: "nginx", Version: "15.0.0"}}}
+	tests := []struct {
+		name       string
+		components []corev1alpha1.Component
+		wantName   string
+		wantErr    error
+	}{
+		{
+			name: "match one",
+			components: []corev1alpha1.Component{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "kubebb-system", Name: "bitnami.nginx"},
+					Status:     corev1alpha1.ComponentStatus{Name: "nginx", Versions: []corev1alpha1.ComponentVersion{{Version: "15.1.0"}, {Version: "15.0.0"}}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "kubebb-system", Name: "bitnami.redis"},
+					Status:     corev1alpha1.ComponentStatus{Name: "redis", Versions: []corev1alpha1.ComponentVersion{{Version: "15.0.0"}}},
+				},
+			},
+			wantName: "bitnami.nginx",
+		},
+		{
+			name: "version not found",
+			components: []corev1alpha1.Component{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "kubebb-system", Name: "bitnami.nginx"},
+					Status:     corev1alpha1.ComponentStatus{Name: "nginx", Versions: []corev1alpha1.ComponentVersion{{Version: "15.1.0"}}},
+				},
+			},
+			wantErr: ErrNoComponentMatched,
+		},
+		{
+			name: "ambiguous",
+			components: []corev1alpha1.Component{
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "kubebb-system", Name: "bitnami.nginx"},
+					Status:     corev1alpha1.ComponentStatus{Name: "nginx", Versions: []corev1alpha1.ComponentVersion{{Version: "15.0.0"}}},
+				},
+				{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "kubebb-system", Name: "mirror.nginx"},
+					Status:     corev1alpha1.ComponentStatus{Name: "nginx", Versions: []corev1alpha1.ComponentVersion{{Version: "15.0.0"}}},
+				},
+			},
+			wantErr: ErrManyComponentMatched,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindComponentForRelease(rel, tt.components)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FindComponentForRelease() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got.Name != tt.wantName {
+				t.Errorf("FindComponentForRelease() = %v, want %v", got.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestGenerateComponentPlanForRelease(t *testing.T) {
+	component := corev1alpha1.Component{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kubebb-system", Name: "bitnami.nginx"},
+		Status:     corev1alpha1.ComponentStatus{Name: "nginx", Versions: []corev1alpha1.ComponentVersion{{Version: "15.0.0"}}},
+	}
+	rel := &release.Release{
+		Name:      "my-nginx",
+		Namespace: "default",
+		Info:      &release.Info{Description: "Install complete", Status: release.StatusDeployed},
+		Chart:     &chart.Chart{Metadata: &chart.Metadata{Name: "nginx", Version: "15.0.0"}},
+		Config:    map[string]interface{}{"replicaCount": 2},
+	}
+	plan, err := GenerateComponentPlanForRelease(rel, &component)
+	if err != nil {
+		t.Fatalf("GenerateComponentPlanForRelease() error = %v", err)
+	}
+	if plan.Name != "my-nginx" || plan.Namespace != "default" || plan.GetReleaseName() != "my-nginx" {
+		t.Errorf("unexpected plan %s/%s with release name %s", plan.Namespace, plan.Name, plan.GetReleaseName())
+	}
+	if !plan.NeedAdoptRelease() || !plan.Spec.Approved {
+		t.Errorf("plan should be approved and adopt the release")
+	}
+	if plan.Spec.ComponentRef.Name != "bitnami.nginx" || plan.Spec.InstallVersion != "15.0.0" {
+		t.Errorf("unexpected component %v version %s", plan.Spec.ComponentRef, plan.Spec.InstallVersion)
+	}
+	if plan.Spec.Override.Values == nil || string(plan.Spec.Override.Values.Raw) != `{"replicaCount":2}` {
+		t.Errorf("unexpected override values %v", plan.Spec.Override.Values)
+	}
+
+	other := corev1alpha1.Component{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "kubebb-system", Name: "bitnami.redis"},
+		Status:     corev1alpha1.ComponentStatus{Name: "redis", Versions: []corev1alpha1.ComponentVersion{{Version: "15.0.0"}}},
+	}
+	if _, err = GenerateComponentPlanForRelease(rel, &other); err == nil {
+		t.Errorf("expect error when chart name does not match")
+	}
+}
@@ -60,6 +60,9 @@ type HelmRelease interface {
 	GetDefaultRollbackCfg() *action.Rollback
 	Rollback(ctx context.Context, logger logr.Logger, client *action.Rollback, releaseName string, revision int) (out string, err error)
 	RollbackWithDefaultConfig(ctx context.Context, logger logr.Logger, releaseName string, revision int) (out string, err error)
+	GetDefaultReleaseTestingCfg() *action.ReleaseTesting
+	ReleaseTesting(ctx context.Context, logger logr.Logger, client *action.ReleaseTesting, releaseName string) (rel *release.Release, out string, err error)
+	ReleaseTestingWithDefaultConfig(ctx context.Context, logger logr.Logger, releaseName string) (rel *release.Release, out string, err error)
 	GetDefaultUninstallCfg() *action.Uninstall
 	Uninstall(ctx context.Context, logger logr.Logger, client *action.Uninstall, releaseNames ...string) (out string, err error)
 	UninstallWithDefaultConfig(ctx context.Context, logger logr.Logger, releaseNames ...string) (out string, err error)
@@ -67,6 +70,7 @@ type HelmRelease interface {
 	Pull(ctx context.Context, logger logr.Logger, client *action.Pull, args ...string) (out string, err error)
 	PullWithDefaultConfig(ctx context.Context, logger logr.Logger, args ...string) (out string, err error)
 	GetLastRelease(releaseName string) (*release.Release, error)
+	ListDeployedReleases() ([]*release.Release, error)
 }
 
 var _ HelmRelease = &HelmWrapper{}
@@ -658,6 +662,33 @@ func (h *HelmWrapper) RollbackWithDefaultConfig(ctx context.Context, logger logr
 	client := h.GetDefaultRollbackCfg()
 	return h.Rollback(ctx, logger, client, releaseName, revision)
 }
+
+func (h *HelmWrapper) GetDefaultReleaseTestingCfg() *action.ReleaseTesting {
+	client := action.NewReleaseTesting(h.config)
+	client.Namespace = h.namespace
+	client.Timeout = 300 * time.Second // helm test --timeout
+	return client
+}
+
+// ReleaseTesting [RELEASE], the logs of the test pods are returned as out when the test fails
+func (h *HelmWrapper) ReleaseTesting(ctx context.Context, logger logr.Logger, client *action.ReleaseTesting, releaseName string) (rel *release.Release, out string, err error) {
+	rel, err = client.Run(releaseName)
+	if err != nil && rel != nil {
+		if logErr := client.GetPodLogs(h.buf, rel); logErr != nil {
+			logger.Error(logErr, "failed to get test pod logs", "release", releaseName)
+		}
+	}
+	out = h.buf.String()
+	h.buf.Reset()
+	return rel, out, err
+}
+
+// ReleaseTestingWithDefaultConfig [RELEASE]
+func (h *HelmWrapper) ReleaseTestingWithDefaultConfig(ctx context.Context, logger logr.Logger, releaseName string) (rel *release.Release, out string, err error) {
+	client := h.GetDefaultReleaseTestingCfg()
+	return h.ReleaseTesting(ctx, logger, client, releaseName)
+}
+
 func (h *HelmWrapper) GetDefaultUninstallCfg() *action.Uninstall {
 	client := action.NewUninstall(h.config)
 	client.DryRun = false              // helm uninstall --dry-run
@@ -737,3 +768,40 @@ func (h *HelmWrapper) GetLastRelease(releaseName string) (*release.Release, erro
 	}
 	return rel, err
 }
+
+// GetLastDeployedRevision returns the latest revision before the given one which was deployed successfully,
+// 0 is returned if there is no such revision.
+func (h *HelmWrapper) GetLastDeployedRevision(releaseName string, before int) (int, error) {
+	history, err := h.config.Releases.History(releaseName)
+	if err != nil {
+		if errors.Is(err, driver.ErrReleaseNotFound) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return lastDeployedRevision(history, before), nil
+}
+
+// lastDeployedRevision skips the failed, pending and uninstalled revisions like helm upgrade --atomic does,
+// only the deployed and superseded ones are deployed successfully.
+func lastDeployedRevision(history []*release.Release, before int) int {
+	revision := 0
+	for _, rel := range history {
+		if rel == nil || rel.Info == nil || rel.Version >= before || rel.Version <= revision {
+			continue
+		}
+		if rel.Info.Status == release.StatusDeployed || rel.Info.Status == release.StatusSuperseded {
+			revision = rel.Version
+		}
+	}
+	return revision
+}
+
+// ListDeployedReleases lists all deployed releases in the namespace
+func (h *HelmWrapper) ListDeployedReleases() ([]*release.Release, error) {
+	rels, err := h.config.Releases.ListDeployed()
+	if err != nil && errors.Is(err, driver.ErrReleaseNotFound) {
+		err = nil
+	}
+	return rels, err
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"testing"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestLastDeployedRevision(t *testing.T) {
+	history := []*release.Release{
+		{Version: 1, Info: &release.Info{Status: release.StatusSuperseded}},
+		{Version: 2, Info: &release.Info{Status: release.StatusFailed}},
+		{Version: 3, Info: &release.Info{Status: release.StatusSuperseded}},
+		{Version: 4, Info: &release.Info{Status: release.StatusFailed}},
+		{Version: 5, Info: &release.Info{Status: release.StatusPendingUpgrade}},
+		{Version: 6, Info: &release.Info{Status: release.StatusDeployed}},
+	}
+	tests := []struct {
+		name    string
+		history []*release.Release
+		before  int
+		want    int
+	}{
+		{name: "skip failed and pending revisions", history: history, before: 6, want: 3},
+		{name: "previous revision deployed", history: history, before: 4, want: 3},
+		{name: "only failed revisions before", history: history, before: 3, want: 1},
+		{name: "no revision before", history: history, before: 1, want: 0},
+		{name: "empty history", history: nil, before: 6, want: 0},
+		{name: "nil release info", history: []*release.Release{nil, {Version: 1}}, before: 2, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lastDeployedRevision(tt.history, tt.before); got != tt.want {
+				t.Errorf("lastDeployedRevision() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
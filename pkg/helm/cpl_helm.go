@@ -18,6 +18,7 @@ package helm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
@@ -31,6 +32,12 @@ import (
 	"helm.sh/helm/v3/pkg/helmpath"
 	"helm.sh/helm/v3/pkg/release"
 	hrepo "helm.sh/helm/v3/pkg/repo"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -45,6 +52,7 @@ type CoreHelm interface {
 	GetLastRelease() (rel *release.Release, err error)
 	GetManifestsByDryRun(ctx context.Context, chartName string) (data string, err error)
 	Rollback(ctx context.Context) error
+	Verify(ctx context.Context) error
 	GetOCIRepoCharts(ctx context.Context, pullURL string, skipTags map[string]bool) (latest *chart.Metadata, all []*hrepo.ChartVersion, err error)
 	PullAndParse(ctx context.Context, pullURL, version string) (out string, chartRequested *chart.Chart, err error)
 	Pull(ctx context.Context, pullURL, version string) (out, dir, entryName string, err error)
@@ -53,6 +61,10 @@ type CoreHelm interface {
 
 var _ CoreHelm = &CoreHelmWrapper{}
 
+// remoteVerifyJobTTL is the default ttlSecondsAfterFinished of the verification jobs in member clusters,
+// which can not be owned and garbage collected with the ComponentPlan.
+const remoteVerifyJobTTL int32 = 3600
+
 // CoreHelmWrapper is a wrapper for helm command
 type CoreHelmWrapper struct {
 	*HelmWrapper
@@ -60,6 +72,7 @@ type CoreHelmWrapper struct {
 	repo      *corev1alpha1.Repository
 	component *corev1alpha1.Component
 	cli       client.Client
+	targetCli client.Client // client of the cluster where the release is installed, default is cli
 	logger    logr.Logger
 }
 
@@ -174,7 +187,26 @@ func ReleaseLog(rel *release.Release) []interface{} {
 }
 
 func (c *CoreHelmWrapper) Rollback(ctx context.Context) (err error) {
-	return c.rollback(ctx)
+	return c.rollbackTo(ctx, c.cpl.Status.InstalledRevision)
+}
+
+// Verify runs the helm test hooks and the verification job of the ComponentPlan
+func (c *CoreHelmWrapper) Verify(ctx context.Context) (err error) {
+	v := c.cpl.Spec.Verification
+	if v == nil {
+		return nil
+	}
+	if v.HelmTest {
+		if err = c.test(ctx, v.Timeout()); err != nil {
+			return err
+		}
+	}
+	if v.JobTemplate != nil {
+		if err = c.runVerifyJob(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // GetOCIRepoCharts retrieves the latest chart metadata and all component versions for a given OCI repository.
@@ -342,7 +374,10 @@ func (c *CoreHelmWrapper) install(ctx context.Context, dryRun bool, chartName st
 	}
 	i.InsecureSkipTLSverify = c.repo.Spec.Insecure
 	i.PassCredentialsAll = false // TODO do we need add this args to override?
-	i.PostRenderer = newPostRenderer(c.repo.Spec.ImageOverride, c.cpl.Spec.Override.Images)
+	i.PostRenderer, err = c.newPostRenderer(ctx, dryRun)
+	if err != nil {
+		return nil, err
+	}
 	log.V(1).Info(fmt.Sprintf("Original chart version: %q", i.Version))
 	i.ReleaseName = c.cpl.GetReleaseName()
 	valueOpts, err := c.setVals(ctx)
@@ -400,7 +435,10 @@ func (c *CoreHelmWrapper) upgrade(ctx context.Context, dryRun bool, chartName st
 	}
 	i.InsecureSkipTLSverify = c.repo.Spec.Insecure
 	i.PassCredentialsAll = false // TODO do we need add this args to override?
-	i.PostRenderer = newPostRenderer(c.repo.Spec.ImageOverride, c.cpl.Spec.Override.Images)
+	i.PostRenderer, err = c.newPostRenderer(ctx, dryRun)
+	if err != nil {
+		return nil, err
+	}
 	valueOpts, err := c.setVals(ctx)
 	if err != nil {
 		return nil, err
@@ -412,6 +450,62 @@ func (c *CoreHelmWrapper) upgrade(ctx context.Context, dryRun bool, chartName st
 	return rel, err
 }
 
+// newPostRenderer overrides the images by the rules of the repository with the default rules of the cluster,
+// and pins the images to the digests planned in the ComponentPlan status except in dry run.
+func (c *CoreHelmWrapper) newPostRenderer(ctx context.Context, dryRun bool) (*postRenderer, error) {
+	cfg, err := c.ImageOverrideConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var digests map[string]string
+	if cfg.PinImageDigest && !dryRun {
+		digests = c.cpl.Status.ImageDigests
+	}
+	fieldSpecs, err := cfg.FieldSpecs()
+	if err != nil {
+		return nil, err
+	}
+	components, err := c.postRenderComponents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newPostRenderer(cfg.ImageOverride, fieldSpecs, c.cpl.Spec.Override.Images, digests).withPostRender(c.cpl.Namespace, c.cpl.Spec.PostRender, components), nil
+}
+
+// ImageOverrideConfig returns the image override rules of the repository with the default rules of the cluster
+func (c *CoreHelmWrapper) ImageOverrideConfig(ctx context.Context) (*corev1alpha1.ImageOverrideConfig, error) {
+	var err error
+	cfg := &corev1alpha1.ImageOverrideConfig{}
+	if c.cli != nil {
+		if cfg, err = corev1alpha1.GetImageOverrideConfig(ctx, c.cli); err != nil {
+			return nil, err
+		}
+	}
+	return cfg.ForRepository(c.repo), nil
+}
+
+// postRenderComponents gets the files of the kustomize Components of PostRender from the ConfigMaps
+func (c *CoreHelmWrapper) postRenderComponents(ctx context.Context) ([]map[string]string, error) {
+	if c.cpl.Spec.PostRender == nil || len(c.cpl.Spec.PostRender.Components) == 0 {
+		return nil, nil
+	}
+	if c.cli == nil {
+		return nil, fmt.Errorf("no client to get the components of postRender")
+	}
+	res := make([]map[string]string, 0, len(c.cpl.Spec.PostRender.Components))
+	for _, component := range c.cpl.Spec.PostRender.Components {
+		cm := &corev1.ConfigMap{}
+		if err := c.cli.Get(ctx, types.NamespacedName{Namespace: c.cpl.Namespace, Name: component.ConfigMapRef}, cm); err != nil {
+			return nil, fmt.Errorf("get postRender component %s: %w", component.ConfigMapRef, err)
+		}
+		if _, ok := cm.Data[kustomizationFile]; !ok {
+			return nil, fmt.Errorf("postRender component %s has no %s", component.ConfigMapRef, kustomizationFile)
+		}
+		res = append(res, cm.Data)
+	}
+	return res, nil
+}
+
 func (c *CoreHelmWrapper) uninstall(ctx context.Context) (err error) {
 	log := c.logger.WithValues("ComponentPlan", klog.KObj(c.cpl))
 	i := c.GetDefaultUninstallCfg()
@@ -429,7 +523,8 @@ func (c *CoreHelmWrapper) uninstall(ctx context.Context) (err error) {
 	return nil
 }
 
-func (c *CoreHelmWrapper) rollback(ctx context.Context) (err error) {
+// rollbackTo rolls the release back to the given revision, 0 means the previous revision
+func (c *CoreHelmWrapper) rollbackTo(ctx context.Context, revision int) (err error) {
 	log := c.logger.WithValues("ComponentPlan", klog.KObj(c.cpl))
 	i := c.GetDefaultRollbackCfg()
 	i.DryRun = false // do not need to simulate the rollback
@@ -441,16 +536,91 @@ func (c *CoreHelmWrapper) rollback(ctx context.Context) (err error) {
 	i.MaxHistory = c.cpl.Spec.GetMaxHistory()
 	i.Recreate = c.cpl.Spec.RecreatePods
 	i.Force = c.cpl.Spec.Force
-	i.Version = c.cpl.Status.InstalledRevision
-	out, err := c.HelmWrapper.Rollback(ctx, c.logger, i, c.cpl.GetReleaseName(), c.cpl.Status.InstalledRevision)
+	i.Version = revision
+	out, err := c.HelmWrapper.Rollback(ctx, c.logger, i, c.cpl.GetReleaseName(), revision)
 	if err != nil {
 		return err
 	}
-	log.Info(fmt.Sprintf("release \"%s\" rollback to revision:%d", c.cpl.GetReleaseName(), c.cpl.Status.InstalledRevision))
+	log.Info(fmt.Sprintf("release \"%s\" rollback to revision:%d", c.cpl.GetReleaseName(), revision))
 	log.Info(out)
 	return nil
 }
 
+func (c *CoreHelmWrapper) test(ctx context.Context, timeout time.Duration) (err error) {
+	log := c.logger.WithValues("ComponentPlan", klog.KObj(c.cpl))
+	i := c.GetDefaultReleaseTestingCfg()
+	i.Namespace = c.cpl.Namespace
+	i.Timeout = timeout
+	_, out, err := c.HelmWrapper.ReleaseTesting(ctx, c.logger, i, c.cpl.GetReleaseName())
+	if out != "" {
+		log.Info(out)
+	}
+	if err != nil {
+		return fmt.Errorf("helm test failed: %w", err)
+	}
+	log.Info(fmt.Sprintf("release \"%s\" helm test passed", c.cpl.GetReleaseName()))
+	return nil
+}
+
+// runVerifyJob creates the verification job for the current revision in the cluster where the release is installed,
+// and waits for it to finish. An existing job of the same revision is reused.
+func (c *CoreHelmWrapper) runVerifyJob(ctx context.Context) (err error) {
+	log := c.logger.WithValues("ComponentPlan", klog.KObj(c.cpl))
+	cli := c.cli
+	if c.targetCli != nil {
+		cli = c.targetCli
+	}
+	tpl := c.cpl.Spec.Verification.JobTemplate
+	job := &batchv1.Job{}
+	job.Name = corev1alpha1.GenerateComponentPlanVerifyJobName(c.cpl, c.cpl.Status.InstalledRevision)
+	job.Namespace = c.cpl.Namespace
+	if err = cli.Get(ctx, client.ObjectKeyFromObject(job), job); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		job.Labels = make(map[string]string, len(tpl.Labels)+1)
+		for k, v := range tpl.Labels {
+			job.Labels[k] = v
+		}
+		job.Labels[corev1alpha1.ComponentPlanReleaseNameLabel] = c.cpl.GetReleaseName()
+		job.Annotations = tpl.Annotations
+		job.Spec = *tpl.Spec.DeepCopy()
+		if c.cpl.Spec.TargetCluster == nil {
+			job.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(c.cpl, corev1alpha1.GroupVersion.WithKind("ComponentPlan"))}
+		} else if job.Spec.TTLSecondsAfterFinished == nil {
+			// the ComponentPlan does not exist in the member cluster, the job can not be owned by it
+			ttl := remoteVerifyJobTTL
+			job.Spec.TTLSecondsAfterFinished = &ttl
+		}
+		if err = cli.Create(ctx, job); err != nil {
+			return err
+		}
+		log.Info("verification job created", "job", klog.KObj(job))
+	}
+	err = wait.PollImmediateUntil(time.Second*3, func() (done bool, err error) {
+		if err = cli.Get(ctx, client.ObjectKeyFromObject(job), job); err != nil {
+			return false, err
+		}
+		for _, cond := range job.Status.Conditions {
+			if cond.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch cond.Type {
+			case batchv1.JobComplete:
+				log.Info("verification job completed", "job", klog.KObj(job))
+				return true, nil
+			case batchv1.JobFailed:
+				return false, fmt.Errorf("verification job %s failed: %s", job.Name, cond.Message)
+			}
+		}
+		return false, nil
+	}, ctx.Done())
+	if errors.Is(err, wait.ErrWaitTimeout) {
+		return fmt.Errorf("verification job %s does not finish in time", job.Name)
+	}
+	return err
+}
+
 func (c *CoreHelmWrapper) setVals(ctx context.Context) (valueOpts *values.Options, err error) {
 	valueOpts = &values.Options{}
 	for _, valuesFrom := range c.cpl.Spec.Override.ValuesFrom {
@@ -479,29 +649,50 @@ func (c *CoreHelmWrapper) setVals(ctx context.Context) (valueOpts *values.Option
 }
 
 func generateDescription(plan *corev1alpha1.ComponentPlan) string {
+	if hash := plan.Status.ValuesFromHash; hash != "" {
+		return fmt.Sprintf("core:%s/%s/%s/%d/%s %s", plan.GetNamespace(), plan.GetName(), plan.GetUID(), plan.GetGeneration(), hash, plan.Spec.Config.Description)
+	}
 	return fmt.Sprintf("core:%s/%s/%s/%d %s", plan.GetNamespace(), plan.GetName(), plan.GetUID(), plan.GetGeneration(), plan.Spec.Config.Description)
 }
 
 func ParseDescription(desc string) (ns, name, uid string, generation int64, raw string) {
 	raw = desc
-	if !strings.HasPrefix(desc, "core:") {
+	t, rest, ok := splitDescription(desc)
+	if !ok {
 		return
 	}
+	ns = t[0]
+	name = t[1]
+	uid = t[2]
+	generation, _ = strconv.ParseInt(t[3], 10, 64)
+	return ns, name, uid, generation, rest
+}
+
+// ParseDescriptionValuesFromHash returns the hash of ValuesFrom content recorded in the description,
+// empty if the release is installed without ValuesFrom or by an older version.
+func ParseDescriptionValuesFromHash(desc string) string {
+	t, _, ok := splitDescription(desc)
+	if !ok || len(t) != 5 {
+		return ""
+	}
+	return t[4]
+}
+
+// splitDescription splits the description in the format of `core:<ns>/<name>/<uid>/<generation>[/<valuesFromHash>] <raw>`
+func splitDescription(desc string) (t []string, raw string, ok bool) {
+	if !strings.HasPrefix(desc, "core:") {
+		return nil, "", false
+	}
 	other := strings.TrimPrefix(desc, "core:")
 	s := strings.Split(other, " ")
 	if len(s) < 2 {
-		return
+		return nil, "", false
 	}
-	s0 := s[0]
-	t := strings.Split(s0, "/")
-	if len(t) != 4 {
-		return
+	t = strings.Split(s[0], "/")
+	if len(t) != 4 && len(t) != 5 {
+		return nil, "", false
 	}
-	ns = t[0]
-	name = t[1]
-	uid = t[2]
-	generation, _ = strconv.ParseInt(t[3], 10, 64)
-	return ns, name, uid, generation, strings.Join(s[1:], " ")
+	return t, strings.Join(s[1:], " "), true
 }
 
 // ParseDigestFromPullOut extracts the sha256 digest from the `helm pull` output string.
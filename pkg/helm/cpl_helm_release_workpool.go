@@ -27,9 +27,17 @@ import (
 
 	"github.com/go-logr/logr"
 	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -47,31 +55,51 @@ type ReleaseWorkerPool interface {
 	// Uninstall is an asynchronous function.
 	Uninstall(ctx context.Context, plan *v1alpha1.ComponentPlan) (doing bool, err error)
 	// GetLastRelease is a synchronization function
-	GetLastRelease(plan *v1alpha1.ComponentPlan) (rel *release.Release, err error)
+	GetLastRelease(ctx context.Context, plan *v1alpha1.ComponentPlan) (rel *release.Release, err error)
 	// RollBack is an asynchronous function
 	RollBack(ctx context.Context, plan *v1alpha1.ComponentPlan) (rel *release.Release, doing bool, err error)
+	// GetClient returns the client of the cluster where the release is installed, it is a synchronization function
+	GetClient(ctx context.Context, plan *v1alpha1.ComponentPlan) (client.Client, error)
+	// Verify is an asynchronous function, rel is not nil only when the release is rolled back after the verification failed
+	Verify(ctx context.Context, plan *v1alpha1.ComponentPlan) (rel *release.Release, doing bool, err error)
 }
 
 type WorkerPool struct {
 	sync.Mutex
 	cli           client.Client
+	scheme        *runtime.Scheme
 	logger        logr.Logger
 	installJobs   map[string]*installWorker   // key: jobkey()
 	uninstallJobs map[string]*uninstallWorker // key: jobkey()
 	rollbackJobs  map[string]*rollbackWorker  // key: jobkey()
+	verifyJobs    map[string]*verifyWorker    // key: jobkey()
 	resultCache   cache.Store
 	getter        map[string]genericclioptions.RESTClientGetter // key: getterKey()
+	targets       map[string]*targetCluster                     // key: targetKey()
+	newClient     func(*rest.Config, client.Options) (client.Client, error)
 }
 
-func NewWorkerPool(logger logr.Logger, cli client.Client) *WorkerPool {
+// targetCluster caches the getter and client of a member cluster built from one resourceVersion of the kubeconfig Secret
+type targetCluster struct {
+	resourceVersion string
+	kubeconfig      []byte
+	getter          genericclioptions.RESTClientGetter
+	client          client.Client
+}
+
+func NewWorkerPool(logger logr.Logger, cli client.Client, scheme *runtime.Scheme) *WorkerPool {
 	return &WorkerPool{
 		cli:           cli,
+		scheme:        scheme,
 		logger:        logger,
 		installJobs:   make(map[string]*installWorker),
 		uninstallJobs: make(map[string]*uninstallWorker),
 		rollbackJobs:  make(map[string]*rollbackWorker),
+		verifyJobs:    make(map[string]*verifyWorker),
 		resultCache:   cache.NewTTLStore(workCacheKey, 1*time.Hour),
 		getter:        make(map[string]genericclioptions.RESTClientGetter),
+		targets:       make(map[string]*targetCluster),
+		newClient:     client.New,
 	}
 }
 
@@ -91,8 +119,13 @@ func planCacheKey(plan *v1alpha1.ComponentPlan) string {
 	return fmt.Sprintf("%s/%d", plan.GetUID(), plan.GetGeneration())
 }
 
-func (r *WorkerPool) GetLastRelease(plan *v1alpha1.ComponentPlan) (rel *release.Release, err error) {
-	getter, err := r.getGetter(plan.Namespace, "")
+func (r *WorkerPool) GetLastRelease(ctx context.Context, plan *v1alpha1.ComponentPlan) (rel *release.Release, err error) {
+	var getter genericclioptions.RESTClientGetter
+	if plan.Spec.TargetCluster != nil {
+		getter, err = r.getTargetGetter(ctx, plan)
+	} else {
+		getter, err = r.getGetter(plan.Namespace, "")
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -104,7 +137,7 @@ func (r *WorkerPool) GetLastRelease(plan *v1alpha1.ComponentPlan) (rel *release.
 }
 
 func (r *WorkerPool) GetManifests(ctx context.Context, plan *v1alpha1.ComponentPlan, repo *v1alpha1.Repository, chartName string) (data string, err error) {
-	getter, err := r.getGetterByPlan(plan)
+	getter, err := r.getGetterByPlan(ctx, plan)
 	if err != nil {
 		return "", err
 	}
@@ -116,7 +149,7 @@ func (r *WorkerPool) GetManifests(ctx context.Context, plan *v1alpha1.ComponentP
 }
 
 func (r *WorkerPool) InstallOrUpgrade(ctx context.Context, plan *v1alpha1.ComponentPlan, repo *v1alpha1.Repository, chartName string) (rel *release.Release, isRunning bool, err error) {
-	getter, err := r.getGetterByPlan(plan)
+	getter, err := r.getGetterByPlan(ctx, plan)
 	if err != nil {
 		return nil, false, err
 	}
@@ -150,7 +183,7 @@ func (r *WorkerPool) InstallOrUpgrade(ctx context.Context, plan *v1alpha1.Compon
 }
 
 func (r *WorkerPool) Uninstall(ctx context.Context, plan *v1alpha1.ComponentPlan) (doing bool, err error) {
-	getter, err := r.getGetterByPlan(plan)
+	getter, err := r.getGetterByPlan(ctx, plan)
 	if err != nil {
 		return false, err
 	}
@@ -165,7 +198,7 @@ func (r *WorkerPool) Uninstall(ctx context.Context, plan *v1alpha1.ComponentPlan
 	return
 }
 func (r *WorkerPool) RollBack(ctx context.Context, plan *v1alpha1.ComponentPlan) (rel *release.Release, doing bool, err error) {
-	getter, err := r.getGetterByPlan(plan)
+	getter, err := r.getGetterByPlan(ctx, plan)
 	if err != nil {
 		return nil, false, err
 	}
@@ -179,6 +212,28 @@ func (r *WorkerPool) RollBack(ctx context.Context, plan *v1alpha1.ComponentPlan)
 	return job.GetResult()
 }
 
+func (r *WorkerPool) Verify(ctx context.Context, plan *v1alpha1.ComponentPlan) (rel *release.Release, doing bool, err error) {
+	getter, err := r.getGetterByPlan(ctx, plan)
+	if err != nil {
+		return nil, false, err
+	}
+	targetCli, err := r.GetClient(ctx, plan)
+	if err != nil {
+		return nil, false, err
+	}
+	r.Lock()
+	defer r.Unlock()
+	job, ok := r.verifyJobs[r.jobKey(plan)]
+	if !ok || !job.isSame(plan) {
+		if ok && job.isRunning {
+			job.cancel()
+		}
+		r.verifyJobs[r.jobKey(plan)] = newVerifyWorker(ctx, r.jobKey(plan), r.logger, plan, getter, r.cli, targetCli)
+		return nil, true, nil
+	}
+	return job.GetResult()
+}
+
 func (r *WorkerPool) getterKey(ns, impersonateUserName string) string {
 	return ns + "/" + impersonateUserName
 }
@@ -187,10 +242,140 @@ func (r *WorkerPool) jobKey(plan *v1alpha1.ComponentPlan) string {
 	return plan.GetNamespace() + "/" + plan.GetReleaseName()
 }
 
-func (r *WorkerPool) getGetterByPlan(plan *v1alpha1.ComponentPlan) (getter genericclioptions.RESTClientGetter, err error) {
+func (r *WorkerPool) getGetterByPlan(ctx context.Context, plan *v1alpha1.ComponentPlan) (getter genericclioptions.RESTClientGetter, err error) {
+	if plan.Spec.TargetCluster != nil {
+		return r.getTargetGetter(ctx, plan)
+	}
 	return r.getGetter(plan.Namespace, plan.Spec.Creator)
 }
 
+// GetClient returns the client of the member cluster if the plan has a target cluster,
+// otherwise the client of the cluster where kubebb runs.
+func (r *WorkerPool) GetClient(ctx context.Context, plan *v1alpha1.ComponentPlan) (client.Client, error) {
+	if plan.Spec.TargetCluster == nil {
+		return r.cli, nil
+	}
+	r.Lock()
+	defer r.Unlock()
+	target, err := r.getTarget(ctx, plan)
+	if err != nil {
+		return nil, err
+	}
+	if target.client != nil {
+		return target.client, nil
+	}
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(target.kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig of target cluster: %w", err)
+	}
+	c, err := r.newClient(cfg, client.Options{Scheme: r.scheme})
+	if err != nil {
+		return nil, err
+	}
+	target.client = c
+	return c, nil
+}
+
+// getTargetGetter returns a getter using the kubeconfig of the member cluster. No impersonation is used,
+// because the creator in this cluster does not exist in the member cluster, instead the webhook checks
+// the creator can get the kubeconfig Secret.
+func (r *WorkerPool) getTargetGetter(ctx context.Context, plan *v1alpha1.ComponentPlan) (getter genericclioptions.RESTClientGetter, err error) {
+	r.Lock()
+	defer r.Unlock()
+	target, err := r.getTarget(ctx, plan)
+	if err != nil {
+		return nil, err
+	}
+	if target.getter != nil {
+		return target.getter, nil
+	}
+	target.getter, err = newKubeconfigGetter(target.kubeconfig, plan.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig of target cluster: %w", err)
+	}
+	return target.getter, nil
+}
+
+// getTarget returns the cached member cluster of the plan, the cache entry is replaced
+// when the kubeconfig Secret is updated. The caller must hold the lock.
+func (r *WorkerPool) getTarget(ctx context.Context, plan *v1alpha1.ComponentPlan) (*targetCluster, error) {
+	kubeconfig, resourceVersion, err := plan.Spec.TargetCluster.GetKubeConfig(ctx, r.cli, plan.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	key := r.targetKey(plan)
+	if target, ok := r.targets[key]; ok {
+		if target.resourceVersion == resourceVersion {
+			return target, nil
+		}
+	}
+	target := &targetCluster{resourceVersion: resourceVersion, kubeconfig: kubeconfig}
+	r.targets[key] = target
+	return target, nil
+}
+
+func (r *WorkerPool) targetKey(plan *v1alpha1.ComponentPlan) string {
+	return plan.Namespace + "/" + plan.Spec.TargetCluster.SecretRef
+}
+
+// kubeconfigGetter is a RESTClientGetter of a kubeconfig kept in memory, so the credentials of
+// the member cluster are never written to disk.
+type kubeconfigGetter struct {
+	clientConfig clientcmd.ClientConfig
+	config       *rest.Config
+
+	once      sync.Once
+	discovery discovery.CachedDiscoveryInterface
+	err       error
+}
+
+var _ genericclioptions.RESTClientGetter = &kubeconfigGetter{}
+
+func newKubeconfigGetter(kubeconfig []byte, namespace string) (*kubeconfigGetter, error) {
+	apiConfig, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	clientConfig := clientcmd.NewDefaultClientConfig(*apiConfig, &clientcmd.ConfigOverrides{Context: clientcmdapi.Context{Namespace: namespace}})
+	cfg, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &kubeconfigGetter{clientConfig: clientConfig, config: cfg}, nil
+}
+
+func (g *kubeconfigGetter) ToRESTConfig() (*rest.Config, error) {
+	return rest.CopyConfig(g.config), nil
+}
+
+func (g *kubeconfigGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	g.once.Do(func() {
+		cfg := rest.CopyConfig(g.config)
+		// the same as genericclioptions.ConfigFlags, discovery requests a lot of api groups
+		cfg.Burst = 100
+		dc, err := discovery.NewDiscoveryClientForConfig(cfg)
+		if err != nil {
+			g.err = err
+			return
+		}
+		g.discovery = memory.NewMemCacheClient(dc)
+	})
+	return g.discovery, g.err
+}
+
+func (g *kubeconfigGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	dc, err := g.ToDiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(dc)
+	return restmapper.NewShortcutExpander(mapper, dc), nil
+}
+
+func (g *kubeconfigGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	return g.clientConfig
+}
+
 func (r *WorkerPool) getGetter(ns, impersonateUserName string) (getter genericclioptions.RESTClientGetter, err error) {
 	key := r.getterKey(ns, impersonateUserName)
 	r.Lock()
@@ -298,7 +483,8 @@ func (w *installWorker) isExpired(plan *v1alpha1.ComponentPlan, repo *v1alpha1.R
 	isPlanSame := w.plan.GetUID() == plan.GetUID()
 	isRepoSame := w.repo.GetUID() == repo.GetUID()
 	isPlanGenerationUpdated := plan.GetGeneration() > w.plan.GetGeneration()
-	if isPlanSame && isRepoSame && isPlanGenerationUpdated {
+	isValuesFromUpdated := plan.Status.ValuesFromHash != w.plan.Status.ValuesFromHash
+	if isPlanSame && isRepoSame && (isPlanGenerationUpdated || isValuesFromUpdated) {
 		return true
 	}
 	if !isPlanSame || !isRepoSame {
@@ -400,3 +586,72 @@ func newRollBackWorker(ctx context.Context, name string, logger logr.Logger, pla
 	}()
 	return w
 }
+
+type verifyWorker struct {
+	baseWorker
+}
+
+func newVerifyWorker(ctx context.Context, name string, logger logr.Logger, plan *v1alpha1.ComponentPlan, getter genericclioptions.RESTClientGetter, cli, targetCli client.Client) *verifyWorker {
+	w := &verifyWorker{
+		baseWorker: baseWorker{
+			name:      name,
+			logger:    logger,
+			plan:      plan,
+			isRunning: true,
+		},
+	}
+	subCtx, cancel := context.WithTimeout(ctx, plan.Spec.Verification.Timeout())
+	w.cancel = cancel
+	go func() {
+		w.logger.V(1).Info("start verify worker", w.logKV()...)
+		startTime := metav1.Now()
+		defer func() {
+			w.logger.V(1).Info(fmt.Sprintf("stop verify worker, cost %s", time.Since(startTime.Time)), w.logKV()...)
+			w.isRunning = false
+			cancel()
+		}()
+		c, err := NewCoreHelmWrapper(getter, plan.Namespace, w.logger, cli, plan, nil, nil)
+		if err != nil {
+			w.status = release.StatusFailed
+			w.err = err
+			return
+		}
+		c.targetCli = targetCli
+		w.err = c.Verify(subCtx)
+		if w.err == nil {
+			w.status = release.StatusDeployed
+			return
+		}
+		w.status = release.StatusFailed
+		if !plan.Spec.Verification.RollbackOnFailure || plan.Status.InstalledRevision <= 1 {
+			return
+		}
+		revision, err := c.GetLastDeployedRevision(plan.GetReleaseName(), plan.Status.InstalledRevision)
+		if err != nil {
+			w.err = fmt.Errorf("%w, and failed to get the release history: %s", w.err, err)
+			return
+		}
+		if revision == 0 {
+			w.err = fmt.Errorf("%w, no deployed revision to rollback", w.err)
+			return
+		}
+		// subCtx may be timeout already, rollback with the parent context
+		if err = c.rollbackTo(ctx, revision); err != nil {
+			w.err = fmt.Errorf("%w, and rollback failed: %s", w.err, err)
+			return
+		}
+		rel, err := c.GetLastRelease()
+		if err != nil {
+			w.err = fmt.Errorf("%w, rolled back but failed to get release: %s", w.err, err)
+			return
+		}
+		w.release = rel
+		w.err = fmt.Errorf("%w, rolled back to revision %d", w.err, revision)
+	}()
+	return w
+}
+
+// isSame checks whether the worker verifies the same revision of the same plan
+func (w *verifyWorker) isSame(plan *v1alpha1.ComponentPlan) bool {
+	return w.baseWorker.isSame(plan) && w.plan.Status.InstalledRevision == plan.Status.InstalledRevision
+}
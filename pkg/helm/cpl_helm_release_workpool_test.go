@@ -0,0 +1,206 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: member
+  cluster:
+    server: https://member.example.com:6443
+contexts:
+- name: member
+  context:
+    cluster: member
+    user: member
+current-context: member
+users:
+- name: member
+  user:
+    token: abc
+`
+
+func newTestTargetPool(t *testing.T) (*WorkerPool, client.Client, *corev1alpha1.ComponentPlan) {
+	t.Helper()
+	t.Setenv("TMPDIR", t.TempDir())
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "member", Namespace: "default"},
+		Data:       map[string][]byte{"kubeconfig": []byte(testKubeconfig)},
+	}
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+	plan := &corev1alpha1.ComponentPlan{ObjectMeta: metav1.ObjectMeta{Name: "plan", Namespace: "default"}}
+	plan.Spec.TargetCluster = &corev1alpha1.TargetCluster{SecretRef: "member"}
+	return NewWorkerPool(logr.Discard(), cli, scheme), cli, plan
+}
+
+func rotateKubeconfig(t *testing.T, cli client.Client) {
+	t.Helper()
+	secret := &corev1.Secret{}
+	if err := cli.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "member"}, secret); err != nil {
+		t.Fatal(err)
+	}
+	secret.Data["kubeconfig"] = []byte(testKubeconfig + "\n")
+	if err := cli.Update(context.Background(), secret); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWorkerPoolGetClient(t *testing.T) {
+	pool, cli, plan := newTestTargetPool(t)
+	created := 0
+	pool.newClient = func(cfg *rest.Config, opts client.Options) (client.Client, error) {
+		created++
+		if cfg.Host != "https://member.example.com:6443" {
+			t.Errorf("client built with host %s, want the member cluster", cfg.Host)
+		}
+		if opts.Scheme != pool.scheme {
+			t.Errorf("client built without the manager scheme")
+		}
+		return fake.NewClientBuilder().WithScheme(pool.scheme).Build(), nil
+	}
+	ctx := context.Background()
+
+	local := plan.DeepCopy()
+	local.Spec.TargetCluster = nil
+	if got, err := pool.GetClient(ctx, local); err != nil || got != cli {
+		t.Fatalf("GetClient() without target cluster = %v, %v, want the local client", got, err)
+	}
+
+	first, err := pool.GetClient(ctx, plan)
+	if err != nil {
+		t.Fatalf("GetClient() error = %v", err)
+	}
+	second, err := pool.GetClient(ctx, plan)
+	if err != nil {
+		t.Fatalf("GetClient() error = %v", err)
+	}
+	if first != second || created != 1 {
+		t.Errorf("client should be cached, created %d times", created)
+	}
+
+	rotateKubeconfig(t, cli)
+	third, err := pool.GetClient(ctx, plan)
+	if err != nil {
+		t.Fatalf("GetClient() error = %v", err)
+	}
+	if third == first || created != 2 {
+		t.Errorf("client should be rebuilt after the kubeconfig Secret is updated, created %d times", created)
+	}
+	if len(pool.targets) != 1 {
+		t.Errorf("got %d cached target clusters, want the old one replaced", len(pool.targets))
+	}
+
+	missing := plan.DeepCopy()
+	missing.Spec.TargetCluster.SecretRef = "none"
+	if _, err = pool.GetClient(ctx, missing); err == nil {
+		t.Errorf("GetClient() should fail without the kubeconfig Secret")
+	}
+}
+
+func TestWorkerPoolGetTargetGetter(t *testing.T) {
+	pool, cli, plan := newTestTargetPool(t)
+	ctx := context.Background()
+
+	first, err := pool.getTargetGetter(ctx, plan)
+	if err != nil {
+		t.Fatalf("getTargetGetter() error = %v", err)
+	}
+	cfg, err := first.ToRESTConfig()
+	if err != nil || cfg.Host != "https://member.example.com:6443" {
+		t.Fatalf("ToRESTConfig() = %v, %v, want the member cluster", cfg, err)
+	}
+	if ns, _, err := first.ToRawKubeConfigLoader().Namespace(); err != nil || ns != plan.Namespace {
+		t.Errorf("namespace = %s, %v, want %s", ns, err, plan.Namespace)
+	}
+	if _, err = os.Stat(filepath.Join(os.TempDir(), "kubebb-kubeconfig")); !os.IsNotExist(err) {
+		t.Errorf("kubeconfig should not be written to disk, got %v", err)
+	}
+	if second, err := pool.getTargetGetter(ctx, plan); err != nil || second != first {
+		t.Errorf("getter should be cached, got %v, %v", second, err)
+	}
+
+	rotateKubeconfig(t, cli)
+	third, err := pool.getTargetGetter(ctx, plan)
+	if err != nil {
+		t.Fatalf("getTargetGetter() error = %v", err)
+	}
+	if third == first {
+		t.Errorf("getter should be rebuilt after the kubeconfig Secret is updated")
+	}
+	if len(pool.targets) != 1 || len(pool.getter) != 0 {
+		t.Errorf("got %d target clusters and %d getters cached, want the old one replaced", len(pool.targets), len(pool.getter))
+	}
+}
+
+func TestTargetClusterResourcesDiff(t *testing.T) {
+	pool, _, plan := newTestTargetPool(t)
+	existing := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "default"}, Data: map[string]string{"a": "1"}}
+	member := fake.NewClientBuilder().WithScheme(pool.scheme).WithObjects(existing).Build()
+	pool.newClient = func(*rest.Config, client.Options) (client.Client, error) {
+		return member, nil
+	}
+	ctx := context.Background()
+	manifest := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm
+  namespace: default
+data:
+  a: "2"
+`
+	targetCli, err := pool.GetClient(ctx, plan)
+	if err != nil {
+		t.Fatalf("GetClient() error = %v", err)
+	}
+	resources, _, err := corev1alpha1.GetResourcesAndImages(ctx, logr.Discard(), targetCli, manifest, plan.Namespace, nil)
+	if err != nil || len(resources) != 1 {
+		t.Fatalf("GetResourcesAndImages() = %v, %v", resources, err)
+	}
+	if resources[0].NewCreated != nil || resources[0].SpecDiffwithExist == nil {
+		t.Errorf("resource existing in the member cluster should be diffed, got %+v", resources[0])
+	}
+
+	resources, _, err = corev1alpha1.GetResourcesAndImages(ctx, logr.Discard(), pool.cli, manifest, plan.Namespace, nil)
+	if err != nil || len(resources) != 1 {
+		t.Fatalf("GetResourcesAndImages() = %v, %v", resources, err)
+	}
+	if resources[0].NewCreated == nil || !*resources[0].NewCreated {
+		t.Errorf("resource missing in the local cluster should be new created, got %+v", resources[0])
+	}
+}
@@ -16,7 +16,20 @@
 
 package helm
 
-import "testing"
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
 
 func TestParseDescription(t *testing.T) {
 	type args struct {
@@ -53,6 +66,17 @@ func TestParseDescription(t *testing.T) {
 			wantGeneration: 1,
 			wantRaw:        "",
 		},
+		{
+			name: "with values from hash",
+			args: args{
+				desc: "core:kubebb-system/nginx/894d23c7-a177-4e6c-9d5c-bd8efe1e6df8/1/abcdef description",
+			},
+			wantNs:         "kubebb-system",
+			wantName:       "nginx",
+			wantUID:        "894d23c7-a177-4e6c-9d5c-bd8efe1e6df8",
+			wantGeneration: 1,
+			wantRaw:        "description",
+		},
 		{
 			name: "empty",
 			args: args{
@@ -109,6 +133,37 @@ func TestParseDescription(t *testing.T) {
 	}
 }
 
+func TestParseDescriptionValuesFromHash(t *testing.T) {
+	tests := []struct {
+		name string
+		desc string
+		want string
+	}{
+		{
+			name: "with hash",
+			desc: "core:kubebb-system/nginx/894d23c7-a177-4e6c-9d5c-bd8efe1e6df8/1/abcdef description",
+			want: "abcdef",
+		},
+		{
+			name: "without hash",
+			desc: "core:kubebb-system/nginx/894d23c7-a177-4e6c-9d5c-bd8efe1e6df8/1 description",
+			want: "",
+		},
+		{
+			name: "raw description",
+			desc: "balabala xxxx",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseDescriptionValuesFromHash(tt.desc); got != tt.want {
+				t.Errorf("ParseDescriptionValuesFromHash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestParseDigestFromPullOut(t *testing.T) {
 	type args struct {
 		out string
@@ -154,3 +209,56 @@ Error: failed to untar: a file or directory with the name nginx already exists
 		})
 	}
 }
+
+func TestRunVerifyJobCleanup(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := batchv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	keep, defaultTTL := int32(60), remoteVerifyJobTTL
+	tests := []struct {
+		name      string
+		target    bool
+		ttl       *int32
+		wantTTL   *int32
+		wantOwner bool
+	}{
+		{name: "owned by the plan in the same cluster", wantOwner: true},
+		{name: "ttl in the member cluster", target: true, wantTTL: &defaultTTL},
+		{name: "ttl of the template is kept", target: true, ttl: &keep, wantTTL: &keep},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &corev1alpha1.ComponentPlan{ObjectMeta: metav1.ObjectMeta{Name: "plan", Namespace: "default", UID: "uid"}}
+			plan.Spec.Verification = &corev1alpha1.Verification{JobTemplate: &batchv1.JobTemplateSpec{Spec: batchv1.JobSpec{TTLSecondsAfterFinished: tt.ttl}}}
+			plan.Status.InstalledRevision = 1
+			c := &CoreHelmWrapper{cpl: plan, cli: fake.NewClientBuilder().WithScheme(scheme).Build(), logger: logr.Discard()}
+			cli := c.cli
+			if tt.target {
+				plan.Spec.TargetCluster = &corev1alpha1.TargetCluster{SecretRef: "member"}
+				c.targetCli = fake.NewClientBuilder().WithScheme(scheme).Build()
+				cli = c.targetCli
+			}
+			// the job never finishes with the fake client
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			if err := c.runVerifyJob(ctx); err == nil {
+				t.Fatalf("runVerifyJob() should not pass without the job completed")
+			}
+			job := &batchv1.Job{}
+			if err := cli.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: corev1alpha1.GenerateComponentPlanVerifyJobName(plan, 1)}, job); err != nil {
+				t.Fatal(err)
+			}
+			if got := len(job.OwnerReferences) == 1; got != tt.wantOwner {
+				t.Errorf("job owned = %t, want %t", got, tt.wantOwner)
+			}
+			got := job.Spec.TTLSecondsAfterFinished
+			if (got == nil) != (tt.wantTTL == nil) || (got != nil && *got != *tt.wantTTL) {
+				t.Errorf("ttlSecondsAfterFinished = %v, want %v", got, tt.wantTTL)
+			}
+		})
+	}
+}
@@ -201,7 +201,13 @@ func (c *chartWorker) do(cd ChartDef) error {
 	}
 
 	if rel, err := cd.H.Template(c.options.ctx, cd.Version, dir+"/"+entryName); err == nil {
-		if _, images, err := v1alpha1.GetResourcesAndImages(c.options.ctx, c.logger, c.options.client, rel.Manifest, cd.Component.Namespace); err == nil {
+		var fieldSpecs []v1alpha1.ImageFieldSpec
+		if cfg, err := cd.H.ImageOverrideConfig(c.options.ctx); err != nil {
+			c.logger.Error(err, "failed to get image override config, only the images of the workloads are collected")
+		} else if fieldSpecs, err = cfg.FieldSpecs(); err != nil {
+			c.logger.Error(err, "failed to get image fields, only the images of the workloads are collected")
+		}
+		if _, images, err := v1alpha1.GetResourcesAndImages(c.options.ctx, c.logger, c.options.client, rel.Manifest, cd.Component.Namespace, fieldSpecs); err == nil {
 			cm.Data[v1alpha1.ImagesConfigMapKey] = strings.Join(images, ",")
 		} else {
 			c.logger.Error(err, "")
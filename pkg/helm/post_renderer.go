@@ -18,28 +18,91 @@ package helm
 
 import (
 	"bytes"
+	"fmt"
+	"path"
 	"sync"
 
 	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/resmap"
 	kustomize "sigs.k8s.io/kustomize/api/types"
 	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/resid"
 	"sigs.k8s.io/yaml"
 
 	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
 	"github.com/kubebb/core/pkg/repoimage"
+	"github.com/kubebb/core/pkg/utils"
 )
 
-const inputFile = "input.yaml"
+const (
+	inputFile         = "input.yaml"
+	kustomizationFile = "kustomization.yaml"
+)
 
 type postRenderer struct {
 	// For https://github.com/kubernetes-sigs/kustomize/issues/3659
 	kustomizeRenderMutex sync.Mutex
 	repoOverride         []corev1alpha1.ImageOverride
+	fieldSpecs           []corev1alpha1.ImageFieldSpec
 	images               []kustomize.Image
+	digests              map[string]string
+	postRender           *corev1alpha1.PostRender
+	// namespace is the namespace of the ComponentPlan, PostRender can not move the resources to other namespaces
+	namespace string
+	// components are the files of PostRender.Components in order, with the file name as the key
+	components []map[string]string
+}
+
+func newPostRenderer(repoOverride []corev1alpha1.ImageOverride, fieldSpecs []corev1alpha1.ImageFieldSpec, images []kustomize.Image, digests map[string]string) *postRenderer {
+	return &postRenderer{repoOverride: repoOverride, fieldSpecs: fieldSpecs, images: images, digests: digests}
 }
 
-func newPostRenderer(repoOverride []corev1alpha1.ImageOverride, images []kustomize.Image) *postRenderer {
-	return &postRenderer{repoOverride: repoOverride, images: images}
+// withPostRender adds the kustomization of PostRender with the files of its Components
+func (c *postRenderer) withPostRender(namespace string, postRender *corev1alpha1.PostRender, components []map[string]string) *postRenderer {
+	c.namespace = namespace
+	c.postRender = postRender
+	c.components = components
+	return c
+}
+
+// setPostRender sets the fields of PostRender into the kustomization, and writes the files of the components
+func (c *postRenderer) setPostRender(fs filesys.FileSystem, cfg *kustomize.Kustomization) error {
+	if c.postRender == nil {
+		return nil
+	}
+	if err := c.postRender.Validate(c.namespace); err != nil {
+		return err
+	}
+	for i, files := range c.components {
+		dir := fmt.Sprintf("components/%d", i)
+		for name, content := range files {
+			if err := fs.WriteFile(path.Join(dir, name), []byte(content)); err != nil {
+				return err
+			}
+		}
+		cfg.Components = append(cfg.Components, dir)
+	}
+	for _, p := range c.postRender.Patches {
+		patch := kustomize.Patch{Patch: p.Patch}
+		if t := p.Target; t != nil {
+			patch.Target = &kustomize.Selector{
+				ResId: resid.ResId{
+					Gvk:       resid.Gvk{Group: t.Group, Version: t.Version, Kind: t.Kind},
+					Name:      t.Name,
+					Namespace: t.Namespace,
+				},
+				LabelSelector:      t.LabelSelector,
+				AnnotationSelector: t.AnnotationSelector,
+			}
+		}
+		cfg.Patches = append(cfg.Patches, patch)
+	}
+	if len(c.postRender.CommonLabels) != 0 {
+		cfg.Labels = append(cfg.Labels, kustomize.Label{Pairs: c.postRender.CommonLabels})
+	}
+	cfg.CommonAnnotations = c.postRender.CommonAnnotations
+	cfg.Namespace = c.postRender.Namespace
+	return nil
 }
 
 func (c *postRenderer) Run(renderedManifests *bytes.Buffer) (modifiedManifests *bytes.Buffer, err error) {
@@ -48,6 +111,9 @@ func (c *postRenderer) Run(renderedManifests *bytes.Buffer) (modifiedManifests *
 	cfg.APIVersion = kustomize.KustomizationVersion
 	cfg.Kind = kustomize.KustomizationKind
 	cfg.Images = c.images
+	if err = c.setPostRender(fs, &cfg); err != nil {
+		return nil, err
+	}
 
 	cfg.Resources = append(cfg.Resources, inputFile)
 	f, err := fs.Create(inputFile)
@@ -63,7 +129,7 @@ func (c *postRenderer) Run(renderedManifests *bytes.Buffer) (modifiedManifests *
 	if err != nil {
 		return nil, err
 	}
-	f, err = fs.Create("kustomization.yaml")
+	f, err = fs.Create(kustomizationFile)
 	if err != nil {
 		return nil, err
 	}
@@ -85,13 +151,15 @@ func (c *postRenderer) Run(renderedManifests *bytes.Buffer) (modifiedManifests *
 	if err != nil {
 		return nil, err
 	}
-	path := corev1alpha1.GetImageOverridePath()
-	if len(path) != 0 && len(c.repoOverride) != 0 {
-		fsslice := make([]kustomize.FieldSpec, len(path))
-		for i, p := range path {
-			fsslice[i] = kustomize.FieldSpec{Path: p}
+	if err = c.checkNamespaces(renderedManifests.Bytes(), resMap); err != nil {
+		return nil, err
+	}
+	if len(c.fieldSpecs) != 0 && (len(c.repoOverride) != 0 || len(c.digests) != 0) {
+		fsslice := make([]kustomize.FieldSpec, len(c.fieldSpecs))
+		for i, spec := range c.fieldSpecs {
+			fsslice[i] = kustomize.FieldSpec{Gvk: resid.Gvk{Group: spec.Group, Version: spec.Version, Kind: spec.Kind}, Path: spec.Path}
 		}
-		if err = resMap.ApplyFilter(repoimage.Filter{ImageOverride: c.repoOverride, FsSlice: fsslice}); err != nil {
+		if err = resMap.ApplyFilter(repoimage.Filter{ImageOverride: c.repoOverride, FsSlice: fsslice, Digests: c.digests}); err != nil {
 			return nil, err
 		}
 	}
@@ -101,3 +169,41 @@ func (c *postRenderer) Run(renderedManifests *bytes.Buffer) (modifiedManifests *
 	}
 	return bytes.NewBuffer(yaml), nil
 }
+
+// checkNamespaces checks PostRender only modifies the resources in the rendered manifests of the chart,
+// without moving them to other namespaces or making them cluster-scoped. The resources without a namespace
+// in the manifests are installed into the namespace of the ComponentPlan by helm, so it is allowed to set it.
+func (c *postRenderer) checkNamespaces(rendered []byte, resMap resmap.ResMap) error {
+	if c.postRender == nil {
+		return nil
+	}
+	objs, err := utils.SplitYAML(rendered)
+	if err != nil {
+		return err
+	}
+	key := func(apiVersion, kind, name string) string {
+		return apiVersion + "/" + kind + "/" + name
+	}
+	namespaces := make(map[string][]string, len(objs))
+	for _, obj := range objs {
+		k := key(obj.GetAPIVersion(), obj.GetKind(), obj.GetName())
+		namespaces[k] = append(namespaces[k], obj.GetNamespace())
+	}
+	for _, res := range resMap.Resources() {
+		origins, ok := namespaces[key(res.GetApiVersion(), res.GetKind(), res.GetName())]
+		if !ok {
+			return fmt.Errorf("%w: %s %s", corev1alpha1.ErrPostRenderResource, res.GetKind(), res.GetName())
+		}
+		allowed := false
+		for _, ns := range origins {
+			if res.GetNamespace() == ns || (res.GetNamespace() == c.namespace && (ns == "" || c.postRender.Namespace != "")) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%w: %s %s in namespace %q", corev1alpha1.ErrPostRenderNamespace, res.GetKind(), res.GetName(), res.GetNamespace())
+		}
+	}
+	return nil
+}
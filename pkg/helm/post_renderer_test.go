@@ -65,6 +65,18 @@ spec:
       containers:
       - image: 172.22.50.223/bestchains-dev/fabric-operator:7776e71
         name: operator
+`
+	workflow = `apiVersion: argoproj.io/v1alpha1
+kind: Workflow
+metadata:
+  name: nginx
+  namespace: default
+spec:
+  entrypoint: main
+  templates:
+  - container:
+      image: nginx
+    name: main
 `
 	finalGotImage = `apiVersion: v1
 kind: Pod
@@ -106,8 +118,11 @@ spec:
 
 func Test_postRenderer_Run(t *testing.T) {
 	testBuf := bytes.NewBufferString(nginxPod + "\n---\n" + controllerDeploy)
+	defaultFieldSpecs, _ := (&corev1alpha1.ImageOverrideConfig{}).FieldSpecs()
+	workflowFieldSpecs, _ := (&corev1alpha1.ImageOverrideConfig{ImageFieldPresets: []string{"argo-workflows"}}).FieldSpecs()
 	type fields struct {
 		repoOverride []corev1alpha1.ImageOverride
+		fieldSpecs   []corev1alpha1.ImageFieldSpec
 		images       []kustomize.Image
 	}
 	type args struct {
@@ -192,12 +207,41 @@ func Test_postRenderer_Run(t *testing.T) {
 			wantModifiedManifests: bytes.NewBufferString(finalGotImage),
 			wantErr:               false,
 		},
+		{
+			name: "override registry of custom resources without field specs",
+			fields: fields{
+				repoOverride: []corev1alpha1.ImageOverride{{Registry: "docker.io", NewRegistry: "docker.cc"}},
+				fieldSpecs:   defaultFieldSpecs,
+			},
+			args: args{
+				renderedManifests: bytes.NewBufferString(workflow),
+			},
+			wantModifiedManifests: bytes.NewBufferString(workflow),
+			wantErr:               false,
+		},
+		{
+			name: "override registry of custom resources with preset field specs",
+			fields: fields{
+				repoOverride: []corev1alpha1.ImageOverride{{Registry: "docker.io", NewRegistry: "docker.cc"}},
+				fieldSpecs:   workflowFieldSpecs,
+			},
+			args: args{
+				renderedManifests: bytes.NewBufferString(workflow),
+			},
+			wantModifiedManifests: bytes.NewBufferString(strings.ReplaceAll(workflow, `image: nginx`, `image: docker.cc/library/nginx:latest`)),
+			wantErr:               false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			fieldSpecs := tt.fields.fieldSpecs
+			if fieldSpecs == nil {
+				fieldSpecs = defaultFieldSpecs
+			}
 			c := &postRenderer{
 				kustomizeRenderMutex: sync.Mutex{},
 				repoOverride:         tt.fields.repoOverride,
+				fieldSpecs:           fieldSpecs,
 				images:               tt.fields.images,
 			}
 			gotModifiedManifests, err := c.Run(tt.args.renderedManifests)
@@ -214,3 +258,168 @@ func Test_postRenderer_Run(t *testing.T) {
 		})
 	}
 }
+
+func Test_postRenderer_PostRender(t *testing.T) {
+	deploy := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+  namespace: default
+spec:
+  selector:
+    matchLabels:
+      app: nginx
+  template:
+    metadata:
+      labels:
+        app: nginx
+    spec:
+      containers:
+      - image: nginx:1.25
+        name: nginx
+`
+	sidecarComponent := map[string]string{
+		"kustomization.yaml": `apiVersion: kustomize.config.k8s.io/v1alpha1
+kind: Component
+patches:
+- path: sidecar.yaml
+  target:
+    kind: Deployment
+`,
+		"sidecar.yaml": `- op: add
+  path: /spec/template/metadata/annotations
+  value:
+    sidecar.istio.io/inject: "true"
+`,
+	}
+	tests := []struct {
+		name       string
+		namespace  string
+		postRender *corev1alpha1.PostRender
+		components []map[string]string
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:       "empty post render",
+			postRender: &corev1alpha1.PostRender{},
+			want:       deploy,
+		},
+		{
+			name: "strategic merge patch",
+			postRender: &corev1alpha1.PostRender{Patches: []corev1alpha1.PostRenderPatch{{
+				Patch: "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: nginx\nspec:\n  template:\n    spec:\n      nodeSelector:\n        disk: ssd\n",
+			}}},
+			want: deploy + "      nodeSelector:\n        disk: ssd\n",
+		},
+		{
+			name: "json 6902 patch",
+			postRender: &corev1alpha1.PostRender{Patches: []corev1alpha1.PostRenderPatch{{
+				Patch:  `[{"op": "replace", "path": "/spec/template/spec/containers/0/name", "value": "web"}]`,
+				Target: &corev1alpha1.PostRenderPatchTarget{Kind: "Deployment", Name: "ngi.*"},
+			}}},
+			want: strings.ReplaceAll(deploy, "        name: nginx\n", "        name: web\n"),
+		},
+		{
+			name:      "labels, annotations and namespace",
+			namespace: "prod",
+			postRender: &corev1alpha1.PostRender{
+				CommonLabels:      map[string]string{"team": "a"},
+				CommonAnnotations: map[string]string{"owner": "b"},
+				Namespace:         "prod",
+			},
+			want: strings.NewReplacer(
+				"  name: nginx\n  namespace: default\n", "  annotations:\n    owner: b\n  labels:\n    team: a\n  name: nginx\n  namespace: prod\n",
+				"    metadata:\n      labels:", "    metadata:\n      annotations:\n        owner: b\n      labels:",
+			).Replace(deploy),
+		},
+		{
+			name:       "components",
+			postRender: &corev1alpha1.PostRender{Components: []corev1alpha1.PostRenderComponent{{ConfigMapRef: "sidecar"}}},
+			components: []map[string]string{sidecarComponent},
+			want:       strings.ReplaceAll(deploy, "    metadata:\n      labels:", "    metadata:\n      annotations:\n        sidecar.istio.io/inject: \"true\"\n      labels:"),
+		},
+		{
+			name:       "namespace other than the componentplan",
+			postRender: &corev1alpha1.PostRender{Namespace: "prod"},
+			wantErr:    true,
+		},
+		{
+			name: "patch changes metadata.namespace",
+			postRender: &corev1alpha1.PostRender{Patches: []corev1alpha1.PostRenderPatch{{
+				Patch:  `[{"op": "replace", "path": "/metadata/namespace", "value": "prod"}]`,
+				Target: &corev1alpha1.PostRenderPatchTarget{Kind: "Deployment"},
+			}}},
+			wantErr: true,
+		},
+		{
+			name:       "component changes metadata.namespace",
+			postRender: &corev1alpha1.PostRender{Components: []corev1alpha1.PostRenderComponent{{ConfigMapRef: "move"}}},
+			components: []map[string]string{{
+				"kustomization.yaml": "apiVersion: kustomize.config.k8s.io/v1alpha1\nkind: Component\npatches:\n- path: move.yaml\n  target:\n    kind: Deployment\n",
+				"move.yaml":          "- op: replace\n  path: /metadata/namespace\n  value: prod\n",
+			}},
+			wantErr: true,
+		},
+		{
+			name: "patch removes metadata.namespace",
+			postRender: &corev1alpha1.PostRender{Patches: []corev1alpha1.PostRenderPatch{{
+				Patch:  `[{"op": "remove", "path": "/metadata/namespace"}]`,
+				Target: &corev1alpha1.PostRenderPatchTarget{Kind: "Deployment"},
+			}}},
+			wantErr: true,
+		},
+		{
+			name: "patch renames the resource",
+			postRender: &corev1alpha1.PostRender{Patches: []corev1alpha1.PostRenderPatch{{
+				Patch:  `[{"op": "replace", "path": "/metadata/name", "value": "web"}]`,
+				Target: &corev1alpha1.PostRenderPatchTarget{Kind: "Deployment"},
+			}}},
+			wantErr: true,
+		},
+		{
+			name:       "component adds a cluster-scoped resource",
+			postRender: &corev1alpha1.PostRender{Components: []corev1alpha1.PostRenderComponent{{ConfigMapRef: "admin"}}},
+			components: []map[string]string{{
+				"kustomization.yaml": "apiVersion: kustomize.config.k8s.io/v1alpha1\nkind: Component\nresources:\n- admin.yaml\n",
+				"admin.yaml":         "apiVersion: rbac.authorization.k8s.io/v1\nkind: ClusterRoleBinding\nmetadata:\n  name: admin\nroleRef:\n  apiGroup: rbac.authorization.k8s.io\n  kind: ClusterRole\n  name: cluster-admin\nsubjects:\n- kind: ServiceAccount\n  name: default\n  namespace: default\n",
+			}},
+			wantErr: true,
+		},
+		{
+			name:       "component adds a namespaced resource",
+			postRender: &corev1alpha1.PostRender{Components: []corev1alpha1.PostRenderComponent{{ConfigMapRef: "config"}}},
+			components: []map[string]string{{
+				"kustomization.yaml": "apiVersion: kustomize.config.k8s.io/v1alpha1\nkind: Component\nresources:\n- config.yaml\n",
+				"config.yaml":        "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n  namespace: default\n",
+			}},
+			wantErr: true,
+		},
+		{
+			name:       "invalid component",
+			postRender: &corev1alpha1.PostRender{Components: []corev1alpha1.PostRenderComponent{{ConfigMapRef: "invalid"}}},
+			components: []map[string]string{{"kustomization.yaml": "resources:\n- missing.yaml\n"}},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			namespace := tt.namespace
+			if namespace == "" {
+				namespace = "default"
+			}
+			c := newPostRenderer(nil, nil, nil, nil).withPostRender(namespace, tt.postRender, tt.components)
+			got, err := c.Run(bytes.NewBufferString(deploy))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Run() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if strings.TrimSpace(got.String()) != strings.TrimSpace(tt.want) {
+				t.Errorf("diff: %s", compare.YAMLCmp(tt.want, got.String()))
+			}
+		})
+	}
+}
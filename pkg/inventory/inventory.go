@@ -0,0 +1,174 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"golang.org/x/sync/errgroup"
+	"k8s.io/utils/pointer"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+// Resolver resolves the digest of an image
+type Resolver interface {
+	Resolve(ctx context.Context, image string) (digest string, err error)
+}
+
+// ReportSource provides the vulnerability summary of an image from the reports of a scanner,
+// it returns nil if there is no report of the image.
+type ReportSource interface {
+	Summary(ctx context.Context, image, digest string) (*corev1alpha1.VulnerabilitySummary, error)
+}
+
+// DefaultResolveTimeout limits the time to resolve an image, so an unreachable registry does not block the reconcile
+const DefaultResolveTimeout = 10 * time.Second
+
+var (
+	// ResolveConcurrency is the number of images of a ComponentPlan resolved at the same time
+	ResolveConcurrency = 8
+	// ResolveTotalTimeout limits the time to resolve all the images of a ComponentPlan,
+	// so the time a reconcile is blocked does not grow with the number of images.
+	ResolveTotalTimeout = 30 * time.Second
+)
+
+// RemoteResolver resolves the digest by a HEAD request to the registry,
+// the images referenced by digest are resolved without any request.
+type RemoteResolver struct {
+	Options []remote.Option
+	// Timeout limits the time to resolve each image, DefaultResolveTimeout is used if not set
+	Timeout time.Duration
+}
+
+func (r *RemoteResolver) Resolve(ctx context.Context, image string) (string, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return "", err
+	}
+	if d, ok := ref.(name.Digest); ok {
+		return d.DigestStr(), nil
+	}
+	timeout := r.Timeout
+	if timeout == 0 {
+		timeout = DefaultResolveTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	desc, err := remote.Head(ref, append([]remote.Option{remote.WithContext(ctx)}, r.Options...)...)
+	if err != nil {
+		return "", err
+	}
+	return desc.Digest.String(), nil
+}
+
+// WithKeychain returns a copy of the resolver which authenticates to the registries by the keychain
+func (r *RemoteResolver) WithKeychain(keychain authn.Keychain) *RemoteResolver {
+	options := append([]remote.Option{}, r.Options...)
+	return &RemoteResolver{Options: append(options, remote.WithAuthFromKeychain(keychain)), Timeout: r.Timeout}
+}
+
+// forEachImage runs fn for each image concurrently, at most ResolveConcurrency at the same time,
+// the context passed to fn expires after ResolveTotalTimeout for all the images.
+func forEachImage(ctx context.Context, images []string, fn func(ctx context.Context, i int)) {
+	ctx, cancel := context.WithTimeout(ctx, ResolveTotalTimeout)
+	defer cancel()
+	g := errgroup.Group{}
+	g.SetLimit(ResolveConcurrency)
+	for i := range images {
+		i := i
+		g.Go(func() error {
+			fn(ctx, i)
+			return nil
+		})
+	}
+	_ = g.Wait()
+}
+
+// ResolveDigests resolves the digests of the images to pin them, with the image as the key.
+// The images with a digest already are skipped, it fails if any image can not be resolved.
+func ResolveDigests(ctx context.Context, resolver Resolver, images []string) (map[string]string, error) {
+	resolved := make([]string, len(images))
+	errs := make([]error, len(images))
+	forEachImage(ctx, images, func(ctx context.Context, i int) {
+		if strings.Contains(images[i], "@") {
+			return
+		}
+		resolved[i], errs[i] = resolver.Resolve(ctx, images[i])
+	})
+	digests := make(map[string]string, len(images))
+	var msgs []string
+	for i, image := range images {
+		if errs[i] != nil {
+			msgs = append(msgs, fmt.Sprintf("%s: %s", image, errs[i]))
+			continue
+		}
+		if resolved[i] != "" {
+			digests[image] = resolved[i]
+		}
+	}
+	if len(msgs) > 0 {
+		return nil, fmt.Errorf("failed to resolve image digests: %s", strings.Join(msgs, "; "))
+	}
+	return digests, nil
+}
+
+// Builder builds the inventory of the images with the Resolver and the ReportSource, both are optional.
+type Builder struct {
+	Resolver Resolver
+	Source   ReportSource
+}
+
+// Build returns the inventory of the images in the same order,
+// the failures of an image are recorded in its message instead of failing the whole inventory.
+// The availability of an image is unknown if it is not resolved before ResolveTotalTimeout.
+func (b *Builder) Build(ctx context.Context, images []string) []corev1alpha1.ImageInventory {
+	res := make([]corev1alpha1.ImageInventory, len(images))
+	forEachImage(ctx, images, func(ctx context.Context, i int) {
+		image := images[i]
+		item := corev1alpha1.ImageInventory{Image: image}
+		var msgs []string
+		if b.Resolver != nil {
+			digest, err := b.Resolver.Resolve(ctx, image)
+			if err != nil {
+				msgs = append(msgs, fmt.Sprintf("resolve digest: %s", err))
+			}
+			item.Digest = digest
+			if err == nil || ctx.Err() == nil {
+				item.Available = pointer.Bool(err == nil)
+			}
+		} else if i := strings.LastIndex(image, "@"); i != -1 {
+			item.Digest = image[i+1:]
+		}
+		if b.Source != nil {
+			summary, err := b.Source.Summary(ctx, image, item.Digest)
+			if err != nil {
+				msgs = append(msgs, fmt.Sprintf("get vulnerabilities: %s", err))
+			}
+			item.Vulnerabilities = summary
+		}
+		item.Message = strings.Join(msgs, "; ")
+		res[i] = item
+	})
+	return res
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"k8s.io/utils/pointer"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+func TestRemoteResolver(t *testing.T) {
+	server := httptest.NewServer(registry.New())
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	img, err := random.Image(64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := name.ParseReference(host + "/nginx:1.25")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = remote.Write(ref, img); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := img.Digest()
+
+	tests := []struct {
+		name    string
+		image   string
+		want    string
+		wantErr bool
+	}{
+		{name: "tag", image: host + "/nginx:1.25", want: want.String()},
+		{name: "digest", image: "nginx@sha256:0000000000000000000000000000000000000000000000000000000000000000", want: "sha256:0000000000000000000000000000000000000000000000000000000000000000"},
+		{name: "not found", image: host + "/nginx:1.26", wantErr: true},
+		{name: "invalid", image: "Nginx:", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&RemoteResolver{}).Resolve(context.TODO(), tt.image)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// an unreachable registry does not block longer than the timeout
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer slow.Close()
+	start := time.Now()
+	if _, err = (&RemoteResolver{Timeout: 100 * time.Millisecond}).Resolve(context.TODO(), strings.TrimPrefix(slow.URL, "http://")+"/nginx:1.25"); err == nil {
+		t.Errorf("Resolve() got no error from the slow registry")
+	}
+	if cost := time.Since(start); cost > 5*time.Second {
+		t.Errorf("Resolve() took %s, want it limited by the timeout", cost)
+	}
+}
+
+type fakeResolver map[string]string
+
+func (f fakeResolver) Resolve(_ context.Context, image string) (string, error) {
+	if d, ok := f[image]; ok {
+		return d, nil
+	}
+	return "", errors.New("not found")
+}
+
+type fakeSource map[string]*corev1alpha1.VulnerabilitySummary
+
+func (f fakeSource) Summary(_ context.Context, _, digest string) (*corev1alpha1.VulnerabilitySummary, error) {
+	return f[digest], nil
+}
+
+func TestBuilderBuild(t *testing.T) {
+	summary := &corev1alpha1.VulnerabilitySummary{Source: "fake", High: 1}
+	images := []string{"nginx:1.25", "busybox@sha256:abc", "missing:1.0"}
+	tests := []struct {
+		name    string
+		builder *Builder
+		want    []corev1alpha1.ImageInventory
+	}{
+		{
+			name:    "no resolver and source",
+			builder: &Builder{},
+			want:    []corev1alpha1.ImageInventory{{Image: "nginx:1.25"}, {Image: "busybox@sha256:abc", Digest: "sha256:abc"}, {Image: "missing:1.0"}},
+		},
+		{
+			name:    "resolver and source",
+			builder: &Builder{Resolver: fakeResolver{"nginx:1.25": "sha256:123"}, Source: fakeSource{"sha256:123": summary}},
+			want: []corev1alpha1.ImageInventory{
+				{Image: "nginx:1.25", Digest: "sha256:123", Vulnerabilities: summary, Available: pointer.Bool(true)},
+				{Image: "busybox@sha256:abc", Message: "resolve digest: not found", Available: pointer.Bool(false)},
+				{Image: "missing:1.0", Message: "resolve digest: not found", Available: pointer.Bool(false)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.builder.Build(context.TODO(), images); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Build() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveDigests(t *testing.T) {
+	resolver := fakeResolver{"nginx:1.25": "sha256:123"}
+	tests := []struct {
+		name    string
+		images  []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "resolved", images: []string{"nginx:1.25", "busybox@sha256:abc"}, want: map[string]string{"nginx:1.25": "sha256:123"}},
+		{name: "not found", images: []string{"nginx:1.25", "missing:1.0"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveDigests(context.TODO(), resolver, tt.images)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ResolveDigests() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ResolveDigests() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// blockingResolver resolves the images in fast, and blocks the others until the context is done
+type blockingResolver map[string]string
+
+func (f blockingResolver) Resolve(ctx context.Context, image string) (string, error) {
+	if d, ok := f[image]; ok {
+		return d, nil
+	}
+	<-ctx.Done()
+	return "", ctx.Err()
+}
+
+func TestBuilderBuildDeadline(t *testing.T) {
+	oldTimeout, oldConcurrency := ResolveTotalTimeout, ResolveConcurrency
+	defer func() { ResolveTotalTimeout, ResolveConcurrency = oldTimeout, oldConcurrency }()
+	ResolveTotalTimeout, ResolveConcurrency = 200*time.Millisecond, 2
+
+	images := []string{"nginx:1.25", "slow-a:1.0", "slow-b:1.0", "slow-c:1.0", "slow-d:1.0"}
+	start := time.Now()
+	got := (&Builder{Resolver: blockingResolver{"nginx:1.25": "sha256:123"}}).Build(context.TODO(), images)
+	if cost := time.Since(start); cost > 2*time.Second {
+		t.Errorf("Build() took %s, want it limited by the total timeout", cost)
+	}
+	if len(got) != len(images) {
+		t.Fatalf("Build() got %d images, want %d", len(got), len(images))
+	}
+	want := corev1alpha1.ImageInventory{Image: "nginx:1.25", Digest: "sha256:123", Available: pointer.Bool(true)}
+	if !reflect.DeepEqual(got[0], want) {
+		t.Errorf("Build() = %v, want %v", got[0], want)
+	}
+	for i, item := range got[1:] {
+		if item.Image != images[i+1] || item.Available != nil || item.Message == "" {
+			t.Errorf("Build() = %v, want unknown availability of %s", item, images[i+1])
+		}
+	}
+
+	start = time.Now()
+	if _, err := ResolveDigests(context.TODO(), blockingResolver{}, images); err == nil {
+		t.Errorf("ResolveDigests() got no error after the total timeout")
+	}
+	if cost := time.Since(start); cost > 2*time.Second {
+		t.Errorf("ResolveDigests() took %s, want it limited by the total timeout", cost)
+	}
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/kubebb/core/pkg/utils"
+)
+
+const defaultServiceAccount = "default"
+
+// secretKeychain authenticates to the registries with the docker configs in the image pull secrets
+type secretKeychain map[string]authn.AuthConfig
+
+var _ authn.Keychain = secretKeychain{}
+
+func (k secretKeychain) Resolve(target authn.Resource) (authn.Authenticator, error) {
+	if cfg, ok := k[normalizeRegistry(target.RegistryStr())]; ok {
+		return authn.FromConfig(cfg), nil
+	}
+	return authn.Anonymous, nil
+}
+
+// normalizeRegistry returns the host of the registry in the docker config,
+// which may be an url like https://index.docker.io/v1/
+func normalizeRegistry(registry string) string {
+	registry = strings.TrimPrefix(strings.TrimPrefix(registry, "https://"), "http://")
+	registry = strings.SplitN(registry, "/", 2)[0]
+	switch registry {
+	case "docker.io", "registry-1.docker.io":
+		return "index.docker.io"
+	}
+	return registry
+}
+
+func (k secretKeychain) add(secret *corev1.Secret) error {
+	var auths map[string]authn.AuthConfig
+	switch secret.Type {
+	case corev1.SecretTypeDockerConfigJson:
+		config := struct {
+			Auths map[string]authn.AuthConfig `json:"auths"`
+		}{}
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+			return err
+		}
+		auths = config.Auths
+	case corev1.SecretTypeDockercfg:
+		if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &auths); err != nil {
+			return err
+		}
+	}
+	for registry, cfg := range auths {
+		k[normalizeRegistry(registry)] = cfg
+	}
+	return nil
+}
+
+// PullSecretNames returns the image pull secrets of the workloads in the manifest,
+// including the ones of their ServiceAccounts, which are found in the manifest first, then in the cluster.
+func PullSecretNames(ctx context.Context, c client.Reader, namespace, data string) ([]string, error) {
+	objs, err := utils.SplitYAML([]byte(data))
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	accounts := make(map[string]bool)
+	rendered := make(map[string]*corev1.ServiceAccount)
+	for _, obj := range objs {
+		if obj.GetKind() == "ServiceAccount" && obj.GroupVersionKind().Group == "" {
+			sa := &corev1.ServiceAccount{}
+			if err = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, sa); err != nil {
+				return nil, err
+			}
+			rendered[sa.Name] = sa
+			continue
+		}
+		spec := utils.GetWorkloadPodSpec(obj)
+		if spec == nil {
+			continue
+		}
+		for _, s := range spec.ImagePullSecrets {
+			names[s.Name] = true
+		}
+		account := spec.ServiceAccountName
+		if account == "" {
+			account = defaultServiceAccount
+		}
+		accounts[account] = true
+	}
+	for account := range accounts {
+		sa, ok := rendered[account]
+		if !ok {
+			sa = &corev1.ServiceAccount{}
+			if err = c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: account}, sa); err != nil {
+				if apierrors.IsNotFound(err) {
+					continue
+				}
+				return nil, err
+			}
+		}
+		for _, s := range sa.ImagePullSecrets {
+			names[s.Name] = true
+		}
+	}
+	res := make([]string, 0, len(names))
+	for name := range names {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// PullSecretKeychain returns the keychain of the image pull secrets in the namespace, the missing secrets are ignored
+// like the kubelet does.
+func PullSecretKeychain(ctx context.Context, c client.Reader, namespace string, secrets []string) (authn.Keychain, error) {
+	keychain := secretKeychain{}
+	for _, name := range secrets {
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		if err := keychain.add(secret); err != nil {
+			return nil, err
+		}
+	}
+	return keychain, nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPullSecretNames(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.ServiceAccount{
+			ObjectMeta:       metav1.ObjectMeta{Name: "default", Namespace: "default"},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "default-secret"}},
+		},
+		&corev1.ServiceAccount{
+			ObjectMeta:       metav1.ObjectMeta{Name: "app", Namespace: "default"},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "cluster-app-secret"}},
+		},
+	).Build()
+	manifest := `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: app
+imagePullSecrets:
+- name: app-secret
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      serviceAccountName: app
+      imagePullSecrets:
+      - name: workload-secret
+      containers:
+      - name: app
+        image: nginx:1.25
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: job
+spec:
+  template:
+    spec:
+      containers:
+      - name: job
+        image: busybox:1.36
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  name: missing
+spec:
+  template:
+    spec:
+      serviceAccountName: missing
+      containers:
+      - name: job
+        image: busybox:1.36
+`
+	got, err := PullSecretNames(context.TODO(), c, "default", manifest)
+	if err != nil {
+		t.Fatalf("PullSecretNames() error = %v", err)
+	}
+	if want := []string{"app-secret", "default-secret", "workload-secret"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PullSecretNames() = %v, want %v", got, want)
+	}
+}
+
+func TestPullSecretKeychain(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "dockerhub", Namespace: "default"},
+			Type:       corev1.SecretTypeDockerConfigJson,
+			Data:       map[string][]byte{corev1.DockerConfigJsonKey: []byte(`{"auths":{"https://index.docker.io/v1/":{"auth":"dXNlcjpwYXNz"}}}`)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "private", Namespace: "default"},
+			Type:       corev1.SecretTypeDockercfg,
+			Data:       map[string][]byte{corev1.DockerConfigKey: []byte(`{"registry.local:5000":{"username":"admin","password":"secret"}}`)},
+		},
+	).Build()
+	keychain, err := PullSecretKeychain(context.TODO(), c, "default", []string{"dockerhub", "private", "missing"})
+	if err != nil {
+		t.Fatalf("PullSecretKeychain() error = %v", err)
+	}
+	tests := []struct {
+		name  string
+		image string
+		want  authn.AuthConfig
+	}{
+		{name: "docker hub", image: "nginx:1.25", want: authn.AuthConfig{Username: "user", Password: "pass"}},
+		{name: "private registry", image: "registry.local:5000/app:1.0", want: authn.AuthConfig{Username: "admin", Password: "secret"}},
+		{name: "anonymous", image: "ghcr.io/app:1.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, err := name.ParseReference(tt.image)
+			if err != nil {
+				t.Fatal(err)
+			}
+			auth, err := keychain.Resolve(ref.Context())
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			got, err := auth.Authorization()
+			if err != nil {
+				t.Fatalf("Authorization() error = %v", err)
+			}
+			if got.Username != tt.want.Username || got.Password != tt.want.Password {
+				t.Errorf("Authorization() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"fmt"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/pointer"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+const (
+	// PrePullLabel selects the pods of the pre-pull DaemonSet, the value is the name of the ComponentPlan
+	PrePullLabel = corev1alpha1.Group + "/prepull"
+	// PrePullGenerationAnnotation records the generation of the ComponentPlan whose images are pulled by the DaemonSet
+	PrePullGenerationAnnotation = corev1alpha1.Group + "/prepull-generation"
+
+	prePullVolume    = "prepull"
+	prePullMountPath = "/prepull"
+	// prePullUser runs the containers as nobody, the images only run the busybox true command
+	prePullUser int64 = 65534
+)
+
+var (
+	// PrePullHelperImage provides a static busybox binary which runs in every image to pull,
+	// so the images without any shell can be pulled too.
+	PrePullHelperImage = "busybox:1.36"
+	// PrePullPauseImage keeps the pods of the pre-pull DaemonSet running after the images are pulled
+	PrePullPauseImage = "registry.k8s.io/pause:3.9"
+)
+
+// NewPrePullDaemonSet returns the DaemonSet to pull the images on the nodes. Every image runs as an init container
+// which exits at once, so the pod is ready only when all the images are pulled on its node.
+// The containers run as a non-root user without any capabilities, since the images are not approved yet.
+func NewPrePullDaemonSet(plan *corev1alpha1.ComponentPlan, images, pullSecrets []string) *appsv1.DaemonSet {
+	labels := map[string]string{PrePullLabel: plan.Name}
+	mount := []corev1.VolumeMount{{Name: prePullVolume, MountPath: prePullMountPath}}
+	securityContext := &corev1.SecurityContext{
+		AllowPrivilegeEscalation: pointer.Bool(false),
+		Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+	}
+	initContainers := []corev1.Container{{
+		Name:            "helper",
+		Image:           PrePullHelperImage,
+		Command:         []string{"cp", "/bin/busybox", prePullMountPath + "/busybox"},
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		VolumeMounts:    mount,
+		SecurityContext: securityContext,
+	}}
+	for i, image := range images {
+		initContainers = append(initContainers, corev1.Container{
+			Name:            prePullContainerName(i),
+			Image:           image,
+			Command:         []string{prePullMountPath + "/busybox", "true"},
+			ImagePullPolicy: corev1.PullIfNotPresent,
+			VolumeMounts:    mount,
+			SecurityContext: securityContext,
+		})
+	}
+	secrets := make([]corev1.LocalObjectReference, 0, len(pullSecrets))
+	for _, s := range pullSecrets {
+		secrets = append(secrets, corev1.LocalObjectReference{Name: s})
+	}
+	ds := &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        corev1alpha1.GenerateComponentPlanPrePullName(plan),
+			Namespace:   plan.Namespace,
+			Labels:      labels,
+			Annotations: map[string]string{PrePullGenerationAnnotation: strconv.FormatInt(plan.Generation, 10)},
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					InitContainers: initContainers,
+					Containers: []corev1.Container{{
+						Name:            "pause",
+						Image:           PrePullPauseImage,
+						ImagePullPolicy: corev1.PullIfNotPresent,
+						SecurityContext: securityContext,
+					}},
+					SecurityContext: &corev1.PodSecurityContext{
+						RunAsNonRoot:   pointer.Bool(true),
+						RunAsUser:      pointer.Int64(prePullUser),
+						RunAsGroup:     pointer.Int64(prePullUser),
+						SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+					},
+					ImagePullSecrets: secrets,
+					NodeSelector:     plan.Spec.PrePull.NodeSelector,
+					Tolerations:      plan.Spec.PrePull.Tolerations,
+					Volumes:          []corev1.Volume{{Name: prePullVolume, VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}}},
+				},
+			},
+		},
+	}
+	return ds
+}
+
+func prePullContainerName(i int) string {
+	return fmt.Sprintf("image-%d", i)
+}
+
+// PrePullPhases returns the pre-pull phase of the images by the init container statuses of the pods.
+// An image is pulled when it is pulled on all the pods, and failed when it fails to pull on any pod.
+func PrePullPhases(images []string, pods []corev1.Pod) map[string]corev1alpha1.PrePullPhase {
+	phases := make(map[string]corev1alpha1.PrePullPhase, len(images))
+	for i, image := range images {
+		name := prePullContainerName(i)
+		pulled := len(pods) > 0
+		failed := false
+		for _, pod := range pods {
+			var status *corev1.ContainerStatus
+			for j := range pod.Status.InitContainerStatuses {
+				if pod.Status.InitContainerStatuses[j].Name == name {
+					status = &pod.Status.InitContainerStatuses[j]
+				}
+			}
+			switch {
+			case status == nil:
+				pulled = false
+			case status.State.Running != nil || status.State.Terminated != nil || status.LastTerminationState.Terminated != nil:
+			case status.State.Waiting != nil && isPullFailure(status.State.Waiting.Reason):
+				pulled = false
+				failed = true
+			default:
+				pulled = false
+			}
+		}
+		switch {
+		case failed:
+			phases[image] = corev1alpha1.PrePullFailed
+		case pulled:
+			phases[image] = corev1alpha1.PrePullPulled
+		default:
+			phases[image] = corev1alpha1.PrePullPulling
+		}
+	}
+	return phases
+}
+
+func isPullFailure(reason string) bool {
+	switch reason {
+	case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "ErrImageNeverPull":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+func TestNewPrePullDaemonSet(t *testing.T) {
+	plan := &corev1alpha1.ComponentPlan{ObjectMeta: metav1.ObjectMeta{Name: "plan", Namespace: "default", Generation: 2}}
+	tolerations := []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "app", Effect: corev1.TaintEffectNoSchedule}}
+	plan.Spec.PrePull = &corev1alpha1.PrePull{NodeSelector: map[string]string{"pool": "app"}, Tolerations: tolerations}
+	ds := NewPrePullDaemonSet(plan, []string{"nginx:1.25", "busybox:1.36"}, []string{"secret"})
+	if ds.Name != "prepull.plan" || ds.Namespace != "default" || ds.Annotations[PrePullGenerationAnnotation] != "2" {
+		t.Errorf("NewPrePullDaemonSet() got metadata %v", ds.ObjectMeta)
+	}
+	spec := ds.Spec.Template.Spec
+	images := make([]string, 0)
+	for _, c := range spec.InitContainers {
+		images = append(images, c.Image)
+	}
+	if want := []string{PrePullHelperImage, "nginx:1.25", "busybox:1.36"}; !reflect.DeepEqual(images, want) {
+		t.Errorf("NewPrePullDaemonSet() got init images %v, want %v", images, want)
+	}
+	if spec.NodeSelector["pool"] != "app" || len(spec.ImagePullSecrets) != 1 || spec.ImagePullSecrets[0].Name != "secret" {
+		t.Errorf("NewPrePullDaemonSet() got pod spec %v", spec)
+	}
+	if !reflect.DeepEqual(spec.Tolerations, tolerations) {
+		t.Errorf("NewPrePullDaemonSet() got tolerations %v, want %v", spec.Tolerations, tolerations)
+	}
+	if sc := spec.SecurityContext; sc == nil || sc.RunAsNonRoot == nil || !*sc.RunAsNonRoot ||
+		sc.SeccompProfile == nil || sc.SeccompProfile.Type != corev1.SeccompProfileTypeRuntimeDefault {
+		t.Errorf("NewPrePullDaemonSet() got pod security context %v", sc)
+	}
+	for _, c := range append(spec.InitContainers, spec.Containers...) {
+		sc := c.SecurityContext
+		if sc == nil || sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation ||
+			sc.Capabilities == nil || !reflect.DeepEqual(sc.Capabilities.Drop, []corev1.Capability{"ALL"}) {
+			t.Errorf("NewPrePullDaemonSet() got security context %v of container %s", sc, c.Name)
+		}
+	}
+}
+
+func TestPrePullPhases(t *testing.T) {
+	done := corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}
+	waiting := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "PodInitializing"}}
+	backoff := corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}
+	images := []string{"a", "b"}
+	tests := []struct {
+		name string
+		pods []corev1.Pod
+		want map[string]corev1alpha1.PrePullPhase
+	}{
+		{
+			name: "no pods",
+			want: map[string]corev1alpha1.PrePullPhase{"a": corev1alpha1.PrePullPulling, "b": corev1alpha1.PrePullPulling},
+		},
+		{
+			name: "pulled on one node",
+			pods: []corev1.Pod{
+				{Status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{
+					{Name: prePullContainerName(0), State: done},
+					{Name: prePullContainerName(1), State: done},
+				}}},
+				{Status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{
+					{Name: prePullContainerName(0), State: done},
+					{Name: prePullContainerName(1), State: waiting},
+				}}},
+			},
+			want: map[string]corev1alpha1.PrePullPhase{"a": corev1alpha1.PrePullPulled, "b": corev1alpha1.PrePullPulling},
+		},
+		{
+			name: "failed",
+			pods: []corev1.Pod{
+				{Status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{
+					{Name: prePullContainerName(0), State: done},
+					{Name: prePullContainerName(1), State: backoff},
+				}}},
+				{Status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{
+					{Name: prePullContainerName(0), State: done},
+					{Name: prePullContainerName(1), State: done},
+				}}},
+			},
+			want: map[string]corev1alpha1.PrePullPhase{"a": corev1alpha1.PrePullPulled, "b": corev1alpha1.PrePullFailed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PrePullPhases(images, tt.pods); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PrePullPhases() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
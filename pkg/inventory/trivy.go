@@ -0,0 +1,168 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+const TrivySource = "trivy"
+
+// trivyReport is the part of the trivy json report (trivy image --format json) used to count the vulnerabilities
+type trivyReport struct {
+	ArtifactName string    `json:"ArtifactName"`
+	CreatedAt    time.Time `json:"CreatedAt"`
+	Metadata     struct {
+		RepoTags    []string `json:"RepoTags"`
+		RepoDigests []string `json:"RepoDigests"`
+	} `json:"Metadata"`
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID string `json:"VulnerabilityID"`
+			Severity        string `json:"Severity"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+func (r *trivyReport) match(image, digest string) bool {
+	if r.ArtifactName == image {
+		return true
+	}
+	for _, tag := range r.Metadata.RepoTags {
+		if tag == image {
+			return true
+		}
+	}
+	if digest == "" {
+		return false
+	}
+	for _, d := range r.Metadata.RepoDigests {
+		if strings.HasSuffix(d, "@"+digest) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *trivyReport) summary() *corev1alpha1.VulnerabilitySummary {
+	s := &corev1alpha1.VulnerabilitySummary{Source: TrivySource}
+	if !r.CreatedAt.IsZero() {
+		s.ScannedAt = &metav1.Time{Time: r.CreatedAt}
+	}
+	seen := make(map[string]bool)
+	for _, result := range r.Results {
+		for _, v := range result.Vulnerabilities {
+			// the same vulnerability may be reported by several targets of the image
+			if seen[v.VulnerabilityID] {
+				continue
+			}
+			seen[v.VulnerabilityID] = true
+			switch strings.ToUpper(v.Severity) {
+			case "CRITICAL":
+				s.Critical++
+			case "HIGH":
+				s.High++
+			case "MEDIUM":
+				s.Medium++
+			case "LOW":
+				s.Low++
+			default:
+				s.Unknown++
+			}
+		}
+	}
+	return s
+}
+
+// TrivyReportSource reads the trivy json reports in Dir, a report matches the image by its name, tags or digests.
+// If several reports match, the latest one is used. The reports are parsed once and cached until their files change.
+type TrivyReportSource struct {
+	Dir string
+
+	mu      sync.Mutex
+	reports map[string]*indexedReport // key: path of the report file
+}
+
+// indexedReport is a parsed report file, the vulnerabilities are only kept in the summary
+type indexedReport struct {
+	modTime time.Time
+	size    int64
+	report  *trivyReport // nil if the file is not a trivy report
+	summary *corev1alpha1.VulnerabilitySummary
+}
+
+func (t *TrivyReportSource) Summary(_ context.Context, image, digest string) (*corev1alpha1.VulnerabilitySummary, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := t.index(); err != nil {
+		return nil, err
+	}
+	var latest *indexedReport
+	for _, r := range t.reports {
+		if r.report != nil && r.report.match(image, digest) && (latest == nil || r.report.CreatedAt.After(latest.report.CreatedAt)) {
+			latest = r
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+	return latest.summary.DeepCopy(), nil
+}
+
+// index parses the new and changed report files in Dir and forgets the removed ones
+func (t *TrivyReportSource) index() error {
+	files, err := filepath.Glob(filepath.Join(t.Dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	reports := make(map[string]*indexedReport, len(files))
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return err
+		}
+		if r, ok := t.reports[f]; ok && r.modTime.Equal(info.ModTime()) && r.size == info.Size() {
+			reports[f] = r
+			continue
+		}
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		r := &indexedReport{modTime: info.ModTime(), size: info.Size()}
+		report := &trivyReport{}
+		// not a trivy report is just ignored
+		if err = json.Unmarshal(data, report); err == nil {
+			r.summary = report.summary()
+			report.Results = nil
+			r.report = report
+		}
+		reports[f] = r
+	}
+	t.reports = reports
+	return nil
+}
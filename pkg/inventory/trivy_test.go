@@ -0,0 +1,128 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+const trivyReportTemplate = `{
+  "SchemaVersion": 2,
+  "ArtifactName": "%s",
+  "ArtifactType": "container_image",
+  "CreatedAt": "%s",
+  "Metadata": {"RepoTags": ["%s"], "RepoDigests": ["docker.io/library/nginx@sha256:123"]},
+  "Results": [
+    {"Target": "os", "Vulnerabilities": [
+      {"VulnerabilityID": "CVE-1", "Severity": "CRITICAL"},
+      {"VulnerabilityID": "CVE-2", "Severity": "HIGH"},
+      {"VulnerabilityID": "CVE-3", "Severity": "%s"}
+    ]},
+    {"Target": "app", "Vulnerabilities": [
+      {"VulnerabilityID": "CVE-1", "Severity": "CRITICAL"},
+      {"VulnerabilityID": "CVE-4", "Severity": "UNKNOWN"}
+    ]}
+  ]
+}`
+
+func writeReport(t *testing.T, dir, file, content string) {
+	if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTrivyReportSourceSummary(t *testing.T) {
+	dir := t.TempDir()
+	writeReport(t, dir, "old.json", fmt.Sprintf(trivyReportTemplate, "nginx:1.25", "2023-10-01T00:00:00Z", "nginx:1.25", "LOW"))
+	writeReport(t, dir, "new.json", fmt.Sprintf(trivyReportTemplate, "nginx:1.25", "2023-11-01T00:00:00Z", "docker.io/library/nginx:1.25", "MEDIUM"))
+	writeReport(t, dir, "other.json", `{"foo": [1]}`)
+	writeReport(t, dir, "not-json.txt", `foo`)
+
+	tests := []struct {
+		name       string
+		image      string
+		digest     string
+		wantNil    bool
+		wantMedium int
+		wantLow    int
+	}{
+		{name: "latest report by name", image: "nginx:1.25", wantMedium: 1},
+		{name: "by tag", image: "docker.io/library/nginx:1.25", wantMedium: 1},
+		{name: "by digest", image: "registry.local/nginx:latest", digest: "sha256:123", wantMedium: 1},
+		{name: "no report", image: "busybox:1.36", digest: "sha256:456", wantNil: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&TrivyReportSource{Dir: dir}).Summary(context.TODO(), tt.image, tt.digest)
+			if err != nil {
+				t.Fatalf("Summary() error = %v", err)
+			}
+			if tt.wantNil {
+				if got != nil {
+					t.Errorf("Summary() = %v, want nil", got)
+				}
+				return
+			}
+			if got == nil || got.Source != TrivySource || got.Critical != 1 || got.High != 1 || got.Medium != tt.wantMedium ||
+				got.Low != tt.wantLow || got.Unknown != 1 || got.ScannedAt == nil {
+				t.Errorf("Summary() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestTrivyReportSourceIndex(t *testing.T) {
+	dir := t.TempDir()
+	writeReport(t, dir, "nginx.json", fmt.Sprintf(trivyReportTemplate, "nginx:1.25", "2023-10-01T00:00:00Z", "nginx:1.25", "LOW"))
+	source := &TrivyReportSource{Dir: dir}
+	summary := func() *corev1alpha1.VulnerabilitySummary {
+		t.Helper()
+		got, err := source.Summary(context.TODO(), "nginx:1.25", "")
+		if err != nil {
+			t.Fatalf("Summary() error = %v", err)
+		}
+		return got
+	}
+	if got := summary(); got == nil || got.Low != 1 {
+		t.Fatalf("Summary() = %+v, want the low vulnerability", got)
+	}
+
+	// the changed report is parsed again
+	writeReport(t, dir, "nginx.json", fmt.Sprintf(trivyReportTemplate, "nginx:1.25", "2023-11-01T00:00:00Z", "nginx:1.25", "MEDIUM"))
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(dir, "nginx.json"), later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := summary(); got == nil || got.Low != 0 || got.Medium != 1 {
+		t.Fatalf("Summary() = %+v, want the medium vulnerability of the changed report", got)
+	}
+
+	// the removed report is forgotten
+	if err := os.Remove(filepath.Join(dir, "nginx.json")); err != nil {
+		t.Fatal(err)
+	}
+	if got := summary(); got != nil {
+		t.Fatalf("Summary() = %+v, want nil after the report is removed", got)
+	}
+}
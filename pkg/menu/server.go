@@ -0,0 +1,159 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package menu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+const (
+	// Path is the path of the menu tree endpoint
+	Path = "/menus"
+
+	// RootParam is the query parameter of the root menu of the menu tree endpoint
+	RootParam = "root"
+
+	// UserHeader and RolesHeader carry the name and the Kubernetes groups of the user authenticated by the auth proxy
+	// in front of the endpoint, the proxy overwrites them so the values sent by the client are never used.
+	// The roles of a user are its Kubernetes groups, the groups can be repeated or separated by commas.
+	UserHeader  = "X-Remote-User"
+	RolesHeader = "X-Remote-Group"
+
+	// ModuleBitsKey is the key of the module bits in the ConfigMap of the Handler, its value maps
+	// the users and groups to their module bits, like {"users": {"alice": [1]}, "groups": {"tenant-admin": [1, 3]}}.
+	ModuleBitsKey = "moduleBits"
+)
+
+// moduleBitsMapping is the value of ModuleBitsKey
+type moduleBitsMapping struct {
+	Users  map[string][]int32 `json:"users,omitempty"`
+	Groups map[string][]int32 `json:"groups,omitempty"`
+}
+
+// Handler serves the menu tree filtered by the roles and module bits of the user, like /menus?root=yunti-kubebb-menu.
+// The identity is only read from the headers set by the auth proxy, the endpoint must not be exposed without it.
+// The module bits are resolved from the ConfigMap by the user and its groups, no user has module bits if it is not set.
+type Handler struct {
+	Client     client.Reader
+	Logger     logr.Logger
+	ModuleBits types.NamespacedName
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	name := r.Header.Get(UserHeader)
+	if name == "" {
+		http.Error(w, "no user is authenticated by the auth proxy", http.StatusUnauthorized)
+		return
+	}
+	user := User{Roles: splitParam(r.Header.Values(RolesHeader))}
+	bits, err := h.moduleBits(r.Context(), name, user.Roles)
+	if err != nil {
+		h.Logger.Error(err, "Failed to get module bits", "user", name)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	user.ModuleBits = bits
+
+	menus := &corev1alpha1.MenuList{}
+	if err := h.Client.List(r.Context(), menus); err != nil {
+		h.Logger.Error(err, "Failed to list menus")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(BuildTree(menus.Items, user, r.URL.Query().Get(RootParam))); err != nil {
+		h.Logger.Error(err, "Failed to write menu tree")
+	}
+}
+
+// moduleBits returns the module bits of the user and its groups in the ConfigMap of the Handler
+func (h *Handler) moduleBits(ctx context.Context, name string, groups []string) ([]int32, error) {
+	if h.ModuleBits.Name == "" {
+		return nil, nil
+	}
+	cm := &corev1.ConfigMap{}
+	if err := h.Client.Get(ctx, h.ModuleBits, cm); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	mapping := moduleBitsMapping{}
+	if err := yaml.Unmarshal([]byte(cm.Data[ModuleBitsKey]), &mapping); err != nil {
+		return nil, fmt.Errorf("invalid %s in configmap %s: %w", ModuleBitsKey, h.ModuleBits, err)
+	}
+	bits := append([]int32{}, mapping.Users[name]...)
+	for _, group := range groups {
+		bits = append(bits, mapping.Groups[group]...)
+	}
+	return bits, nil
+}
+
+func splitParam(values []string) []string {
+	res := make([]string, 0)
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				res = append(res, s)
+			}
+		}
+	}
+	return res
+}
+
+// Server serves the Handler on Addr, it is added to the manager as a Runnable
+// and runs on every replica, not only the leader. Addr should be a loopback address behind the auth proxy.
+type Server struct {
+	Addr    string
+	Handler *Handler
+}
+
+func (s *Server) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle(Path, s.Handler)
+	srv := &http.Server{Addr: s.Addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	s.Handler.Logger.Info("Starting menu server", "addr", s.Addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
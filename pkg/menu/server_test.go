@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package menu
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+func TestHandler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1alpha1.AddToScheme(scheme)
+	_ = corev1.AddToScheme(scheme)
+	objs := make([]client.Object, 0)
+	for _, m := range testMenus {
+		m := m
+		objs = append(objs, &m)
+	}
+	moduleBits := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "menu-module-bits", Namespace: "kubebb-system"},
+		Data:       map[string]string{ModuleBitsKey: `{"users": {"alice": [1]}, "groups": {"platform-admin": [3]}}`},
+	}
+	objs = append(objs, moduleBits)
+	h := &Handler{
+		Client:     fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build(),
+		Logger:     log.Log,
+		ModuleBits: types.NamespacedName{Namespace: moduleBits.Namespace, Name: moduleBits.Name},
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		query      string
+		header     http.Header
+		wantStatus int
+		want       []string
+	}{
+		{name: "anonymous", wantStatus: http.StatusUnauthorized},
+		{name: "no roles", header: http.Header{UserHeader: {"bob"}}, wantStatus: http.StatusOK, want: []string{"root", "first", "second"}},
+		{name: "roles and module bits", header: http.Header{UserHeader: {"alice"}, RolesHeader: {"view,platform-admin"}}, wantStatus: http.StatusOK, want: []string{"root", "first", "admin", "admin-child", "module", "second"}},
+		{name: "module bits in headers are ignored", header: http.Header{UserHeader: {"bob"}, "X-Remote-Extra-Module-Bits": {"1"}}, wantStatus: http.StatusOK, want: []string{"root", "first", "second"}},
+		{name: "roles in query are ignored", query: "?role=platform-admin&moduleBit=1", header: http.Header{UserHeader: {"bob"}}, wantStatus: http.StatusOK, want: []string{"root", "first", "second"}},
+		{name: "root", query: "?root=admin", header: http.Header{UserHeader: {"bob"}, RolesHeader: {"platform-admin"}}, wantStatus: http.StatusOK, want: []string{"admin-child"}},
+		{name: "method not allowed", method: http.MethodPost, wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(method, Path+tt.query, nil)
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			h.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ServeHTTP() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			got := make([]*Node, 0)
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("ServeHTTP() got invalid body %s", w.Body.String())
+			}
+			if !reflect.DeepEqual(names(got), tt.want) {
+				t.Errorf("ServeHTTP() = %v, want %v", names(got), tt.want)
+			}
+		})
+	}
+}
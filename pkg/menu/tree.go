@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package menu
+
+import (
+	"sort"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+// User is the identity to filter the menus, only the menus visible to the user are rendered.
+type User struct {
+	Roles      []string
+	ModuleBits []int32
+}
+
+// CanSee returns true if the user has one of the RequiredRoles and one of the RequiredModuleBits of the menu,
+// an empty requirement is satisfied by everyone.
+func (u User) CanSee(spec *corev1alpha1.MenuSpec) bool {
+	if len(spec.RequiredRoles) > 0 && !hasAny(spec.RequiredRoles, u.Roles) {
+		return false
+	}
+	if len(spec.RequiredModuleBits) > 0 && !hasAny(spec.RequiredModuleBits, u.ModuleBits) {
+		return false
+	}
+	return true
+}
+
+func hasAny[T comparable](required, owned []T) bool {
+	for _, r := range required {
+		for _, o := range owned {
+			if r == o {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Node is a resolved Menu in the menu tree
+type Node struct {
+	Name                  string `json:"name"`
+	corev1alpha1.MenuSpec `json:",inline"`
+	Children              []*Node `json:"children,omitempty"`
+}
+
+// BuildTree returns the menu tree under the root menu, or the top level menus if root is empty.
+// The disabled, invalid and invisible menus are not rendered, nor are their children.
+// The nodes of the same level are sorted by Column, RankingInColumn and name.
+func BuildTree(menus []corev1alpha1.Menu, user User, root string) []*Node {
+	nodes := make(map[string]*Node, len(menus))
+	for i := range menus {
+		m := &menus[i]
+		if m.Spec.Disabled || !m.IsValid() || !user.CanSee(&m.Spec) {
+			continue
+		}
+		nodes[m.Name] = &Node{Name: m.Name, MenuSpec: *m.Spec.DeepCopy()}
+	}
+	children := make(map[string][]*Node)
+	for i := range menus {
+		if node, ok := nodes[menus[i].Name]; ok {
+			parent := menus[i].GetParent()
+			children[parent] = append(children[parent], node)
+		}
+	}
+	// the menus in a cycle are invalid, attached only guards against the menus not validated yet
+	attached := make(map[string]bool)
+	var attach func(name string) []*Node
+	attach = func(name string) []*Node {
+		res := make([]*Node, 0, len(children[name]))
+		for _, n := range children[name] {
+			if !attached[n.Name] {
+				attached[n.Name] = true
+				res = append(res, n)
+			}
+		}
+		sortNodes(res)
+		for _, n := range res {
+			n.Children = attach(n.Name)
+		}
+		return res
+	}
+	if _, ok := nodes[root]; !ok && root != "" {
+		return make([]*Node, 0)
+	}
+	attached[root] = true
+	return attach(root)
+}
+
+func sortNodes(nodes []*Node) {
+	sort.Slice(nodes, func(i, j int) bool {
+		a, b := nodes[i], nodes[j]
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		if a.RankingInColumn != b.RankingInColumn {
+			return a.RankingInColumn < b.RankingInColumn
+		}
+		return a.Name < b.Name
+	})
+}
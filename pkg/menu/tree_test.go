@@ -0,0 +1,111 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package menu
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+var testMenus = []corev1alpha1.Menu{
+	{ObjectMeta: metav1.ObjectMeta{Name: "root"}},
+	{ObjectMeta: metav1.ObjectMeta{Name: "second"}, Spec: corev1alpha1.MenuSpec{Parent: "root", Column: 2, RankingInColumn: 1}},
+	{ObjectMeta: metav1.ObjectMeta{Name: "first"}, Spec: corev1alpha1.MenuSpec{Parent: "root", Column: 1, RankingInColumn: 1}},
+	{ObjectMeta: metav1.ObjectMeta{Name: "admin"}, Spec: corev1alpha1.MenuSpec{Parent: "root", Column: 1, RankingInColumn: 2, RequiredRoles: []string{"platform-admin"}}},
+	{ObjectMeta: metav1.ObjectMeta{Name: "admin-child"}, Spec: corev1alpha1.MenuSpec{Parent: "admin", RankingInColumn: 1}},
+	{ObjectMeta: metav1.ObjectMeta{Name: "module"}, Spec: corev1alpha1.MenuSpec{Parent: "root", Column: 1, RankingInColumn: 3, RequiredModuleBits: []int32{2, 3}}},
+	{ObjectMeta: metav1.ObjectMeta{Name: "disabled"}, Spec: corev1alpha1.MenuSpec{Parent: "root", Column: 1, RankingInColumn: 4, Disabled: true}},
+	{
+		ObjectMeta: metav1.ObjectMeta{Name: "invalid"},
+		Spec:       corev1alpha1.MenuSpec{Parent: "root", Column: 1, RankingInColumn: 5},
+		Status: corev1alpha1.MenuStatus{ConditionedStatus: corev1alpha1.ConditionedStatus{
+			Conditions: []corev1alpha1.Condition{corev1alpha1.MenuInvalid(corev1alpha1.MenuReasonSlotConflict, "")},
+		}},
+	},
+}
+
+func names(nodes []*Node) []string {
+	res := make([]string, 0)
+	for _, n := range nodes {
+		res = append(res, n.Name)
+		res = append(res, names(n.Children)...)
+	}
+	return res
+}
+
+func TestUserCanSee(t *testing.T) {
+	tests := []struct {
+		name string
+		user User
+		spec corev1alpha1.MenuSpec
+		want bool
+	}{
+		{name: "no requirement", want: true},
+		{name: "role required", spec: corev1alpha1.MenuSpec{RequiredRoles: []string{"admin"}}},
+		{name: "has role", user: User{Roles: []string{"view", "admin"}}, spec: corev1alpha1.MenuSpec{RequiredRoles: []string{"admin"}}, want: true},
+		{name: "module bit required", user: User{ModuleBits: []int32{1}}, spec: corev1alpha1.MenuSpec{RequiredModuleBits: []int32{2, 3}}},
+		{name: "has one module bit", user: User{ModuleBits: []int32{3}}, spec: corev1alpha1.MenuSpec{RequiredModuleBits: []int32{2, 3}}, want: true},
+		{
+			name: "role and module bit required",
+			user: User{Roles: []string{"admin"}},
+			spec: corev1alpha1.MenuSpec{RequiredRoles: []string{"admin"}, RequiredModuleBits: []int32{2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.CanSee(&tt.spec); got != tt.want {
+				t.Errorf("CanSee() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildTree(t *testing.T) {
+	tests := []struct {
+		name string
+		user User
+		root string
+		want []string
+	}{
+		{name: "anonymous", want: []string{"root", "first", "second"}},
+		{name: "admin", user: User{Roles: []string{"platform-admin"}}, want: []string{"root", "first", "admin", "admin-child", "second"}},
+		{name: "module", user: User{ModuleBits: []int32{3}}, want: []string{"root", "first", "module", "second"}},
+		{name: "subtree", user: User{Roles: []string{"platform-admin"}}, root: "admin", want: []string{"admin-child"}},
+		{name: "invisible subtree", root: "admin", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := names(BuildTree(testMenus, tt.user, tt.root)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BuildTree() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildTreeIgnoresCycle(t *testing.T) {
+	menus := []corev1alpha1.Menu{
+		{ObjectMeta: metav1.ObjectMeta{Name: "a"}, Spec: corev1alpha1.MenuSpec{Parent: "b"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "b"}, Spec: corev1alpha1.MenuSpec{Parent: "a"}},
+	}
+	if got := names(BuildTree(menus, User{}, "a")); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("BuildTree() = %v, want [b]", got)
+	}
+}
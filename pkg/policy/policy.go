@@ -0,0 +1,197 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/types"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/cache"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+	"github.com/kubebb/core/pkg/utils"
+)
+
+const (
+	// ObjectVariable is the variable name of the resource in the expressions of PolicyRule
+	ObjectVariable = "object"
+	// CostLimit limits the cost to evaluate an expression against a resource, so an expensive rule can not hang the reconcile
+	CostLimit = 1000000
+	// InterruptCheckFrequency is the number of the iterations of a comprehension between the checks of the context
+	InterruptCheckFrequency = 100
+
+	programCacheSize = 1024
+	programCacheTTL  = time.Hour
+)
+
+// programs caches the compiled programs of the rules of a Policy by its uid,
+// they are compiled again when the generation of the Policy changes.
+var programs = cache.NewLRUExpireCache(programCacheSize)
+
+type cachedPrograms struct {
+	generation int64
+	programs   []cel.Program
+}
+
+// Compile compiles the expression of the rule into a CEL program
+func Compile(rule *corev1alpha1.PolicyRule) (cel.Program, error) {
+	env, err := cel.NewEnv(cel.Declarations(decls.NewVar(ObjectVariable, decls.Dyn)))
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(rule.Expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("%w %s: %s", corev1alpha1.ErrPolicyRuleInvalid, rule.Name, issues.Err())
+	}
+	if t := ast.ResultType(); t.GetPrimitive() != exprpb.Type_BOOL && t.GetDyn() == nil {
+		return nil, fmt.Errorf("%w %s: expression must return bool", corev1alpha1.ErrPolicyRuleInvalid, rule.Name)
+	}
+	return env.Program(ast, cel.CostLimit(CostLimit), cel.InterruptCheckFrequency(InterruptCheckFrequency))
+}
+
+// compilePolicy returns the programs of the rules of the Policy in order, from the cache if the generation is not changed
+func compilePolicy(p *corev1alpha1.Policy) ([]cel.Program, error) {
+	if v, ok := programs.Get(p.UID); ok {
+		if cached := v.(cachedPrograms); cached.generation == p.Generation {
+			return cached.programs, nil
+		}
+	}
+	prgs := make([]cel.Program, len(p.Spec.Rules))
+	for i := range p.Spec.Rules {
+		prg, err := Compile(&p.Spec.Rules[i])
+		if err != nil {
+			return nil, err
+		}
+		prgs[i] = prg
+	}
+	if p.UID != "" {
+		programs.Add(p.UID, cachedPrograms{generation: p.Generation, programs: prgs}, programCacheTTL)
+	}
+	return prgs, nil
+}
+
+// Validate checks the Policy and compiles the expressions of its rules
+func Validate(p *corev1alpha1.Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	for i := range p.Spec.Rules {
+		if _, err := Compile(&p.Spec.Rules[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Violation is a resource which violates a rule of a Policy
+type Violation struct {
+	Policy   string
+	Rule     string
+	Resource string
+	Message  string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s violates %s/%s: %s", v.Resource, v.Policy, v.Rule, v.Message)
+}
+
+// Evaluate checks every resource in the manifest against the rules of the Policies, the disabled Policies are skipped.
+// The violations are sorted by the Policy, the rule and the resource, it fails if ctx is done during the evaluation.
+func Evaluate(ctx context.Context, policies []corev1alpha1.Policy, manifest string) ([]Violation, error) {
+	objs, err := utils.SplitYAML([]byte(manifest))
+	if err != nil {
+		return nil, err
+	}
+	violations := make([]Violation, 0)
+	for _, p := range policies {
+		if p.Spec.Disabled {
+			continue
+		}
+		prgs, err := compilePolicy(&p)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %w", p.Name, err)
+		}
+		for i := range p.Spec.Rules {
+			rule := &p.Spec.Rules[i]
+			for _, obj := range objs {
+				if !rule.Matches(obj.GroupVersionKind().Group, obj.GetKind()) {
+					continue
+				}
+				msg, ok := check(ctx, prgs[i], rule, obj)
+				if err = ctx.Err(); err != nil {
+					return nil, err
+				}
+				if !ok {
+					violations = append(violations, Violation{Policy: p.Name, Rule: rule.Name, Resource: resourceName(obj), Message: msg})
+				}
+			}
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].Policy != violations[j].Policy {
+			return violations[i].Policy < violations[j].Policy
+		}
+		if violations[i].Rule != violations[j].Rule {
+			return violations[i].Rule < violations[j].Rule
+		}
+		return violations[i].Resource < violations[j].Resource
+	})
+	return violations, nil
+}
+
+// check returns false with the message if the resource violates the rule,
+// the evaluation error like exceeding the CostLimit is a violation too
+func check(ctx context.Context, prg cel.Program, rule *corev1alpha1.PolicyRule, obj *unstructured.Unstructured) (string, bool) {
+	out, _, err := prg.ContextEval(ctx, map[string]interface{}{ObjectVariable: obj.Object})
+	if err != nil {
+		return fmt.Sprintf("%s (evaluation failed: %s)", rule.GetMessage(), err), false
+	}
+	if out.Type() != types.BoolType {
+		return fmt.Sprintf("%s (evaluation returned %s instead of bool)", rule.GetMessage(), out.Type().TypeName()), false
+	}
+	if out != types.True {
+		return rule.GetMessage(), false
+	}
+	return "", true
+}
+
+func resourceName(obj *unstructured.Unstructured) string {
+	if obj.GetNamespace() == "" {
+		return obj.GetKind() + "/" + obj.GetName()
+	}
+	return obj.GetKind() + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// Error is the error of the violations to block the ComponentPlan
+func Error(violations []Violation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	msgs := make([]string, len(violations))
+	for i, v := range violations {
+		msgs[i] = v.String()
+	}
+	return fmt.Errorf("%d policy violations: %s", len(violations), strings.Join(msgs, "; "))
+}
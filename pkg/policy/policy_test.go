@@ -0,0 +1,238 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+const manifest = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: nginx
+  namespace: default
+spec:
+  ports:
+  - port: 80
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: nginx
+  namespace: default
+  labels:
+    app.kubernetes.io/name: nginx
+spec:
+  template:
+    spec:
+      containers:
+      - name: nginx
+        image: docker.io/library/nginx:1.25
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: agent
+  namespace: default
+spec:
+  template:
+    spec:
+      hostNetwork: true
+      containers:
+      - name: agent
+        image: quay.io/agent:1.0
+        securityContext:
+          privileged: true
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: agent
+`
+
+func TestEvaluate(t *testing.T) {
+	workloads := &corev1alpha1.PolicyMatch{APIGroups: []string{"apps"}, Kinds: []string{"Deployment", "DaemonSet"}}
+	privileged := corev1alpha1.PolicyRule{
+		Name:       "no-privileged",
+		Match:      workloads,
+		Expression: `object.spec.template.spec.containers.all(c, !has(c.securityContext) || !has(c.securityContext.privileged) || !c.securityContext.privileged)`,
+		Message:    "privileged containers are not allowed",
+	}
+	labels := corev1alpha1.PolicyRule{
+		Name:       "required-labels",
+		Match:      workloads,
+		Expression: `has(object.metadata.labels) && 'app.kubernetes.io/name' in object.metadata.labels`,
+	}
+	registries := corev1alpha1.PolicyRule{
+		Name:       "allowed-registries",
+		Match:      workloads,
+		Expression: `object.spec.template.spec.containers.all(c, c.image.startsWith('docker.io/'))`,
+		Message:    "registry is not allowed",
+	}
+	hostNetwork := corev1alpha1.PolicyRule{
+		Name:       "no-host-network",
+		Expression: `!object.spec.template.spec.hostNetwork`,
+		Message:    "host network is not allowed",
+	}
+	tests := []struct {
+		name     string
+		policies []corev1alpha1.Policy
+		want     []Violation
+		wantErr  bool
+	}{
+		{
+			name: "no policy",
+			want: []Violation{},
+		},
+		{
+			name:     "disabled policy",
+			policies: []corev1alpha1.Policy{{ObjectMeta: metav1.ObjectMeta{Name: "security"}, Spec: corev1alpha1.PolicySpec{Rules: []corev1alpha1.PolicyRule{privileged}, Disabled: true}}},
+			want:     []Violation{},
+		},
+		{
+			name: "violations of the rules",
+			policies: []corev1alpha1.Policy{
+				{ObjectMeta: metav1.ObjectMeta{Name: "security"}, Spec: corev1alpha1.PolicySpec{Rules: []corev1alpha1.PolicyRule{privileged, registries}}},
+				{ObjectMeta: metav1.ObjectMeta{Name: "labels"}, Spec: corev1alpha1.PolicySpec{Rules: []corev1alpha1.PolicyRule{labels}}},
+			},
+			want: []Violation{
+				{Policy: "labels", Rule: "required-labels", Resource: "DaemonSet/default/agent", Message: labels.Expression},
+				{Policy: "security", Rule: "allowed-registries", Resource: "DaemonSet/default/agent", Message: "registry is not allowed"},
+				{Policy: "security", Rule: "no-privileged", Resource: "DaemonSet/default/agent", Message: "privileged containers are not allowed"},
+			},
+		},
+		{
+			name:     "evaluation errors are violations",
+			policies: []corev1alpha1.Policy{{ObjectMeta: metav1.ObjectMeta{Name: "network"}, Spec: corev1alpha1.PolicySpec{Rules: []corev1alpha1.PolicyRule{hostNetwork}}}},
+			want: []Violation{
+				{Policy: "network", Rule: "no-host-network", Resource: "ClusterRole/agent", Message: "host network is not allowed (evaluation failed: no such key: spec)"},
+				{Policy: "network", Rule: "no-host-network", Resource: "DaemonSet/default/agent", Message: "host network is not allowed"},
+				{Policy: "network", Rule: "no-host-network", Resource: "Deployment/default/nginx", Message: "host network is not allowed (evaluation failed: no such key: hostNetwork)"},
+				{Policy: "network", Rule: "no-host-network", Resource: "Service/default/nginx", Message: "host network is not allowed (evaluation failed: no such key: template)"},
+			},
+		},
+		{
+			name: "invalid rule",
+			policies: []corev1alpha1.Policy{{
+				ObjectMeta: metav1.ObjectMeta{Name: "invalid"},
+				Spec:       corev1alpha1.PolicySpec{Rules: []corev1alpha1.PolicyRule{{Name: "invalid", Expression: "object.spec ==="}}},
+			}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(context.TODO(), tt.policies, manifest)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Evaluate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	if err := Error(nil); err != nil {
+		t.Errorf("Error() = %v, want nil", err)
+	}
+	err := Error([]Violation{
+		{Policy: "security", Rule: "no-privileged", Resource: "DaemonSet/default/agent", Message: "privileged"},
+		{Policy: "labels", Rule: "required-labels", Resource: "ClusterRole/agent", Message: "no labels"},
+	})
+	want := "2 policy violations: DaemonSet/default/agent violates security/no-privileged: privileged; ClusterRole/agent violates labels/required-labels: no labels"
+	if err == nil || err.Error() != want {
+		t.Errorf("Error() = %v, want %v", err, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	type testCase struct {
+		rules []corev1alpha1.PolicyRule
+		err   bool
+	}
+	for _, tc := range []testCase{
+		{rules: []corev1alpha1.PolicyRule{{Name: "a", Expression: "object.kind == 'Pod'"}, {Name: "b", Expression: "has(object.metadata.labels)"}}},
+		{rules: []corev1alpha1.PolicyRule{{Name: "dyn", Expression: "object.spec.enabled"}}},
+		{rules: []corev1alpha1.PolicyRule{{Name: "a", Expression: "true"}, {Name: "a", Expression: "true"}}, err: true},
+		{rules: []corev1alpha1.PolicyRule{{Name: "syntax", Expression: "object.kind =="}}, err: true},
+		{rules: []corev1alpha1.PolicyRule{{Name: "undeclared", Expression: "resource.kind == 'Pod'"}}, err: true},
+		{rules: []corev1alpha1.PolicyRule{{Name: "not bool", Expression: "'Pod'"}}, err: true},
+	} {
+		err := Validate(&corev1alpha1.Policy{Spec: corev1alpha1.PolicySpec{Rules: tc.rules}})
+		if (err != nil) != tc.err {
+			t.Fatalf("Test Failed. rules: %v, expected error: %t, actual: %v", tc.rules, tc.err, err)
+		}
+		if err != nil && !errors.Is(err, corev1alpha1.ErrPolicyRuleInvalid) {
+			t.Fatalf("Test Failed. expected ErrPolicyRuleInvalid, actual: %v", err)
+		}
+	}
+}
+
+func TestEvaluateLimits(t *testing.T) {
+	digits := "[0, 1, 2, 3, 4, 5, 6, 7, 8, 9]"
+	expensive := corev1alpha1.PolicyRule{
+		Name:       "expensive",
+		Expression: fmt.Sprintf("%[1]s.all(a, %[1]s.all(b, %[1]s.all(c, %[1]s.all(d, %[1]s.all(e, %[1]s.all(f, true))))))", digits),
+	}
+	policies := []corev1alpha1.Policy{{ObjectMeta: metav1.ObjectMeta{Name: "cost"}, Spec: corev1alpha1.PolicySpec{Rules: []corev1alpha1.PolicyRule{expensive}}}}
+
+	got, err := Evaluate(context.TODO(), policies, manifest)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if len(got) != 4 || !strings.Contains(got[0].Message, "cost limit exceeded") {
+		t.Errorf("Evaluate() = %v, want violations of the cost limit", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	if _, err = Evaluate(ctx, policies, manifest); !errors.Is(err, context.Canceled) {
+		t.Errorf("Evaluate() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestCompilePolicyCache(t *testing.T) {
+	p := &corev1alpha1.Policy{
+		ObjectMeta: metav1.ObjectMeta{Name: "cache", UID: "cache-uid", Generation: 1},
+		Spec:       corev1alpha1.PolicySpec{Rules: []corev1alpha1.PolicyRule{{Name: "a", Expression: "true"}}},
+	}
+	if _, err := compilePolicy(p); err != nil {
+		t.Fatalf("compilePolicy() error = %v", err)
+	}
+	// the programs of the same generation are cached
+	p.Spec.Rules[0].Expression = "object.spec ==="
+	if _, err := compilePolicy(p); err != nil {
+		t.Errorf("compilePolicy() error = %v, want the cached programs", err)
+	}
+	p.Generation = 2
+	if _, err := compilePolicy(p); !errors.Is(err, corev1alpha1.ErrPolicyRuleInvalid) {
+		t.Errorf("compilePolicy() error = %v, want %v", err, corev1alpha1.ErrPolicyRuleInvalid)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	corev1alpha1 "github.com/kubebb/core/api/v1alpha1"
+)
+
+// log is for logging in this package.
+var policylog = logf.Log.WithName("policy-resource")
+
+// Validator validates the Policies with their expressions compiled,
+// it lives here instead of api/v1alpha1 to keep CEL out of the API package.
+type Validator struct{}
+
+func (v *Validator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&corev1alpha1.Policy{}).
+		WithValidator(v).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-core-kubebb-k8s-com-cn-v1alpha1-policy,mutating=false,failurePolicy=fail,sideEffects=None,groups=core.kubebb.k8s.com.cn,resources=policies,verbs=create;update,versions=v1alpha1,name=vpolicy.kb.io,admissionReviewVersions=v1
+
+var _ webhook.CustomValidator = &Validator{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+func (v *Validator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	policy, ok := obj.(*corev1alpha1.Policy)
+	if !ok {
+		policylog.Error(corev1alpha1.ErrDecode, "obj "+corev1alpha1.ErrDecode.Error())
+		return corev1alpha1.ErrDecode
+	}
+	log := policylog.WithValues("name", policy.Name, "method", "ValidateCreate")
+	if err := Validate(policy); err != nil {
+		log.Info(err.Error())
+		return err
+	}
+	log.Info("validate create done")
+	return nil
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+func (v *Validator) ValidateUpdate(ctx context.Context, oldObj runtime.Object, newObj runtime.Object) error {
+	policy, ok := newObj.(*corev1alpha1.Policy)
+	if !ok {
+		policylog.Error(corev1alpha1.ErrDecode, "newObj "+corev1alpha1.ErrDecode.Error())
+		return corev1alpha1.ErrDecode
+	}
+	log := policylog.WithValues("name", policy.Name, "method", "ValidateUpdate")
+	if err := Validate(policy); err != nil {
+		log.Info(err.Error())
+		return err
+	}
+	log.Info("validate update done")
+	return nil
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+func (v *Validator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
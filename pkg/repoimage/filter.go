@@ -34,6 +34,10 @@ type Filter struct {
 	// e.g. Path: "spec/myContainers[]/image"
 	FsSlice types.FsSlice `json:"fieldSpecs,omitempty" yaml:"fieldSpecs,omitempty"`
 
+	// Digests pins the images to the digests after overriding, with the overridden image as the key,
+	// the images with a digest already are not changed.
+	Digests map[string]string `json:"digests,omitempty" yaml:"digests,omitempty"`
+
 	trackableSetter filtersutil.TrackableSetter
 }
 
@@ -46,11 +50,17 @@ func (f *Filter) WithMutationTracker(callback func(key, value, tag string, node
 }
 
 func (f Filter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
-	_, err := kio.FilterAll(yaml.FilterFunc(f.filter)).Filter(nodes)
+	matchers, err := newMatchers(f.ImageOverride)
+	if err != nil {
+		return nil, err
+	}
+	_, err = kio.FilterAll(yaml.FilterFunc(func(node *yaml.RNode) (*yaml.RNode, error) {
+		return f.filter(node, matchers)
+	})).Filter(nodes)
 	return nodes, err
 }
 
-func (f Filter) filter(node *yaml.RNode) (*yaml.RNode, error) {
+func (f Filter) filter(node *yaml.RNode, matchers []matcher) (*yaml.RNode, error) {
 	// FsSlice is an allowlist, not a denyList, so to deny
 	// something via configuration a new config mechanism is
 	// needed. Until then, hardcode it.
@@ -60,7 +70,8 @@ func (f Filter) filter(node *yaml.RNode) (*yaml.RNode, error) {
 	if err := node.PipeE(fsslice.Filter{
 		FsSlice: f.FsSlice,
 		SetValue: updater{
-			ImageOverride:   f.ImageOverride,
+			matchers:        matchers,
+			Digests:         f.Digests,
 			trackableSetter: f.trackableSetter,
 		}.SetImageValue,
 	}); err != nil {
@@ -514,6 +514,128 @@ spec:
 				},
 			},
 		},
+		"glob match with new path and tag": {
+			input: `
+apiVersion: example.com/v1
+kind: Foo
+metadata:
+  name: instance
+spec:
+  containers:
+  - image: nginx:1.2.1@sha256:46d5b90a7f4e9996351ad893a26bcbd27216676ad4d5316088ce351fb2c2c3dd
+  - image: quay.io/prometheus/node-exporter:v1.6.0
+  - image: docker.io/bitnami/redis:7.0
+`,
+			expectedOutput: `
+apiVersion: example.com/v1
+kind: Foo
+metadata:
+  name: instance
+spec:
+  containers:
+  - image: docker.cc/mirror/nginx:1.25
+  - image: quay.io/prometheus/node-exporter:v1.6.0
+  - image: docker.io/bitnami/redis:7.0
+`,
+			filter: Filter{
+				ImageOverride: []corev1alpha1.ImageOverride{
+					{
+						Match:        &corev1alpha1.ImageMatch{Glob: "docker.io/library/*"},
+						NewRegistry:  "docker.cc",
+						PathOverride: &corev1alpha1.PathOverride{NewPath: "mirror"},
+						NewTag:       "1.25",
+					},
+				},
+			},
+			fsSlice: []types.FieldSpec{
+				{
+					Path: "spec/containers/image",
+				},
+			},
+		},
+		"regex match and the later rule wins": {
+			input: `
+apiVersion: example.com/v1
+kind: Foo
+metadata:
+  name: instance
+spec:
+  containers:
+  - image: quay.io/prometheus/node-exporter:v1.6.0
+  - image: docker.io/bitnami/redis:7.0
+  - image: docker.io/library/nginx:1.25
+`,
+			expectedOutput: `
+apiVersion: example.com/v1
+kind: Foo
+metadata:
+  name: instance
+spec:
+  containers:
+  - image: 192.168.1.1:5000/prometheus/node-exporter:v1.6.0
+  - image: 192.168.1.1:5000/bitnami/redis:7.0
+  - image: docker.cc/library/nginx:1.25
+`,
+			filter: Filter{
+				ImageOverride: []corev1alpha1.ImageOverride{
+					{
+						Match:       &corev1alpha1.ImageMatch{Regex: `(docker|quay)\.io/.*`},
+						NewRegistry: "192.168.1.1:5000",
+					},
+					{
+						Match:       &corev1alpha1.ImageMatch{Regex: `docker\.io/library/nginx`},
+						NewRegistry: "docker.cc",
+					},
+				},
+			},
+			fsSlice: []types.FieldSpec{
+				{
+					Path: "spec/containers/image",
+				},
+			},
+		},
+		"pin digests after override": {
+			input: `
+apiVersion: example.com/v1
+kind: Foo
+metadata:
+  name: instance
+spec:
+  containers:
+  - image: nginx:1.25
+  - image: busybox:1.36
+  - image: redis:7.0@sha256:46d5b90a7f4e9996351ad893a26bcbd27216676ad4d5316088ce351fb2c2c3dd
+`,
+			expectedOutput: `
+apiVersion: example.com/v1
+kind: Foo
+metadata:
+  name: instance
+spec:
+  containers:
+  - image: docker.cc/library/nginx:1.25@sha256:03c1151dfb9695f66e31c93008bc74d7d9870ef29739f6f36a261652b5d266a6
+  - image: busybox:1.36@sha256:affa73a743c5d81bd90fae203ff0ce11a544efd89b63402f7ba19919fe11615d
+  - image: redis:7.0@sha256:46d5b90a7f4e9996351ad893a26bcbd27216676ad4d5316088ce351fb2c2c3dd
+`,
+			filter: Filter{
+				ImageOverride: []corev1alpha1.ImageOverride{
+					{
+						Match:       &corev1alpha1.ImageMatch{Glob: "docker.io/library/nginx"},
+						NewRegistry: "docker.cc",
+					},
+				},
+				Digests: map[string]string{
+					"docker.cc/library/nginx:1.25": "sha256:03c1151dfb9695f66e31c93008bc74d7d9870ef29739f6f36a261652b5d266a6",
+					"busybox:1.36":                 "sha256:affa73a743c5d81bd90fae203ff0ce11a544efd89b63402f7ba19919fe11615d",
+					"redis:7.0":                    "sha256:03c1151dfb9695f66e31c93008bc74d7d9870ef29739f6f36a261652b5d266a6",
+				},
+			},
+			fsSlice: []types.FieldSpec{
+				{
+					Path: "spec/containers/image",
+				},
+			},
+		},
 	}
 
 	for tn, tc := range testCases {
@@ -531,3 +653,37 @@ spec:
 		})
 	}
 }
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		override corev1alpha1.ImageOverride
+		want     bool
+		wantErr  bool
+	}{
+		{name: "registry", override: corev1alpha1.ImageOverride{Registry: "docker.io"}, want: true},
+		{name: "other registry", override: corev1alpha1.ImageOverride{Registry: "quay.io"}},
+		{name: "empty match", override: corev1alpha1.ImageOverride{Registry: "docker.io", Match: &corev1alpha1.ImageMatch{}}},
+		{name: "glob", override: corev1alpha1.ImageOverride{Match: &corev1alpha1.ImageMatch{Glob: "docker.io/*/nginx"}}, want: true},
+		{name: "glob does not match slash", override: corev1alpha1.ImageOverride{Match: &corev1alpha1.ImageMatch{Glob: "docker.io/*"}}},
+		{name: "regex is anchored", override: corev1alpha1.ImageOverride{Match: &corev1alpha1.ImageMatch{Regex: "library/nginx"}}},
+		{name: "glob and regex", override: corev1alpha1.ImageOverride{Match: &corev1alpha1.ImageMatch{Glob: "docker.io/*/nginx", Regex: ".*/library/.*"}}, want: true},
+		{name: "invalid glob", override: corev1alpha1.ImageOverride{Match: &corev1alpha1.ImageMatch{Glob: "docker.io/["}}, wantErr: true},
+		{name: "invalid regex", override: corev1alpha1.ImageOverride{Match: &corev1alpha1.ImageMatch{Regex: "docker.io/("}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matchers, err := newMatchers([]corev1alpha1.ImageOverride{tt.override})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("newMatchers() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got := matchers[0].Match("docker.io", "docker.io/library/nginx"); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
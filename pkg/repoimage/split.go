@@ -49,6 +49,17 @@ func Split(s string) (registry string, path string, remains string, err error) {
 	return
 }
 
+// Name returns the image name without tag and digest from the parts returned by Split, like docker.io/library/nginx
+func Name(registry, path, remains string) string {
+	if i := strings.IndexAny(remains, ":@"); i != -1 {
+		remains = remains[:i]
+	}
+	if path == "" {
+		return registry + "/" + remains
+	}
+	return registry + "/" + path + "/" + remains
+}
+
 const (
 	//	https://github.com/distribution/distribution/blob/6a57630cf40122000083e60bcb7e97c50a904c5e/reference/normalize.go#L31
 	DefaultDomain    = "docker.io"
@@ -17,6 +17,8 @@ limitations under the License.
 package repoimage
 
 import (
+	pathpkg "path"
+	"regexp"
 	"strings"
 
 	"sigs.k8s.io/kustomize/api/filters/filtersutil"
@@ -30,8 +32,9 @@ import (
 // that will update the value of the yaml node based on the provided
 // ImageTag if the current value matches the format of an image reference.
 type updater struct {
-	Kind            string                   `yaml:"kind,omitempty"`
-	ImageOverride   []v1alpha1.ImageOverride `yaml:"imageOverride,omitempty"`
+	Kind            string            `yaml:"kind,omitempty"`
+	Digests         map[string]string `yaml:"digests,omitempty"`
+	matchers        []matcher
 	trackableSetter filtersutil.TrackableSetter
 }
 
@@ -49,25 +52,31 @@ func (u updater) SetImageValue(rn *yaml.RNode) error {
 		// ignore err
 		return nil
 	}
-	var newRegistry, newPath string
-	for _, o := range u.ImageOverride {
-		if registry != o.Registry {
+	name := Name(registry, path, remainers)
+	var newRegistry, newPath, newTag string
+	for _, m := range u.matchers {
+		if !m.Match(registry, name) {
 			continue
 		}
+		o := m.ImageOverride
 		if o.NewRegistry != "" {
 			newRegistry = o.NewRegistry
 		}
+		if o.NewTag != "" {
+			newTag = o.NewTag
+		}
 		if o.PathOverride == nil {
 			continue
 		}
-		if o.PathOverride.Path == path {
+		if o.PathOverride.Path == path || (o.Match != nil && o.PathOverride.Path == "") {
 			newPath = o.PathOverride.NewPath
 			if o.PathOverride.NewPath == "" {
 				newPath = " "
 			}
 		}
 	}
-	if newRegistry != "" || newPath != "" {
+	newValue := value
+	if newRegistry != "" || newPath != "" || newTag != "" {
 		v := []string{registry}
 		if newRegistry != "" {
 			v[0] = newRegistry
@@ -79,12 +88,62 @@ func (u updater) SetImageValue(rn *yaml.RNode) error {
 		} else if path != "" {
 			v = append(v, path)
 		}
+		if newTag != "" {
+			remainers = name[strings.LastIndex(name, "/")+1:] + ":" + newTag
+		}
 		v = append(v, remainers)
-		return u.trackableSetter.SetScalar(strings.Join(v, "/"))(rn)
+		newValue = strings.Join(v, "/")
+	}
+	if digest, ok := u.Digests[newValue]; ok && !strings.Contains(newValue, "@") {
+		newValue += "@" + digest
+	}
+	if newValue != value {
+		return u.trackableSetter.SetScalar(newValue)(rn)
 	}
 	return nil
 }
 
+// matcher is an ImageOverride with the patterns of Match compiled once for all the images
+type matcher struct {
+	v1alpha1.ImageOverride
+	regex *regexp.Regexp
+}
+
+// newMatchers compiles the patterns of the ImageOverrides, it fails if any pattern is invalid
+func newMatchers(overrides []v1alpha1.ImageOverride) ([]matcher, error) {
+	res := make([]matcher, 0, len(overrides))
+	for _, o := range overrides {
+		m := matcher{ImageOverride: o}
+		if o.Match != nil {
+			re, err := o.Match.Compile()
+			if err != nil {
+				return nil, err
+			}
+			m.regex = re
+		}
+		res = append(res, m)
+	}
+	return res, nil
+}
+
+// Match returns whether the ImageOverride matches the image with the registry and the name without tag and digest.
+// The images are matched by Match if it is set, otherwise by Registry.
+func (m matcher) Match(registry, name string) bool {
+	if m.ImageOverride.Match == nil {
+		return registry == m.Registry
+	}
+	if m.ImageOverride.Match.Glob == "" && m.regex == nil {
+		return false
+	}
+	if m.ImageOverride.Match.Glob != "" {
+		// the glob is validated in newMatchers
+		if matched, _ := pathpkg.Match(m.ImageOverride.Match.Glob, name); !matched {
+			return false
+		}
+	}
+	return m.regex == nil || m.regex.MatchString(name)
+}
+
 func (u updater) Filter(rn *yaml.RNode) (*yaml.RNode, error) {
 	if err := u.SetImageValue(rn); err != nil {
 		return nil, err
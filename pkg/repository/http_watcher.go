@@ -323,6 +323,8 @@ func (c *HTTPWatcher) diff(indexFile *hrepo.IndexFile) ([3][]v1alpha1.Component,
 			continue
 		}
 		delete(targetComponentMap, key)
+		// the scores are recorded by the ratings, not from the repository
+		tmp.Status.Scores = component.Status.Scores
 		// If the version lengths of the two are different or a Component in the cluster is marked as deprecated, its status should be updated.
 		if len(component.Status.Versions) != len(tmp.Status.Versions) || component.Status.Deprecated {
 			component.Status = tmp.Status
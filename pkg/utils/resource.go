@@ -124,40 +124,110 @@ func OmitManagedFields(o runtime.Object) runtime.Object {
 func GetCronJobImage(obj *unstructured.Unstructured) (image []string) {
 	cj := batchv1.CronJob{}
 	_ = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &cj)
-	image = ParseContainerImage(cj.Spec.JobTemplate.Spec.Template.Spec.Containers)
-	image = append(image, ParseContainerImage(cj.Spec.JobTemplate.Spec.Template.Spec.InitContainers)...)
-	return image
+	return ParsePodSpecImage(cj.Spec.JobTemplate.Spec.Template.Spec)
 }
 
 func GetJobImage(obj *unstructured.Unstructured) (image []string) {
 	job := batchv1.Job{}
 	_ = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &job)
-	image = ParseContainerImage(job.Spec.Template.Spec.Containers)
-	image = append(image, ParseContainerImage(job.Spec.Template.Spec.InitContainers)...)
-	return image
+	return ParsePodSpecImage(job.Spec.Template.Spec)
 }
 
 func GetStatefulSetImage(obj *unstructured.Unstructured) (image []string) {
 	sts := appsv1.StatefulSet{}
 	_ = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &sts)
-	image = ParseContainerImage(sts.Spec.Template.Spec.Containers)
-	image = append(image, ParseContainerImage(sts.Spec.Template.Spec.InitContainers)...)
-	return image
+	return ParsePodSpecImage(sts.Spec.Template.Spec)
 }
 
 func GetDeploymentImage(obj *unstructured.Unstructured) (image []string) {
 	deploy := appsv1.Deployment{}
 	_ = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &deploy)
-	image = ParseContainerImage(deploy.Spec.Template.Spec.Containers)
-	image = append(image, ParseContainerImage(deploy.Spec.Template.Spec.InitContainers)...)
-	return image
+	return ParsePodSpecImage(deploy.Spec.Template.Spec)
+}
+
+func GetDaemonSetImage(obj *unstructured.Unstructured) (image []string) {
+	ds := appsv1.DaemonSet{}
+	_ = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &ds)
+	return ParsePodSpecImage(ds.Spec.Template.Spec)
+}
+
+func GetReplicaSetImage(obj *unstructured.Unstructured) (image []string) {
+	rs := appsv1.ReplicaSet{}
+	_ = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &rs)
+	return ParsePodSpecImage(rs.Spec.Template.Spec)
 }
 
 func GetPodImage(obj *unstructured.Unstructured) (image []string) {
 	pod := corev1.Pod{}
 	_ = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &pod)
-	image = ParseContainerImage(pod.Spec.Containers)
-	image = append(image, ParseContainerImage(pod.Spec.InitContainers)...)
+	return ParsePodSpecImage(pod.Spec)
+}
+
+// GetWorkloadImage returns the images of the workload object, or nil if the object is not a known workload
+func GetWorkloadImage(obj *unstructured.Unstructured) (image []string) {
+	spec := GetWorkloadPodSpec(obj)
+	if spec == nil {
+		return nil
+	}
+	return ParsePodSpecImage(*spec)
+}
+
+// GetWorkloadPodSpec returns the pod spec of the workload object, or nil if the object is not a known workload
+func GetWorkloadPodSpec(obj *unstructured.Unstructured) *corev1.PodSpec {
+	var template *corev1.PodTemplateSpec
+	gvk := obj.GroupVersionKind()
+	switch gvk.Group {
+	case "":
+		switch gvk.Kind { // nolint
+		case "Pod":
+			pod := corev1.Pod{}
+			_ = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &pod)
+			return &pod.Spec
+		}
+	case "apps":
+		switch gvk.Kind {
+		case "Deployment":
+			deploy := appsv1.Deployment{}
+			_ = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &deploy)
+			template = &deploy.Spec.Template
+		case "StatefulSet":
+			sts := appsv1.StatefulSet{}
+			_ = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &sts)
+			template = &sts.Spec.Template
+		case "DaemonSet":
+			ds := appsv1.DaemonSet{}
+			_ = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &ds)
+			template = &ds.Spec.Template
+		case "ReplicaSet":
+			rs := appsv1.ReplicaSet{}
+			_ = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &rs)
+			template = &rs.Spec.Template
+		}
+	case "batch":
+		switch gvk.Kind {
+		case "Job":
+			job := batchv1.Job{}
+			_ = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &job)
+			template = &job.Spec.Template
+		case "CronJob":
+			cj := batchv1.CronJob{}
+			_ = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &cj)
+			template = &cj.Spec.JobTemplate.Spec.Template
+		}
+	}
+	if template == nil {
+		return nil
+	}
+	return &template.Spec
+}
+
+// ParsePodSpecImage returns the images of the containers, init containers and ephemeral containers of the pod spec
+func ParsePodSpecImage(spec corev1.PodSpec) (image []string) {
+	image = ParseContainerImage(spec.Containers)
+	image = append(image, ParseContainerImage(spec.InitContainers)...)
+	for _, container := range spec.EphemeralContainers {
+		image = append(image, container.Image)
+	}
 	return image
 }
 
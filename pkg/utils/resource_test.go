@@ -0,0 +1,54 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetWorkloadImage(t *testing.T) {
+	podSpec := `
+      initContainers:
+      - name: init
+        image: busybox:1.36
+      containers:
+      - name: app
+        image: nginx:1.25
+      ephemeralContainers:
+      - name: debug
+        image: debian:12`
+	type testCase struct {
+		manifest string
+		exp      []string
+	}
+	for _, tc := range []testCase{
+		{"apiVersion: v1\nkind: Pod\nmetadata:\n  name: a\nspec:" + strings.ReplaceAll(podSpec, "\n    ", "\n"), []string{"nginx:1.25", "busybox:1.36", "debian:12"}},
+		{"apiVersion: apps/v1\nkind: DaemonSet\nmetadata:\n  name: a\nspec:\n  template:\n    spec:" + podSpec, []string{"nginx:1.25", "busybox:1.36", "debian:12"}},
+		{"apiVersion: apps/v1\nkind: ReplicaSet\nmetadata:\n  name: a\nspec:\n  template:\n    spec:" + podSpec, []string{"nginx:1.25", "busybox:1.36", "debian:12"}},
+		{"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: a\ndata:\n  image: nginx:1.25", nil},
+	} {
+		objs, err := SplitYAML([]byte(tc.manifest))
+		if err != nil || len(objs) != 1 {
+			t.Fatalf("failed to parse manifest %s: %v", tc.manifest, err)
+		}
+		if r := GetWorkloadImage(objs[0]); !reflect.DeepEqual(tc.exp, r) {
+			t.Fatalf("expect %v get %v", tc.exp, r)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/getsops/sops/v3/age"
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+	"github.com/getsops/sops/v3/decrypt"
+)
+
+var (
+	ErrSOPSEmptyKey = errors.New("sops age key is empty")
+
+	// sopsMutex serializes the decryptions, because sops reads the age identities from the process environment
+	sopsMutex sync.Mutex
+)
+
+// DecryptSOPSValues decrypts a sops encrypted yaml document with the age identities in key,
+// the metadata rules of sops like the unencrypted suffix and the mac are handled by sops itself.
+func DecryptSOPSValues(data []byte, key []byte) ([]byte, error) {
+	identities := strings.TrimSpace(string(key))
+	if identities == "" {
+		return nil, ErrSOPSEmptyKey
+	}
+	sopsMutex.Lock()
+	defer sopsMutex.Unlock()
+	old, ok := os.LookupEnv(age.SopsAgeKeyEnv)
+	if err := os.Setenv(age.SopsAgeKeyEnv, identities); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if ok {
+			_ = os.Setenv(age.SopsAgeKeyEnv, old)
+		} else {
+			_ = os.Unsetenv(age.SopsAgeKeyEnv)
+		}
+	}()
+	return decrypt.DataWithFormat(data, formats.Yaml)
+}
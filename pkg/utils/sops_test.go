@@ -0,0 +1,139 @@
+/*
+Copyright 2023 The Kubebb Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/getsops/sops/v3"
+	"github.com/getsops/sops/v3/aes"
+	sopsage "github.com/getsops/sops/v3/age"
+	"github.com/getsops/sops/v3/cmd/sops/common"
+	"github.com/getsops/sops/v3/cmd/sops/formats"
+	"github.com/getsops/sops/v3/config"
+	"github.com/getsops/sops/v3/keys"
+	"github.com/getsops/sops/v3/version"
+	"sigs.k8s.io/yaml"
+)
+
+// encryptSOPSValues encrypts the yaml document for the age identity like sops does, only used in tests
+func encryptSOPSValues(t *testing.T, identity *age.X25519Identity, plain string, metadata sops.Metadata) string {
+	key, err := sopsage.MasterKeyFromRecipient(identity.Recipient().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := common.StoreForFormat(formats.Yaml, config.NewStoresConfig())
+	branches, err := store.LoadPlainFile([]byte(plain))
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata.KeyGroups = []sops.KeyGroup{[]keys.MasterKey{key}}
+	metadata.Version = version.Version
+	tree := sops.Tree{Branches: branches, Metadata: metadata}
+	dataKey, errs := tree.GenerateDataKey()
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if err = common.EncryptTree(common.EncryptTreeOpts{DataKey: dataKey, Tree: &tree, Cipher: aes.NewCipher()}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := store.EmitEncryptedFile(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestDecryptSOPSValues(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := `
+image:
+  tag: v1
+password: secret
+replicas: 3
+debug: true
+hosts:
+- a.example.com
+`
+	exp := map[string]interface{}{
+		"image":    map[string]interface{}{"tag": "v1"},
+		"password": "secret",
+		"replicas": float64(3),
+		"debug":    true,
+		"hosts":    []interface{}{"a.example.com"},
+	}
+	testCases := []struct {
+		name     string
+		metadata sops.Metadata
+		plain    string
+	}{
+		{name: "all values encrypted", plain: "replicas"},
+		{name: "unencrypted suffix", metadata: sops.Metadata{UnencryptedSuffix: "_unencrypted"}, plain: "image"},
+		{name: "encrypted regex", metadata: sops.Metadata{EncryptedRegex: "^password$"}, plain: "replicas"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			input := plain
+			if tc.metadata.UnencryptedSuffix != "" {
+				input = strings.Replace(plain, "image:", "image_unencrypted:", 1)
+			}
+			encrypted := encryptSOPSValues(t, identity, input, tc.metadata)
+			if strings.Contains(encrypted, "secret") {
+				t.Fatalf("password is not encrypted: %s", encrypted)
+			}
+			out, err := DecryptSOPSValues([]byte(encrypted), []byte(identity.String()+"\n"))
+			if err != nil {
+				t.Fatalf("expect no error get %v", err)
+			}
+			got := make(map[string]interface{})
+			if err = yaml.Unmarshal(out, &got); err != nil {
+				t.Fatal(err)
+			}
+			if v, ok := got["image_unencrypted"]; ok {
+				delete(got, "image_unencrypted")
+				got["image"] = v
+			}
+			if !reflect.DeepEqual(exp, got) {
+				t.Fatalf("expect %v get %v", exp, got)
+			}
+			if _, err = DecryptSOPSValues([]byte(encrypted), []byte(other.String())); err == nil {
+				t.Fatalf("expect error with another age key get nil")
+			}
+		})
+	}
+
+	// the plain value is tampered, the mac does not match
+	encrypted := encryptSOPSValues(t, identity, plain, sops.Metadata{UnencryptedSuffix: "_unencrypted"})
+	tampered := strings.Replace(encrypted, "hosts:", "extra_unencrypted: true\nhosts:", 1)
+	if _, err = DecryptSOPSValues([]byte(tampered), []byte(identity.String())); err == nil {
+		t.Fatalf("expect mac mismatch error get nil")
+	}
+	if _, err = DecryptSOPSValues([]byte(encrypted), []byte(" \n")); !errors.Is(err, ErrSOPSEmptyKey) {
+		t.Fatalf("expect %v get %v", ErrSOPSEmptyKey, err)
+	}
+}